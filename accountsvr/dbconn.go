@@ -7,10 +7,18 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
-type databaseConnection interface {
+// DatabaseConnection is the connection to the database server, it is satisfied by *pgx.Conn.
+type DatabaseConnection interface {
 	Config() *pgx.ConnConfig
 	Close(ctx context.Context) error
 	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 	IsClosed() bool
 }
+
+// ConnectFunc returns a connection to the database server using the config.
+type ConnectFunc func(ctx context.Context, config *pgx.ConnConfig) (DatabaseConnection, error)
+
+func pgxConnect(ctx context.Context, config *pgx.ConnConfig) (DatabaseConnection, error) {
+	return pgx.ConnectConfig(ctx, config)
+}
@@ -7,20 +7,44 @@ import (
 	corev1 "k8s.io/api/core/v1"
 )
 
+// Credentials are the login credentials of a role, the verifier is the SCRAM-SHA-256 verifier the
+// server stores for the password so the relay can log in to the server with the same verifier.
+type Credentials struct {
+	Username string
+	Password string
+	Verifier string
+}
+
 type Server interface {
 	Connect(ctx context.Context) error
 	Close(ctx context.Context) error
 	ListUsers(ctx context.Context) []string
 	IsRole(ctx context.Context, roleName string) (bool, error)
+	IsManagedRole(ctx context.Context, roleName string) (bool, error)
 	IsDatabase(ctx context.Context, dbName string) (string, bool, error)
-	CreateRole(ctx context.Context, roleName string) (string, string, error)
-	UpdateRolePassword(ctx context.Context, roleName string) (string, string, error)
+	CreateRole(ctx context.Context, roleName string) (Credentials, error)
+	UpdateRolePassword(ctx context.Context, roleName string) (Credentials, error)
+	VerifyRolePassword(ctx context.Context, roleName, password string) (bool, error)
+	AlterRole(ctx context.Context, roleName string, role *dbov1.DatabaseAccountRole) error
+	GrantRoles(ctx context.Context, roleName string, memberOf []string) error
+	RevokeRoles(ctx context.Context, roleName string, memberOf []string) error
+	SetRoleSettings(ctx context.Context, roleName string, settings map[string]string, reset []string) error
+	SetDatabaseSettings(ctx context.Context, dbName string, settings map[string]string, reset []string) error
+	SyncExtensions(
+		ctx context.Context,
+		dbName string,
+		extensions []dbov1.DatabaseAccountExtension,
+	) (map[string]string, error)
+	CreateMemberRole(ctx context.Context, roleName, ownerRole string) (Credentials, error)
+	SetRoleLogin(ctx context.Context, roleName string, login bool) error
+	DeleteRole(ctx context.Context, roleName string) error
 	CreateDatabase(ctx context.Context, dbName, roleName string) (string, error)
-	CreateSchema(ctx context.Context, schemaName, roleName string) error
+	CreateSchema(ctx context.Context, dbName, schemaName, roleName string) error
 	GetDatabaseHostConfig() string
+	GetDatabasePortConfig() uint16
 	GetDatabaseHost(dbAccount *dbov1.DatabaseAccount) string
 	CopyInitConfigToSecret(dbAccount *dbov1.DatabaseAccount, secret *corev1.Secret)
-	Delete(ctx context.Context, name string) error
+	Delete(ctx context.Context, dbName, roleName string) error
 
 	// connect(ctx context.Context) error
 	// generatePassword(ctx context.Context) string
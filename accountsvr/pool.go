@@ -0,0 +1,98 @@
+package accountsvr
+
+import (
+	"context"
+	"sync"
+
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	"go.uber.org/multierr"
+)
+
+// ServerFactory returns a Server connected to the supplied DSN.
+type ServerFactory func(ctx context.Context, dsn dbov1.PostgreSQLDSN) (Server, error)
+
+// Pool holds a Server for each DatabaseServer resource, keyed by the resource name.
+type Pool struct {
+	mu      sync.Mutex
+	factory ServerFactory
+	servers map[string]poolEntry
+}
+
+type poolEntry struct {
+	dsn dbov1.PostgreSQLDSN
+	svr Server
+}
+
+// NewPool returns a Pool that uses the factory to create new servers.
+func NewPool(factory ServerFactory) *Pool {
+	return &Pool{
+		factory: factory,
+		servers: map[string]poolEntry{},
+	}
+}
+
+// NewDatabaseServerPool returns a Pool that creates DatabaseServer connections.
+func NewDatabaseServerPool() *Pool {
+	return NewPool(func(ctx context.Context, dsn dbov1.PostgreSQLDSN) (Server, error) {
+		s, err := NewDatabaseServer(ctx, dsn)
+		if err != nil {
+			return nil, err
+		}
+
+		return s, nil
+	})
+}
+
+// Get returns the Server for the key, a new Server is created if there is none
+// or the DSN has changed since it was created.
+func (p *Pool) Get(ctx context.Context, key string, dsn dbov1.PostgreSQLDSN) (Server, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e, ok := p.servers[key]; ok {
+		if e.dsn == dsn {
+			return e.svr, nil
+		}
+
+		_ = e.svr.Close(ctx)
+		delete(p.servers, key)
+	}
+
+	svr, err := p.factory(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	p.servers[key] = poolEntry{dsn: dsn, svr: svr}
+
+	return svr, nil
+}
+
+// Remove closes and removes the Server for the key.
+func (p *Pool) Remove(ctx context.Context, key string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e, ok := p.servers[key]
+	if !ok {
+		return nil
+	}
+
+	delete(p.servers, key)
+
+	return e.svr.Close(ctx)
+}
+
+// Close closes all servers in the pool.
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var returnErr error
+	for key, e := range p.servers {
+		returnErr = multierr.Append(returnErr, e.svr.Close(ctx))
+		delete(p.servers, key)
+	}
+
+	return returnErr
+}
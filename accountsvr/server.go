@@ -4,32 +4,48 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	dbov1 "github.com/dosquad/database-operator/api/v1"
 	"github.com/dosquad/database-operator/internal/helper"
 	"github.com/dosquad/database-operator/internal/valid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"go.uber.org/multierr"
 	corev1 "k8s.io/api/core/v1"
 	logr "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 var (
-	ErrRoleExists = errors.New("role already exists")
+	ErrRoleExists     = errors.New("role already exists")
+	ErrRoleNotManaged = errors.New("role exists and is not managed by the operator")
 )
 
+// ManagedRoleComment is the comment added to roles created by the operator.
+const ManagedRoleComment = "managed by database-operator"
+
+// authFailedCodes are the SQLSTATE codes returned when a login is rejected, invalid_password and
+// invalid_authorization_specification.
+//
+//nolint:gochecknoglobals // constant list of error codes.
+var authFailedCodes = []string{"28P01", "28000"}
+
 type DatabaseServer struct {
-	connString dbov1.PostgreSQLDSN
-	conn       databaseConnection
+	connString  dbov1.PostgreSQLDSN
+	conn        DatabaseConnection
+	connectFunc ConnectFunc
 }
 
 const (
 	DatabaseKeyDSN            = "dsn"
 	DatabaseKeyUsername       = "username"
 	DatabaseKeyPassword       = "password"
+	DatabaseKeyVerifier       = "verifier"
 	DatabaseKeyHost           = "host"
 	DatabaseKeyPort           = "port"
 	DatabaseKeySchema         = "schema"
@@ -37,11 +53,19 @@ const (
 	DatabaseKeyOnDelete       = "onDelete"
 	DatabaseKeyPGBouncerConf  = "pgbouncer.ini"
 	DatabaseKeyPGBouncerUsers = "userlist.txt"
+	DatabaseKeyPgCatConf      = "pgcat.toml"
+	DatabaseKeyOdysseyConf    = "odyssey.conf"
+	DatabaseKeyCACert         = "ca.crt"
+	DatabaseKeySSLMode        = "sslmode"
+
+	DatabaseKeyRelayAdminPassword = "relay-admin-password"
+	DatabaseKeyRelayAdminVerifier = "relay-admin-verifier"
 )
 
 func NewDatabaseServer(ctx context.Context, connString dbov1.PostgreSQLDSN) (*DatabaseServer, error) {
 	s := &DatabaseServer{
-		connString: connString,
+		connString:  connString,
+		connectFunc: pgxConnect,
 	}
 
 	return s, s.connect(ctx)
@@ -51,16 +75,44 @@ func NewDatabaseServer(ctx context.Context, connString dbov1.PostgreSQLDSN) (*Da
 func NewDatabaseServerWithMock(
 	_ context.Context,
 	connString dbov1.PostgreSQLDSN,
-	conn databaseConnection,
+	conn DatabaseConnection,
 ) (*DatabaseServer, error) {
 	s := &DatabaseServer{
-		connString: connString,
-		conn:       conn,
+		connString:  connString,
+		conn:        conn,
+		connectFunc: pgxConnect,
 	}
 
 	return s, nil
 }
 
+// WithConnectFunc sets the function used to connect to the databases on the server.
+func (s *DatabaseServer) WithConnectFunc(f ConnectFunc) *DatabaseServer {
+	s.connectFunc = f
+
+	return s
+}
+
+// connectDatabase returns a new connection to the database on the server, the caller is
+// responsible for closing the connection.
+func (s *DatabaseServer) connectDatabase(ctx context.Context, dbName string) (DatabaseConnection, error) {
+	{
+		var err error
+		dbName, err = valid.PGIdentifier(dbName).Validate()
+		if err != nil {
+			return nil, fmt.Errorf("database name[%s]: %w", dbName, err)
+		}
+	}
+
+	config, err := pgx.ParseConfig(s.connString.String())
+	if err != nil {
+		return nil, err
+	}
+	config.Database = dbName
+
+	return s.connectFunc(ctx, config)
+}
+
 // func (s *DatabaseServer) CheckInvalidName(name string) (string, error) {
 // 	name = nameRegex.ReplaceAllString(name, "")
 
@@ -148,13 +200,14 @@ func (s *DatabaseServer) ListUsers(ctx context.Context) []string {
 	return o
 }
 
+// IsRole returns true if the role exists, pg_roles is used as pg_user only lists roles that can login.
 func (s *DatabaseServer) IsRole(ctx context.Context, roleName string) (bool, error) {
 	_ = s.Connect(ctx)
 
 	var rows pgx.Rows
 	{
 		var err error
-		rows, err = s.conn.Query(ctx, `select usename from pg_catalog.pg_user where usename=$1`, roleName)
+		rows, err = s.conn.Query(ctx, `select rolname from pg_catalog.pg_roles where rolname=$1`, roleName)
 		if err != nil {
 			return false, err
 		}
@@ -188,43 +241,101 @@ func (s *DatabaseServer) IsDatabase(ctx context.Context, dbName string) (string,
 	return dbName, rows.Next(), nil
 }
 
-func (s *DatabaseServer) CreateRole(ctx context.Context, roleName string) (string, string, error) {
+func (s *DatabaseServer) CreateRole(ctx context.Context, roleName string) (Credentials, error) {
 	_ = s.Connect(ctx)
 	// logger := logr.FromContext(ctx)
 
 	if v, err := s.IsRole(ctx, roleName); err != nil || v {
 		if v {
-			return "", "", ErrRoleExists
+			return Credentials{}, ErrRoleExists
 		}
-		return "", "", err
+		return Credentials{}, err
 	}
 
 	{
 		var err error
 		roleName, err = valid.PGIdentifier(roleName).Validate()
 		if err != nil {
-			return "", "", fmt.Errorf("role name[%s]: %w", roleName, err)
+			return Credentials{}, fmt.Errorf("role name[%s]: %w", roleName, err)
 		}
 	}
 
-	password := helper.GeneratePassword(ctx)
+	creds, err := newCredentials(ctx, roleName)
+	if err != nil {
+		return Credentials{}, err
+	}
 
 	stmt := fmt.Sprintf(
 		`CREATE ROLE %s LOGIN PASSWORD %s`,
 		valid.PGIdentifier(roleName).Sanitize(),
-		valid.PGValue(password).Sanitize(),
+		valid.PGValue(creds.Verifier).Sanitize(),
 	)
 	// stmt := `CREATE ROLE $1 LOGIN PASSWORD $2`
 	// logger.V(1).Info(fmt.Sprintf("SQL: %s (%s, %s)", stmt, roleName, password))
 	// if _, err := s.conn.Exec(ctx, stmt, roleName, password); err != nil {
 	if _, err := s.conn.Exec(ctx, stmt); err != nil {
-		return "", "", err
+		return Credentials{}, err
+	}
+
+	stmt = fmt.Sprintf(
+		`COMMENT ON ROLE %s IS %s`,
+		valid.PGIdentifier(roleName).Sanitize(),
+		valid.PGValue(ManagedRoleComment).Sanitize(),
+	)
+	if _, err := s.conn.Exec(ctx, stmt); err != nil {
+		return Credentials{}, err
+	}
+
+	return creds, nil
+}
+
+// newCredentials returns a generated password for the role and its SCRAM-SHA-256 verifier, the server
+// stores the verifier as is so the password is never sent to the server or written to its logs.
+func newCredentials(ctx context.Context, roleName string) (Credentials, error) {
+	password := helper.GeneratePassword(ctx)
+	verifier, err := helper.ScramSHA256Verifier(password)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("role name[%s]: %w", roleName, err)
+	}
+
+	return Credentials{Username: roleName, Password: password, Verifier: verifier}, nil
+}
+
+// IsManagedRole returns true if the role was created by the operator, roles created before the
+// managed comment was added are identified by the resource prefix.
+func (s *DatabaseServer) IsManagedRole(ctx context.Context, roleName string) (bool, error) {
+	_ = s.Connect(ctx)
+
+	if strings.HasPrefix(roleName, helper.DatabaseResourcePrefix) {
+		return true, nil
+	}
+
+	var rows pgx.Rows
+	{
+		var err error
+		rows, err = s.conn.Query(ctx,
+			`select shobj_description(oid, 'pg_authid') from pg_catalog.pg_roles where rolname=$1`,
+			roleName,
+		)
+		if err != nil {
+			return false, err
+		}
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return false, nil
 	}
 
-	return roleName, password, nil
+	var comment *string
+	if err := rows.Scan(&comment); err != nil {
+		return false, err
+	}
+
+	return comment != nil && *comment == ManagedRoleComment, nil
 }
 
-func (s *DatabaseServer) UpdateRolePassword(ctx context.Context, roleName string) (string, string, error) {
+func (s *DatabaseServer) UpdateRolePassword(ctx context.Context, roleName string) (Credentials, error) {
 	_ = s.Connect(ctx)
 	// logger := logr.FromContext(ctx)
 
@@ -232,24 +343,411 @@ func (s *DatabaseServer) UpdateRolePassword(ctx context.Context, roleName string
 		var err error
 		roleName, err = valid.PGIdentifier(roleName).Validate()
 		if err != nil {
-			return "", "", fmt.Errorf("role name[%s]: %w", roleName, err)
+			return Credentials{}, fmt.Errorf("role name[%s]: %w", roleName, err)
 		}
 	}
 
-	password := helper.GeneratePassword(ctx)
+	creds, err := newCredentials(ctx, roleName)
+	if err != nil {
+		return Credentials{}, err
+	}
 
 	stmt := fmt.Sprintf(`ALTER ROLE %s LOGIN PASSWORD %s`,
 		valid.PGIdentifier(roleName).Sanitize(),
-		valid.PGValue(password).Sanitize(),
+		valid.PGValue(creds.Verifier).Sanitize(),
 	)
 	// stmt := `ALTER ROLE $1 LOGIN PASSWORD $2`
 	// logger.V(1).Info(fmt.Sprintf("SQL: %s (%s, %s)", stmt, roleName, password))
 	// if _, err := s.conn.Exec(ctx, `ALTER ROLE $1 LOGIN PASSWORD $2`, roleName, password); err != nil {
 	if _, err := s.conn.Exec(ctx, stmt); err != nil {
-		return "", "", err
+		return Credentials{}, err
+	}
+
+	return creds, nil
+}
+
+// VerifyRolePassword returns true if the role can login to the server with the password, a
+// failed authentication is not an error.
+func (s *DatabaseServer) VerifyRolePassword(ctx context.Context, roleName, password string) (bool, error) {
+	{
+		var err error
+		roleName, err = valid.PGIdentifier(roleName).Validate()
+		if err != nil {
+			return false, fmt.Errorf("role name[%s]: %w", roleName, err)
+		}
+	}
+
+	config, err := pgx.ParseConfig(s.connString.String())
+	if err != nil {
+		return false, err
+	}
+	config.User = roleName
+	config.Password = password
+
+	conn, err := s.connectFunc(ctx, config)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && slices.Contains(authFailedCodes, pgErr.Code) {
+			return false, nil
+		}
+
+		return false, err
+	}
+	defer conn.Close(ctx)
+
+	return true, nil
+}
+
+// AlterRole sets the attributes of the role, a nil role resets the attributes to the defaults.
+func (s *DatabaseServer) AlterRole(ctx context.Context, roleName string, role *dbov1.DatabaseAccountRole) error {
+	_ = s.Connect(ctx)
+
+	{
+		var err error
+		roleName, err = valid.PGIdentifier(roleName).Validate()
+		if err != nil {
+			return fmt.Errorf("role name[%s]: %w", roleName, err)
+		}
+	}
+
+	validUntil := "infinity"
+	if role != nil && role.ValidUntil != nil {
+		validUntil = role.ValidUntil.UTC().Format(time.RFC3339)
+	}
+
+	attrs := []string{
+		"CONNECTION LIMIT " + strconv.FormatInt(int64(role.GetConnectionLimit()), 10),
+		"VALID UNTIL " + valid.PGValue(validUntil).Sanitize(),
+		roleAttribute(role != nil && role.CreateDB, "CREATEDB"),
+		roleAttribute(role.GetInherit(), "INHERIT"),
+		roleAttribute(role != nil && role.BypassRLS, "BYPASSRLS"),
+	}
+
+	stmt := fmt.Sprintf(`ALTER ROLE %s WITH %s`,
+		valid.PGIdentifier(roleName).Sanitize(),
+		strings.Join(attrs, " "),
+	)
+	if _, err := s.conn.Exec(ctx, stmt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// roleAttribute returns the role attribute or the negated attribute if not enabled.
+func roleAttribute(enabled bool, attr string) string {
+	if enabled {
+		return attr
+	}
+
+	return "NO" + attr
+}
+
+// GrantRoles grants membership of the roles to the role.
+func (s *DatabaseServer) GrantRoles(ctx context.Context, roleName string, memberOf []string) error {
+	return s.execRoleMembership(ctx, `GRANT %s TO %s`, roleName, memberOf)
+}
+
+// RevokeRoles revokes membership of the roles from the role.
+func (s *DatabaseServer) RevokeRoles(ctx context.Context, roleName string, memberOf []string) error {
+	return s.execRoleMembership(ctx, `REVOKE %s FROM %s`, roleName, memberOf)
+}
+
+func (s *DatabaseServer) execRoleMembership(ctx context.Context, format, roleName string, memberOf []string) error {
+	if len(memberOf) == 0 {
+		return nil
+	}
+
+	_ = s.Connect(ctx)
+
+	{
+		var err error
+		roleName, err = valid.PGIdentifier(roleName).Validate()
+		if err != nil {
+			return fmt.Errorf("role name[%s]: %w", roleName, err)
+		}
+	}
+
+	roles := make([]string, 0, len(memberOf))
+	for _, name := range memberOf {
+		name, err := valid.PGIdentifier(name).Validate()
+		if err != nil {
+			return fmt.Errorf("member of role name[%s]: %w", name, err)
+		}
+
+		roles = append(roles, valid.PGIdentifier(name).Sanitize())
+	}
+
+	stmt := fmt.Sprintf(format, strings.Join(roles, ", "), valid.PGIdentifier(roleName).Sanitize())
+	if _, err := s.conn.Exec(ctx, stmt); err != nil {
+		return err
 	}
 
-	return roleName, password, nil
+	return nil
+}
+
+// SetRoleSettings sets the run-time parameters of the role and resets the parameters in reset.
+func (s *DatabaseServer) SetRoleSettings(
+	ctx context.Context,
+	roleName string,
+	settings map[string]string,
+	reset []string,
+) error {
+	return s.execSettings(ctx, "ROLE", roleName, settings, reset)
+}
+
+// SetDatabaseSettings sets the run-time parameters of the database and resets the parameters in reset.
+func (s *DatabaseServer) SetDatabaseSettings(
+	ctx context.Context,
+	dbName string,
+	settings map[string]string,
+	reset []string,
+) error {
+	return s.execSettings(ctx, "DATABASE", dbName, settings, reset)
+}
+
+func (s *DatabaseServer) execSettings(
+	ctx context.Context,
+	objectType, name string,
+	settings map[string]string,
+	reset []string,
+) error {
+	_ = s.Connect(ctx)
+
+	{
+		var err error
+		name, err = valid.PGIdentifier(name).Validate()
+		if err != nil {
+			return fmt.Errorf("%s name[%s]: %w", strings.ToLower(objectType), name, err)
+		}
+	}
+
+	stmts := []string{}
+	for _, key := range slices.Sorted(maps.Keys(settings)) {
+		setting, err := valid.PGSettingName(key).Validate()
+		if err != nil {
+			return fmt.Errorf("setting[%s]: %w", key, err)
+		}
+
+		stmts = append(stmts, fmt.Sprintf(`ALTER %s %s SET %s = %s`,
+			objectType, valid.PGIdentifier(name).Sanitize(), setting,
+			valid.PGSettingName(setting).SanitizeValue(settings[key]),
+		))
+	}
+
+	for _, key := range slices.Sorted(slices.Values(reset)) {
+		setting, err := valid.PGSettingName(key).Validate()
+		if err != nil {
+			return fmt.Errorf("setting[%s]: %w", key, err)
+		}
+
+		stmts = append(stmts, fmt.Sprintf(`ALTER %s %s RESET %s`,
+			objectType, valid.PGIdentifier(name).Sanitize(), setting,
+		))
+	}
+
+	for _, stmt := range stmts {
+		if _, err := s.conn.Exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SyncExtensions installs the extensions in the database and updates the extensions with a version
+// that differs from the installed version, the installed versions of the extensions are returned.
+func (s *DatabaseServer) SyncExtensions(
+	ctx context.Context,
+	dbName string,
+	extensions []dbov1.DatabaseAccountExtension,
+) (map[string]string, error) {
+	conn, err := s.connectDatabase(ctx, dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	installed, err := listExtensions(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ext := range extensions {
+		name, err := valid.PGExtensionName(ext.Name).Validate()
+		if err != nil {
+			return nil, fmt.Errorf("extension name[%s]: %w", name, err)
+		}
+
+		var stmt string
+		current, ok := installed[name]
+		switch {
+		case !ok:
+			stmt = `CREATE EXTENSION IF NOT EXISTS ` + valid.PGExtensionName(name).Sanitize()
+			if ext.Version != "" {
+				stmt += ` VERSION ` + valid.PGValue(ext.Version).Sanitize()
+			}
+		case ext.Version != "" && ext.Version != current:
+			stmt = fmt.Sprintf(`ALTER EXTENSION %s UPDATE TO %s`,
+				valid.PGExtensionName(name).Sanitize(),
+				valid.PGValue(ext.Version).Sanitize(),
+			)
+		default:
+			continue
+		}
+
+		if _, err := conn.Exec(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("extension[%s]: %w", name, err)
+		}
+	}
+
+	if installed, err = listExtensions(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	versions := map[string]string{}
+	for _, ext := range extensions {
+		versions[ext.Name] = installed[ext.Name]
+	}
+
+	return versions, nil
+}
+
+// listExtensions returns the installed extensions and their versions.
+func listExtensions(ctx context.Context, conn DatabaseConnection) (map[string]string, error) {
+	rows, err := conn.Query(ctx, `SELECT extname, extversion FROM pg_catalog.pg_extension`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	installed := map[string]string{}
+	for rows.Next() {
+		var name, version string
+		if err := rows.Scan(&name, &version); err != nil {
+			return nil, err
+		}
+
+		installed[name] = version
+	}
+
+	return installed, rows.Err()
+}
+
+// CreateMemberRole creates a login role that is a member of the owner role, the password is reset if
+// the role already exists. Sessions of the member role act as the owner role so objects created by
+// either login role are owned by the owner role.
+func (s *DatabaseServer) CreateMemberRole(ctx context.Context, roleName, ownerRole string) (Credentials, error) {
+	_ = s.Connect(ctx)
+
+	{
+		var err error
+		roleName, err = valid.PGIdentifier(roleName).Validate()
+		if err != nil {
+			return Credentials{}, fmt.Errorf("role name[%s]: %w", roleName, err)
+		}
+	}
+
+	{
+		var err error
+		ownerRole, err = valid.PGIdentifier(ownerRole).Validate()
+		if err != nil {
+			return Credentials{}, fmt.Errorf("owner role name[%s]: %w", ownerRole, err)
+		}
+	}
+
+	var exists bool
+	{
+		rows, err := s.conn.Query(ctx, `select rolname from pg_catalog.pg_roles where rolname=$1`, roleName)
+		if err != nil {
+			return Credentials{}, err
+		}
+		exists = rows.Next()
+		rows.Close()
+	}
+
+	creds, err := newCredentials(ctx, roleName)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	stmts := []string{
+		fmt.Sprintf(`CREATE ROLE %s LOGIN PASSWORD %s`,
+			valid.PGIdentifier(roleName).Sanitize(),
+			valid.PGValue(creds.Verifier).Sanitize(),
+		),
+		fmt.Sprintf(`GRANT %s TO %s`,
+			valid.PGIdentifier(ownerRole).Sanitize(),
+			valid.PGIdentifier(roleName).Sanitize(),
+		),
+		fmt.Sprintf(`ALTER ROLE %s SET role = %s`,
+			valid.PGIdentifier(roleName).Sanitize(),
+			valid.PGIdentifier(ownerRole).Sanitize(),
+		),
+		fmt.Sprintf(`COMMENT ON ROLE %s IS %s`,
+			valid.PGIdentifier(roleName).Sanitize(),
+			valid.PGValue(ManagedRoleComment).Sanitize(),
+		),
+	}
+	if exists {
+		stmts[0] = fmt.Sprintf(`ALTER ROLE %s LOGIN PASSWORD %s`,
+			valid.PGIdentifier(roleName).Sanitize(),
+			valid.PGValue(creds.Verifier).Sanitize(),
+		)
+	}
+
+	for _, stmt := range stmts {
+		if _, err := s.conn.Exec(ctx, stmt); err != nil {
+			return Credentials{}, err
+		}
+	}
+
+	return creds, nil
+}
+
+// SetRoleLogin allows or revokes the ability of the role to login.
+func (s *DatabaseServer) SetRoleLogin(ctx context.Context, roleName string, login bool) error {
+	_ = s.Connect(ctx)
+
+	{
+		var err error
+		roleName, err = valid.PGIdentifier(roleName).Validate()
+		if err != nil {
+			return fmt.Errorf("role name[%s]: %w", roleName, err)
+		}
+	}
+
+	option := "NOLOGIN"
+	if login {
+		option = "LOGIN"
+	}
+
+	stmt := fmt.Sprintf(`ALTER ROLE %s %s`, valid.PGIdentifier(roleName).Sanitize(), option)
+	if _, err := s.conn.Exec(ctx, stmt); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteRole removes the role, it is not an error if the role does not exist.
+func (s *DatabaseServer) DeleteRole(ctx context.Context, roleName string) error {
+	_ = s.Connect(ctx)
+
+	{
+		var err error
+		roleName, err = valid.PGIdentifier(roleName).Validate()
+		if err != nil {
+			return fmt.Errorf("role name[%s]: %w", roleName, err)
+		}
+	}
+
+	stmt := `DROP ROLE IF EXISTS ` + valid.PGIdentifier(roleName).Sanitize()
+	if _, err := s.conn.Exec(ctx, stmt); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (s *DatabaseServer) CreateDatabase(ctx context.Context, dbName, roleName string) (string, error) {
@@ -286,13 +784,14 @@ func (s *DatabaseServer) CreateDatabase(ctx context.Context, dbName, roleName st
 	return dbName, nil
 }
 
-func (s *DatabaseServer) CreateSchema(ctx context.Context, schemaName, roleName string) error {
-	_ = s.Connect(ctx)
+// CreateSchema creates the schema owned by the role in the database, the statement is run on a
+// connection to the database rather than the admin database.
+func (s *DatabaseServer) CreateSchema(ctx context.Context, dbName, schemaName, roleName string) error {
 	// logger := logr.FromContext(ctx)
 
 	{
 		var err error
-		schemaName, err = valid.PGIdentifier(schemaName).Validate()
+		schemaName, err = valid.PGSchemaName(schemaName).Validate()
 		if err != nil {
 			return fmt.Errorf("schema name[%s]: %w", schemaName, err)
 		}
@@ -306,14 +805,20 @@ func (s *DatabaseServer) CreateSchema(ctx context.Context, schemaName, roleName
 		}
 	}
 
+	conn, err := s.connectDatabase(ctx, dbName)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
 	stmt := fmt.Sprintf(
 		`CREATE SCHEMA IF NOT EXISTS %s AUTHORIZATION %s`,
-		valid.PGIdentifier(schemaName).Sanitize(),
+		valid.PGSchemaName(schemaName).Sanitize(),
 		valid.PGIdentifier(roleName).Sanitize(),
 	)
 	// stmt := `CREATE SCHEMA IF NOT EXISTS $1 AUTHORIZATION $2`
 	// logger.V(1).Info(fmt.Sprintf("SQL: %s (%s, %s)", stmt, schemaName, roleName))
-	if _, err := s.conn.Exec(ctx, stmt); err != nil {
+	if _, err := conn.Exec(ctx, stmt); err != nil {
 		return err
 	}
 
@@ -324,9 +829,13 @@ func (s *DatabaseServer) GetDatabaseHostConfig() string {
 	return s.conn.Config().Host
 }
 
+func (s *DatabaseServer) GetDatabasePortConfig() uint16 {
+	return s.conn.Config().Port
+}
+
 func (s *DatabaseServer) GetDatabaseHost(dbAccount *dbov1.DatabaseAccount) string {
 	if dbAccount.GetSpecCreateRelay() {
-		return dbAccount.GetStatefulSetName().Name
+		return dbAccount.GetRelayName().Name
 	}
 
 	return s.GetDatabaseHostConfig()
@@ -340,7 +849,7 @@ func (s *DatabaseServer) CopyInitConfigToSecret(
 		secret.Data = make(map[string][]byte)
 	}
 	secret.Data[DatabaseKeyHost] = []byte(s.GetDatabaseHost(dbAccount))
-	secret.Data[DatabaseKeyPort] = []byte(strconv.FormatUint(uint64(s.conn.Config().Port), 10))
+	secret.Data[DatabaseKeyPort] = []byte(strconv.FormatUint(uint64(s.GetDatabasePortConfig()), 10))
 }
 
 func GetSecretKV(secret *corev1.Secret, key string) string {
@@ -384,24 +893,30 @@ func GenerateDSN(secret *corev1.Secret) string {
 	return u.String()
 }
 
-func (s *DatabaseServer) Delete(ctx context.Context, name string) error {
+func (s *DatabaseServer) Delete(ctx context.Context, dbName, roleName string) error {
 	_ = s.Connect(ctx)
 	// logger := logr.FromContext(ctx)
 
 	{
 		var err error
-		name, err = valid.PGIdentifier(name).Validate()
+		dbName, err = valid.PGIdentifier(dbName).Validate()
 		if err != nil {
-			return fmt.Errorf("name[%s]: %w", name, err)
+			return fmt.Errorf("database name[%s]: %w", dbName, err)
 		}
 	}
 
-	var returnErr error
+	{
+		var err error
+		roleName, err = valid.PGIdentifier(roleName).Validate()
+		if err != nil {
+			return fmt.Errorf("role name[%s]: %w", roleName, err)
+		}
+	}
 
-	name = valid.PGIdentifier(name).Sanitize()
+	var returnErr error
 
 	{
-		stmt := fmt.Sprintf(`DROP DATABASE IF EXISTS %s WITH (FORCE)`, name)
+		stmt := fmt.Sprintf(`DROP DATABASE IF EXISTS %s WITH (FORCE)`, valid.PGIdentifier(dbName).Sanitize())
 		// logger.V(1).Info(fmt.Sprintf("SQL: %s", stmt))
 		// if _, err := s.conn.Exec(ctx, `DROP DATABASE IF EXISTS $1 WITH (FORCE)`, name); err != nil {
 		if _, err := s.conn.Exec(ctx, stmt); err != nil {
@@ -413,7 +928,7 @@ func (s *DatabaseServer) Delete(ctx context.Context, name string) error {
 	}
 
 	{
-		stmt := `DROP ROLE IF EXISTS ` + name
+		stmt := `DROP ROLE IF EXISTS ` + valid.PGIdentifier(roleName).Sanitize()
 		// logger.V(1).Info(fmt.Sprintf("SQL: %s", stmt))
 		// if _, err := s.conn.Exec(ctx, `DROP ROLE IF EXISTS $1`, name); err != nil {
 		if _, err := s.conn.Exec(ctx, stmt); err != nil {
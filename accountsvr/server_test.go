@@ -4,6 +4,8 @@ package accountsvr_test
 import (
 	"context"
 	"errors"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
@@ -12,6 +14,7 @@ import (
 	"github.com/dosquad/database-operator/accountsvr"
 	accountsvrtest "github.com/dosquad/database-operator/accountsvr/test"
 	dbov1 "github.com/dosquad/database-operator/api/v1"
+	"github.com/dosquad/database-operator/internal/helper"
 	"github.com/dosquad/database-operator/internal/testhelp"
 	"github.com/dosquad/database-operator/internal/valid"
 	"github.com/google/go-cmp/cmp"
@@ -20,6 +23,7 @@ import (
 	"github.com/sethvargo/go-password/password"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 func testNewMockDB(t *testing.T) (
@@ -281,6 +285,7 @@ func TestAccountSvr_IsRole(t *testing.T) {
 		expectedError error
 	}{
 		{"ExpectSuccess_RoleExists", "thunderball", true, nil},
+		{"ExpectSuccess_NoLoginRoleExists", "octopussy", true, nil},
 		{"ExpectFail_RoleDoesNotExist", "goldfinger", false, nil},
 		{"ExpectFail_ServerError", "internal-server-error", false, internalServerError},
 	}
@@ -291,13 +296,17 @@ func TestAccountSvr_IsRole(t *testing.T) {
 			start, mDB, svr, ctx, cancel := testNewMockDB(t)
 			t.Cleanup(cancel)
 
-			mDB.OnQuery = func(_ context.Context, _ string, a ...any) (pgx.Rows, error) {
+			mDB.OnQuery = func(_ context.Context, sql string, a ...any) (pgx.Rows, error) {
 				if len(a) > 0 {
 					if v, ok := a[0].(string); ok {
 						testhelp.Logf(t, start, "username queried: %s", v)
 						switch strings.ToLower(v) {
 						case "thunderball": // valid role
 							return accountsvrtest.NewMockRows(mDB, nil, []string{"thunderball"}), nil
+						case "octopussy": // role without login is only listed in pg_roles
+							if strings.Contains(sql, "pg_roles") {
+								return accountsvrtest.NewMockRows(mDB, nil, []string{"octopussy"}), nil
+							}
 						case "internal-server-error": // internal server error
 							return nil, internalServerError
 						}
@@ -333,6 +342,88 @@ func TestAccountSvr_IsRole(t *testing.T) {
 	}
 }
 
+func TestAccountSvr_IsManagedRole(t *testing.T) {
+	t.Parallel()
+	internalServerError := errors.New("internal-server-error")
+	tests := []struct {
+		name          string
+		roleName      string
+		expectedQuery bool
+		managed       bool
+		expectedError error
+	}{
+		{"ExpectSuccess_ResourcePrefix", "k8s_01h97g9exfs6bw874x0k567jr7", false, true, nil},
+		{"ExpectSuccess_ManagedComment", "thunderball", true, true, nil},
+		{"ExpectFail_OtherComment", "goldfinger", true, false, nil},
+		{"ExpectFail_NoComment", "moonraker", true, false, nil},
+		{"ExpectFail_RoleDoesNotExist", "octopussy", true, false, nil},
+		{"ExpectFail_ServerError", "internal-server-error", true, false, internalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start, mDB, svr, ctx, cancel := testNewMockDB(t)
+			t.Cleanup(cancel)
+
+			mDB.OnQuery = func(_ context.Context, _ string, a ...any) (pgx.Rows, error) {
+				var comment *string
+				if len(a) > 0 {
+					if v, ok := a[0].(string); ok {
+						testhelp.Logf(t, start, "role queried: %s", v)
+						switch strings.ToLower(v) {
+						case "thunderball": // managed role
+							comment = ptr.To(accountsvr.ManagedRoleComment)
+						case "goldfinger": // role with another comment
+							comment = ptr.To("created by hand")
+						case "moonraker": // role without a comment
+						case "internal-server-error": // internal server error
+							return nil, internalServerError
+						default: // no role found
+							return accountsvrtest.NewMockRows(mDB, nil, []string{}), nil
+						}
+					}
+				}
+
+				rows := accountsvrtest.NewMockRows(mDB, nil, []string{""})
+				rows.OnScan = func(dest ...any) error {
+					if v, ok := dest[0].(**string); ok {
+						*v = comment
+					}
+
+					return nil
+				}
+
+				return rows, nil
+			}
+
+			managed, err := svr.IsManagedRole(ctx, tt.roleName)
+			if !errors.Is(err, tt.expectedError) {
+				testhelp.Errorf(t, start,
+					"accountsvr.IsManagedRole(ctx): error, got '%v', want '%v'", err, tt.expectedError,
+				)
+			}
+
+			if managed != tt.managed {
+				testhelp.Errorf(t, start,
+					"accountsvr.IsManagedRole(ctx): managed, got '%t', want '%t'", managed, tt.managed,
+				)
+			}
+
+			expectCalledFunc := map[string]int{
+				"IsClosed": 1,
+			}
+			if tt.expectedQuery {
+				expectCalledFunc["Query"] = 1
+			}
+
+			if diff := cmp.Diff(mDB.CallCountMap(), expectCalledFunc); diff != "" {
+				testhelp.Errorf(t, start, "accountsvr.IsManagedRole(ctx): called functions -got +want:\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestAccountSvr_IsDatabase(t *testing.T) {
 	t.Parallel()
 	internalServerError := errors.New("internal-server-error")
@@ -436,7 +527,8 @@ func TestAccountSvr_CreateRole(t *testing.T) {
 			start, mDB, svr, ctx, cancel := testNewMockDB(t)
 			t.Cleanup(cancel)
 
-			generatedPassword := ""
+			generatedVerifier := ""
+			execStmts := []string{}
 
 			mDB.OnQuery = func(_ context.Context, _ string, a ...any) (pgx.Rows, error) {
 				if len(a) > 0 {
@@ -456,11 +548,12 @@ func TestAccountSvr_CreateRole(t *testing.T) {
 			}
 			mDB.OnExec = func(_ context.Context, s string, a ...any) (pgconn.CommandTag, error) {
 				// testhelp.Logf(t, start, "mDB.Exec(): stmt, got '%s'", s)
+				execStmts = append(execStmts, s)
 				a = replaceArgs(t, start, s, a)
 				if len(a) > 1 {
 					if v, ok := a[1].(string); ok {
-						testhelp.Logf(t, start, "password: %s", v)
-						generatedPassword = v
+						testhelp.Logf(t, start, "password verifier: %s", v)
+						generatedVerifier = v
 					}
 				}
 				if len(a) > 0 {
@@ -479,7 +572,8 @@ func TestAccountSvr_CreateRole(t *testing.T) {
 				return pgconn.NewCommandTag(""), nil
 			}
 
-			roleName, pw, err := svr.CreateRole(ctx, tt.rolename)
+			creds, err := svr.CreateRole(ctx, tt.rolename)
+			roleName, pw := creds.Username, creds.Password
 			if !errors.Is(err, tt.expectedError) {
 				testhelp.Errorf(t, start,
 					"accountsvr.CreateRole(ctx): error, got '%v', want '%v'", err, tt.expectedError,
@@ -498,15 +592,27 @@ func TestAccountSvr_CreateRole(t *testing.T) {
 			}
 			if tt.expectedExec {
 				expectCalledFunc["Exec"] = 1
+				if err == nil {
+					// the managed role comment is added after the role is created.
+					expectCalledFunc["Exec"] = 2
+				}
 
-				if err == nil && pw != generatedPassword {
+				if err == nil && (creds.Verifier != generatedVerifier ||
+					!helper.ScramSHA256VerifierMatches(generatedVerifier, pw)) {
 					testhelp.Errorf(t, start,
-						"accountsvr.CreateRole(ctx): generated password, got '%s', want '%s'", pw, generatedPassword,
+						"accountsvr.CreateRole(ctx): password verifier, got '%s', want '%s'",
+						generatedVerifier, creds.Verifier,
 					)
 				}
 			}
 
 			if err == nil {
+				for _, stmt := range execStmts {
+					if strings.Contains(stmt, pw) {
+						testhelp.Errorf(t, start, "accountsvr.CreateRole(ctx): password sent in statement '%s'", stmt)
+					}
+				}
+
 				if !strings.ContainsAny(pw, password.Digits) ||
 					!strings.ContainsAny(pw, password.LowerLetters) ||
 					!strings.ContainsAny(pw, password.UpperLetters) ||
@@ -547,15 +653,15 @@ func TestAccountSvr_UpdateRolePassword(t *testing.T) {
 			start, mDB, svr, ctx, cancel := testNewMockDB(t)
 			t.Cleanup(cancel)
 
-			generatedPassword := ""
+			generatedVerifier := ""
 
 			mDB.OnExec = func(_ context.Context, s string, a ...any) (pgconn.CommandTag, error) {
 				// testhelp.Logf(t, start, "mDB.Exec(): stmt, got '%s'", s)
 				a = replaceArgs(t, start, s, a)
 				if len(a) > 1 {
 					if v, ok := a[1].(string); ok {
-						testhelp.Logf(t, start, "password: %s", v)
-						generatedPassword = v
+						testhelp.Logf(t, start, "password verifier: %s", v)
+						generatedVerifier = v
 					}
 				}
 				if len(a) > 0 {
@@ -574,7 +680,8 @@ func TestAccountSvr_UpdateRolePassword(t *testing.T) {
 				return pgconn.NewCommandTag(""), dbNotFound
 			}
 
-			roleName, pw, err := svr.UpdateRolePassword(ctx, tt.rolename)
+			creds, err := svr.UpdateRolePassword(ctx, tt.rolename)
+			roleName, pw := creds.Username, creds.Password
 			if !errors.Is(err, tt.expectedError) {
 				testhelp.Errorf(t, start,
 					"accountsvr.UpdateRolePassword(ctx): error, got '%v', want '%v'", err, tt.expectedError,
@@ -594,10 +701,11 @@ func TestAccountSvr_UpdateRolePassword(t *testing.T) {
 			if tt.expectedExec {
 				expectCalledFunc["Exec"] = 1
 
-				if err == nil && pw != generatedPassword {
+				if err == nil && (creds.Verifier != generatedVerifier ||
+					!helper.ScramSHA256VerifierMatches(generatedVerifier, pw)) {
 					testhelp.Errorf(t, start,
-						"accountsvr.UpdateRolePassword(ctx): generated password, got '%s', want '%s'",
-						pw, generatedPassword,
+						"accountsvr.UpdateRolePassword(ctx): password verifier, got '%s', want '%s'",
+						generatedVerifier, creds.Verifier,
 					)
 				}
 			}
@@ -623,6 +731,82 @@ func TestAccountSvr_UpdateRolePassword(t *testing.T) {
 	}
 }
 
+func TestAccountSvr_VerifyRolePassword(t *testing.T) {
+	t.Parallel()
+	internalServerError := errors.New("internal-server-error")
+	tests := []struct {
+		name          string
+		rolename      string
+		connectError  error
+		expectValid   bool
+		expectConnect bool
+		expectedError error
+	}{
+		{"ExpectSuccess", "roly", nil, true, true, nil},
+		{"ExpectSuccess_InvalidPassword", "roly", &pgconn.PgError{Code: "28P01"}, false, true, nil},
+		{"ExpectSuccess_RoleDoesNotExist", "roly", &pgconn.PgError{Code: "28000"}, false, true, nil},
+		{"ExpectFail_ServerError", "roly", internalServerError, false, true, internalServerError},
+		{"ExpectFail_RoleNameLength", strings.Repeat("x", 64), nil, false, false, valid.ErrInvalidName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			start := time.Now()
+			ctx := t.Context()
+			dsn := dbov1.PostgreSQLDSN("postgresql://localhost:53357/testdb")
+			mDB := accountsvrtest.NewMockDB(t, nil, dsn)
+			dbConn := accountsvrtest.NewMockDB(t, nil, dsn)
+
+			var connectConfig *pgx.ConnConfig
+			svr, _ := accountsvr.NewDatabaseServerWithMock(ctx, dsn, mDB)
+			svr.WithConnectFunc(func(_ context.Context, config *pgx.ConnConfig) (accountsvr.DatabaseConnection, error) {
+				connectConfig = config
+				if tt.connectError != nil {
+					return nil, tt.connectError
+				}
+
+				return dbConn, nil
+			})
+
+			ok, err := svr.VerifyRolePassword(ctx, tt.rolename, "secret")
+			if !errors.Is(err, tt.expectedError) {
+				testhelp.Errorf(t, start,
+					"accountsvr.VerifyRolePassword(ctx): error, got '%v', want '%v'", err, tt.expectedError,
+				)
+			}
+
+			if ok != tt.expectValid {
+				testhelp.Errorf(t, start, "accountsvr.VerifyRolePassword(ctx): got '%t', want '%t'", ok, tt.expectValid)
+			}
+
+			if (connectConfig != nil) != tt.expectConnect {
+				testhelp.Errorf(t, start, "accountsvr.VerifyRolePassword(ctx): connected, got '%t', want '%t'",
+					connectConfig != nil, tt.expectConnect)
+			}
+
+			if connectConfig != nil && (connectConfig.User != tt.rolename || connectConfig.Password != "secret") {
+				testhelp.Errorf(t, start,
+					"accountsvr.VerifyRolePassword(ctx): credentials, got '%s:%s', want '%s:secret'",
+					connectConfig.User, connectConfig.Password, tt.rolename,
+				)
+			}
+
+			expectClose := 0
+			if tt.expectValid {
+				expectClose = 1
+			}
+
+			if v, _ := dbConn.CallCount("Close"); v != expectClose {
+				testhelp.Errorf(t, start,
+					"accountsvr.VerifyRolePassword(ctx): close count, got '%d', want '%d'", v, expectClose,
+				)
+			}
+		})
+	}
+}
+
 func TestAccountSvr_CreateDatabase(t *testing.T) {
 	t.Parallel()
 	internalServerError := errors.New("internal-server-error")
@@ -720,9 +904,14 @@ func TestAccountSvr_CreateSchema(t *testing.T) {
 			"ExpectFail_ServerErrorExec",
 			"exec_internal_server_error", "rolename", "", "rolename", true, internalServerError,
 		},
+		{"ExpectSuccess_ShortSchemaName", "v1", "rolename", "v1", "rolename", true, nil},
 		{
 			"ExpectFail_SchemaNameLength",
-			strings.Repeat("x", 64), "rolename", "", "", false, valid.ErrInvalidName,
+			strings.Repeat("x", 64), "rolename", "", "", false, valid.ErrInvalidSchema,
+		},
+		{
+			"ExpectFail_SchemaNameReserved",
+			"pg_app", "rolename", "", "", false, valid.ErrInvalidSchema,
 		},
 		{
 			"ExpectFail_RoleNameLength",
@@ -734,13 +923,16 @@ func TestAccountSvr_CreateSchema(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			start, mDB, svr, ctx, cancel := testNewMockDB(t)
-			t.Cleanup(cancel)
+			start := time.Now()
+			ctx := t.Context()
+			dsn := dbov1.PostgreSQLDSN("postgresql://localhost:53357/testdb")
+			mDB := accountsvrtest.NewMockDB(t, nil, dsn)
+			dbConn := accountsvrtest.NewMockDB(t, nil, dsn)
 
 			execSchemaName := ""
 			execRoleName := ""
 
-			mDB.OnExec = func(_ context.Context, s string, a ...any) (pgconn.CommandTag, error) {
+			dbConn.OnExec = func(_ context.Context, s string, a ...any) (pgconn.CommandTag, error) {
 				// testhelp.Logf(t, start, "mDB.Exec(): stmt, got '%s'", s)
 				a = replaceArgs(t, start, s, a)
 				if len(a) > 1 {
@@ -766,7 +958,15 @@ func TestAccountSvr_CreateSchema(t *testing.T) {
 				return pgconn.NewCommandTag(""), nil
 			}
 
-			err := svr.CreateSchema(ctx, tt.schemaName, tt.roleName)
+			var connectedDatabase string
+			svr, _ := accountsvr.NewDatabaseServerWithMock(ctx, dsn, mDB)
+			svr.WithConnectFunc(func(_ context.Context, config *pgx.ConnConfig) (accountsvr.DatabaseConnection, error) {
+				connectedDatabase = config.Database
+
+				return dbConn, nil
+			})
+
+			err := svr.CreateSchema(ctx, "roly", tt.schemaName, tt.roleName)
 			if !errors.Is(err, tt.expectedError) {
 				testhelp.Errorf(t, start,
 					"accountsvr.CreateSchema(ctx): error, got '%v', want '%v'", err, tt.expectedError,
@@ -774,6 +974,12 @@ func TestAccountSvr_CreateSchema(t *testing.T) {
 			}
 
 			if err == nil {
+				if connectedDatabase != "roly" {
+					testhelp.Errorf(t, start,
+						"accountsvr.CreateSchema(ctx): connected database, got '%s', want 'roly'", connectedDatabase,
+					)
+				}
+
 				if execRoleName != tt.expectRoleName {
 					testhelp.Errorf(t, start,
 						"accountsvr.CreateSchema(ctx): role name, got '%s', want '%s'",
@@ -789,14 +995,17 @@ func TestAccountSvr_CreateSchema(t *testing.T) {
 				}
 			}
 
-			expectCalledFunc := map[string]int{
-				"IsClosed": 1,
-			}
+			expectCalledFunc := map[string]int{}
 			if tt.expectedExec {
 				expectCalledFunc["Exec"] = 1
+				expectCalledFunc["Close"] = 1
 			}
 
-			if diff := cmp.Diff(mDB.CallCountMap(), expectCalledFunc); diff != "" {
+			if diff := cmp.Diff(mDB.CallCountMap(), map[string]int{}); diff != "" {
+				testhelp.Errorf(t, start, "accountsvr.CreateSchema(ctx): admin called functions -got +want:\n%s", diff)
+			}
+
+			if diff := cmp.Diff(dbConn.CallCountMap(), expectCalledFunc); diff != "" {
 				testhelp.Errorf(t, start, "accountsvr.CreateSchema(ctx): called functions -got +want:\n%s", diff)
 			}
 		})
@@ -821,6 +1030,22 @@ func TestAccountSvr_GetDatabaseHostConfig(t *testing.T) {
 	}
 }
 
+func TestAccountSvr_GetDatabasePortConfig(t *testing.T) {
+	start, mDB, svr, _, cancel := testNewMockDB(t)
+	t.Cleanup(cancel)
+	mDB.OnConfig = func() *pgx.ConnConfig {
+		return &pgx.ConnConfig{
+			Config: pgconn.Config{
+				Port: 6543,
+			},
+		}
+	}
+
+	if port := svr.GetDatabasePortConfig(); port != 6543 {
+		testhelp.Errorf(t, start, "svr.GetDatabasePortConfig(): got '%d', want '%d'", port, 6543)
+	}
+}
+
 func TestAccountSvr_GetDatabaseHost(t *testing.T) {
 	t.Parallel()
 
@@ -1023,24 +1248,28 @@ func TestAccountSvr_Delete(t *testing.T) {
 	tests := []struct {
 		name                 string
 		targetName           string
+		targetRoleName       string
 		expectedDatabaseName string
 		expectedRoleName     string
 		expectedExec         int
 		expectedError        error
 	}{
-		{"ExpectSuccess", "newroly", "newroly", "newroly", 2, nil},
-		{"ExpectSuccess_CorrectedSchemaName", "new-roly", "newroly", "newroly", 2, nil},
+		{"ExpectSuccess", "newroly", "newroly", "newroly", "newroly", 2, nil},
+		{"ExpectSuccess_CorrectedSchemaName", "new-roly", "new-roly", "newroly", "newroly", 2, nil},
+		{"ExpectSuccess_SeparateRoleName", "newdb", "legacy_user", "newdb", "legacy_user", 2, nil},
 		{
 			"ExpectFail_ServerErrorExecDatabase",
-			"exec_db_internal_server_error", "exec_db_internal_server_error", "",
+			"exec_db_internal_server_error", "exec_db_internal_server_error", "exec_db_internal_server_error", "",
 			1, internalServerError,
 		},
 		{
 			"ExpectFail_ServerErrorExecRole",
-			"exec_role_internal_server_error", "exec_role_internal_server_error", "exec_role_internal_server_error",
+			"exec_role_internal_server_error", "exec_role_internal_server_error",
+			"exec_role_internal_server_error", "exec_role_internal_server_error",
 			2, internalServerError,
 		},
-		{"ExpectFail_SchemaNameLength", strings.Repeat("x", 64), "", "", 0, valid.ErrInvalidName},
+		{"ExpectFail_SchemaNameLength", strings.Repeat("x", 64), "newroly", "", "", 0, valid.ErrInvalidName},
+		{"ExpectFail_RoleNameLength", "newroly", strings.Repeat("x", 64), "", "", 0, valid.ErrInvalidName},
 	}
 
 	for _, tt := range tests {
@@ -1084,7 +1313,7 @@ func TestAccountSvr_Delete(t *testing.T) {
 				return pgconn.NewCommandTag(""), nil
 			}
 
-			err := svr.Delete(ctx, tt.targetName)
+			err := svr.Delete(ctx, tt.targetName, tt.targetRoleName)
 			if !errors.Is(err, tt.expectedError) {
 				testhelp.Errorf(t, start,
 					"accountsvr.Delete(ctx): error, got '%v', want '%v'", err, tt.expectedError,
@@ -1123,3 +1352,469 @@ func TestAccountSvr_Delete(t *testing.T) {
 // 	t.Parallel()
 
 // }
+
+func TestAccountSvr_CreateMemberRole(t *testing.T) {
+	t.Parallel()
+	internalServerError := errors.New("internal-server-error")
+	tests := []struct {
+		name           string
+		rolename       string
+		roleExists     bool
+		expectRolename string
+		expectedStmt   []string
+		expectedError  error
+	}{
+		{
+			"ExpectSuccess_NewRole", "roly_a", false, "roly_a",
+			[]string{
+				`CREATE ROLE "roly_a" LOGIN PASSWORD`, `GRANT "roly" TO "roly_a"`,
+				`ALTER ROLE "roly_a" SET role = "roly"`,
+				`COMMENT ON ROLE "roly_a" IS 'managed by database-operator'`,
+			},
+			nil,
+		},
+		{
+			"ExpectSuccess_ExistingRole", "roly_b", true, "roly_b",
+			[]string{
+				`ALTER ROLE "roly_b" LOGIN PASSWORD`, `GRANT "roly" TO "roly_b"`,
+				`ALTER ROLE "roly_b" SET role = "roly"`,
+				`COMMENT ON ROLE "roly_b" IS 'managed by database-operator'`,
+			},
+			nil,
+		},
+		{
+			"ExpectFail_ServerErrorExec", "exec_internal_server_error", false, "",
+			[]string{`CREATE ROLE "exec_internal_server_error" LOGIN PASSWORD`},
+			internalServerError,
+		},
+		{"ExpectFail_RoleNameLength", strings.Repeat("x", 64), false, "", []string{}, valid.ErrInvalidName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start, mDB, svr, ctx, cancel := testNewMockDB(t)
+			t.Cleanup(cancel)
+
+			mDB.OnQuery = func(_ context.Context, _ string, _ ...any) (pgx.Rows, error) {
+				if tt.roleExists {
+					return accountsvrtest.NewMockRows(mDB, nil, []string{tt.rolename}), nil
+				}
+
+				return accountsvrtest.NewMockRows(mDB, nil, []string{}), nil
+			}
+
+			stmts := []string{}
+			mDB.OnExec = func(_ context.Context, s string, _ ...any) (pgconn.CommandTag, error) {
+				if i := strings.Index(s, " PASSWORD "); i > 0 {
+					s = s[:i+len(" PASSWORD")]
+				}
+				stmts = append(stmts, s)
+				if strings.Contains(s, "exec_internal_server_error") {
+					return pgconn.NewCommandTag(""), internalServerError
+				}
+
+				return pgconn.NewCommandTag(""), nil
+			}
+
+			creds, err := svr.CreateMemberRole(ctx, tt.rolename, "roly")
+			roleName, pw := creds.Username, creds.Password
+			if !errors.Is(err, tt.expectedError) {
+				testhelp.Errorf(t, start,
+					"accountsvr.CreateMemberRole(ctx): error, got '%v', want '%v'", err, tt.expectedError,
+				)
+			}
+
+			if roleName != tt.expectRolename {
+				testhelp.Errorf(t, start,
+					"accountsvr.CreateMemberRole(ctx): role name, got '%s', want '%s'", roleName, tt.expectRolename,
+				)
+			}
+
+			if err == nil && pw == "" {
+				testhelp.Errorf(t, start, "accountsvr.CreateMemberRole(ctx): password, got '', want 'password'")
+			}
+
+			if err == nil && !helper.ScramSHA256VerifierMatches(creds.Verifier, pw) {
+				testhelp.Errorf(t, start,
+					"accountsvr.CreateMemberRole(ctx): verifier, got '%s', want verifier of the password",
+					creds.Verifier,
+				)
+			}
+
+			if diff := cmp.Diff(stmts, tt.expectedStmt); diff != "" {
+				testhelp.Errorf(t, start, "accountsvr.CreateMemberRole(ctx): statements -got +want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAccountSvr_AlterRole(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		rolename      string
+		role          *dbov1.DatabaseAccountRole
+		expectedStmt  []string
+		expectedError error
+	}{
+		{
+			"ExpectSuccess_Defaults", "roly", nil,
+			[]string{`ALTER ROLE "roly" WITH CONNECTION LIMIT -1 VALID UNTIL 'infinity' NOCREATEDB INHERIT NOBYPASSRLS`},
+			nil,
+		},
+		{
+			"ExpectSuccess_Attributes", "roly",
+			&dbov1.DatabaseAccountRole{
+				ConnectionLimit: ptr.To[int32](10),
+				ValidUntil:      ptr.To(metav1.NewTime(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))),
+				CreateDB:        true,
+				Inherit:         ptr.To(false),
+				BypassRLS:       true,
+			},
+			[]string{
+				`ALTER ROLE "roly" WITH CONNECTION LIMIT 10 VALID UNTIL '2030-01-02T03:04:05Z' CREATEDB NOINHERIT BYPASSRLS`,
+			},
+			nil,
+		},
+		{"ExpectFail_RoleNameLength", strings.Repeat("x", 64), nil, []string{}, valid.ErrInvalidName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start, mDB, svr, ctx, cancel := testNewMockDB(t)
+			t.Cleanup(cancel)
+
+			stmts := []string{}
+			mDB.OnExec = func(_ context.Context, s string, _ ...any) (pgconn.CommandTag, error) {
+				stmts = append(stmts, s)
+
+				return pgconn.NewCommandTag(""), nil
+			}
+
+			if err := svr.AlterRole(ctx, tt.rolename, tt.role); !errors.Is(err, tt.expectedError) {
+				testhelp.Errorf(t, start,
+					"accountsvr.AlterRole(ctx): error, got '%v', want '%v'", err, tt.expectedError,
+				)
+			}
+
+			if diff := cmp.Diff(stmts, tt.expectedStmt); diff != "" {
+				testhelp.Errorf(t, start, "accountsvr.AlterRole(ctx): statements -got +want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAccountSvr_GrantRevokeRoles(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		grant         bool
+		memberOf      []string
+		expectedStmt  []string
+		expectedError error
+	}{
+		{"ExpectSuccess_Grant", true, []string{"pg_monitor", "pg_read_all_data"},
+			[]string{`GRANT "pg_monitor", "pg_read_all_data" TO "roly"`}, nil},
+		{"ExpectSuccess_Revoke", false, []string{"pg_monitor"}, []string{`REVOKE "pg_monitor" FROM "roly"`}, nil},
+		{"ExpectSuccess_Empty", true, []string{}, []string{}, nil},
+		{"ExpectFail_MemberOfName", true, []string{"postgres"}, []string{}, valid.ErrInvalidName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start, mDB, svr, ctx, cancel := testNewMockDB(t)
+			t.Cleanup(cancel)
+
+			stmts := []string{}
+			mDB.OnExec = func(_ context.Context, s string, _ ...any) (pgconn.CommandTag, error) {
+				stmts = append(stmts, s)
+
+				return pgconn.NewCommandTag(""), nil
+			}
+
+			f := svr.RevokeRoles
+			if tt.grant {
+				f = svr.GrantRoles
+			}
+
+			if err := f(ctx, "roly", tt.memberOf); !errors.Is(err, tt.expectedError) {
+				testhelp.Errorf(t, start,
+					"accountsvr.GrantRoles/RevokeRoles(ctx): error, got '%v', want '%v'", err, tt.expectedError,
+				)
+			}
+
+			if diff := cmp.Diff(stmts, tt.expectedStmt); diff != "" {
+				testhelp.Errorf(t, start, "accountsvr.GrantRoles/RevokeRoles(ctx): statements -got +want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAccountSvr_SetSettings(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		database      bool
+		settings      map[string]string
+		reset         []string
+		expectedStmt  []string
+		expectedError error
+	}{
+		{
+			"ExpectSuccess_Role", false,
+			map[string]string{"statement_timeout": "30s", "search_path": "app, public"},
+			[]string{"lock_timeout"},
+			[]string{
+				`ALTER ROLE "roly" SET search_path = 'app', 'public'`,
+				`ALTER ROLE "roly" SET statement_timeout = '30s'`,
+				`ALTER ROLE "roly" RESET lock_timeout`,
+			},
+			nil,
+		},
+		{
+			"ExpectSuccess_Database", true,
+			map[string]string{"work_mem": "64MB"},
+			nil,
+			[]string{`ALTER DATABASE "roly" SET work_mem = '64MB'`},
+			nil,
+		},
+		{
+			"ExpectSuccess_QuotedValue", false,
+			map[string]string{"application_name": "it's"},
+			nil,
+			[]string{`ALTER ROLE "roly" SET application_name = 'it''s'`},
+			nil,
+		},
+		{
+			"ExpectFail_InvalidSetting", false,
+			map[string]string{"work_mem; DROP ROLE x": "1"},
+			nil,
+			[]string{},
+			valid.ErrInvalidSetting,
+		},
+		{
+			"ExpectFail_InvalidReset", true,
+			nil,
+			[]string{"role"},
+			[]string{},
+			valid.ErrInvalidSetting,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start, mDB, svr, ctx, cancel := testNewMockDB(t)
+			t.Cleanup(cancel)
+
+			stmts := []string{}
+			mDB.OnExec = func(_ context.Context, s string, _ ...any) (pgconn.CommandTag, error) {
+				stmts = append(stmts, s)
+
+				return pgconn.NewCommandTag(""), nil
+			}
+
+			f := svr.SetRoleSettings
+			if tt.database {
+				f = svr.SetDatabaseSettings
+			}
+
+			if err := f(ctx, "roly", tt.settings, tt.reset); !errors.Is(err, tt.expectedError) {
+				testhelp.Errorf(t, start,
+					"accountsvr.SetRoleSettings/SetDatabaseSettings(ctx): error, got '%v', want '%v'",
+					err, tt.expectedError,
+				)
+			}
+
+			if diff := cmp.Diff(stmts, tt.expectedStmt); diff != "" {
+				testhelp.Errorf(t, start,
+					"accountsvr.SetRoleSettings/SetDatabaseSettings(ctx): statements -got +want:\n%s", diff,
+				)
+			}
+		})
+	}
+}
+
+func TestAccountSvr_SyncExtensions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name           string
+		installed      map[string]string
+		extensions     []dbov1.DatabaseAccountExtension
+		expectedStmt   []string
+		expectVersions map[string]string
+		expectedError  error
+	}{
+		{
+			"ExpectSuccess_Create", map[string]string{"plpgsql": "1.0"},
+			[]dbov1.DatabaseAccountExtension{{Name: "uuid-ossp"}, {Name: "pg_trgm", Version: "1.6"}},
+			[]string{
+				`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`,
+				`CREATE EXTENSION IF NOT EXISTS "pg_trgm" VERSION '1.6'`,
+			},
+			map[string]string{"uuid-ossp": "1.1", "pg_trgm": "1.6"},
+			nil,
+		},
+		{
+			"ExpectSuccess_Update", map[string]string{"pg_trgm": "1.5"},
+			[]dbov1.DatabaseAccountExtension{{Name: "pg_trgm", Version: "1.6"}},
+			[]string{`ALTER EXTENSION "pg_trgm" UPDATE TO '1.6'`},
+			map[string]string{"pg_trgm": "1.6"},
+			nil,
+		},
+		{
+			"ExpectSuccess_Installed", map[string]string{"pgcrypto": "1.3"},
+			[]dbov1.DatabaseAccountExtension{{Name: "pgcrypto"}},
+			[]string{},
+			map[string]string{"pgcrypto": "1.3"},
+			nil,
+		},
+		{
+			"ExpectFail_ExtensionName", map[string]string{},
+			[]dbov1.DatabaseAccountExtension{{Name: "pgcrypto; DROP"}},
+			[]string{},
+			nil,
+			valid.ErrInvalidExt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start := time.Now()
+			ctx := t.Context()
+			dsn := dbov1.PostgreSQLDSN("postgresql://localhost:53357/testdb")
+			mDB := accountsvrtest.NewMockDB(t, nil, dsn)
+			dbConn := accountsvrtest.NewMockDB(t, nil, dsn)
+
+			installed := maps.Clone(tt.installed)
+			stmts := []string{}
+			dbConn.OnExec = func(_ context.Context, s string, _ ...any) (pgconn.CommandTag, error) {
+				stmts = append(stmts, s)
+
+				// the mock database installs the requested or the default version.
+				for _, ext := range tt.extensions {
+					if strings.Contains(s, `"`+ext.Name+`"`) {
+						installed[ext.Name] = ext.Version
+						if ext.Version == "" {
+							installed[ext.Name] = "1.1"
+						}
+					}
+				}
+
+				return pgconn.NewCommandTag(""), nil
+			}
+			dbConn.OnQuery = func(_ context.Context, _ string, _ ...any) (pgx.Rows, error) {
+				names := slices.Sorted(maps.Keys(installed))
+				rows := accountsvrtest.NewMockRows(dbConn, nil, names)
+				idx := 0
+				rows.OnScan = func(dest ...any) error {
+					*dest[0].(*string) = names[idx]
+					*dest[1].(*string) = installed[names[idx]]
+					idx++
+
+					return nil
+				}
+
+				return rows, nil
+			}
+
+			var connectedDatabase string
+			svr, _ := accountsvr.NewDatabaseServerWithMock(ctx, dsn, mDB)
+			svr.WithConnectFunc(func(_ context.Context, config *pgx.ConnConfig) (accountsvr.DatabaseConnection, error) {
+				connectedDatabase = config.Database
+
+				return dbConn, nil
+			})
+
+			versions, err := svr.SyncExtensions(ctx, "roly", tt.extensions)
+			if !errors.Is(err, tt.expectedError) {
+				testhelp.Errorf(t, start,
+					"accountsvr.SyncExtensions(ctx): error, got '%v', want '%v'", err, tt.expectedError,
+				)
+			}
+
+			if connectedDatabase != "roly" {
+				testhelp.Errorf(t, start,
+					"accountsvr.SyncExtensions(ctx): connected database, got '%s', want 'roly'", connectedDatabase,
+				)
+			}
+
+			if diff := cmp.Diff(stmts, tt.expectedStmt); diff != "" {
+				testhelp.Errorf(t, start, "accountsvr.SyncExtensions(ctx): statements -got +want:\n%s", diff)
+			}
+
+			if diff := cmp.Diff(versions, tt.expectVersions); diff != "" {
+				testhelp.Errorf(t, start, "accountsvr.SyncExtensions(ctx): versions -got +want:\n%s", diff)
+			}
+
+			if v, _ := dbConn.CallCount("Close"); v != 1 {
+				testhelp.Errorf(t, start, "accountsvr.SyncExtensions(ctx): close count, got '%d', want '1'", v)
+			}
+		})
+	}
+}
+
+func TestAccountSvr_SetRoleLogin(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		rolename      string
+		login         bool
+		expectedStmt  []string
+		expectedError error
+	}{
+		{"ExpectSuccess_Login", "roly_a", true, []string{`ALTER ROLE "roly_a" LOGIN`}, nil},
+		{"ExpectSuccess_NoLogin", "roly_a", false, []string{`ALTER ROLE "roly_a" NOLOGIN`}, nil},
+		{"ExpectFail_RoleNameLength", strings.Repeat("x", 64), false, []string{}, valid.ErrInvalidName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start, mDB, svr, ctx, cancel := testNewMockDB(t)
+			t.Cleanup(cancel)
+
+			stmts := []string{}
+			mDB.OnExec = func(_ context.Context, s string, _ ...any) (pgconn.CommandTag, error) {
+				stmts = append(stmts, s)
+
+				return pgconn.NewCommandTag(""), nil
+			}
+
+			if err := svr.SetRoleLogin(ctx, tt.rolename, tt.login); !errors.Is(err, tt.expectedError) {
+				testhelp.Errorf(t, start,
+					"accountsvr.SetRoleLogin(ctx): error, got '%v', want '%v'", err, tt.expectedError,
+				)
+			}
+
+			if diff := cmp.Diff(stmts, tt.expectedStmt); diff != "" {
+				testhelp.Errorf(t, start, "accountsvr.SetRoleLogin(ctx): statements -got +want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAccountSvr_DeleteRole(t *testing.T) {
+	t.Parallel()
+	start, mDB, svr, ctx, cancel := testNewMockDB(t)
+	t.Cleanup(cancel)
+
+	stmts := []string{}
+	mDB.OnExec = func(_ context.Context, s string, _ ...any) (pgconn.CommandTag, error) {
+		stmts = append(stmts, s)
+
+		return pgconn.NewCommandTag(""), nil
+	}
+
+	if err := svr.DeleteRole(ctx, "roly_a"); err != nil {
+		testhelp.Errorf(t, start, "accountsvr.DeleteRole(ctx): error, got '%v', want 'nil'", err)
+	}
+
+	if diff := cmp.Diff(stmts, []string{`DROP ROLE IF EXISTS "roly_a"`}); diff != "" {
+		testhelp.Errorf(t, start, "accountsvr.DeleteRole(ctx): statements -got +want:\n%s", diff)
+	}
+}
@@ -3,7 +3,9 @@ package test
 import (
 	"context"
 	"net/url"
+	"strconv"
 
+	"github.com/dosquad/database-operator/accountsvr"
 	dbov1 "github.com/dosquad/database-operator/api/v1"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -16,20 +18,36 @@ type MockServer struct {
 	dsn        *url.URL
 	calledFunc map[string]int
 
-	OnCheckInvalidName       func(name string) (string, error)
-	OnConnect                func(ctx context.Context) error
-	OnClose                  func(ctx context.Context) error
-	OnListUsers              func(ctx context.Context) []string
-	OnIsRole                 func(ctx context.Context, roleName string) (bool, error)
-	OnIsDatabase             func(ctx context.Context, dbName string) (string, bool, error)
-	OnCreateRole             func(ctx context.Context, roleName string) (string, string, error)
-	OnUpdateRolePassword     func(ctx context.Context, roleName string) (string, string, error)
+	OnCheckInvalidName    func(name string) (string, error)
+	OnConnect             func(ctx context.Context) error
+	OnClose               func(ctx context.Context) error
+	OnListUsers           func(ctx context.Context) []string
+	OnIsRole              func(ctx context.Context, roleName string) (bool, error)
+	OnIsManagedRole       func(ctx context.Context, roleName string) (bool, error)
+	OnIsDatabase          func(ctx context.Context, dbName string) (string, bool, error)
+	OnCreateRole          func(ctx context.Context, roleName string) (accountsvr.Credentials, error)
+	OnUpdateRolePassword  func(ctx context.Context, roleName string) (accountsvr.Credentials, error)
+	OnVerifyRolePassword  func(ctx context.Context, roleName, password string) (bool, error)
+	OnAlterRole           func(ctx context.Context, roleName string, role *dbov1.DatabaseAccountRole) error
+	OnGrantRoles          func(ctx context.Context, roleName string, memberOf []string) error
+	OnRevokeRoles         func(ctx context.Context, roleName string, memberOf []string) error
+	OnSetRoleSettings     func(ctx context.Context, roleName string, settings map[string]string, reset []string) error
+	OnSetDatabaseSettings func(ctx context.Context, dbName string, settings map[string]string, reset []string) error
+	OnSyncExtensions      func(
+		ctx context.Context,
+		dbName string,
+		extensions []dbov1.DatabaseAccountExtension,
+	) (map[string]string, error)
+	OnCreateMemberRole       func(ctx context.Context, roleName, ownerRole string) (accountsvr.Credentials, error)
+	OnSetRoleLogin           func(ctx context.Context, roleName string, login bool) error
+	OnDeleteRole             func(ctx context.Context, roleName string) error
 	OnCreateDatabase         func(ctx context.Context, dbName, roleName string) (string, error)
-	OnCreateSchema           func(ctx context.Context, schemaName, roleName string) error
+	OnCreateSchema           func(ctx context.Context, dbName, schemaName, roleName string) error
 	OnGetDatabaseHostConfig  func() string
+	OnGetDatabasePortConfig  func() uint16
 	OnGetDatabaseHost        func(dbAccount *dbov1.DatabaseAccount) string
 	OnCopyInitConfigToSecret func(dbAccount *dbov1.DatabaseAccount, secret *corev1.Secret)
-	OnDelete                 func(ctx context.Context, name string) error
+	OnDelete                 func(ctx context.Context, dbName, roleName string) error
 }
 
 func NewMockServer(dsn string) *MockServer {
@@ -103,6 +121,15 @@ func (m *MockServer) IsRole(ctx context.Context, roleName string) (bool, error)
 	return true, nil
 }
 
+func (m *MockServer) IsManagedRole(ctx context.Context, roleName string) (bool, error) {
+	m.calledFunc["IsManagedRole"]++
+	if m.OnIsManagedRole != nil {
+		return m.OnIsManagedRole(ctx, roleName)
+	}
+
+	return true, nil
+}
+
 func (m *MockServer) IsDatabase(ctx context.Context, dbName string) (string, bool, error) {
 	m.calledFunc["IsDatabase"]++
 	if m.OnIsDatabase != nil {
@@ -112,23 +139,134 @@ func (m *MockServer) IsDatabase(ctx context.Context, dbName string) (string, boo
 	return dbName, true, nil
 }
 
-func (m *MockServer) CreateRole(ctx context.Context, roleName string) (string, string, error) {
+func (m *MockServer) CreateRole(ctx context.Context, roleName string) (accountsvr.Credentials, error) {
 	m.calledFunc["CreateRole"]++
 	if m.OnCreateRole != nil {
 		return m.OnCreateRole(ctx, roleName)
 	}
 
-	// return roleName, password nil
-	return roleName, "mockpassword", nil
+	return accountsvr.Credentials{Username: roleName, Password: "mockpassword"}, nil
 }
 
-func (m *MockServer) UpdateRolePassword(ctx context.Context, roleName string) (string, string, error) {
+func (m *MockServer) UpdateRolePassword(ctx context.Context, roleName string) (accountsvr.Credentials, error) {
 	m.calledFunc["UpdateRolePassword"]++
 	if m.OnUpdateRolePassword != nil {
 		return m.OnUpdateRolePassword(ctx, roleName)
 	}
 
-	return roleName, "", nil
+	return accountsvr.Credentials{Username: roleName}, nil
+}
+
+func (m *MockServer) VerifyRolePassword(ctx context.Context, roleName, password string) (bool, error) {
+	m.calledFunc["VerifyRolePassword"]++
+	if m.OnVerifyRolePassword != nil {
+		return m.OnVerifyRolePassword(ctx, roleName, password)
+	}
+
+	return true, nil
+}
+
+func (m *MockServer) AlterRole(ctx context.Context, roleName string, role *dbov1.DatabaseAccountRole) error {
+	m.calledFunc["AlterRole"]++
+	if m.OnAlterRole != nil {
+		return m.OnAlterRole(ctx, roleName, role)
+	}
+
+	return nil
+}
+
+func (m *MockServer) GrantRoles(ctx context.Context, roleName string, memberOf []string) error {
+	m.calledFunc["GrantRoles"]++
+	if m.OnGrantRoles != nil {
+		return m.OnGrantRoles(ctx, roleName, memberOf)
+	}
+
+	return nil
+}
+
+func (m *MockServer) RevokeRoles(ctx context.Context, roleName string, memberOf []string) error {
+	m.calledFunc["RevokeRoles"]++
+	if m.OnRevokeRoles != nil {
+		return m.OnRevokeRoles(ctx, roleName, memberOf)
+	}
+
+	return nil
+}
+
+func (m *MockServer) SetRoleSettings(
+	ctx context.Context,
+	roleName string,
+	settings map[string]string,
+	reset []string,
+) error {
+	m.calledFunc["SetRoleSettings"]++
+	if m.OnSetRoleSettings != nil {
+		return m.OnSetRoleSettings(ctx, roleName, settings, reset)
+	}
+
+	return nil
+}
+
+func (m *MockServer) SetDatabaseSettings(
+	ctx context.Context,
+	dbName string,
+	settings map[string]string,
+	reset []string,
+) error {
+	m.calledFunc["SetDatabaseSettings"]++
+	if m.OnSetDatabaseSettings != nil {
+		return m.OnSetDatabaseSettings(ctx, dbName, settings, reset)
+	}
+
+	return nil
+}
+
+func (m *MockServer) SyncExtensions(
+	ctx context.Context,
+	dbName string,
+	extensions []dbov1.DatabaseAccountExtension,
+) (map[string]string, error) {
+	m.calledFunc["SyncExtensions"]++
+	if m.OnSyncExtensions != nil {
+		return m.OnSyncExtensions(ctx, dbName, extensions)
+	}
+
+	versions := map[string]string{}
+	for _, ext := range extensions {
+		versions[ext.Name] = ext.Version
+	}
+
+	return versions, nil
+}
+
+func (m *MockServer) CreateMemberRole(
+	ctx context.Context,
+	roleName, ownerRole string,
+) (accountsvr.Credentials, error) {
+	m.calledFunc["CreateMemberRole"]++
+	if m.OnCreateMemberRole != nil {
+		return m.OnCreateMemberRole(ctx, roleName, ownerRole)
+	}
+
+	return accountsvr.Credentials{Username: roleName}, nil
+}
+
+func (m *MockServer) SetRoleLogin(ctx context.Context, roleName string, login bool) error {
+	m.calledFunc["SetRoleLogin"]++
+	if m.OnSetRoleLogin != nil {
+		return m.OnSetRoleLogin(ctx, roleName, login)
+	}
+
+	return nil
+}
+
+func (m *MockServer) DeleteRole(ctx context.Context, roleName string) error {
+	m.calledFunc["DeleteRole"]++
+	if m.OnDeleteRole != nil {
+		return m.OnDeleteRole(ctx, roleName)
+	}
+
+	return nil
 }
 
 func (m *MockServer) CreateDatabase(ctx context.Context, dbName, roleName string) (string, error) {
@@ -140,10 +278,10 @@ func (m *MockServer) CreateDatabase(ctx context.Context, dbName, roleName string
 	return dbName, nil
 }
 
-func (m *MockServer) CreateSchema(ctx context.Context, schemaName, roleName string) error {
+func (m *MockServer) CreateSchema(ctx context.Context, dbName, schemaName, roleName string) error {
 	m.calledFunc["CreateSchema"]++
 	if m.OnCreateSchema != nil {
-		return m.OnCreateSchema(ctx, schemaName, roleName)
+		return m.OnCreateSchema(ctx, dbName, schemaName, roleName)
 	}
 
 	return nil
@@ -158,6 +296,17 @@ func (m *MockServer) GetDatabaseHostConfig() string {
 	return m.dsn.Hostname()
 }
 
+func (m *MockServer) GetDatabasePortConfig() uint16 {
+	m.calledFunc["GetDatabasePortConfig"]++
+	if m.OnGetDatabasePortConfig != nil {
+		return m.OnGetDatabasePortConfig()
+	}
+
+	port, _ := strconv.ParseUint(m.dsn.Port(), 10, 16)
+
+	return uint16(port)
+}
+
 func (m *MockServer) GetDatabaseHost(dbAccount *dbov1.DatabaseAccount) string {
 	m.calledFunc["GetDatabaseHost"]++
 	if m.OnGetDatabaseHost != nil {
@@ -165,7 +314,7 @@ func (m *MockServer) GetDatabaseHost(dbAccount *dbov1.DatabaseAccount) string {
 	}
 
 	if dbAccount.GetSpecCreateRelay() {
-		return dbAccount.GetStatefulSetName().Name
+		return dbAccount.GetRelayName().Name
 	}
 
 	return m.GetDatabaseHostConfig()
@@ -184,10 +333,10 @@ func (m *MockServer) CopyInitConfigToSecret(dbAccount *dbov1.DatabaseAccount, se
 	secret.Data["port"] = []byte(m.dsn.Port())
 }
 
-func (m *MockServer) Delete(ctx context.Context, name string) error {
+func (m *MockServer) Delete(ctx context.Context, dbName, roleName string) error {
 	m.calledFunc["Delete"]++
 	if m.OnDelete != nil {
-		return m.OnDelete(ctx, name)
+		return m.OnDelete(ctx, dbName, roleName)
 	}
 
 	return nil
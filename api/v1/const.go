@@ -1,11 +1,171 @@
 package v1
 
+import "time"
+
 const (
 	// KindDatabaseAccount is the kind of DatabaseAccount.
 	KindDatabaseAccount = "DatabaseAccount"
 
+	// KindDatabaseServer is the kind of DatabaseServer.
+	KindDatabaseServer = "DatabaseServer"
+
+	// DefaultDatabaseServerDSNKey is the default key in the secret referenced by a DatabaseServer.
+	DefaultDatabaseServerDSNKey = "dsn"
+
+	// AnnotationRotateAt is the annotation used to request the password of a DatabaseAccount is
+	// rotated, the value is an RFC3339 timestamp of when it should be rotated.
+	AnnotationRotateAt = "dbo.dosquad.github.io/rotate-at"
+
+	// AnnotationDefaultOnDelete is the namespace annotation with the onDelete of accounts in the
+	// namespace that do not set it.
+	AnnotationDefaultOnDelete = "dbo.dosquad.github.io/default-on-delete"
+
+	// AnnotationDefaultRelayType is the namespace annotation with the relay type of accounts in the
+	// namespace that do not set it.
+	AnnotationDefaultRelayType = "dbo.dosquad.github.io/default-relay-type"
+
+	// AnnotationDefaultRelayPoolMode is the namespace annotation with the relay pool mode of accounts
+	// in the namespace that do not set it.
+	AnnotationDefaultRelayPoolMode = "dbo.dosquad.github.io/default-relay-pool-mode"
+
+	// AnnotationDefaultSecretLabels is the namespace annotation with the labels added to the secret
+	// template of accounts in the namespace, e.g. "team=data,tier=backend".
+	AnnotationDefaultSecretLabels = "dbo.dosquad.github.io/default-secret-labels"
+
+	// AnnotationDefaultRotationInterval is the namespace annotation with the password rotation
+	// interval of accounts in the namespace that do not set a rotation schedule, e.g. "720h".
+	AnnotationDefaultRotationInterval = "dbo.dosquad.github.io/default-rotation-interval"
+
+	// AnnotationDefaultRotationMode is the namespace annotation with the password rotation mode of
+	// accounts in the namespace that do not set it.
+	AnnotationDefaultRotationMode = "dbo.dosquad.github.io/default-rotation-mode"
+
+	// LabelDatabaseAccount is the label with the name of the account on the secrets of the versions of
+	// the account credentials.
+	LabelDatabaseAccount = "dbo.dosquad.github.io/database-account"
+
+	// DefaultSecretVersionRetention is the default time the secret of a previous version of the
+	// credentials is kept after it is replaced.
+	DefaultSecretVersionRetention = 24 * time.Hour
+
+	// DefaultRelayResolveInterval is the default time between resolving the database server hosts of
+	// the relay NetworkPolicies.
+	DefaultRelayResolveInterval = 5 * time.Minute
+
+	// DefaultRotationGracePeriod is the default time the previous login role can be used after a
+	// DualRole password rotation.
+	DefaultRotationGracePeriod = time.Hour
+
+	// DualRoleSuffixA and DualRoleSuffixB are appended to the account role for the login roles
+	// used by DualRole password rotation.
+	DualRoleSuffixA = "_a"
+	DualRoleSuffixB = "_b"
+
 	// DefaultRelayImage is the default image used for the relay.
 	DefaultRelayImage = "edoburu/pgbouncer:1.20.1-p0"
+
+	// DefaultPgCatImage is the default image used for PgCat relays, Odyssey relays require an image
+	// in the controller configuration.
+	DefaultPgCatImage = "ghcr.io/postgresml/pgcat:latest"
+
+	// SharedRelayName is the name of the relay shared by the accounts of a namespace.
+	SharedRelayName = "dbo-shared-relay"
+
+	// DefaultRelayMaxClientConn is the default maximum number of client connections to the relay.
+	DefaultRelayMaxClientConn int32 = 100
+
+	// DefaultRelayDefaultPoolSize is the default number of server connections per user and database.
+	DefaultRelayDefaultPoolSize int32 = 20
+
+	// DefaultRelayServerIdleTimeout is the default time an idle server connection is kept open.
+	DefaultRelayServerIdleTimeout = 10 * time.Minute
+)
+
+// RelayPoolMode is when a server connection of the relay is released back to the pool.
+// +kubebuilder:validation:Enum=session;transaction;statement
+type RelayPoolMode string
+
+func (d RelayPoolMode) String() string {
+	return string(d)
+}
+
+const (
+	// RelayPoolModeSession releases the server connection when the client disconnects.
+	RelayPoolModeSession RelayPoolMode = "session"
+
+	// RelayPoolModeTransaction releases the server connection when the transaction finishes.
+	RelayPoolModeTransaction RelayPoolMode = "transaction"
+
+	// RelayPoolModeStatement releases the server connection when the query finishes.
+	RelayPoolModeStatement RelayPoolMode = "statement"
+)
+
+// RelayType is the connection pooler used for a relay.
+// +kubebuilder:validation:Enum=pgbouncer;pgcat;odyssey
+type RelayType string
+
+func (d RelayType) String() string {
+	return string(d)
+}
+
+const (
+	// RelayTypePgBouncer uses PgBouncer for the relay, this is the default.
+	RelayTypePgBouncer RelayType = "pgbouncer"
+
+	// RelayTypePgCat uses PgCat for the relay.
+	RelayTypePgCat RelayType = "pgcat"
+
+	// RelayTypeOdyssey uses Odyssey for the relay.
+	RelayTypeOdyssey RelayType = "odyssey"
+)
+
+// RelayMode is how the relay of an account is deployed.
+// +kubebuilder:validation:Enum=Dedicated;Shared
+type RelayMode string
+
+func (d RelayMode) String() string {
+	return string(d)
+}
+
+const (
+	// RelayModeDedicated is a relay deployed for the account.
+	RelayModeDedicated RelayMode = "Dedicated"
+
+	// RelayModeShared is the shared relay of the namespace of the account.
+	RelayModeShared RelayMode = "Shared"
+)
+
+// RelayTLSMode is the sslmode used by the relay for TLS connections.
+// +kubebuilder:validation:Enum=disable;allow;prefer;require;verify-ca;verify-full
+type RelayTLSMode string
+
+func (d RelayTLSMode) String() string {
+	return string(d)
+}
+
+// IsVerify returns true if the mode verifies the certificate of the peer.
+func (d RelayTLSMode) IsVerify() bool {
+	return d == RelayTLSModeVerifyCA || d == RelayTLSModeVerifyFull
+}
+
+const (
+	// RelayTLSModeDisable does not use TLS.
+	RelayTLSModeDisable RelayTLSMode = "disable"
+
+	// RelayTLSModeAllow uses TLS when the peer requires it.
+	RelayTLSModeAllow RelayTLSMode = "allow"
+
+	// RelayTLSModePrefer uses TLS when the peer supports it.
+	RelayTLSModePrefer RelayTLSMode = "prefer"
+
+	// RelayTLSModeRequire requires TLS without verifying the certificate.
+	RelayTLSModeRequire RelayTLSMode = "require"
+
+	// RelayTLSModeVerifyCA requires TLS and verifies the certificate is signed by the CA.
+	RelayTLSModeVerifyCA RelayTLSMode = "verify-ca"
+
+	// RelayTLSModeVerifyFull requires TLS and verifies the certificate and the host name.
+	RelayTLSModeVerifyFull RelayTLSMode = "verify-full"
 )
 
 // DatabaseAccountOnDelete is the options that can be set for onDelete.
@@ -24,6 +184,44 @@ const (
 	OnDeleteDelete DatabaseAccountOnDelete = "delete"
 )
 
+// DatabaseAccountSecretPolicy is what is done when the secret of an account already exists and was
+// not created by the controller.
+// +kubebuilder:validation:Enum=Fail;Adopt;Replace
+type DatabaseAccountSecretPolicy string
+
+func (d DatabaseAccountSecretPolicy) String() string {
+	return string(d)
+}
+
+const (
+	// SecretPolicyFail fails the account when the existing secret is immutable.
+	SecretPolicyFail DatabaseAccountSecretPolicy = "Fail"
+
+	// SecretPolicyAdopt uses the username and password of the existing secret, they are verified
+	// against the database server before the secret is adopted.
+	SecretPolicyAdopt DatabaseAccountSecretPolicy = "Adopt"
+
+	// SecretPolicyReplace recreates the existing secret when it is immutable.
+	SecretPolicyReplace DatabaseAccountSecretPolicy = "Replace"
+)
+
+// DatabaseAccountRotationMode is how the password of an account is rotated.
+// +kubebuilder:validation:Enum=InPlace;DualRole
+type DatabaseAccountRotationMode string
+
+func (d DatabaseAccountRotationMode) String() string {
+	return string(d)
+}
+
+const (
+	// RotationModeInPlace changes the password of the login role.
+	RotationModeInPlace DatabaseAccountRotationMode = "InPlace"
+
+	// RotationModeDualRole switches the secret between two login roles and revokes the login of
+	// the previous role after a grace period.
+	RotationModeDualRole DatabaseAccountRotationMode = "DualRole"
+)
+
 // DatabaseAccountCreateStage is the stage the account creation is up to.
 // +kubebuilder:validation:Enum=Init;UserCreate;DatabaseCreate;RelayCreate;Error;Ready;Terminating
 type DatabaseAccountCreateStage string
@@ -57,3 +255,82 @@ const (
 	// TerminatingStage is when the account is being removed.
 	TerminatingStage DatabaseAccountCreateStage = "Terminating"
 )
+
+// Condition types used in the DatabaseAccount status conditions.
+const (
+	// ConditionReady is true when the account is ready to be used.
+	ConditionReady = "Ready"
+
+	// ConditionUserReady is true when the database role has been created.
+	ConditionUserReady = "UserReady"
+
+	// ConditionDatabaseReady is true when the database has been created.
+	ConditionDatabaseReady = "DatabaseReady"
+
+	// ConditionRelayReady is true when the relay has been created or is not required.
+	ConditionRelayReady = "RelayReady"
+
+	// ConditionDegraded is true when the account has failed and needs attention.
+	ConditionDegraded = "Degraded"
+)
+
+// Condition reasons used in the DatabaseAccount status conditions.
+const (
+	// ConditionReasonPending is used when the resource has not been created yet.
+	ConditionReasonPending = "Pending"
+
+	// ConditionReasonCreated is used when the resource has been created.
+	ConditionReasonCreated = "Created"
+
+	// ConditionReasonExists is used when the resource already existed and has been reused.
+	ConditionReasonExists = "Exists"
+
+	// ConditionReasonNotRequired is used when the resource is not required by the spec.
+	ConditionReasonNotRequired = "NotRequired"
+
+	// ConditionReasonAvailable is used when the account is available.
+	ConditionReasonAvailable = "Available"
+
+	// ConditionReasonAsExpected is used when the account is not degraded.
+	ConditionReasonAsExpected = "AsExpected"
+
+	// ConditionReasonFailed is used when creating the resource failed.
+	ConditionReasonFailed = "Failed"
+
+	// ConditionReasonSecretImmutable is used when the secret exists and is immutable.
+	ConditionReasonSecretImmutable = "SecretImmutable"
+
+	// ConditionReasonSecretAdoptFailed is used when the credentials in the existing secret can not be
+	// verified against the database server.
+	ConditionReasonSecretAdoptFailed = "SecretAdoptFailed"
+
+	// ConditionReasonTerminating is used when the account is being removed.
+	ConditionReasonTerminating = "Terminating"
+
+	// ConditionReasonInvalidUsername is used when the username in the spec is not a valid role name.
+	ConditionReasonInvalidUsername = "InvalidUsername"
+
+	// ConditionReasonInvalidRelaySettings is used when the relay settings can not be used for a relay.
+	ConditionReasonInvalidRelaySettings = "InvalidRelaySettings"
+
+	// ConditionReasonRoleNotManaged is used when the role exists and was not created by the operator.
+	ConditionReasonRoleNotManaged = "RoleNotManaged"
+
+	// ConditionReasonBypassRLSNotAllowed is used when the role requests BYPASSRLS and it is not allowed.
+	ConditionReasonBypassRLSNotAllowed = "BypassRLSNotAllowed"
+
+	// ConditionReasonExtensionNotAllowed is used when an extension is not allowed to be installed.
+	ConditionReasonExtensionNotAllowed = "ExtensionNotAllowed"
+
+	// ConditionReasonSettingNotAllowed is used when a run-time parameter is not allowed to be set.
+	ConditionReasonSettingNotAllowed = "SettingNotAllowed"
+
+	// ConditionReasonPasswordRotating is used while the password of the account is being rotated.
+	ConditionReasonPasswordRotating = "PasswordRotating"
+
+	// ConditionReasonRelayProgressing is used while the relay pods are starting and not yet serving.
+	ConditionReasonRelayProgressing = "RelayProgressing"
+
+	// ConditionReasonRelayDegraded is used when the relay pods are failing to start.
+	ConditionReasonRelayDegraded = "RelayDegraded"
+)
@@ -0,0 +1,257 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v2 "github.com/dosquad/database-operator/api/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// ConvertTo converts the account to the v2 hub, a createRelay without a relay becomes an empty relay
+// and an error without a Degraded condition becomes the condition.
+func (d *DatabaseAccount) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v2.DatabaseAccount)
+	if !ok {
+		return fmt.Errorf("expected a v2 DatabaseAccount but got %T", dstRaw)
+	}
+
+	dst.ObjectMeta = *d.ObjectMeta.DeepCopy()
+
+	relay, err := convertRelay[DatabaseAccountRelay, v2.DatabaseAccountRelay](d.GetSpecRelay())
+	if err != nil {
+		return err
+	}
+
+	dst.Spec = v2.DatabaseAccountSpec{
+		Name:     v2.PostgreSQLResourceName(d.Spec.Name),
+		OnDelete: v2.DatabaseAccountOnDelete(d.Spec.OnDelete),
+		Role: v2.DatabaseAccountRole{
+			Username:         d.Spec.Username,
+			Attributes:       roleAttributesToV2(d.Spec.Role),
+			Settings:         d.Spec.RoleSettings,
+			PasswordRotation: passwordRotationToV2(d.Spec.PasswordRotation),
+		},
+		Database: v2.DatabaseAccountDatabase{
+			Settings:      d.Spec.DatabaseSettings,
+			Schemas:       d.Spec.Schemas,
+			DefaultSchema: d.Spec.DefaultSchema,
+		},
+		Relay: relay,
+		Secret: v2.DatabaseAccountSecret{
+			Name:        d.Spec.SecretName,
+			Labels:      d.Spec.SecretTemplate.Labels,
+			Annotations: d.Spec.SecretTemplate.Annotations,
+			Policy:      v2.DatabaseAccountSecretPolicy(d.Spec.SecretPolicy),
+		},
+	}
+
+	if d.Spec.ServerRef != nil {
+		dst.Spec.ServerRef = &v2.DatabaseAccountServerRef{Name: d.Spec.ServerRef.Name}
+	}
+
+	for _, ext := range d.Spec.Extensions {
+		dst.Spec.Database.Extensions = append(dst.Spec.Database.Extensions,
+			v2.DatabaseAccountExtension{Name: ext.Name, Version: ext.Version})
+	}
+
+	d.convertStatusTo(dst)
+
+	return nil
+}
+
+// convertStatusTo converts the status of the account to the v2 status.
+func (d *DatabaseAccount) convertStatusTo(dst *v2.DatabaseAccount) {
+	dst.Status = v2.DatabaseAccountStatus{
+		Phase:              v2.DatabaseAccountPhase(d.Status.Stage),
+		ObservedGeneration: d.Status.ObservedGeneration,
+		Conditions:         d.Status.Conditions,
+		Name:               v2.PostgreSQLResourceName(d.Status.Name),
+		Role: v2.DatabaseAccountRoleStatus{
+			Username:        d.Status.Username,
+			Attributes:      roleAttributesToV2(d.Status.Role),
+			Settings:        d.Status.RoleSettings,
+			LastRotated:     d.Status.LastRotated,
+			RotateAtHandled: d.Status.RotateAtHandled,
+			Active:          d.Status.ActiveRole,
+			Previous:        d.Status.PreviousRole,
+			RevokeAt:        d.Status.RevokeAt,
+		},
+		Database: v2.DatabaseAccountDatabaseStatus{
+			Settings:   d.Status.DatabaseSettings,
+			Extensions: d.Status.Extensions,
+			Schemas:    d.Status.Schemas,
+		},
+		Secret: v2.DatabaseAccountSecretStatus{CurrentName: d.Status.CurrentSecretName},
+		Relay:  v2.DatabaseAccountRelayStatus{Mode: v2.RelayMode(d.Status.RelayMode)},
+	}
+
+	for _, previous := range d.Status.PreviousSecrets {
+		dst.Status.Secret.Previous = append(dst.Status.Secret.Previous,
+			v2.DatabaseAccountPreviousSecret{Name: previous.Name, DeleteAt: previous.DeleteAt})
+	}
+
+	// accounts marked as error before status conditions were added only have the error message.
+	if d.Status.Error && d.GetCondition(ConditionDegraded) == nil {
+		dst.Status.Conditions = append(append([]metav1.Condition{}, dst.Status.Conditions...), metav1.Condition{
+			Type:               ConditionDegraded,
+			Status:             metav1.ConditionTrue,
+			Reason:             ConditionReasonFailed,
+			Message:            d.Status.ErrorMessage,
+			ObservedGeneration: d.Status.ObservedGeneration,
+		})
+	}
+}
+
+// ConvertFrom converts the v2 hub to the account, the legacy Ready, Error and ErrorMessage fields are
+// derived from the conditions.
+func (d *DatabaseAccount) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v2.DatabaseAccount)
+	if !ok {
+		return fmt.Errorf("expected a v2 DatabaseAccount but got %T", srcRaw)
+	}
+
+	d.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	relay, err := convertRelay[v2.DatabaseAccountRelay, DatabaseAccountRelay](src.Spec.Relay)
+	if err != nil {
+		return err
+	}
+
+	d.Spec = DatabaseAccountSpec{
+		Username:         src.Spec.Role.Username,
+		OnDelete:         DatabaseAccountOnDelete(src.Spec.OnDelete),
+		Relay:            relay,
+		Name:             PostgreSQLResourceName(src.Spec.Name),
+		SecretName:       src.Spec.Secret.Name,
+		SecretPolicy:     DatabaseAccountSecretPolicy(src.Spec.Secret.Policy),
+		PasswordRotation: passwordRotationFromV2(src.Spec.Role.PasswordRotation),
+		Role:             roleAttributesFromV2(src.Spec.Role.Attributes),
+		RoleSettings:     src.Spec.Role.Settings,
+		DatabaseSettings: src.Spec.Database.Settings,
+		Schemas:          src.Spec.Database.Schemas,
+		DefaultSchema:    src.Spec.Database.DefaultSchema,
+		SecretTemplate: DatabaseAccountSpecSecretTemplate{
+			Labels:      src.Spec.Secret.Labels,
+			Annotations: src.Spec.Secret.Annotations,
+		},
+	}
+
+	if src.Spec.ServerRef != nil {
+		d.Spec.ServerRef = &DatabaseAccountServerRef{Name: src.Spec.ServerRef.Name}
+	}
+
+	for _, ext := range src.Spec.Database.Extensions {
+		d.Spec.Extensions = append(d.Spec.Extensions, DatabaseAccountExtension{Name: ext.Name, Version: ext.Version})
+	}
+
+	d.Status = DatabaseAccountStatus{
+		Stage:              DatabaseAccountCreateStage(src.Status.Phase),
+		Ready:              src.IsConditionTrue(ConditionReady),
+		Error:              src.IsConditionTrue(ConditionDegraded),
+		Name:               PostgreSQLResourceName(src.Status.Name),
+		Username:           src.Status.Role.Username,
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         src.Status.Conditions,
+		LastRotated:        src.Status.Role.LastRotated,
+		RotateAtHandled:    src.Status.Role.RotateAtHandled,
+		ActiveRole:         src.Status.Role.Active,
+		PreviousRole:       src.Status.Role.Previous,
+		RevokeAt:           src.Status.Role.RevokeAt,
+		Role:               roleAttributesFromV2(src.Status.Role.Attributes),
+		RoleSettings:       src.Status.Role.Settings,
+		DatabaseSettings:   src.Status.Database.Settings,
+		Extensions:         src.Status.Database.Extensions,
+		Schemas:            src.Status.Database.Schemas,
+		CurrentSecretName:  src.Status.Secret.CurrentName,
+		RelayMode:          RelayMode(src.Status.Relay.Mode),
+	}
+
+	for _, previous := range src.Status.Secret.Previous {
+		d.Status.PreviousSecrets = append(d.Status.PreviousSecrets,
+			DatabaseAccountPreviousSecret{Name: previous.Name, DeleteAt: previous.DeleteAt})
+	}
+
+	if d.Status.Error {
+		d.Status.ErrorMessage = src.GetCondition(ConditionDegraded).Message
+	}
+
+	return nil
+}
+
+// convertRelay converts the relay between the versions, the relay has the same fields in both
+// versions so it is converted through its JSON representation.
+func convertRelay[S, T any](src *S) (*T, error) {
+	if src == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(src)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert relay: %w", err)
+	}
+
+	dst := new(T)
+	if err := json.Unmarshal(data, dst); err != nil {
+		return nil, fmt.Errorf("unable to convert relay: %w", err)
+	}
+
+	return dst, nil
+}
+
+func roleAttributesToV2(src *DatabaseAccountRole) *v2.DatabaseAccountRoleAttributes {
+	if src == nil {
+		return nil
+	}
+
+	return &v2.DatabaseAccountRoleAttributes{
+		ConnectionLimit: src.ConnectionLimit,
+		ValidUntil:      src.ValidUntil,
+		CreateDB:        src.CreateDB,
+		Inherit:         src.Inherit,
+		BypassRLS:       src.BypassRLS,
+		MemberOf:        src.MemberOf,
+	}
+}
+
+func roleAttributesFromV2(src *v2.DatabaseAccountRoleAttributes) *DatabaseAccountRole {
+	if src == nil {
+		return nil
+	}
+
+	return &DatabaseAccountRole{
+		ConnectionLimit: src.ConnectionLimit,
+		ValidUntil:      src.ValidUntil,
+		CreateDB:        src.CreateDB,
+		Inherit:         src.Inherit,
+		BypassRLS:       src.BypassRLS,
+		MemberOf:        src.MemberOf,
+	}
+}
+
+func passwordRotationToV2(src *DatabaseAccountPasswordRotation) *v2.DatabaseAccountPasswordRotation {
+	if src == nil {
+		return nil
+	}
+
+	return &v2.DatabaseAccountPasswordRotation{
+		Interval:    src.Interval,
+		Schedule:    src.Schedule,
+		Mode:        v2.DatabaseAccountRotationMode(src.Mode),
+		GracePeriod: src.GracePeriod,
+	}
+}
+
+func passwordRotationFromV2(src *v2.DatabaseAccountPasswordRotation) *DatabaseAccountPasswordRotation {
+	if src == nil {
+		return nil
+	}
+
+	return &DatabaseAccountPasswordRotation{
+		Interval:    src.Interval,
+		Schedule:    src.Schedule,
+		Mode:        DatabaseAccountRotationMode(src.Mode),
+		GracePeriod: src.GracePeriod,
+	}
+}
@@ -0,0 +1,133 @@
+package v1_test
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/dosquad/database-operator/api/v1"
+	v1test "github.com/dosquad/database-operator/api/v1/test"
+	v2 "github.com/dosquad/database-operator/api/v2"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestDatabaseAccount_Convert_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	now := metav1.NewTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	dbAccount := v1test.NewDatabaseAccount()
+	dbAccount.Spec = v1.DatabaseAccountSpec{
+		Username:     "legacy_app",
+		OnDelete:     v1.OnDeleteRetain,
+		Name:         "app_db",
+		SecretName:   "app-credentials",
+		SecretPolicy: v1.SecretPolicyAdopt,
+		SecretTemplate: v1.DatabaseAccountSpecSecretTemplate{
+			Labels:      map[string]string{"team": "data"},
+			Annotations: map[string]string{"note": "value"},
+		},
+		ServerRef: &v1.DatabaseAccountServerRef{Name: "primary"},
+		PasswordRotation: &v1.DatabaseAccountPasswordRotation{
+			Interval: &metav1.Duration{Duration: time.Hour},
+			Mode:     v1.RotationModeDualRole,
+		},
+		Role:             &v1.DatabaseAccountRole{ConnectionLimit: ptr.To[int32](5), MemberOf: []string{"pg_monitor"}},
+		RoleSettings:     map[string]string{"statement_timeout": "30s"},
+		DatabaseSettings: map[string]string{"work_mem": "64MB"},
+		Extensions:       []v1.DatabaseAccountExtension{{Name: "pgcrypto", Version: "1.3"}},
+		Schemas:          []string{"app_schema"},
+		DefaultSchema:    "app_schema",
+		Relay: &v1.DatabaseAccountRelay{
+			RelaySettings: v1.RelaySettings{PoolMode: v1.RelayPoolModeTransaction},
+			Type:          v1.RelayTypePgCat,
+			Replicas:      ptr.To[int32](2),
+			Tolerations:   []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+			TLS:           &v1.RelayTLS{Client: &v1.RelayClientTLS{SSLMode: v1.RelayTLSModeRequire}},
+			AllowedClients: []v1.RelayAllowedClient{
+				{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "testapp"}}},
+			},
+		},
+	}
+	dbAccount.Status = v1.DatabaseAccountStatus{
+		Stage:              v1.ReadyStage,
+		Ready:              true,
+		Name:               "app_db",
+		Username:           "legacy_app",
+		ObservedGeneration: 3,
+		Conditions: []metav1.Condition{
+			{Type: v1.ConditionReady, Status: metav1.ConditionTrue, Reason: "Available", LastTransitionTime: now},
+		},
+		LastRotated:       &now,
+		RotateAtHandled:   "now",
+		ActiveRole:        "legacy_app_a",
+		PreviousRole:      "legacy_app_b",
+		RevokeAt:          &now,
+		Role:              &v1.DatabaseAccountRole{ConnectionLimit: ptr.To[int32](5)},
+		RoleSettings:      map[string]string{"statement_timeout": "30s"},
+		DatabaseSettings:  map[string]string{"work_mem": "64MB"},
+		Extensions:        map[string]string{"pgcrypto": "1.3"},
+		Schemas:           []string{"app_schema"},
+		CurrentSecretName: "app-credentials-5f8d7c6b9a",
+		PreviousSecrets: []v1.DatabaseAccountPreviousSecret{
+			{Name: "app-credentials-1a2b3c4d5e", DeleteAt: now},
+		},
+		RelayMode: v1.RelayModeDedicated,
+	}
+
+	hub := &v2.DatabaseAccount{}
+	if err := dbAccount.DeepCopy().ConvertTo(hub); err != nil {
+		t.Fatalf("DatabaseAccount.ConvertTo(): error, got '%v', want 'nil'", err)
+	}
+
+	if hub.Spec.Role.Username != "legacy_app" || hub.Spec.Secret.Name != "app-credentials" ||
+		hub.Spec.Secret.Policy != v2.SecretPolicyAdopt ||
+		hub.Spec.Database.DefaultSchema != "app_schema" || hub.Status.Phase != v2.PhaseReady {
+		t.Errorf("DatabaseAccount.ConvertTo(): got '%v'", hub)
+	}
+
+	got := v1.DatabaseAccount{}
+	if err := got.ConvertFrom(hub); err != nil {
+		t.Fatalf("DatabaseAccount.ConvertFrom(): error, got '%v', want 'nil'", err)
+	}
+
+	// the type meta is set by the conversion webhook.
+	got.TypeMeta = dbAccount.TypeMeta
+	if diff := cmp.Diff(got, dbAccount); diff != "" {
+		t.Errorf("DatabaseAccount.ConvertFrom(): -got +want:\n%s", diff)
+	}
+}
+
+func TestDatabaseAccount_ConvertTo_Legacy(t *testing.T) {
+	t.Parallel()
+
+	dbAccount := v1test.NewDatabaseAccount()
+	dbAccount.Spec.CreateRelay = true
+	dbAccount.Status.Stage = v1.ErrorStage
+	dbAccount.Status.Error = true
+	dbAccount.Status.ErrorMessage = "invalid relay settings"
+
+	hub := &v2.DatabaseAccount{}
+	if err := dbAccount.ConvertTo(hub); err != nil {
+		t.Fatalf("DatabaseAccount.ConvertTo(): error, got '%v', want 'nil'", err)
+	}
+
+	// the deprecated createRelay is the same as an empty relay.
+	if diff := cmp.Diff(hub.Spec.Relay, &v2.DatabaseAccountRelay{}); diff != "" {
+		t.Errorf("DatabaseAccount.ConvertTo(): relay -got +want:\n%s", diff)
+	}
+
+	if c := hub.GetCondition(v2.ConditionDegraded); c == nil || c.Message != "invalid relay settings" {
+		t.Errorf("DatabaseAccount.ConvertTo(): Degraded condition, got '%v'", c)
+	}
+
+	got := v1.DatabaseAccount{}
+	if err := got.ConvertFrom(hub); err != nil {
+		t.Fatalf("DatabaseAccount.ConvertFrom(): error, got '%v', want 'nil'", err)
+	}
+
+	if !got.GetSpecCreateRelay() || !got.Status.Error || got.Status.ErrorMessage != "invalid relay settings" {
+		t.Errorf("DatabaseAccount.ConvertFrom(): got '%v'", got)
+	}
+}
@@ -10,9 +10,14 @@ import (
 	v1test "github.com/dosquad/database-operator/api/v1/test"
 	"github.com/dosquad/database-operator/internal/testhelp"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+var ignoreTransitionTime = cmpopts.IgnoreFields(metav1.Condition{}, "LastTransitionTime")
+
 func TestDatabaseAccount_GetReference(t *testing.T) {
 	dba := v1test.NewDatabaseAccount()
 
@@ -66,6 +71,20 @@ func TestGetStatefulSetName_Custom(t *testing.T) {
 	}
 }
 
+func TestGetRelayName(t *testing.T) {
+	dba := v1test.NewDatabaseAccount()
+	dba.Spec.Relay = &v1.DatabaseAccountRelay{}
+
+	if v := dba.GetRelayName(); v.String() != "default/testaccount" {
+		t.Errorf("dba.GetRelayName() expected '%v' received '%v'", "default/testaccount", v.String())
+	}
+
+	dba.Spec.Relay.Shared = true
+	if v := dba.GetRelayName(); v.String() != "default/"+v1.SharedRelayName {
+		t.Errorf("dba.GetRelayName() expected '%v' received '%v'", "default/"+v1.SharedRelayName, v.String())
+	}
+}
+
 func TestGetDatabaseName(t *testing.T) {
 	dba := v1test.NewDatabaseAccount()
 
@@ -89,6 +108,24 @@ func TestGetDatabaseName_Error(t *testing.T) {
 	}
 }
 
+func TestGetRoleName(t *testing.T) {
+	dba := v1test.NewDatabaseAccount()
+
+	if v, err := dba.GetRoleName(); err != nil || v != v1test.DBUser {
+		t.Errorf("dba.GetRoleName() expected '%v' 'nil' received '%v' '%v'", v1test.DBUser, v, err)
+	}
+
+	dba.Status.Username = "legacy_app"
+
+	if v, err := dba.GetRoleName(); err != nil || v != "legacy_app" {
+		t.Errorf("dba.GetRoleName() expected 'legacy_app' 'nil' received '%v' '%v'", v, err)
+	}
+
+	if v, err := dba.GetDatabaseName(); err != nil || v != v1test.DBUser {
+		t.Errorf("dba.GetDatabaseName() expected '%v' 'nil' received '%v' '%v'", v1test.DBUser, v, err)
+	}
+}
+
 func TestGetSpecOnDelete(t *testing.T) {
 	dba := v1test.NewDatabaseAccount()
 
@@ -171,6 +208,141 @@ func TestGetSpecCreateRelay_Specified(t *testing.T) {
 // 	return client.NewNamespacedClient(nonNamespacedClient, ns)
 // }
 
+func TestGetSpecRelay(t *testing.T) {
+	dba := v1test.NewDatabaseAccount()
+	if v := dba.GetSpecRelay(); v != nil {
+		t.Errorf("dba.GetSpecRelay() default expected 'nil', received '%v'", v)
+	}
+
+	dba.Spec.CreateRelay = true
+	if v := dba.GetSpecRelay(); v == nil {
+		t.Errorf("dba.GetSpecRelay() createRelay expected relay, received 'nil'")
+	}
+
+	dba.Spec.CreateRelay = false
+	dba.Spec.Relay = &v1.DatabaseAccountRelay{RelaySettings: v1.RelaySettings{PoolMode: v1.RelayPoolModeTransaction}}
+	if v := dba.GetSpecRelay(); v == nil || v.PoolMode != v1.RelayPoolModeTransaction {
+		t.Errorf("dba.GetSpecRelay() relay expected spec relay, received '%v'", v)
+	}
+
+	if v := dba.GetSpecCreateRelay(); !v {
+		t.Errorf("dba.GetSpecCreateRelay() relay expected 'true', received '%t'", v)
+	}
+}
+
+func TestGetSpecRelayMode(t *testing.T) {
+	dba := v1test.NewDatabaseAccount()
+	if v := dba.GetSpecRelayMode(); v != "" {
+		t.Errorf("dba.GetSpecRelayMode() default expected '', received '%s'", v)
+	}
+
+	dba.Spec.CreateRelay = true
+	if v := dba.GetSpecRelayMode(); v != v1.RelayModeDedicated {
+		t.Errorf("dba.GetSpecRelayMode() createRelay expected '%s', received '%s'", v1.RelayModeDedicated, v)
+	}
+
+	dba.Spec.Relay = &v1.DatabaseAccountRelay{Shared: true}
+	if v := dba.GetSpecRelayMode(); v != v1.RelayModeShared {
+		t.Errorf("dba.GetSpecRelayMode() shared expected '%s', received '%s'", v1.RelayModeShared, v)
+	}
+}
+
+func TestRelayTLS_Validate(t *testing.T) {
+	caRef := &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "db-ca"}, Key: "ca.crt"}
+
+	tests := []struct {
+		name      string
+		tls       *v1.RelayTLS
+		expectErr error
+	}{
+		{"Nil", nil, nil},
+		{"Defaults", &v1.RelayTLS{Client: &v1.RelayClientTLS{}, Server: &v1.RelayServerTLS{}}, nil},
+		{
+			"VerifyServer",
+			&v1.RelayTLS{Server: &v1.RelayServerTLS{SSLMode: v1.RelayTLSModeVerifyFull, CASecretRef: caRef}},
+			nil,
+		},
+		{
+			"VerifyServerWithoutCA",
+			&v1.RelayTLS{Server: &v1.RelayServerTLS{SSLMode: v1.RelayTLSModeVerifyCA}},
+			v1.ErrInvalidRelaySettings,
+		},
+		{
+			"UnknownMode",
+			&v1.RelayTLS{Client: &v1.RelayClientTLS{SSLMode: "always"}},
+			v1.ErrInvalidRelaySettings,
+		},
+		{
+			"SecretRefWithoutName",
+			&v1.RelayTLS{Client: &v1.RelayClientTLS{SecretRef: &corev1.LocalObjectReference{}}},
+			v1.ErrInvalidRelaySettings,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.tls.Validate(); !errors.Is(err, tt.expectErr) {
+				t.Errorf("RelayTLS.Validate() expected '%v' received '%v'", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestDatabaseAccountRelay_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		relay     *v1.DatabaseAccountRelay
+		expectErr error
+	}{
+		{"Nil", nil, nil},
+		{"Shared", &v1.DatabaseAccountRelay{Shared: true}, nil},
+		{"TLS", &v1.DatabaseAccountRelay{TLS: &v1.RelayTLS{Client: &v1.RelayClientTLS{}}}, nil},
+		{
+			"SharedTLS",
+			&v1.DatabaseAccountRelay{Shared: true, TLS: &v1.RelayTLS{Client: &v1.RelayClientTLS{}}},
+			v1.ErrInvalidRelaySettings,
+		},
+		{
+			"InvalidTLS",
+			&v1.DatabaseAccountRelay{TLS: &v1.RelayTLS{Client: &v1.RelayClientTLS{SSLMode: "always"}}},
+			v1.ErrInvalidRelaySettings,
+		},
+		{"Type", &v1.DatabaseAccountRelay{Type: v1.RelayTypeOdyssey}, nil},
+		{"UnknownType", &v1.DatabaseAccountRelay{Type: "pgpool"}, v1.ErrInvalidRelaySettings},
+		{
+			"SharedType",
+			&v1.DatabaseAccountRelay{Shared: true, Type: v1.RelayTypePgCat},
+			v1.ErrInvalidRelaySettings,
+		},
+		{
+			"AllowedClients",
+			&v1.DatabaseAccountRelay{AllowedClients: []v1.RelayAllowedClient{{PodSelector: &metav1.LabelSelector{}}}},
+			nil,
+		},
+		{
+			"AllowedClientNoSelector",
+			&v1.DatabaseAccountRelay{AllowedClients: []v1.RelayAllowedClient{{}}},
+			v1.ErrInvalidRelaySettings,
+		},
+		{
+			"SharedAllowedClients",
+			&v1.DatabaseAccountRelay{
+				Shared:         true,
+				AllowedClients: []v1.RelayAllowedClient{{PodSelector: &metav1.LabelSelector{}}},
+			},
+			v1.ErrInvalidRelaySettings,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.relay.Validate(); !errors.Is(err, tt.expectErr) {
+				t.Errorf("DatabaseAccountRelay.Validate() expected '%v' received '%v'", tt.expectErr, err)
+			}
+		})
+	}
+}
+
 func TestUpdateStatus(t *testing.T) {
 	t.Parallel()
 	start := time.Now()
@@ -275,6 +447,16 @@ func TestSetStage(t *testing.T) {
 		{"", v1.TerminatingStage, 1, func(da *v1.DatabaseAccount) {
 			da.Status.Stage = v1.TerminatingStage
 			da.Status.Ready = false
+			da.Status.ObservedGeneration = da.Generation
+			da.Status.Conditions = []metav1.Condition{
+				{
+					Type:               v1.ConditionReady,
+					Status:             metav1.ConditionFalse,
+					ObservedGeneration: da.Generation,
+					Reason:             v1.ConditionReasonTerminating,
+					Message:            "Account is being removed",
+				},
+			}
 		}},
 	}
 
@@ -333,9 +515,232 @@ func TestSetStage(t *testing.T) {
 			originaldba := v1test.NewDatabaseAccount()
 			tt.applyExpectedChanges(&originaldba)
 
-			if diff := cmp.Diff(changeObject, originaldba); diff != "" {
+			if diff := cmp.Diff(changeObject, originaldba, ignoreTransitionTime); diff != "" {
 				testhelp.Errorf(t, start, "dba.SetStage(): object change -got +want:\n%s", diff)
 			}
 		})
 	}
 }
+
+func TestSetCondition(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	dba := v1test.NewDatabaseAccount()
+
+	if !dba.SetCondition(v1.ConditionReady, metav1.ConditionTrue, v1.ConditionReasonAvailable, "ready") {
+		testhelp.Errorf(t, start, "dba.SetCondition(Ready): changed, got 'false', want 'true'")
+	}
+
+	if dba.SetCondition(v1.ConditionReady, metav1.ConditionTrue, v1.ConditionReasonAvailable, "ready") {
+		testhelp.Errorf(t, start, "dba.SetCondition(Ready): unchanged, got 'true', want 'false'")
+	}
+
+	if !dba.Status.Ready || dba.Status.ObservedGeneration != dba.Generation {
+		testhelp.Errorf(t, start, "dba.SetCondition(Ready): status, got ready '%t' generation '%d', want 'true' '%d'",
+			dba.Status.Ready, dba.Status.ObservedGeneration, dba.Generation)
+	}
+
+	dba.SetDegraded(v1.ConditionReasonFailed, "broken")
+
+	if !dba.IsConditionTrue(v1.ConditionDegraded) {
+		testhelp.Errorf(t, start, "dba.SetDegraded(): Degraded condition, got 'false', want 'true'")
+	}
+
+	if diff := cmp.Diff([]any{dba.Status.Error, dba.Status.ErrorMessage}, []any{true, "broken"}); diff != "" {
+		testhelp.Errorf(t, start, "dba.SetDegraded(): error status -got +want:\n%s", diff)
+	}
+
+	dba.SetCondition(v1.ConditionDegraded, metav1.ConditionFalse, v1.ConditionReasonAsExpected, "")
+
+	if dba.Status.Error || dba.Status.ErrorMessage != "" {
+		testhelp.Errorf(t, start, "dba.SetCondition(Degraded=False): error status, got '%t' '%s', want 'false' ''",
+			dba.Status.Error, dba.Status.ErrorMessage)
+	}
+
+	// a new generation that fails is observed, the conditions report the failure.
+	dba.Generation++
+	dba.SetDegraded(v1.ConditionReasonFailed, "broken")
+
+	if dba.Status.ObservedGeneration != dba.Generation {
+		testhelp.Errorf(t, start, "dba.SetDegraded(): observed generation, got '%d', want '%d'",
+			dba.Status.ObservedGeneration, dba.Generation)
+	}
+}
+
+func TestGetNextPasswordRotation(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	lastRotated := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		rotation  *v1.DatabaseAccountPasswordRotation
+		expect    time.Time
+		expectOK  bool
+		expectErr error
+	}{
+		{"Disabled", nil, time.Time{}, false, nil},
+		{
+			"Interval",
+			&v1.DatabaseAccountPasswordRotation{Interval: &metav1.Duration{Duration: 24 * time.Hour}},
+			lastRotated.Add(24 * time.Hour), true, nil,
+		},
+		{
+			"Schedule",
+			&v1.DatabaseAccountPasswordRotation{Schedule: "30 2 * * *"},
+			time.Date(2024, 1, 3, 2, 30, 0, 0, time.UTC), true, nil,
+		},
+		{
+			"InvalidSchedule",
+			&v1.DatabaseAccountPasswordRotation{Schedule: "not a schedule"},
+			time.Time{}, false, v1.ErrInvalidRotationSchedule,
+		},
+		{"OnRequestOnly", &v1.DatabaseAccountPasswordRotation{}, time.Time{}, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dba := v1test.NewDatabaseAccount()
+			dba.Spec.PasswordRotation = tt.rotation
+			dba.Status.LastRotated = &metav1.Time{Time: lastRotated}
+
+			next, ok, err := dba.GetNextPasswordRotation()
+			if !errors.Is(err, tt.expectErr) {
+				testhelp.Errorf(t, start, "dba.GetNextPasswordRotation(): error, got '%v', want '%v'", err, tt.expectErr)
+			}
+
+			if ok != tt.expectOK {
+				testhelp.Errorf(t, start, "dba.GetNextPasswordRotation(): ok, got '%t', want '%t'", ok, tt.expectOK)
+			}
+
+			if !next.Equal(tt.expect) {
+				testhelp.Errorf(t, start, "dba.GetNextPasswordRotation(): next, got '%s', want '%s'", next, tt.expect)
+			}
+		})
+	}
+}
+
+func TestGetPasswordRotationRequest(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	tests := []struct {
+		name       string
+		annotation string
+		handled    string
+		expect     time.Time
+		expectOK   bool
+	}{
+		{"NoAnnotation", "", "", time.Time{}, false},
+		{"Timestamp", "2024-01-02T03:04:05Z", "", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), true},
+		{"Handled", "2024-01-02T03:04:05Z", "2024-01-02T03:04:05Z", time.Time{}, false},
+		{"NotTimestamp", "now", "", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dba := v1test.NewDatabaseAccount()
+			if tt.annotation != "" {
+				dba.Annotations = map[string]string{v1.AnnotationRotateAt: tt.annotation}
+			}
+			dba.Status.RotateAtHandled = tt.handled
+
+			at, ok := dba.GetPasswordRotationRequest()
+			if ok != tt.expectOK {
+				testhelp.Errorf(t, start, "dba.GetPasswordRotationRequest(): ok, got '%t', want '%t'", ok, tt.expectOK)
+			}
+
+			if !at.Equal(tt.expect) {
+				testhelp.Errorf(t, start, "dba.GetPasswordRotationRequest(): time, got '%s', want '%s'", at, tt.expect)
+			}
+		})
+	}
+}
+
+func TestGetNextLoginRoleName(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	dba := v1test.NewDatabaseAccount()
+	name := dba.Status.Name.String()
+
+	tests := []struct {
+		activeRole      string
+		expectLoginRole string
+		expectNextRole  string
+	}{
+		{"", name, name + v1.DualRoleSuffixA},
+		{name + v1.DualRoleSuffixA, name + v1.DualRoleSuffixA, name + v1.DualRoleSuffixB},
+		{name + v1.DualRoleSuffixB, name + v1.DualRoleSuffixB, name + v1.DualRoleSuffixA},
+	}
+
+	for _, tt := range tests {
+		dba.Status.ActiveRole = tt.activeRole
+
+		if v, err := dba.GetLoginRoleName(); err != nil || v != tt.expectLoginRole {
+			testhelp.Errorf(t, start, "dba.GetLoginRoleName(): got '%s' '%v', want '%s' 'nil'", v, err, tt.expectLoginRole)
+		}
+
+		if v, err := dba.GetNextLoginRoleName(); err != nil || v != tt.expectNextRole {
+			testhelp.Errorf(t, start, "dba.GetNextLoginRoleName(): got '%s' '%v', want '%s' 'nil'", v, err, tt.expectNextRole)
+		}
+	}
+}
+
+func TestGetSchemas(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	tests := []struct {
+		name           string
+		schemas        []string
+		defaultSchema  string
+		roleSettings   map[string]string
+		expectSchemas  []string
+		expectSettings map[string]string
+	}{
+		{"NoSchemas", nil, "", map[string]string{"work_mem": "64MB"}, nil, map[string]string{"work_mem": "64MB"}},
+		{
+			"FirstSchemaDefault", []string{"app_data", "public"}, "", nil,
+			[]string{"app_data", "public"}, map[string]string{"search_path": "app_data, public"},
+		},
+		{
+			"DefaultSchema", []string{"app_data", "reporting"}, "reporting", map[string]string{"work_mem": "64MB"},
+			[]string{"reporting", "app_data"},
+			map[string]string{"work_mem": "64MB", "search_path": "reporting, app_data"},
+		},
+		{
+			"SearchPathSetting", []string{"app_data"}, "", map[string]string{"search_path": "public"},
+			[]string{"app_data"}, map[string]string{"search_path": "public"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dba := v1test.NewDatabaseAccount()
+			dba.Spec.Schemas = tt.schemas
+			dba.Spec.DefaultSchema = tt.defaultSchema
+			dba.Spec.RoleSettings = tt.roleSettings
+
+			if diff := cmp.Diff(dba.GetSchemas(), tt.expectSchemas); diff != "" {
+				testhelp.Errorf(t, start, "dba.GetSchemas(): -got +want:\n%s", diff)
+			}
+
+			if diff := cmp.Diff(dba.GetRoleSettings(), tt.expectSettings); diff != "" {
+				testhelp.Errorf(t, start, "dba.GetRoleSettings(): -got +want:\n%s", diff)
+			}
+
+			if _, ok := dba.Spec.RoleSettings["search_path"]; ok != (tt.roleSettings["search_path"] != "") {
+				testhelp.Errorf(t, start, "dba.GetRoleSettings(): spec role settings modified")
+			}
+		})
+	}
+}
@@ -18,9 +18,19 @@ package v1
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -28,20 +38,32 @@ import (
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // DatabaseAccountSpec defines the desired state of DatabaseAccount.
+// +kubebuilder:validation:XValidation:rule="has(oldSelf.username) == has(self.username) && (!has(self.username) || self.username == oldSelf.username)",message="username is immutable"
+// +kubebuilder:validation:XValidation:rule="!has(self.defaultSchema) || (has(self.schemas) && self.defaultSchema in self.schemas)",message="defaultSchema must be one of the schemas"
 type DatabaseAccountSpec struct {
-	// Username is the username for the postgresql Database account.
+	// Username is the login role name for the postgresql Database account, if not specified the
+	// generated resource name is used. It can not be changed once set.
+	//+optional
 	Username string `json:"username,omitempty"`
 
-	// OnDelete specifies if the database should be removed when the user is removed.
+	// OnDelete specifies if the database should be removed when the user is removed, if not
+	// specified the default of the namespace or the controller configuration is used, delete when
+	// neither set one.
 	//+optional
-	// +kubebuilder:default:=delete
 	OnDelete DatabaseAccountOnDelete `json:"onDelete,omitempty"`
 
 	// CreateRelay will create a relay pod and use that for the DSN if requested.
+	//
+	// Deprecated: use Relay, it is the same as an empty relay.
 	//+optional
 	// +kubebuilder:default:=false
 	CreateRelay bool `json:"createRelay,omitempty"`
 
+	// Relay will create a PgBouncer relay pod and use that for the DSN, settings that are not
+	// specified use the relay defaults from the controller configuration.
+	//+optional
+	Relay *DatabaseAccountRelay `json:"relay,omitempty"`
+
 	// Name is the basename used for the resource, if not specified a UUID will be used.
 	//+optional
 	Name PostgreSQLResourceName `json:"name,omitempty"`
@@ -52,6 +74,544 @@ type DatabaseAccountSpec struct {
 
 	// SecretTemplate is the optional spec to be added to secrets generated by the DatabaseAccountSpec.
 	SecretTemplate DatabaseAccountSpecSecretTemplate `json:"secretTemplate,omitempty"`
+
+	// SecretPolicy is what is done when the secret already exists, Fail stops when the secret is
+	// immutable, Adopt uses the credentials in the secret and Replace recreates the secret. If not
+	// specified Fail is used.
+	//+optional
+	SecretPolicy DatabaseAccountSecretPolicy `json:"secretPolicy,omitempty"`
+
+	// ServerRef is the optional reference to the DatabaseServer the account is created on,
+	// if not specified the server from the controller configuration is used.
+	//+optional
+	ServerRef *DatabaseAccountServerRef `json:"serverRef,omitempty"`
+
+	// PasswordRotation is the optional schedule for rotating the password of the account.
+	//+optional
+	PasswordRotation *DatabaseAccountPasswordRotation `json:"passwordRotation,omitempty"`
+
+	// Role is the optional attributes and memberships of the account role.
+	//+optional
+	Role *DatabaseAccountRole `json:"role,omitempty"`
+
+	// RoleSettings are the run-time parameters set on the account role, e.g. "statement_timeout".
+	//+optional
+	// +kubebuilder:validation:XValidation:rule="self.all(k, k.matches('^[a-zA-Z_][a-zA-Z0-9_]*([.][a-zA-Z_][a-zA-Z0-9_]*)?$'))",message="setting names must be valid parameter names"
+	RoleSettings map[string]string `json:"roleSettings,omitempty"`
+
+	// DatabaseSettings are the run-time parameters set on the account database, e.g. "work_mem".
+	//+optional
+	// +kubebuilder:validation:XValidation:rule="self.all(k, k.matches('^[a-zA-Z_][a-zA-Z0-9_]*([.][a-zA-Z_][a-zA-Z0-9_]*)?$'))",message="setting names must be valid parameter names"
+	DatabaseSettings map[string]string `json:"databaseSettings,omitempty"`
+
+	// Extensions are installed in the account database, they must be allowed by the controller
+	// configuration. Extensions removed from the list are not dropped.
+	//+optional
+	// +listType=map
+	// +listMapKey=name
+	Extensions []DatabaseAccountExtension `json:"extensions,omitempty"`
+
+	// Schemas are created in the account database and owned by the account role, the search_path
+	// of the role and the DualRole login roles is set to the schemas unless it is set in the role
+	// settings. Names starting with "pg_" are reserved, schemas removed from the list are not dropped.
+	//+optional
+	// +listType=set
+	// +kubebuilder:validation:items:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]{0,62}$`
+	Schemas []string `json:"schemas,omitempty"`
+
+	// DefaultSchema is the schema written to the secret and listed first in the search_path, if not
+	// specified the first of the schemas is used.
+	//+optional
+	DefaultSchema string `json:"defaultSchema,omitempty"`
+}
+
+// DatabaseAccountExtension is an extension installed in the account database.
+type DatabaseAccountExtension struct {
+	// Name is the name of the extension, e.g. "pgcrypto".
+	// +kubebuilder:validation:Pattern=`^[a-z_][a-z0-9_-]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// Version is the optional version of the extension, the default version is installed if not
+	// specified. The extension is updated when the version is changed.
+	//+optional
+	Version string `json:"version,omitempty"`
+}
+
+// DatabaseAccountRole defines the attributes and memberships of the account role.
+type DatabaseAccountRole struct {
+	// ConnectionLimit is the number of concurrent connections the role can make, -1 is no limit.
+	//+optional
+	// +kubebuilder:validation:Minimum:=-1
+	ConnectionLimit *int32 `json:"connectionLimit,omitempty"`
+
+	// ValidUntil is the time after which the password of the role is no longer valid.
+	//+optional
+	ValidUntil *metav1.Time `json:"validUntil,omitempty"`
+
+	// CreateDB allows the role to create databases.
+	//+optional
+	CreateDB bool `json:"createDB,omitempty"`
+
+	// Inherit allows the role to use the privileges of the roles it is a member of.
+	//+optional
+	// +kubebuilder:default:=true
+	Inherit *bool `json:"inherit,omitempty"`
+
+	// BypassRLS allows the role to bypass row level security policies, it is only applied when
+	// allowed by the controller configuration.
+	//+optional
+	BypassRLS bool `json:"bypassRLS,omitempty"`
+
+	// MemberOf is the list of roles the role is granted membership of, e.g. "pg_read_all_data".
+	//+optional
+	// +listType=set
+	MemberOf []string `json:"memberOf,omitempty"`
+}
+
+// GetConnectionLimit returns the connection limit of the role, -1 is no limit.
+func (r *DatabaseAccountRole) GetConnectionLimit() int32 {
+	if r == nil || r.ConnectionLimit == nil {
+		return -1
+	}
+
+	return *r.ConnectionLimit
+}
+
+// GetInherit returns true if the role inherits the privileges of the roles it is a member of.
+func (r *DatabaseAccountRole) GetInherit() bool {
+	if r == nil || r.Inherit == nil {
+		return true
+	}
+
+	return *r.Inherit
+}
+
+// GetMemberOf returns the list of roles the role is a member of.
+func (r *DatabaseAccountRole) GetMemberOf() []string {
+	if r == nil {
+		return nil
+	}
+
+	return r.MemberOf
+}
+
+// DatabaseAccountRelay defines the PgBouncer relay created for the account.
+// +kubebuilder:validation:XValidation:rule="!has(self.shared) || !self.shared || !has(self.tls)",message="tls can not be set on a shared relay"
+// +kubebuilder:validation:XValidation:rule="!has(self.shared) || !self.shared || !has(self.type)",message="type can not be set on a shared relay"
+// +kubebuilder:validation:XValidation:rule="!has(self.shared) || !self.shared || !has(self.allowedClients)",message="allowedClients can not be set on a shared relay"
+type DatabaseAccountRelay struct {
+	RelaySettings `json:",inline"`
+
+	// Type is the connection pooler used for the relay, relayType from the controller configuration is
+	// used when it is not set.
+	//+optional
+	Type RelayType `json:"type,omitempty"`
+
+	// Shared adds the account to the relay shared by the accounts of the namespace instead of creating
+	// a relay for the account. The pool mode, pool size and database connection limit are applied to
+	// the account database, the other settings of the shared relay are from the controller configuration.
+	//+optional
+	Shared bool `json:"shared,omitempty"`
+
+	// Replicas is the number of relay pods, a PodDisruptionBudget is created when there is more
+	// than one.
+	//+optional
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum:=1
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Resources are the compute resources of the relay container.
+	//+optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeSelector is the node labels the relay pods must be scheduled on.
+	//+optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations are the tolerations of the relay pods.
+	//+optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity is the scheduling affinity of the relay pods.
+	//+optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// TopologySpreadConstraints are how the relay pods are spread across the topology domains.
+	//+optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// ImagePullSecrets are the secrets used to pull the relay image.
+	//+optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// TLS configures TLS for the connections to and from the relay.
+	//+optional
+	TLS *RelayTLS `json:"tls,omitempty"`
+
+	// AllowedClients are the pods allowed to connect to the relay. When it is set a NetworkPolicy is
+	// created that only admits these clients on the postgresql port and only allows the relay to
+	// connect to the database server.
+	//+optional
+	AllowedClients []RelayAllowedClient `json:"allowedClients,omitempty"`
+}
+
+// RelayAllowedClient selects pods allowed to connect to the relay. A pod selector alone selects pods
+// in the namespace of the relay, a namespace selector alone selects every pod in the namespaces, and
+// both select the matching pods in the matching namespaces.
+// +kubebuilder:validation:XValidation:rule="has(self.podSelector) || has(self.namespaceSelector)",message="podSelector or namespaceSelector must be set"
+type RelayAllowedClient struct {
+	// PodSelector selects the client pods by label.
+	//+optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+
+	// NamespaceSelector selects the namespaces of the client pods by label.
+	//+optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// GetReplicas returns the number of relay pods.
+func (r *DatabaseAccountRelay) GetReplicas() int32 {
+	if r == nil || r.Replicas == nil {
+		return 1
+	}
+
+	return *r.Replicas
+}
+
+// GetTLS returns the TLS configuration of the relay, nil is returned if TLS is not configured.
+func (r *DatabaseAccountRelay) GetTLS() *RelayTLS {
+	if r == nil {
+		return nil
+	}
+
+	return r.TLS
+}
+
+// GetType returns the connection pooler of the relay, empty if it is not set.
+func (r *DatabaseAccountRelay) GetType() RelayType {
+	if r == nil {
+		return ""
+	}
+
+	return r.Type
+}
+
+// GetAllowedClients returns the clients allowed to connect to the relay, nil if every client is allowed.
+func (r *DatabaseAccountRelay) GetAllowedClients() []RelayAllowedClient {
+	if r == nil {
+		return nil
+	}
+
+	return r.AllowedClients
+}
+
+// IsShared returns true if the account uses the shared relay of the namespace.
+func (r *DatabaseAccountRelay) IsShared() bool {
+	return r != nil && r.Shared
+}
+
+// Validate returns an error if the type, TLS configuration or allowed clients are invalid, or they are
+// set on a shared relay.
+func (r *DatabaseAccountRelay) Validate() error {
+	switch r.GetType() {
+	case "", RelayTypePgBouncer, RelayTypePgCat, RelayTypeOdyssey:
+	default:
+		return fmt.Errorf("%w: unknown relay type %s", ErrInvalidRelaySettings, r.Type)
+	}
+
+	if r.IsShared() && r.TLS != nil {
+		return fmt.Errorf("%w: tls can not be set on a shared relay", ErrInvalidRelaySettings)
+	}
+
+	if r.IsShared() && r.Type != "" {
+		return fmt.Errorf("%w: type can not be set on a shared relay", ErrInvalidRelaySettings)
+	}
+
+	if r.IsShared() && r.AllowedClients != nil {
+		return fmt.Errorf("%w: allowedClients can not be set on a shared relay", ErrInvalidRelaySettings)
+	}
+
+	for i, client := range r.GetAllowedClients() {
+		if client.PodSelector == nil && client.NamespaceSelector == nil {
+			return fmt.Errorf("%w: allowedClients[%d] has no podSelector or namespaceSelector",
+				ErrInvalidRelaySettings, i)
+		}
+	}
+
+	return r.GetTLS().Validate()
+}
+
+// RelayTLS defines TLS for the connections from applications to the relay and from the relay to the
+// database server.
+type RelayTLS struct {
+	// Client configures TLS for the connections from applications to the relay.
+	//+optional
+	Client *RelayClientTLS `json:"client,omitempty"`
+
+	// Server configures TLS for the connections from the relay to the database server.
+	//+optional
+	Server *RelayServerTLS `json:"server,omitempty"`
+}
+
+// GetClient returns the client TLS configuration, nil is returned if client TLS is not configured.
+func (t *RelayTLS) GetClient() *RelayClientTLS {
+	if t == nil {
+		return nil
+	}
+
+	return t.Client
+}
+
+// GetServer returns the server TLS configuration, nil is returned if server TLS is not configured.
+func (t *RelayTLS) GetServer() *RelayServerTLS {
+	if t == nil {
+		return nil
+	}
+
+	return t.Server
+}
+
+// RelayClientTLS defines TLS for the connections from applications to the relay.
+type RelayClientTLS struct {
+	// SSLMode is the client_tls_sslmode of the relay, require rejects clients not using TLS.
+	//+optional
+	// +kubebuilder:default:=require
+	SSLMode RelayTLSMode `json:"sslMode,omitempty"`
+
+	// SecretRef is a kubernetes.io/tls secret in the namespace of the account with the relay
+	// certificate, the ca.crt of the secret is published to the account secret. A self-signed CA
+	// and certificate are generated by the operator when it is not set.
+	//+optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// GetSSLMode returns the client_tls_sslmode of the relay.
+func (c *RelayClientTLS) GetSSLMode() RelayTLSMode {
+	if c == nil || c.SSLMode == "" {
+		return RelayTLSModeRequire
+	}
+
+	return c.SSLMode
+}
+
+// RelayServerTLS defines TLS for the connections from the relay to the database server.
+//
+// +kubebuilder:validation:XValidation:rule="!(self.sslMode in ['verify-ca', 'verify-full']) || has(self.caSecretRef)",message="caSecretRef is required to verify the server certificate"
+type RelayServerTLS struct {
+	// SSLMode is the server_tls_sslmode of the relay.
+	//+optional
+	// +kubebuilder:default:=require
+	SSLMode RelayTLSMode `json:"sslMode,omitempty"`
+
+	// CASecretRef is the key of a secret in the namespace of the account with the CA bundle used to
+	// verify the certificate of the database server.
+	//+optional
+	CASecretRef *corev1.SecretKeySelector `json:"caSecretRef,omitempty"`
+}
+
+// GetSSLMode returns the server_tls_sslmode of the relay.
+func (s *RelayServerTLS) GetSSLMode() RelayTLSMode {
+	if s == nil || s.SSLMode == "" {
+		return RelayTLSModeRequire
+	}
+
+	return s.SSLMode
+}
+
+// GetCASecretRef returns the secret key of the CA bundle of the database server.
+func (s *RelayServerTLS) GetCASecretRef() *corev1.SecretKeySelector {
+	if s == nil {
+		return nil
+	}
+
+	return s.CASecretRef
+}
+
+// Validate returns an error if the TLS configuration can not be used for a relay.
+func (t *RelayTLS) Validate() error {
+	if t == nil {
+		return nil
+	}
+
+	for _, mode := range []RelayTLSMode{t.Client.GetSSLMode(), t.Server.GetSSLMode()} {
+		switch mode {
+		case RelayTLSModeDisable, RelayTLSModeAllow, RelayTLSModePrefer, RelayTLSModeRequire,
+			RelayTLSModeVerifyCA, RelayTLSModeVerifyFull:
+		default:
+			return fmt.Errorf("%w: unknown TLS sslMode %s", ErrInvalidRelaySettings, mode)
+		}
+	}
+
+	if t.Client != nil && t.Client.SecretRef != nil && t.Client.SecretRef.Name == "" {
+		return fmt.Errorf("%w: tls.client.secretRef requires a name", ErrInvalidRelaySettings)
+	}
+
+	if t.Server != nil && t.Server.GetSSLMode().IsVerify() &&
+		(t.Server.CASecretRef == nil || t.Server.CASecretRef.Name == "") {
+		return fmt.Errorf("%w: tls.server.caSecretRef is required to verify the server certificate",
+			ErrInvalidRelaySettings)
+	}
+
+	return nil
+}
+
+// RelaySettings defines the PgBouncer settings of a relay.
+// +kubebuilder:validation:XValidation:rule="!has(self.defaultPoolSize) || !has(self.maxClientConn) || self.defaultPoolSize <= self.maxClientConn",message="defaultPoolSize can not be more than maxClientConn"
+type RelaySettings struct {
+	// PoolMode is when a server connection is released back to the pool.
+	//+optional
+	PoolMode RelayPoolMode `json:"poolMode,omitempty"`
+
+	// MaxClientConn is the maximum number of client connections to the relay.
+	//+optional
+	// +kubebuilder:validation:Minimum:=1
+	MaxClientConn *int32 `json:"maxClientConn,omitempty"`
+
+	// DefaultPoolSize is the number of server connections for each user and database.
+	//+optional
+	// +kubebuilder:validation:Minimum:=1
+	DefaultPoolSize *int32 `json:"defaultPoolSize,omitempty"`
+
+	// ServerIdleTimeout is how long an idle server connection is kept open, e.g. "10m".
+	//+optional
+	ServerIdleTimeout *metav1.Duration `json:"serverIdleTimeout,omitempty"`
+
+	// MaxDBConnections is the maximum number of server connections to the database, 0 is no limit.
+	//+optional
+	// +kubebuilder:validation:Minimum:=0
+	MaxDBConnections *int32 `json:"maxDBConnections,omitempty"`
+}
+
+// WithDefaults returns the settings with the settings that are not specified taken from defaults.
+func (s RelaySettings) WithDefaults(defaults RelaySettings) RelaySettings {
+	if s.PoolMode == "" {
+		s.PoolMode = defaults.PoolMode
+	}
+
+	if s.MaxClientConn == nil {
+		s.MaxClientConn = defaults.MaxClientConn
+	}
+
+	if s.DefaultPoolSize == nil {
+		s.DefaultPoolSize = defaults.DefaultPoolSize
+	}
+
+	if s.ServerIdleTimeout == nil {
+		s.ServerIdleTimeout = defaults.ServerIdleTimeout
+	}
+
+	if s.MaxDBConnections == nil {
+		s.MaxDBConnections = defaults.MaxDBConnections
+	}
+
+	return s
+}
+
+// GetPoolMode returns the pool mode, session if it is not specified.
+func (s RelaySettings) GetPoolMode() RelayPoolMode {
+	if s.PoolMode == "" {
+		return RelayPoolModeSession
+	}
+
+	return s.PoolMode
+}
+
+// GetMaxClientConn returns the maximum number of client connections.
+func (s RelaySettings) GetMaxClientConn() int32 {
+	if s.MaxClientConn == nil {
+		return DefaultRelayMaxClientConn
+	}
+
+	return *s.MaxClientConn
+}
+
+// GetDefaultPoolSize returns the number of server connections for each user and database.
+func (s RelaySettings) GetDefaultPoolSize() int32 {
+	if s.DefaultPoolSize == nil {
+		return DefaultRelayDefaultPoolSize
+	}
+
+	return *s.DefaultPoolSize
+}
+
+// GetServerIdleTimeout returns how long an idle server connection is kept open.
+func (s RelaySettings) GetServerIdleTimeout() time.Duration {
+	if s.ServerIdleTimeout == nil {
+		return DefaultRelayServerIdleTimeout
+	}
+
+	return s.ServerIdleTimeout.Duration
+}
+
+// GetMaxDBConnections returns the maximum number of server connections to the database, 0 is no limit.
+func (s RelaySettings) GetMaxDBConnections() int32 {
+	if s.MaxDBConnections == nil {
+		return 0
+	}
+
+	return *s.MaxDBConnections
+}
+
+// Validate returns an error if the settings can not be used for a relay.
+func (s RelaySettings) Validate() error {
+	switch s.GetPoolMode() {
+	case RelayPoolModeSession, RelayPoolModeTransaction, RelayPoolModeStatement:
+	default:
+		return fmt.Errorf("%w: unknown pool mode %s", ErrInvalidRelaySettings, s.PoolMode)
+	}
+
+	switch {
+	case s.GetMaxClientConn() < 1:
+		return fmt.Errorf("%w: maxClientConn must be at least 1", ErrInvalidRelaySettings)
+	case s.GetDefaultPoolSize() < 1:
+		return fmt.Errorf("%w: defaultPoolSize must be at least 1", ErrInvalidRelaySettings)
+	case s.GetDefaultPoolSize() > s.GetMaxClientConn():
+		return fmt.Errorf("%w: defaultPoolSize can not be more than maxClientConn", ErrInvalidRelaySettings)
+	case s.GetServerIdleTimeout() < 0:
+		return fmt.Errorf("%w: serverIdleTimeout can not be negative", ErrInvalidRelaySettings)
+	case s.GetMaxDBConnections() < 0:
+		return fmt.Errorf("%w: maxDBConnections can not be negative", ErrInvalidRelaySettings)
+	}
+
+	return nil
+}
+
+// DatabaseAccountPasswordRotation defines when and how the password of the account is rotated.
+//
+// +kubebuilder:validation:XValidation:rule="!(has(self.interval) && has(self.schedule))",message="only one of interval or schedule can be set"
+type DatabaseAccountPasswordRotation struct {
+	// Interval is the time between password rotations, e.g. "720h".
+	//+optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Schedule is a standard cron expression for when the password is rotated, e.g. "0 3 * * 0".
+	//+optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// Mode is how the password is rotated, InPlace changes the password of the login role and
+	// DualRole switches between two login roles that are members of the account role.
+	//+optional
+	Mode DatabaseAccountRotationMode `json:"mode,omitempty"`
+
+	// GracePeriod is the time the previous login role can still be used after a DualRole rotation.
+	//+optional
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+}
+
+// DatabaseAccountServerRef is a reference to a cluster scoped DatabaseServer.
+type DatabaseAccountServerRef struct {
+	// Name is the name of the DatabaseServer.
+	Name string `json:"name"`
+}
+
+// DatabaseAccountPreviousSecret is the immutable secret of a previous version of the credentials.
+type DatabaseAccountPreviousSecret struct {
+	// Name is the name of the secret.
+	Name string `json:"name"`
+
+	// DeleteAt is the time the secret is deleted.
+	DeleteAt metav1.Time `json:"deleteAt"`
 }
 
 // DatabaseAccountSpecSecretTemplate defines the desired state of DatabaseAccount.
@@ -92,18 +652,106 @@ type DatabaseAccountStatus struct {
 	// Name is the basename used for the resource.
 	Name PostgreSQLResourceName `json:"name,omitempty"`
 
-	// Ready is the boolean for when a resource is ready to use.
+	// Username is the login role name used for the account.
+	//
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// Ready is the boolean for when a resource is ready to use, it mirrors the Ready condition.
 	//
 	//+kubebuilder:default:=false
 	Ready bool `json:"ready,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed by the controller, the conditions
+	// report whether it was reconciled to ready or failed.
+	//
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the account state.
+	//
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// LastRotated is the time the password was last rotated.
+	//
+	// +optional
+	LastRotated *metav1.Time `json:"lastRotated,omitempty"`
+
+	// RotateAtHandled is the value of the rotate-at annotation that was last handled.
+	//
+	// +optional
+	RotateAtHandled string `json:"rotateAtHandled,omitempty"`
+
+	// ActiveRole is the login role in the secret when using DualRole rotation.
+	//
+	// +optional
+	ActiveRole string `json:"activeRole,omitempty"`
+
+	// PreviousRole is the login role that is revoked when the grace period ends.
+	//
+	// +optional
+	PreviousRole string `json:"previousRole,omitempty"`
+
+	// RevokeAt is the time the login of the previous role is revoked.
+	//
+	// +optional
+	RevokeAt *metav1.Time `json:"revokeAt,omitempty"`
+
+	// CurrentSecretName is the immutable secret with the current version of the credentials.
+	//
+	// +optional
+	CurrentSecretName string `json:"currentSecretName,omitempty"`
+
+	// PreviousSecrets are the immutable secrets of previous versions of the credentials, they are
+	// deleted once the retention of the controller configuration has passed.
+	//
+	// +optional
+	PreviousSecrets []DatabaseAccountPreviousSecret `json:"previousSecrets,omitempty"`
+
+	// Role is the role attributes and memberships that were last applied to the account role.
+	//
+	// +optional
+	Role *DatabaseAccountRole `json:"role,omitempty"`
+
+	// RoleSettings are the run-time parameters that were last applied to the account role.
+	//
+	// +optional
+	RoleSettings map[string]string `json:"roleSettings,omitempty"`
+
+	// DatabaseSettings are the run-time parameters that were last applied to the account database.
+	//
+	// +optional
+	DatabaseSettings map[string]string `json:"databaseSettings,omitempty"`
+
+	// Extensions are the installed versions of the extensions in the spec.
+	//
+	// +optional
+	Extensions map[string]string `json:"extensions,omitempty"`
+
+	// Schemas are the schemas that were created in the account database, the default schema first.
+	//
+	// +optional
+	Schemas []string `json:"schemas,omitempty"`
+
+	// RelayMode is the relay that was last deployed for the account, the relay is removed when the
+	// spec changes to another mode or no relay.
+	//
+	// +optional
+	RelayMode RelayMode `json:"relayMode,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Stage",type=string,JSONPath=`.status.stage`,description="deployment stage for database account"
-// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.ready`,description="ready status of database account"
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`,description="ready status of database account"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:printcolumn:name="Name",priority=1,type=string,JSONPath=`.status.name`,description="name of the database account"
+// +kubebuilder:printcolumn:name="Server",priority=1,type=string,JSONPath=`.spec.serverRef.name`,description="database server of the database account"
 // +kubebuilder:resource:shortName="dba"
 
 // DatabaseAccount is the Schema for the databaseaccounts API.
@@ -124,6 +772,15 @@ func (d *DatabaseAccount) GetReference() *metav1.OwnerReference {
 	}
 }
 
+// GetControllerReference returns the owner reference for resources controlled by the account.
+func (d *DatabaseAccount) GetControllerReference() *metav1.OwnerReference {
+	ref := d.GetReference()
+	ref.Controller = ptr.To(true)
+	ref.BlockOwnerDeletion = ptr.To(true)
+
+	return ref
+}
+
 func (d *DatabaseAccount) GetSecretName() types.NamespacedName {
 	if d.Spec.SecretName != "" {
 		return types.NamespacedName{
@@ -142,6 +799,31 @@ func (d *DatabaseAccount) GetStatefulSetName() types.NamespacedName {
 	return d.GetSecretName()
 }
 
+// GetSpecRelayMode returns how the relay of the account is deployed, empty when there is no relay.
+func (d *DatabaseAccount) GetSpecRelayMode() RelayMode {
+	switch {
+	case !d.GetSpecCreateRelay():
+		return ""
+	case d.GetSpecRelay().IsShared():
+		return RelayModeShared
+	default:
+		return RelayModeDedicated
+	}
+}
+
+// GetRelayName returns the name of the relay Service the account connects through, this is the shared
+// relay of the namespace for accounts using it.
+func (d *DatabaseAccount) GetRelayName() types.NamespacedName {
+	if d.GetSpecRelay().IsShared() {
+		return types.NamespacedName{
+			Namespace: d.GetNamespace(),
+			Name:      SharedRelayName,
+		}
+	}
+
+	return d.GetStatefulSetName()
+}
+
 func (d *DatabaseAccount) GetDatabaseName() (string, error) {
 	if len(d.Status.Name) == 0 {
 		return "", ErrMissingDatabaseUsername
@@ -150,6 +832,16 @@ func (d *DatabaseAccount) GetDatabaseName() (string, error) {
 	return d.Status.Name.String(), nil
 }
 
+// GetRoleName returns the role that owns the database, this is the username from the spec if one was
+// specified, otherwise it is the database name.
+func (d *DatabaseAccount) GetRoleName() (string, error) {
+	if d.Status.Username != "" {
+		return d.Status.Username, nil
+	}
+
+	return d.GetDatabaseName()
+}
+
 func (d *DatabaseAccount) GetSpecOnDelete() DatabaseAccountOnDelete {
 	switch d.Spec.OnDelete {
 	case OnDeleteRetain:
@@ -161,8 +853,229 @@ func (d *DatabaseAccount) GetSpecOnDelete() DatabaseAccountOnDelete {
 	return OnDeleteDelete
 }
 
+func (d *DatabaseAccount) GetSpecSecretPolicy() DatabaseAccountSecretPolicy {
+	switch d.Spec.SecretPolicy {
+	case SecretPolicyAdopt:
+		return SecretPolicyAdopt
+	case SecretPolicyReplace:
+		return SecretPolicyReplace
+	case SecretPolicyFail:
+		return SecretPolicyFail
+	}
+
+	return SecretPolicyFail
+}
+
+func (d *DatabaseAccount) GetSpecServerRef() string {
+	if d.Spec.ServerRef == nil {
+		return ""
+	}
+
+	return d.Spec.ServerRef.Name
+}
+
+func (d *DatabaseAccount) GetSpecPasswordRotation() *DatabaseAccountPasswordRotation {
+	return d.Spec.PasswordRotation
+}
+
+func (d *DatabaseAccount) GetSpecRole() *DatabaseAccountRole {
+	return d.Spec.Role
+}
+
+// IsRoleSynced returns true if the role attributes and memberships in the spec have been applied.
+func (d *DatabaseAccount) IsRoleSynced() bool {
+	return equality.Semantic.DeepEqual(d.Spec.Role, d.Status.Role)
+}
+
+// GetDefaultSchema returns the default schema of the account, the first of the schemas if it is not
+// specified. An empty string is returned if the account has no schemas.
+func (d *DatabaseAccount) GetDefaultSchema() string {
+	if d.Spec.DefaultSchema != "" {
+		return d.Spec.DefaultSchema
+	}
+
+	if len(d.Spec.Schemas) > 0 {
+		return d.Spec.Schemas[0]
+	}
+
+	return ""
+}
+
+// GetSchemas returns the schemas of the account with the default schema first.
+func (d *DatabaseAccount) GetSchemas() []string {
+	defaultSchema := d.GetDefaultSchema()
+	if defaultSchema == "" {
+		return nil
+	}
+
+	schemas := []string{defaultSchema}
+	for _, v := range d.Spec.Schemas {
+		if v != defaultSchema {
+			schemas = append(schemas, v)
+		}
+	}
+
+	return schemas
+}
+
+// IsSchemasSynced returns true if the schemas in the spec have been created.
+func (d *DatabaseAccount) IsSchemasSynced() bool {
+	return slices.Equal(d.GetSchemas(), d.Status.Schemas)
+}
+
+// GetRoleSettings returns the run-time parameters of the account role, the search_path is set to the
+// schemas of the account when it is not in the role settings.
+func (d *DatabaseAccount) GetRoleSettings() map[string]string {
+	schemas := d.GetSchemas()
+	if len(schemas) == 0 {
+		return d.Spec.RoleSettings
+	}
+
+	if _, ok := d.Spec.RoleSettings["search_path"]; ok {
+		return d.Spec.RoleSettings
+	}
+
+	settings := maps.Clone(d.Spec.RoleSettings)
+	if settings == nil {
+		settings = map[string]string{}
+	}
+	settings["search_path"] = strings.Join(schemas, ", ")
+
+	return settings
+}
+
+// IsSettingsSynced returns true if the role and database settings in the spec have been applied.
+func (d *DatabaseAccount) IsSettingsSynced() bool {
+	return equality.Semantic.DeepEqual(d.GetRoleSettings(), d.Status.RoleSettings) &&
+		equality.Semantic.DeepEqual(d.Spec.DatabaseSettings, d.Status.DatabaseSettings)
+}
+
+// IsExtensionsSynced returns true if the extensions in the spec are installed with the requested
+// versions.
+func (d *DatabaseAccount) IsExtensionsSynced() bool {
+	if len(d.Spec.Extensions) != len(d.Status.Extensions) {
+		return false
+	}
+
+	for _, ext := range d.Spec.Extensions {
+		version, ok := d.Status.Extensions[ext.Name]
+		if !ok || (ext.Version != "" && ext.Version != version) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetNextPasswordRotation returns the time the password is next due to be rotated, the schedule
+// starts from the last rotation or when the account was created. False is returned if password
+// rotation is not enabled.
+func (d *DatabaseAccount) GetNextPasswordRotation() (time.Time, bool, error) {
+	rotation := d.GetSpecPasswordRotation()
+	if rotation == nil {
+		return time.Time{}, false, nil
+	}
+
+	from := d.GetCreationTimestamp().Time
+	if d.Status.LastRotated != nil {
+		from = d.Status.LastRotated.Time
+	}
+
+	switch {
+	case rotation.Interval != nil && rotation.Interval.Duration > 0:
+		return from.Add(rotation.Interval.Duration), true, nil
+	case rotation.Schedule != "":
+		schedule, err := cron.ParseStandard(rotation.Schedule)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%w: %w", ErrInvalidRotationSchedule, err)
+		}
+
+		return schedule.Next(from), true, nil
+	}
+
+	// rotation is only done on request.
+	return time.Time{}, false, nil
+}
+
+func (d *DatabaseAccount) GetSpecRotationMode() DatabaseAccountRotationMode {
+	if d.Spec.PasswordRotation == nil {
+		return RotationModeInPlace
+	}
+
+	switch d.Spec.PasswordRotation.Mode {
+	case RotationModeDualRole:
+		return RotationModeDualRole
+	case RotationModeInPlace:
+		return RotationModeInPlace
+	}
+
+	return RotationModeInPlace
+}
+
+func (d *DatabaseAccount) GetSpecRotationGracePeriod() time.Duration {
+	if d.Spec.PasswordRotation == nil || d.Spec.PasswordRotation.GracePeriod == nil {
+		return DefaultRotationGracePeriod
+	}
+
+	return d.Spec.PasswordRotation.GracePeriod.Duration
+}
+
+// GetLoginRoleName returns the role used to login to the database, this is the account role
+// unless DualRole rotation has switched to one of the login roles.
+func (d *DatabaseAccount) GetLoginRoleName() (string, error) {
+	if d.Status.ActiveRole != "" {
+		return d.Status.ActiveRole, nil
+	}
+
+	return d.GetRoleName()
+}
+
+// GetNextLoginRoleName returns the login role that the next DualRole rotation switches to.
+func (d *DatabaseAccount) GetNextLoginRoleName() (string, error) {
+	name, err := d.GetRoleName()
+	if err != nil {
+		return "", err
+	}
+
+	if d.Status.ActiveRole == name+DualRoleSuffixA {
+		return name + DualRoleSuffixB, nil
+	}
+
+	return name + DualRoleSuffixA, nil
+}
+
+// GetPasswordRotationRequest returns the time from the rotate-at annotation, false is returned if
+// there is no annotation or it has already been handled. A value that is not an RFC3339 timestamp
+// is treated as a request to rotate the password now.
+func (d *DatabaseAccount) GetPasswordRotationRequest() (time.Time, bool) {
+	value := d.GetAnnotations()[AnnotationRotateAt]
+	if value == "" || value == d.Status.RotateAtHandled {
+		return time.Time{}, false
+	}
+
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, true
+	}
+
+	return at, true
+}
+
 func (d *DatabaseAccount) GetSpecCreateRelay() bool {
-	return d.Spec.CreateRelay
+	return d.Spec.Relay != nil || d.Spec.CreateRelay
+}
+
+// GetSpecRelay returns the relay of the account, nil if a relay is not requested.
+func (d *DatabaseAccount) GetSpecRelay() *DatabaseAccountRelay {
+	if d.Spec.Relay != nil {
+		return d.Spec.Relay
+	}
+
+	if d.Spec.CreateRelay {
+		return &DatabaseAccountRelay{}
+	}
+
+	return nil
 }
 
 func (d *DatabaseAccount) UpdateStatus(ctx context.Context, r client.StatusClient) error {
@@ -173,6 +1086,49 @@ func (d *DatabaseAccount) Update(ctx context.Context, r client.Writer) error {
 	return r.Update(ctx, d)
 }
 
+// SetCondition sets the status condition and records the observed generation, the legacy
+// Ready, Error and ErrorMessage fields are derived from the Ready and Degraded conditions.
+func (d *DatabaseAccount) SetCondition(
+	conditionType string,
+	status metav1.ConditionStatus,
+	reason, message string,
+) bool {
+	changed := meta.SetStatusCondition(&d.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: d.GetGeneration(),
+	})
+
+	d.Status.ObservedGeneration = d.GetGeneration()
+	d.Status.Ready = d.IsConditionTrue(ConditionReady)
+	d.Status.Error = d.IsConditionTrue(ConditionDegraded)
+	if c := d.GetCondition(ConditionDegraded); c != nil && c.Status == metav1.ConditionTrue {
+		d.Status.ErrorMessage = c.Message
+	} else {
+		d.Status.ErrorMessage = ""
+	}
+
+	return changed
+}
+
+// SetDegraded marks the account as degraded and not ready.
+func (d *DatabaseAccount) SetDegraded(reason, message string) {
+	d.SetCondition(ConditionDegraded, metav1.ConditionTrue, reason, message)
+	d.SetCondition(ConditionReady, metav1.ConditionFalse, reason, message)
+}
+
+// GetCondition returns the status condition of the type, nil is returned if it is not found.
+func (d *DatabaseAccount) GetCondition(conditionType string) *metav1.Condition {
+	return meta.FindStatusCondition(d.Status.Conditions, conditionType)
+}
+
+// IsConditionTrue returns true if the status condition of the type is true.
+func (d *DatabaseAccount) IsConditionTrue(conditionType string) bool {
+	return meta.IsStatusConditionTrue(d.Status.Conditions, conditionType)
+}
+
 func (d *DatabaseAccount) SetStage(ctx context.Context, r client.StatusClient, stage DatabaseAccountCreateStage) error {
 	if d.Status.Stage != stage {
 		d.Status.Stage = stage
@@ -181,7 +1137,7 @@ func (d *DatabaseAccount) SetStage(ctx context.Context, r client.StatusClient, s
 		case UnknownStage, InitStage, UserCreateStage, DatabaseCreateStage, RelayCreateStage, ErrorStage, ReadyStage:
 			// do nothing
 		case TerminatingStage:
-			d.Status.Ready = false
+			d.SetCondition(ConditionReady, metav1.ConditionFalse, ConditionReasonTerminating, "Account is being removed")
 		}
 
 		return r.Status().Update(ctx, d)
@@ -17,6 +17,12 @@ limitations under the License.
 package v1
 
 import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	configv1alpha1 "k8s.io/component-base/config/v1alpha1"
 )
@@ -41,6 +47,61 @@ type DatabaseAccountControllerConfig struct {
 	// +kubebuilder:default:="edoburu/pgbouncer:1.20.1-p0"
 	RelayImage string `json:"relayImage,omitempty"`
 
+	// RelayType is the connection pooler used for relays that do not specify a type.
+	//+optional
+	// +kubebuilder:default:=pgbouncer
+	RelayType RelayType `json:"relayType,omitempty"`
+
+	// RelayImages are the images used for the relay types, relayImage is used for PgBouncer when
+	// it is not set.
+	//+optional
+	RelayImages map[RelayType]string `json:"relayImages,omitempty"`
+
+	// RelayDefaults are the settings used for relays that do not specify them.
+	//+optional
+	RelayDefaults RelaySettings `json:"relayDefaults,omitempty"`
+
+	// SharedRelay is the relay shared by the accounts of a namespace, settings that are not set use
+	// relayDefaults and relayType. allowedClients applies to the shared relay of every namespace,
+	// TLS is not supported on the shared relay.
+	//+optional
+	SharedRelay *DatabaseAccountRelay `json:"sharedRelay,omitempty"`
+
+	// RelayEgress replaces the egress rules of the relay NetworkPolicies. By default the relay can
+	// connect to the resolved addresses of the database servers, or the pods of the Service when the
+	// host is a cluster Service name, and the cluster DNS. Set it when the database server can not be
+	// matched that way, such as a Service without a selector.
+	//+optional
+	RelayEgress []networkingv1.NetworkPolicyEgressRule `json:"relayEgress,omitempty"`
+
+	// RelayResolveInterval is how often the database server hosts of the relay NetworkPolicies are
+	// resolved again, if not set 5m is used.
+	//+optional
+	RelayResolveInterval *metav1.Duration `json:"relayResolveInterval,omitempty"`
+
+	// AccountDefaults are the values filled in on accounts that do not set them, the annotations of
+	// the namespace of the account take precedence over them.
+	//+optional
+	AccountDefaults DatabaseAccountDefaults `json:"accountDefaults,omitempty"`
+
+	// SecretVersionRetention is how long the immutable secret of a previous version of the credentials
+	// is kept after it is replaced, if not set 24h is used.
+	//+optional
+	SecretVersionRetention *metav1.Duration `json:"secretVersionRetention,omitempty"`
+
+	// AllowBypassRLS allows DatabaseAccount roles to be created with the BYPASSRLS attribute.
+	//+optional
+	AllowBypassRLS bool `json:"allowBypassRLS,omitempty"`
+
+	// AllowedExtensions is the list of extensions that can be installed in DatabaseAccount databases.
+	//+optional
+	AllowedExtensions []string `json:"allowedExtensions,omitempty"`
+
+	// AllowedSettings is the list of run-time parameters that can be set on DatabaseAccount roles and
+	// databases, the search_path set from the schemas of an account is always allowed.
+	//+optional
+	AllowedSettings []string `json:"allowedSettings,omitempty"`
+
 	// LeaderElection config
 	//+optional
 	LeaderElection *configv1alpha1.LeaderElectionConfiguration `json:"leaderElection,omitempty"`
@@ -71,6 +132,127 @@ func (d *DatabaseAccountControllerConfig) GetRelayImage() string {
 	return d.RelayImage
 }
 
+// GetSecretVersionRetention returns how long the secret of a previous version of the credentials
+// is kept.
+func (d *DatabaseAccountControllerConfig) GetSecretVersionRetention() time.Duration {
+	if d.SecretVersionRetention == nil {
+		return DefaultSecretVersionRetention
+	}
+
+	return d.SecretVersionRetention.Duration
+}
+
+// GetRelayResolveInterval returns how often the database server hosts of the relay NetworkPolicies
+// are resolved again.
+func (d *DatabaseAccountControllerConfig) GetRelayResolveInterval() time.Duration {
+	if d.RelayResolveInterval == nil {
+		return DefaultRelayResolveInterval
+	}
+
+	return d.RelayResolveInterval.Duration
+}
+
+// GetRelayImageFor returns the image of the relay type, empty if no image is known for the type.
+func (d *DatabaseAccountControllerConfig) GetRelayImageFor(relayType RelayType) string {
+	if image := d.RelayImages[relayType]; image != "" {
+		return image
+	}
+
+	switch relayType {
+	case RelayTypePgBouncer:
+		return d.GetRelayImage()
+	case RelayTypePgCat:
+		return DefaultPgCatImage
+	default:
+		return ""
+	}
+}
+
+// GetRelayType returns the connection pooler of the account relay, the relay type from the
+// configuration is used when the account does not set it and PgBouncer when neither do.
+func (d *DatabaseAccountControllerConfig) GetRelayType(dbAccount *DatabaseAccount) RelayType {
+	if relay := dbAccount.GetSpecRelay(); relay.IsShared() {
+		return d.GetSharedRelayType()
+	} else if relay.GetType() != "" {
+		return relay.GetType()
+	}
+
+	return d.getDefaultRelayType()
+}
+
+// GetSharedRelayType returns the connection pooler of the shared relay.
+func (d *DatabaseAccountControllerConfig) GetSharedRelayType() RelayType {
+	if t := d.GetSharedRelay().GetType(); t != "" {
+		return t
+	}
+
+	return d.getDefaultRelayType()
+}
+
+func (d *DatabaseAccountControllerConfig) getDefaultRelayType() RelayType {
+	if d.RelayType == "" {
+		return RelayTypePgBouncer
+	}
+
+	return d.RelayType
+}
+
+// ValidateRelay returns an error if the relay settings of the account are invalid or no image is
+// configured for the relay type.
+func (d *DatabaseAccountControllerConfig) ValidateRelay(dbAccount *DatabaseAccount) error {
+	if err := d.GetRelaySettings(dbAccount).Validate(); err != nil {
+		return err
+	}
+
+	if err := dbAccount.GetSpecRelay().Validate(); err != nil {
+		return err
+	}
+
+	if relayType := d.GetRelayType(dbAccount); d.GetRelayImageFor(relayType) == "" {
+		return fmt.Errorf("%w: no image configured for relay type %s", ErrInvalidRelaySettings, relayType)
+	}
+
+	return nil
+}
+
+// ValidateSettings returns an error if the account sets a role or database run-time parameter that
+// is not in the allowed settings.
+func (d *DatabaseAccountControllerConfig) ValidateSettings(dbAccount *DatabaseAccount) error {
+	for _, settings := range []map[string]string{dbAccount.Spec.RoleSettings, dbAccount.Spec.DatabaseSettings} {
+		for _, name := range slices.Sorted(maps.Keys(settings)) {
+			if !slices.Contains(d.AllowedSettings, name) {
+				return fmt.Errorf("%w: %s", ErrSettingNotAllowed, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetRelaySettings returns the relay settings of the account with the relay defaults applied.
+func (d *DatabaseAccountControllerConfig) GetRelaySettings(dbAccount *DatabaseAccount) RelaySettings {
+	relay := dbAccount.GetSpecRelay()
+	if relay == nil {
+		return d.RelayDefaults
+	}
+
+	return relay.RelaySettings.WithDefaults(d.RelayDefaults)
+}
+
+// GetSharedRelay returns the shared relay configuration, the TLS configuration is not used.
+func (d *DatabaseAccountControllerConfig) GetSharedRelay() *DatabaseAccountRelay {
+	if d.SharedRelay == nil {
+		return &DatabaseAccountRelay{}
+	}
+
+	return d.SharedRelay
+}
+
+// GetSharedRelaySettings returns the settings of the shared relay with the relay defaults applied.
+func (d *DatabaseAccountControllerConfig) GetSharedRelaySettings() RelaySettings {
+	return d.GetSharedRelay().RelaySettings.WithDefaults(d.RelayDefaults)
+}
+
 func (d *DatabaseAccountControllerConfig) GetDSNHost() string {
 	return d.DatabaseDSN.Host()
 }
@@ -0,0 +1,48 @@
+package v1_test
+
+import (
+	"testing"
+
+	v1 "github.com/dosquad/database-operator/api/v1"
+)
+
+func TestDatabaseServer_GetDSNSecretName(t *testing.T) {
+	dbServer := v1.DatabaseServer{
+		Spec: v1.DatabaseServerSpec{
+			DSNSecretRef: v1.DatabaseServerSecretRef{
+				Namespace: "database-operator-system",
+				Name:      "prod-dsn",
+			},
+		},
+	}
+
+	if v := dbServer.GetDSNSecretName(); v.String() != "database-operator-system/prod-dsn" {
+		t.Errorf("dbServer.GetDSNSecretName() expected '%v' received '%v'", "database-operator-system/prod-dsn", v)
+	}
+}
+
+func TestDatabaseServer_GetDSNSecretKey(t *testing.T) {
+	dbServer := v1.DatabaseServer{}
+
+	if v := dbServer.GetDSNSecretKey(); v != v1.DefaultDatabaseServerDSNKey {
+		t.Errorf("dbServer.GetDSNSecretKey() default expected '%v' received '%v'", v1.DefaultDatabaseServerDSNKey, v)
+	}
+
+	dbServer.Spec.DSNSecretRef.Key = "admin-dsn"
+	if v := dbServer.GetDSNSecretKey(); v != "admin-dsn" {
+		t.Errorf("dbServer.GetDSNSecretKey() expected '%v' received '%v'", "admin-dsn", v)
+	}
+}
+
+func TestDatabaseAccount_GetSpecServerRef(t *testing.T) {
+	dba := v1.DatabaseAccount{}
+
+	if v := dba.GetSpecServerRef(); v != "" {
+		t.Errorf("dba.GetSpecServerRef() default expected '' received '%v'", v)
+	}
+
+	dba.Spec.ServerRef = &v1.DatabaseAccountServerRef{Name: "analytics"}
+	if v := dba.GetSpecServerRef(); v != "analytics" {
+		t.Errorf("dba.GetSpecServerRef() expected '%v' received '%v'", "analytics", v)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DatabaseServerSpec defines the desired state of DatabaseServer.
+type DatabaseServerSpec struct {
+	// DSNSecretRef is the reference to the secret containing the admin DSN for the server.
+	DSNSecretRef DatabaseServerSecretRef `json:"dsnSecretRef"`
+}
+
+// DatabaseServerSecretRef is a reference to a key in a secret.
+type DatabaseServerSecretRef struct {
+	// Namespace is the namespace of the secret.
+	Namespace string `json:"namespace"`
+
+	// Name is the name of the secret.
+	Name string `json:"name"`
+
+	// Key is the key in the secret that contains the DSN.
+	//+optional
+	// +kubebuilder:default:=dsn
+	Key string `json:"key,omitempty"`
+}
+
+// DatabaseServer is the Schema for the databaseservers API.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,shortName="dbs"
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.spec.dsnSecretRef.name`,description="secret containing the admin dsn"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type DatabaseServer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec DatabaseServerSpec `json:"spec,omitempty"`
+}
+
+func (d *DatabaseServer) GetDSNSecretName() types.NamespacedName {
+	return types.NamespacedName{
+		Namespace: d.Spec.DSNSecretRef.Namespace,
+		Name:      d.Spec.DSNSecretRef.Name,
+	}
+}
+
+func (d *DatabaseServer) GetDSNSecretKey() string {
+	if d.Spec.DSNSecretRef.Key == "" {
+		return DefaultDatabaseServerDSNKey
+	}
+
+	return d.Spec.DSNSecretRef.Key
+}
+
+// DatabaseServerList contains a list of DatabaseServer.
+//
+// +kubebuilder:object:root=true
+type DatabaseServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseServer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseServer{}, &DatabaseServerList{})
+}
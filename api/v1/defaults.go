@@ -0,0 +1,187 @@
+package v1
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// DatabaseAccountDefaults are the values filled in on accounts that do not set them.
+type DatabaseAccountDefaults struct {
+	// OnDelete is the onDelete of accounts that do not set it.
+	//+optional
+	OnDelete DatabaseAccountOnDelete `json:"onDelete,omitempty"`
+
+	// SecretLabels are added to the secret template labels of accounts, labels set by the account
+	// are kept.
+	//+optional
+	SecretLabels map[string]string `json:"secretLabels,omitempty"`
+
+	// PasswordRotation is the password rotation of accounts that do not set one, it is only used when
+	// it has an interval or schedule. The mode and grace period are also filled in on accounts that
+	// set a rotation without them.
+	//+optional
+	PasswordRotation *DatabaseAccountPasswordRotation `json:"passwordRotation,omitempty"`
+}
+
+// accountDefaults are the defaults of the accounts of a namespace.
+//
+// +kubebuilder:object:generate=false
+type accountDefaults struct {
+	DatabaseAccountDefaults
+
+	relayType RelayType
+	relay     RelaySettings
+}
+
+// Default fills in the values the account does not set, the annotations of the namespace of the
+// account take precedence over the controller configuration. An error is returned if an annotation
+// has an invalid value.
+func (d *DatabaseAccountControllerConfig) Default(dbAccount *DatabaseAccount, nsAnnotations map[string]string) error {
+	defaults, err := d.namespaceDefaults(nsAnnotations)
+	if err != nil {
+		return err
+	}
+
+	defaults.apply(dbAccount)
+
+	return nil
+}
+
+// namespaceDefaults returns the defaults of the controller configuration with the namespace
+// annotations applied.
+func (d *DatabaseAccountControllerConfig) namespaceDefaults(nsAnnotations map[string]string) (*accountDefaults, error) {
+	defaults := &accountDefaults{
+		DatabaseAccountDefaults: *d.AccountDefaults.DeepCopy(),
+		relayType:               d.getDefaultRelayType(),
+		relay:                   *d.RelayDefaults.DeepCopy(),
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(nsAnnotations)) {
+		if err := defaults.setAnnotation(key, nsAnnotations[key]); err != nil {
+			return nil, err
+		}
+	}
+
+	return defaults, nil
+}
+
+// setAnnotation sets the default from the namespace annotation, annotations that are not defaults
+// are ignored.
+func (a *accountDefaults) setAnnotation(key, value string) error {
+	invalid := func(err error) error {
+		return fmt.Errorf("%w: namespace annotation %s: %w", ErrInvalidDefaults, key, err)
+	}
+
+	switch key {
+	case AnnotationDefaultOnDelete:
+		if v := DatabaseAccountOnDelete(value); v != OnDeleteRetain && v != OnDeleteDelete {
+			return invalid(fmt.Errorf("unknown onDelete %s", value))
+		}
+		a.OnDelete = DatabaseAccountOnDelete(value)
+	case AnnotationDefaultRelayType:
+		relay := &DatabaseAccountRelay{Type: RelayType(value)}
+		if err := relay.Validate(); err != nil {
+			return invalid(err)
+		}
+		a.relayType = relay.Type
+	case AnnotationDefaultRelayPoolMode:
+		settings := RelaySettings{PoolMode: RelayPoolMode(value)}
+		if err := settings.Validate(); err != nil {
+			return invalid(err)
+		}
+		a.relay.PoolMode = settings.PoolMode
+	case AnnotationDefaultSecretLabels:
+		secretLabels, err := labels.ConvertSelectorToLabelsMap(value)
+		if err != nil {
+			return invalid(err)
+		}
+		a.SecretLabels = labels.Merge(a.SecretLabels, secretLabels)
+	case AnnotationDefaultRotationInterval:
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return invalid(err)
+		} else if interval <= 0 {
+			return invalid(fmt.Errorf("interval %s must be positive", value))
+		}
+		rotation := a.getPasswordRotation()
+		rotation.Interval = &metav1.Duration{Duration: interval}
+		rotation.Schedule = ""
+	case AnnotationDefaultRotationMode:
+		if v := DatabaseAccountRotationMode(value); v != RotationModeInPlace && v != RotationModeDualRole {
+			return invalid(fmt.Errorf("unknown rotation mode %s", value))
+		}
+		a.getPasswordRotation().Mode = DatabaseAccountRotationMode(value)
+	}
+
+	return nil
+}
+
+// getPasswordRotation returns the default password rotation, it is created if it is not set.
+func (a *accountDefaults) getPasswordRotation() *DatabaseAccountPasswordRotation {
+	if a.PasswordRotation == nil {
+		a.PasswordRotation = &DatabaseAccountPasswordRotation{}
+	}
+
+	return a.PasswordRotation
+}
+
+// apply fills in the values the account does not set.
+func (a *accountDefaults) apply(dbAccount *DatabaseAccount) {
+	spec := &dbAccount.Spec
+
+	if spec.OnDelete == "" {
+		spec.OnDelete = a.OnDelete
+	}
+	spec.OnDelete = dbAccount.GetSpecOnDelete()
+
+	for key, value := range a.SecretLabels {
+		if _, ok := spec.SecretTemplate.Labels[key]; ok {
+			continue
+		}
+
+		if spec.SecretTemplate.Labels == nil {
+			spec.SecretTemplate.Labels = map[string]string{}
+		}
+		spec.SecretTemplate.Labels[key] = value
+	}
+
+	// the type of a shared relay is the type of the shared relay from the controller configuration.
+	if relay := spec.Relay; relay != nil {
+		if relay.Type == "" && !relay.Shared {
+			relay.Type = a.relayType
+		}
+		relay.RelaySettings = relay.RelaySettings.WithDefaults(a.relay)
+	}
+
+	a.applyPasswordRotation(dbAccount)
+}
+
+// applyPasswordRotation sets the default password rotation on an account without one, the mode and
+// grace period are filled in on an account with a rotation.
+func (a *accountDefaults) applyPasswordRotation(dbAccount *DatabaseAccount) {
+	defaults := a.PasswordRotation
+	rotation := dbAccount.Spec.PasswordRotation
+
+	if rotation == nil {
+		if defaults == nil || (defaults.Interval == nil && defaults.Schedule == "") {
+			return
+		}
+
+		rotation = defaults.DeepCopy()
+		dbAccount.Spec.PasswordRotation = rotation
+	}
+
+	if rotation.Mode == "" && defaults != nil {
+		rotation.Mode = defaults.Mode
+	}
+	rotation.Mode = dbAccount.GetSpecRotationMode()
+
+	if rotation.GracePeriod == nil && defaults != nil && defaults.GracePeriod != nil {
+		rotation.GracePeriod = defaults.GracePeriod.DeepCopy()
+	}
+}
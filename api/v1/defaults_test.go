@@ -0,0 +1,213 @@
+package v1_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/dosquad/database-operator/api/v1"
+	v1test "github.com/dosquad/database-operator/api/v1/test"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestDatabaseAccountControllerConfig_Default(t *testing.T) {
+	t.Parallel()
+
+	dbAccConfig := v1.DatabaseAccountControllerConfig{
+		RelayType:     v1.RelayTypePgCat,
+		RelayDefaults: v1.RelaySettings{MaxClientConn: ptr.To[int32](50)},
+		AccountDefaults: v1.DatabaseAccountDefaults{
+			OnDelete:     v1.OnDeleteDelete,
+			SecretLabels: map[string]string{"team": "platform", "managed": "dbo"},
+			PasswordRotation: &v1.DatabaseAccountPasswordRotation{
+				Interval: &metav1.Duration{Duration: 720 * time.Hour},
+			},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		nsAnnotations map[string]string
+		spec          v1.DatabaseAccountSpec
+		expect        v1.DatabaseAccountSpec
+	}{
+		{
+			"Config",
+			nil,
+			v1.DatabaseAccountSpec{Relay: &v1.DatabaseAccountRelay{}},
+			v1.DatabaseAccountSpec{
+				OnDelete: v1.OnDeleteDelete,
+				Relay: &v1.DatabaseAccountRelay{
+					Type:          v1.RelayTypePgCat,
+					RelaySettings: v1.RelaySettings{MaxClientConn: ptr.To[int32](50)},
+				},
+				SecretTemplate: v1.DatabaseAccountSpecSecretTemplate{
+					Labels: map[string]string{"team": "platform", "managed": "dbo"},
+				},
+				PasswordRotation: &v1.DatabaseAccountPasswordRotation{
+					Interval: &metav1.Duration{Duration: 720 * time.Hour},
+					Mode:     v1.RotationModeInPlace,
+				},
+			},
+		},
+		{
+			"Namespace",
+			map[string]string{
+				v1.AnnotationDefaultOnDelete:         "retain",
+				v1.AnnotationDefaultRelayType:        "odyssey",
+				v1.AnnotationDefaultRelayPoolMode:    "transaction",
+				v1.AnnotationDefaultSecretLabels:     "team=data",
+				v1.AnnotationDefaultRotationInterval: "24h",
+				v1.AnnotationDefaultRotationMode:     "DualRole",
+				"unrelated":                          "value",
+			},
+			v1.DatabaseAccountSpec{Relay: &v1.DatabaseAccountRelay{}},
+			v1.DatabaseAccountSpec{
+				OnDelete: v1.OnDeleteRetain,
+				Relay: &v1.DatabaseAccountRelay{
+					Type: v1.RelayTypeOdyssey,
+					RelaySettings: v1.RelaySettings{
+						PoolMode:      v1.RelayPoolModeTransaction,
+						MaxClientConn: ptr.To[int32](50),
+					},
+				},
+				SecretTemplate: v1.DatabaseAccountSpecSecretTemplate{
+					Labels: map[string]string{"team": "data", "managed": "dbo"},
+				},
+				PasswordRotation: &v1.DatabaseAccountPasswordRotation{
+					Interval: &metav1.Duration{Duration: 24 * time.Hour},
+					Mode:     v1.RotationModeDualRole,
+				},
+			},
+		},
+		{
+			"Object",
+			map[string]string{
+				v1.AnnotationDefaultOnDelete:      "retain",
+				v1.AnnotationDefaultRelayType:     "odyssey",
+				v1.AnnotationDefaultSecretLabels:  "team=data",
+				v1.AnnotationDefaultRotationMode:  "DualRole",
+				v1.AnnotationDefaultRelayPoolMode: "transaction",
+			},
+			v1.DatabaseAccountSpec{
+				OnDelete: v1.OnDeleteDelete,
+				Relay: &v1.DatabaseAccountRelay{
+					Type:          v1.RelayTypePgBouncer,
+					RelaySettings: v1.RelaySettings{PoolMode: v1.RelayPoolModeSession},
+				},
+				SecretTemplate: v1.DatabaseAccountSpecSecretTemplate{
+					Labels: map[string]string{"team": "app"},
+				},
+				PasswordRotation: &v1.DatabaseAccountPasswordRotation{
+					Schedule: "0 3 * * 0",
+					Mode:     v1.RotationModeInPlace,
+				},
+			},
+			v1.DatabaseAccountSpec{
+				OnDelete: v1.OnDeleteDelete,
+				Relay: &v1.DatabaseAccountRelay{
+					Type: v1.RelayTypePgBouncer,
+					RelaySettings: v1.RelaySettings{
+						PoolMode:      v1.RelayPoolModeSession,
+						MaxClientConn: ptr.To[int32](50),
+					},
+				},
+				SecretTemplate: v1.DatabaseAccountSpecSecretTemplate{
+					Labels: map[string]string{"team": "app", "managed": "dbo"},
+				},
+				PasswordRotation: &v1.DatabaseAccountPasswordRotation{
+					Schedule: "0 3 * * 0",
+					Mode:     v1.RotationModeInPlace,
+				},
+			},
+		},
+		{
+			"SharedRelay",
+			nil,
+			v1.DatabaseAccountSpec{Relay: &v1.DatabaseAccountRelay{Shared: true}},
+			v1.DatabaseAccountSpec{
+				OnDelete: v1.OnDeleteDelete,
+				Relay: &v1.DatabaseAccountRelay{
+					Shared:        true,
+					RelaySettings: v1.RelaySettings{MaxClientConn: ptr.To[int32](50)},
+				},
+				SecretTemplate: v1.DatabaseAccountSpecSecretTemplate{
+					Labels: map[string]string{"team": "platform", "managed": "dbo"},
+				},
+				PasswordRotation: &v1.DatabaseAccountPasswordRotation{
+					Interval: &metav1.Duration{Duration: 720 * time.Hour},
+					Mode:     v1.RotationModeInPlace,
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbAccount := v1test.NewDatabaseAccount()
+			dbAccount.Spec = tt.spec
+
+			if err := dbAccConfig.Default(&dbAccount, tt.nsAnnotations); err != nil {
+				t.Fatalf("DatabaseAccountControllerConfig.Default(): error, got '%v', want 'nil'", err)
+			}
+
+			if diff := cmp.Diff(dbAccount.Spec, tt.expect); diff != "" {
+				t.Errorf("DatabaseAccountControllerConfig.Default(): -got +want:\n%s", diff)
+			}
+		})
+	}
+
+	// the defaults of the configuration are not changed by the accounts.
+	if v := dbAccConfig.AccountDefaults.PasswordRotation.Mode; v != "" {
+		t.Errorf("DatabaseAccountControllerConfig.Default(): configuration mode changed to '%s'", v)
+	}
+}
+
+func TestDatabaseAccountControllerConfig_Default_NoRotation(t *testing.T) {
+	t.Parallel()
+
+	// a rotation mode without an interval or schedule does not enable rotation.
+	dbAccount := v1test.NewDatabaseAccount()
+	nsAnnotations := map[string]string{v1.AnnotationDefaultRotationMode: "DualRole"}
+
+	if err := (&v1.DatabaseAccountControllerConfig{}).Default(&dbAccount, nsAnnotations); err != nil {
+		t.Fatalf("DatabaseAccountControllerConfig.Default(): error, got '%v', want 'nil'", err)
+	}
+
+	if dbAccount.Spec.PasswordRotation != nil {
+		t.Errorf("DatabaseAccountControllerConfig.Default(): rotation, got '%v', want 'nil'",
+			dbAccount.Spec.PasswordRotation)
+	}
+
+	if dbAccount.Spec.OnDelete != v1.OnDeleteDelete {
+		t.Errorf("DatabaseAccountControllerConfig.Default(): onDelete, got '%s', want '%s'",
+			dbAccount.Spec.OnDelete, v1.OnDeleteDelete)
+	}
+}
+
+func TestDatabaseAccountControllerConfig_Default_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		v1.AnnotationDefaultOnDelete:         "keep",
+		v1.AnnotationDefaultRelayType:        "pgpool",
+		v1.AnnotationDefaultRelayPoolMode:    "query",
+		v1.AnnotationDefaultSecretLabels:     "team",
+		v1.AnnotationDefaultRotationInterval: "-1h",
+		v1.AnnotationDefaultRotationMode:     "Swap",
+	}
+
+	for key, value := range tests {
+		dbAccount := v1test.NewDatabaseAccount()
+
+		err := (&v1.DatabaseAccountControllerConfig{}).Default(&dbAccount, map[string]string{key: value})
+		if !errors.Is(err, v1.ErrInvalidDefaults) {
+			t.Errorf("DatabaseAccountControllerConfig.Default(%s=%s): error, got '%v', want '%v'",
+				key, value, err, v1.ErrInvalidDefaults)
+		}
+	}
+}
@@ -4,4 +4,8 @@ import "errors"
 
 var (
 	ErrMissingDatabaseUsername = errors.New("missing database username")
+	ErrInvalidRotationSchedule = errors.New("invalid password rotation schedule")
+	ErrInvalidRelaySettings    = errors.New("invalid relay settings")
+	ErrInvalidDefaults         = errors.New("invalid account defaults")
+	ErrSettingNotAllowed       = errors.New("setting is not allowed")
 )
@@ -29,6 +29,9 @@ SOFTWARE.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/component-base/config/v1alpha1"
 	timex "time"
@@ -82,7 +85,7 @@ func (in *DatabaseAccount) DeepCopyInto(out *DatabaseAccount) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccount.
@@ -110,6 +113,47 @@ func (in *DatabaseAccountControllerConfig) DeepCopyInto(out *DatabaseAccountCont
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.ControllerManagerConfiguration.DeepCopyInto(&out.ControllerManagerConfiguration)
 	out.Debug = in.Debug
+	if in.RelayImages != nil {
+		in, out := &in.RelayImages, &out.RelayImages
+		*out = make(map[RelayType]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.RelayDefaults.DeepCopyInto(&out.RelayDefaults)
+	if in.SharedRelay != nil {
+		in, out := &in.SharedRelay, &out.SharedRelay
+		*out = new(DatabaseAccountRelay)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RelayEgress != nil {
+		in, out := &in.RelayEgress, &out.RelayEgress
+		*out = make([]networkingv1.NetworkPolicyEgressRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RelayResolveInterval != nil {
+		in, out := &in.RelayResolveInterval, &out.RelayResolveInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	in.AccountDefaults.DeepCopyInto(&out.AccountDefaults)
+	if in.SecretVersionRetention != nil {
+		in, out := &in.SecretVersionRetention, &out.SecretVersionRetention
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.AllowedExtensions != nil {
+		in, out := &in.AllowedExtensions, &out.AllowedExtensions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedSettings != nil {
+		in, out := &in.AllowedSettings, &out.AllowedSettings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.LeaderElection != nil {
 		in, out := &in.LeaderElection, &out.LeaderElection
 		*out = new(v1alpha1.LeaderElectionConfiguration)
@@ -182,6 +226,48 @@ func (in *DatabaseAccountControllerConfigList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountDefaults) DeepCopyInto(out *DatabaseAccountDefaults) {
+	*out = *in
+	if in.SecretLabels != nil {
+		in, out := &in.SecretLabels, &out.SecretLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PasswordRotation != nil {
+		in, out := &in.PasswordRotation, &out.PasswordRotation
+		*out = new(DatabaseAccountPasswordRotation)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountDefaults.
+func (in *DatabaseAccountDefaults) DeepCopy() *DatabaseAccountDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountExtension) DeepCopyInto(out *DatabaseAccountExtension) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountExtension.
+func (in *DatabaseAccountExtension) DeepCopy() *DatabaseAccountExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountExtension)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseAccountList) DeepCopyInto(out *DatabaseAccountList) {
 	*out = *in
@@ -214,10 +300,213 @@ func (in *DatabaseAccountList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountPasswordRotation) DeepCopyInto(out *DatabaseAccountPasswordRotation) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.GracePeriod != nil {
+		in, out := &in.GracePeriod, &out.GracePeriod
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountPasswordRotation.
+func (in *DatabaseAccountPasswordRotation) DeepCopy() *DatabaseAccountPasswordRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountPasswordRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountPreviousSecret) DeepCopyInto(out *DatabaseAccountPreviousSecret) {
+	*out = *in
+	in.DeleteAt.DeepCopyInto(&out.DeleteAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountPreviousSecret.
+func (in *DatabaseAccountPreviousSecret) DeepCopy() *DatabaseAccountPreviousSecret {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountPreviousSecret)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountRelay) DeepCopyInto(out *DatabaseAccountRelay) {
+	*out = *in
+	in.RelaySettings.DeepCopyInto(&out.RelaySettings)
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]corev1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]corev1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(RelayTLS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AllowedClients != nil {
+		in, out := &in.AllowedClients, &out.AllowedClients
+		*out = make([]RelayAllowedClient, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountRelay.
+func (in *DatabaseAccountRelay) DeepCopy() *DatabaseAccountRelay {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountRelay)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountRole) DeepCopyInto(out *DatabaseAccountRole) {
+	*out = *in
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ValidUntil != nil {
+		in, out := &in.ValidUntil, &out.ValidUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.Inherit != nil {
+		in, out := &in.Inherit, &out.Inherit
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MemberOf != nil {
+		in, out := &in.MemberOf, &out.MemberOf
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountRole.
+func (in *DatabaseAccountRole) DeepCopy() *DatabaseAccountRole {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountServerRef) DeepCopyInto(out *DatabaseAccountServerRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountServerRef.
+func (in *DatabaseAccountServerRef) DeepCopy() *DatabaseAccountServerRef {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountServerRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseAccountSpec) DeepCopyInto(out *DatabaseAccountSpec) {
 	*out = *in
+	if in.Relay != nil {
+		in, out := &in.Relay, &out.Relay
+		*out = new(DatabaseAccountRelay)
+		(*in).DeepCopyInto(*out)
+	}
 	in.SecretTemplate.DeepCopyInto(&out.SecretTemplate)
+	if in.ServerRef != nil {
+		in, out := &in.ServerRef, &out.ServerRef
+		*out = new(DatabaseAccountServerRef)
+		**out = **in
+	}
+	if in.PasswordRotation != nil {
+		in, out := &in.PasswordRotation, &out.PasswordRotation
+		*out = new(DatabaseAccountPasswordRotation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Role != nil {
+		in, out := &in.Role, &out.Role
+		*out = new(DatabaseAccountRole)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RoleSettings != nil {
+		in, out := &in.RoleSettings, &out.RoleSettings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DatabaseSettings != nil {
+		in, out := &in.DatabaseSettings, &out.DatabaseSettings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make([]DatabaseAccountExtension, len(*in))
+		copy(*out, *in)
+	}
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountSpec.
@@ -262,6 +551,59 @@ func (in *DatabaseAccountSpecSecretTemplate) DeepCopy() *DatabaseAccountSpecSecr
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatabaseAccountStatus) DeepCopyInto(out *DatabaseAccountStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastRotated != nil {
+		in, out := &in.LastRotated, &out.LastRotated
+		*out = (*in).DeepCopy()
+	}
+	if in.RevokeAt != nil {
+		in, out := &in.RevokeAt, &out.RevokeAt
+		*out = (*in).DeepCopy()
+	}
+	if in.PreviousSecrets != nil {
+		in, out := &in.PreviousSecrets, &out.PreviousSecrets
+		*out = make([]DatabaseAccountPreviousSecret, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Role != nil {
+		in, out := &in.Role, &out.Role
+		*out = new(DatabaseAccountRole)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RoleSettings != nil {
+		in, out := &in.RoleSettings, &out.RoleSettings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DatabaseSettings != nil {
+		in, out := &in.DatabaseSettings, &out.DatabaseSettings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountStatus.
@@ -273,3 +615,217 @@ func (in *DatabaseAccountStatus) DeepCopy() *DatabaseAccountStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServer) DeepCopyInto(out *DatabaseServer) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServer.
+func (in *DatabaseServer) DeepCopy() *DatabaseServer {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseServer) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerList) DeepCopyInto(out *DatabaseServerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseServer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerList.
+func (in *DatabaseServerList) DeepCopy() *DatabaseServerList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseServerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerSecretRef) DeepCopyInto(out *DatabaseServerSecretRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerSecretRef.
+func (in *DatabaseServerSecretRef) DeepCopy() *DatabaseServerSecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerSecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseServerSpec) DeepCopyInto(out *DatabaseServerSpec) {
+	*out = *in
+	out.DSNSecretRef = in.DSNSecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseServerSpec.
+func (in *DatabaseServerSpec) DeepCopy() *DatabaseServerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseServerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelayAllowedClient) DeepCopyInto(out *RelayAllowedClient) {
+	*out = *in
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelayAllowedClient.
+func (in *RelayAllowedClient) DeepCopy() *RelayAllowedClient {
+	if in == nil {
+		return nil
+	}
+	out := new(RelayAllowedClient)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelayClientTLS) DeepCopyInto(out *RelayClientTLS) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelayClientTLS.
+func (in *RelayClientTLS) DeepCopy() *RelayClientTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(RelayClientTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelayServerTLS) DeepCopyInto(out *RelayServerTLS) {
+	*out = *in
+	if in.CASecretRef != nil {
+		in, out := &in.CASecretRef, &out.CASecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelayServerTLS.
+func (in *RelayServerTLS) DeepCopy() *RelayServerTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(RelayServerTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelaySettings) DeepCopyInto(out *RelaySettings) {
+	*out = *in
+	if in.MaxClientConn != nil {
+		in, out := &in.MaxClientConn, &out.MaxClientConn
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DefaultPoolSize != nil {
+		in, out := &in.DefaultPoolSize, &out.DefaultPoolSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ServerIdleTimeout != nil {
+		in, out := &in.ServerIdleTimeout, &out.ServerIdleTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.MaxDBConnections != nil {
+		in, out := &in.MaxDBConnections, &out.MaxDBConnections
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelaySettings.
+func (in *RelaySettings) DeepCopy() *RelaySettings {
+	if in == nil {
+		return nil
+	}
+	out := new(RelaySettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelayTLS) DeepCopyInto(out *RelayTLS) {
+	*out = *in
+	if in.Client != nil {
+		in, out := &in.Client, &out.Client
+		*out = new(RelayClientTLS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Server != nil {
+		in, out := &in.Server, &out.Server
+		*out = new(RelayServerTLS)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelayTLS.
+func (in *RelayTLS) DeepCopy() *RelayTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(RelayTLS)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,143 @@
+package v2
+
+// DatabaseAccountOnDelete is the options that can be set for onDelete.
+// +kubebuilder:validation:Enum=retain;delete
+type DatabaseAccountOnDelete string
+
+func (d DatabaseAccountOnDelete) String() string {
+	return string(d)
+}
+
+const (
+	// OnDeleteRetain retain the database and role.
+	OnDeleteRetain DatabaseAccountOnDelete = "retain"
+
+	// OnDeleteDelete delete the created database and role.
+	OnDeleteDelete DatabaseAccountOnDelete = "delete"
+)
+
+// DatabaseAccountSecretPolicy is what is done when the secret of an account already exists.
+// +kubebuilder:validation:Enum=Fail;Adopt;Replace
+type DatabaseAccountSecretPolicy string
+
+func (d DatabaseAccountSecretPolicy) String() string {
+	return string(d)
+}
+
+const (
+	// SecretPolicyFail fails the account when the existing secret is immutable.
+	SecretPolicyFail DatabaseAccountSecretPolicy = "Fail"
+
+	// SecretPolicyAdopt uses the verified username and password of the existing secret.
+	SecretPolicyAdopt DatabaseAccountSecretPolicy = "Adopt"
+
+	// SecretPolicyReplace recreates the existing secret when it is immutable.
+	SecretPolicyReplace DatabaseAccountSecretPolicy = "Replace"
+)
+
+// DatabaseAccountRotationMode is how the password of an account is rotated.
+// +kubebuilder:validation:Enum=InPlace;DualRole
+type DatabaseAccountRotationMode string
+
+func (d DatabaseAccountRotationMode) String() string {
+	return string(d)
+}
+
+const (
+	// RotationModeInPlace changes the password of the login role.
+	RotationModeInPlace DatabaseAccountRotationMode = "InPlace"
+
+	// RotationModeDualRole switches the secret between two login roles and revokes the login of
+	// the previous role after a grace period.
+	RotationModeDualRole DatabaseAccountRotationMode = "DualRole"
+)
+
+// RelayPoolMode is when a server connection of the relay is released back to the pool.
+// +kubebuilder:validation:Enum=session;transaction;statement
+type RelayPoolMode string
+
+func (d RelayPoolMode) String() string {
+	return string(d)
+}
+
+const (
+	// RelayPoolModeSession releases the server connection when the client disconnects.
+	RelayPoolModeSession RelayPoolMode = "session"
+
+	// RelayPoolModeTransaction releases the server connection when the transaction finishes.
+	RelayPoolModeTransaction RelayPoolMode = "transaction"
+
+	// RelayPoolModeStatement releases the server connection when the query finishes.
+	RelayPoolModeStatement RelayPoolMode = "statement"
+)
+
+// RelayType is the connection pooler used for a relay.
+// +kubebuilder:validation:Enum=pgbouncer;pgcat;odyssey
+type RelayType string
+
+func (d RelayType) String() string {
+	return string(d)
+}
+
+const (
+	// RelayTypePgBouncer uses PgBouncer for the relay.
+	RelayTypePgBouncer RelayType = "pgbouncer"
+
+	// RelayTypePgCat uses PgCat for the relay.
+	RelayTypePgCat RelayType = "pgcat"
+
+	// RelayTypeOdyssey uses Odyssey for the relay.
+	RelayTypeOdyssey RelayType = "odyssey"
+)
+
+// RelayMode is how the relay of an account is deployed.
+// +kubebuilder:validation:Enum=Dedicated;Shared
+type RelayMode string
+
+func (d RelayMode) String() string {
+	return string(d)
+}
+
+const (
+	// RelayModeDedicated is a relay deployed for the account.
+	RelayModeDedicated RelayMode = "Dedicated"
+
+	// RelayModeShared is the shared relay of the namespace of the account.
+	RelayModeShared RelayMode = "Shared"
+)
+
+// RelayTLSMode is the sslmode used by the relay for TLS connections.
+// +kubebuilder:validation:Enum=disable;allow;prefer;require;verify-ca;verify-full
+type RelayTLSMode string
+
+func (d RelayTLSMode) String() string {
+	return string(d)
+}
+
+// DatabaseAccountPhase is how far the controller is with the account.
+// +kubebuilder:validation:Enum=Init;UserCreate;DatabaseCreate;RelayCreate;Error;Ready;Terminating
+type DatabaseAccountPhase string
+
+func (d DatabaseAccountPhase) String() string {
+	return string(d)
+}
+
+const (
+	// PhaseInit is the first phase of creating the account.
+	PhaseInit DatabaseAccountPhase = "Init"
+
+	// PhaseReady is when the account is ready to be used.
+	PhaseReady DatabaseAccountPhase = "Ready"
+
+	// PhaseError is when the account has failed and won't be completed without changes.
+	PhaseError DatabaseAccountPhase = "Error"
+)
+
+// Condition types used in the DatabaseAccount status conditions, they are the same as v1.
+const (
+	// ConditionReady is true when the account is ready to be used.
+	ConditionReady = "Ready"
+
+	// ConditionDegraded is true when the account has failed and needs attention.
+	ConditionDegraded = "Degraded"
+)
@@ -0,0 +1,4 @@
+package v2
+
+// Hub marks v2 as the version the other versions of DatabaseAccount are converted through.
+func (*DatabaseAccount) Hub() {}
@@ -0,0 +1,529 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PostgreSQLResourceName is a kubernetes validation for a PostgreSQL resource name.
+// +optional
+// +kubebuilder:validation:Pattern:="^[a-zA-Z_][a-zA-Z0-9_]+$"
+// +kubebuilder:validation:MaxLength:=61
+type PostgreSQLResourceName string
+
+func (d PostgreSQLResourceName) String() string {
+	return string(d)
+}
+
+// DatabaseAccountSpec defines the desired state of DatabaseAccount.
+// +kubebuilder:validation:XValidation:rule="(has(oldSelf.role) && has(oldSelf.role.username)) == (has(self.role) && has(self.role.username)) && (!has(self.role) || !has(self.role.username) || self.role.username == oldSelf.role.username)",message="role.username is immutable"
+type DatabaseAccountSpec struct {
+	// Name is the basename used for the role and database, if not specified a name is generated.
+	//+optional
+	Name PostgreSQLResourceName `json:"name,omitempty"`
+
+	// ServerRef is the optional reference to the DatabaseServer the account is created on,
+	// if not specified the server from the controller configuration is used.
+	//+optional
+	ServerRef *DatabaseAccountServerRef `json:"serverRef,omitempty"`
+
+	// OnDelete specifies if the database and role are removed when the account is removed.
+	//+optional
+	OnDelete DatabaseAccountOnDelete `json:"onDelete,omitempty"`
+
+	// Role is the login role of the account.
+	//+optional
+	Role DatabaseAccountRole `json:"role,omitempty"`
+
+	// Database is the database of the account.
+	//+optional
+	Database DatabaseAccountDatabase `json:"database,omitempty"`
+
+	// Relay creates a relay and uses it for the DSN, settings that are not specified use the relay
+	// defaults from the controller configuration.
+	//+optional
+	Relay *DatabaseAccountRelay `json:"relay,omitempty"`
+
+	// Secret is the secret the credentials and DSN of the account are written to.
+	//+optional
+	Secret DatabaseAccountSecret `json:"secret,omitempty"`
+}
+
+// DatabaseAccountServerRef is a reference to a cluster scoped DatabaseServer.
+type DatabaseAccountServerRef struct {
+	// Name is the name of the DatabaseServer.
+	Name string `json:"name"`
+}
+
+// DatabaseAccountRole defines the login role of the account.
+type DatabaseAccountRole struct {
+	// Username is the login role name, if not specified the generated resource name is used. It can
+	// not be changed once set.
+	//+optional
+	Username string `json:"username,omitempty"`
+
+	// Attributes are the attributes and memberships of the role, they are not managed when not set.
+	//+optional
+	Attributes *DatabaseAccountRoleAttributes `json:"attributes,omitempty"`
+
+	// Settings are the run-time parameters set on the role, e.g. "statement_timeout".
+	//+optional
+	// +kubebuilder:validation:XValidation:rule="self.all(k, k.matches('^[a-zA-Z_][a-zA-Z0-9_]*([.][a-zA-Z_][a-zA-Z0-9_]*)?$'))",message="setting names must be valid parameter names"
+	Settings map[string]string `json:"settings,omitempty"`
+
+	// PasswordRotation is the optional schedule for rotating the password of the role.
+	//+optional
+	PasswordRotation *DatabaseAccountPasswordRotation `json:"passwordRotation,omitempty"`
+}
+
+// DatabaseAccountRoleAttributes defines the attributes and memberships of the account role.
+type DatabaseAccountRoleAttributes struct {
+	// ConnectionLimit is the number of concurrent connections the role can make, -1 is no limit.
+	//+optional
+	// +kubebuilder:validation:Minimum:=-1
+	ConnectionLimit *int32 `json:"connectionLimit,omitempty"`
+
+	// ValidUntil is the time after which the password of the role is no longer valid.
+	//+optional
+	ValidUntil *metav1.Time `json:"validUntil,omitempty"`
+
+	// CreateDB allows the role to create databases.
+	//+optional
+	CreateDB bool `json:"createDB,omitempty"`
+
+	// Inherit allows the role to use the privileges of the roles it is a member of.
+	//+optional
+	// +kubebuilder:default:=true
+	Inherit *bool `json:"inherit,omitempty"`
+
+	// BypassRLS allows the role to bypass row level security policies, it is only applied when
+	// allowed by the controller configuration.
+	//+optional
+	BypassRLS bool `json:"bypassRLS,omitempty"`
+
+	// MemberOf is the list of roles the role is granted membership of, e.g. "pg_read_all_data".
+	//+optional
+	// +listType=set
+	MemberOf []string `json:"memberOf,omitempty"`
+}
+
+// DatabaseAccountPasswordRotation defines when and how the password of the account is rotated.
+//
+// +kubebuilder:validation:XValidation:rule="!(has(self.interval) && has(self.schedule))",message="only one of interval or schedule can be set"
+type DatabaseAccountPasswordRotation struct {
+	// Interval is the time between password rotations, e.g. "720h".
+	//+optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Schedule is a standard cron expression for when the password is rotated, e.g. "0 3 * * 0".
+	//+optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// Mode is how the password is rotated, InPlace changes the password of the login role and
+	// DualRole switches between two login roles that are members of the account role.
+	//+optional
+	Mode DatabaseAccountRotationMode `json:"mode,omitempty"`
+
+	// GracePeriod is the time the previous login role can still be used after a DualRole rotation.
+	//+optional
+	GracePeriod *metav1.Duration `json:"gracePeriod,omitempty"`
+}
+
+// DatabaseAccountDatabase defines the database of the account.
+// +kubebuilder:validation:XValidation:rule="!has(self.defaultSchema) || (has(self.schemas) && self.defaultSchema in self.schemas)",message="defaultSchema must be one of the schemas"
+type DatabaseAccountDatabase struct {
+	// Settings are the run-time parameters set on the database, e.g. "work_mem".
+	//+optional
+	// +kubebuilder:validation:XValidation:rule="self.all(k, k.matches('^[a-zA-Z_][a-zA-Z0-9_]*([.][a-zA-Z_][a-zA-Z0-9_]*)?$'))",message="setting names must be valid parameter names"
+	Settings map[string]string `json:"settings,omitempty"`
+
+	// Extensions are installed in the database, they must be allowed by the controller
+	// configuration. Extensions removed from the list are not dropped.
+	//+optional
+	// +listType=map
+	// +listMapKey=name
+	Extensions []DatabaseAccountExtension `json:"extensions,omitempty"`
+
+	// Schemas are created in the database and owned by the account role, the search_path of the role
+	// and the DualRole login roles is set to the schemas unless it is set in the role settings. Names
+	// starting with "pg_" are reserved, schemas removed from the list are not dropped.
+	//+optional
+	// +listType=set
+	// +kubebuilder:validation:items:Pattern=`^[a-zA-Z_][a-zA-Z0-9_]{0,62}$`
+	Schemas []string `json:"schemas,omitempty"`
+
+	// DefaultSchema is the schema written to the secret and listed first in the search_path, if not
+	// specified the first of the schemas is used.
+	//+optional
+	DefaultSchema string `json:"defaultSchema,omitempty"`
+}
+
+// DatabaseAccountExtension is an extension installed in the account database.
+type DatabaseAccountExtension struct {
+	// Name is the name of the extension, e.g. "pgcrypto".
+	// +kubebuilder:validation:Pattern=`^[a-z_][a-z0-9_-]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// Version is the optional version of the extension, the default version is installed if not
+	// specified. The extension is updated when the version is changed.
+	//+optional
+	Version string `json:"version,omitempty"`
+}
+
+// DatabaseAccountSecret defines the secret of the account.
+type DatabaseAccountSecret struct {
+	// Name is the name of the secret, if not specified the name of the account is used.
+	//+optional
+	Name string `json:"name,omitempty"`
+
+	// Labels are added to the secret.
+	//+optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the secret.
+	//+optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Policy is what is done when the secret already exists, if not specified Fail is used.
+	//+optional
+	Policy DatabaseAccountSecretPolicy `json:"policy,omitempty"`
+}
+
+// DatabaseAccountRelay defines the relay created for the account.
+// +kubebuilder:validation:XValidation:rule="!has(self.shared) || !self.shared || !has(self.tls)",message="tls can not be set on a shared relay"
+// +kubebuilder:validation:XValidation:rule="!has(self.shared) || !self.shared || !has(self.type)",message="type can not be set on a shared relay"
+// +kubebuilder:validation:XValidation:rule="!has(self.shared) || !self.shared || !has(self.allowedClients)",message="allowedClients can not be set on a shared relay"
+type DatabaseAccountRelay struct {
+	RelaySettings `json:",inline"`
+
+	// Type is the connection pooler used for the relay, relayType from the controller configuration is
+	// used when it is not set.
+	//+optional
+	Type RelayType `json:"type,omitempty"`
+
+	// Shared adds the account to the relay shared by the accounts of the namespace instead of creating
+	// a relay for the account.
+	//+optional
+	Shared bool `json:"shared,omitempty"`
+
+	// Replicas is the number of relay pods, a PodDisruptionBudget is created when there is more
+	// than one.
+	//+optional
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum:=1
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// Resources are the compute resources of the relay container.
+	//+optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// NodeSelector is the node labels the relay pods must be scheduled on.
+	//+optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations are the tolerations of the relay pods.
+	//+optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity is the scheduling affinity of the relay pods.
+	//+optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// TopologySpreadConstraints are how the relay pods are spread across the topology domains.
+	//+optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// ImagePullSecrets are the secrets used to pull the relay image.
+	//+optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// TLS configures TLS for the connections to and from the relay.
+	//+optional
+	TLS *RelayTLS `json:"tls,omitempty"`
+
+	// AllowedClients are the pods allowed to connect to the relay, a NetworkPolicy is created when it
+	// is set.
+	//+optional
+	AllowedClients []RelayAllowedClient `json:"allowedClients,omitempty"`
+}
+
+// RelaySettings defines the connection pooler settings of a relay.
+// +kubebuilder:validation:XValidation:rule="!has(self.defaultPoolSize) || !has(self.maxClientConn) || self.defaultPoolSize <= self.maxClientConn",message="defaultPoolSize can not be more than maxClientConn"
+type RelaySettings struct {
+	// PoolMode is when a server connection is released back to the pool.
+	//+optional
+	PoolMode RelayPoolMode `json:"poolMode,omitempty"`
+
+	// MaxClientConn is the maximum number of client connections to the relay.
+	//+optional
+	// +kubebuilder:validation:Minimum:=1
+	MaxClientConn *int32 `json:"maxClientConn,omitempty"`
+
+	// DefaultPoolSize is the number of server connections for each user and database.
+	//+optional
+	// +kubebuilder:validation:Minimum:=1
+	DefaultPoolSize *int32 `json:"defaultPoolSize,omitempty"`
+
+	// ServerIdleTimeout is how long an idle server connection is kept open, e.g. "10m".
+	//+optional
+	ServerIdleTimeout *metav1.Duration `json:"serverIdleTimeout,omitempty"`
+
+	// MaxDBConnections is the maximum number of server connections to the database, 0 is no limit.
+	//+optional
+	// +kubebuilder:validation:Minimum:=0
+	MaxDBConnections *int32 `json:"maxDBConnections,omitempty"`
+}
+
+// RelayTLS defines TLS for the connections from applications to the relay and from the relay to the
+// database server.
+type RelayTLS struct {
+	// Client configures TLS for the connections from applications to the relay.
+	//+optional
+	Client *RelayClientTLS `json:"client,omitempty"`
+
+	// Server configures TLS for the connections from the relay to the database server.
+	//+optional
+	Server *RelayServerTLS `json:"server,omitempty"`
+}
+
+// RelayClientTLS defines TLS for the connections from applications to the relay.
+type RelayClientTLS struct {
+	// SSLMode is the client_tls_sslmode of the relay, require rejects clients not using TLS.
+	//+optional
+	// +kubebuilder:default:=require
+	SSLMode RelayTLSMode `json:"sslMode,omitempty"`
+
+	// SecretRef is a kubernetes.io/tls secret in the namespace of the account with the relay
+	// certificate, a self-signed CA and certificate are generated by the operator when it is not set.
+	//+optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+}
+
+// RelayServerTLS defines TLS for the connections from the relay to the database server.
+//
+// +kubebuilder:validation:XValidation:rule="!(self.sslMode in ['verify-ca', 'verify-full']) || has(self.caSecretRef)",message="caSecretRef is required to verify the server certificate"
+type RelayServerTLS struct {
+	// SSLMode is the server_tls_sslmode of the relay.
+	//+optional
+	// +kubebuilder:default:=require
+	SSLMode RelayTLSMode `json:"sslMode,omitempty"`
+
+	// CASecretRef is the key of a secret in the namespace of the account with the CA bundle used to
+	// verify the certificate of the database server.
+	//+optional
+	CASecretRef *corev1.SecretKeySelector `json:"caSecretRef,omitempty"`
+}
+
+// RelayAllowedClient selects pods allowed to connect to the relay.
+// +kubebuilder:validation:XValidation:rule="has(self.podSelector) || has(self.namespaceSelector)",message="podSelector or namespaceSelector must be set"
+type RelayAllowedClient struct {
+	// PodSelector selects the client pods by label.
+	//+optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+
+	// NamespaceSelector selects the namespaces of the client pods by label.
+	//+optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// DatabaseAccountStatus defines the observed state of DatabaseAccount.
+type DatabaseAccountStatus struct {
+	// Phase is how far the controller is with the account, the Ready and Degraded conditions
+	// describe the state of the account.
+	//
+	// +optional
+	//+kubebuilder:default:=Init
+	Phase DatabaseAccountPhase `json:"phase,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed by the controller, the conditions
+	// report whether it was reconciled to ready or failed.
+	//
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of the account state.
+	//
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// Name is the basename used for the role and database.
+	//
+	// +optional
+	Name PostgreSQLResourceName `json:"name,omitempty"`
+
+	// Role is the observed state of the login role.
+	//
+	// +optional
+	Role DatabaseAccountRoleStatus `json:"role,omitempty"`
+
+	// Database is the observed state of the database.
+	//
+	// +optional
+	Database DatabaseAccountDatabaseStatus `json:"database,omitempty"`
+
+	// Secret is the observed state of the versioned secrets of the credentials.
+	//
+	// +optional
+	Secret DatabaseAccountSecretStatus `json:"secret,omitempty"`
+
+	// Relay is the observed state of the relay.
+	//
+	// +optional
+	Relay DatabaseAccountRelayStatus `json:"relay,omitempty"`
+}
+
+// DatabaseAccountRoleStatus defines the observed state of the login role.
+type DatabaseAccountRoleStatus struct {
+	// Username is the login role name used for the account.
+	//
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// Attributes are the role attributes and memberships that were last applied to the role.
+	//
+	// +optional
+	Attributes *DatabaseAccountRoleAttributes `json:"attributes,omitempty"`
+
+	// Settings are the run-time parameters that were last applied to the role.
+	//
+	// +optional
+	Settings map[string]string `json:"settings,omitempty"`
+
+	// LastRotated is the time the password was last rotated.
+	//
+	// +optional
+	LastRotated *metav1.Time `json:"lastRotated,omitempty"`
+
+	// RotateAtHandled is the value of the rotate-at annotation that was last handled.
+	//
+	// +optional
+	RotateAtHandled string `json:"rotateAtHandled,omitempty"`
+
+	// Active is the login role in the secret when using DualRole rotation.
+	//
+	// +optional
+	Active string `json:"active,omitempty"`
+
+	// Previous is the login role that is revoked when the grace period ends.
+	//
+	// +optional
+	Previous string `json:"previous,omitempty"`
+
+	// RevokeAt is the time the login of the previous role is revoked.
+	//
+	// +optional
+	RevokeAt *metav1.Time `json:"revokeAt,omitempty"`
+}
+
+// DatabaseAccountDatabaseStatus defines the observed state of the database.
+type DatabaseAccountDatabaseStatus struct {
+	// Settings are the run-time parameters that were last applied to the database.
+	//
+	// +optional
+	Settings map[string]string `json:"settings,omitempty"`
+
+	// Extensions are the installed versions of the extensions in the spec.
+	//
+	// +optional
+	Extensions map[string]string `json:"extensions,omitempty"`
+
+	// Schemas are the schemas that were created in the database, the default schema first.
+	//
+	// +optional
+	Schemas []string `json:"schemas,omitempty"`
+}
+
+// DatabaseAccountSecretStatus defines the observed state of the versioned secrets.
+type DatabaseAccountSecretStatus struct {
+	// CurrentName is the immutable secret with the current version of the credentials.
+	//
+	// +optional
+	CurrentName string `json:"currentName,omitempty"`
+
+	// Previous are the immutable secrets of previous versions of the credentials.
+	//
+	// +optional
+	Previous []DatabaseAccountPreviousSecret `json:"previous,omitempty"`
+}
+
+// DatabaseAccountRelayStatus defines the observed state of the relay.
+type DatabaseAccountRelayStatus struct {
+	// Mode is the relay that was last deployed for the account, the relay is removed when the spec
+	// changes to another mode or no relay.
+	//
+	// +optional
+	Mode RelayMode `json:"mode,omitempty"`
+}
+
+// DatabaseAccountPreviousSecret is the immutable secret of a previous version of the credentials.
+type DatabaseAccountPreviousSecret struct {
+	// Name is the name of the secret.
+	Name string `json:"name"`
+
+	// DeleteAt is the time the secret is deleted.
+	DeleteAt metav1.Time `json:"deleteAt"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`,description="phase of the database account"
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`,description="ready status of database account"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:printcolumn:name="Name",priority=1,type=string,JSONPath=`.status.name`,description="name of the database account"
+// +kubebuilder:printcolumn:name="Server",priority=1,type=string,JSONPath=`.spec.serverRef.name`,description="database server of the database account"
+// +kubebuilder:resource:shortName="dba"
+
+// DatabaseAccount is the Schema for the databaseaccounts API.
+type DatabaseAccount struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DatabaseAccountSpec   `json:"spec,omitempty"`
+	Status DatabaseAccountStatus `json:"status,omitempty"`
+}
+
+// GetCondition returns the status condition of the type, nil is returned if it is not found.
+func (d *DatabaseAccount) GetCondition(conditionType string) *metav1.Condition {
+	return meta.FindStatusCondition(d.Status.Conditions, conditionType)
+}
+
+// IsConditionTrue returns true if the status condition of the type is true.
+func (d *DatabaseAccount) IsConditionTrue(conditionType string) bool {
+	return meta.IsStatusConditionTrue(d.Status.Conditions, conditionType)
+}
+
+// DatabaseAccountList contains a list of DatabaseAccount
+//
+// +kubebuilder:object:root=true
+type DatabaseAccountList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DatabaseAccount `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DatabaseAccount{}, &DatabaseAccountList{})
+}
@@ -0,0 +1,646 @@
+//go:build !ignore_autogenerated
+
+/*
+MIT License
+
+Copyright (c) 2023 Dev Ops Squad
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+*/
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccount) DeepCopyInto(out *DatabaseAccount) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccount.
+func (in *DatabaseAccount) DeepCopy() *DatabaseAccount {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseAccount) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountDatabase) DeepCopyInto(out *DatabaseAccountDatabase) {
+	*out = *in
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make([]DatabaseAccountExtension, len(*in))
+		copy(*out, *in)
+	}
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountDatabase.
+func (in *DatabaseAccountDatabase) DeepCopy() *DatabaseAccountDatabase {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountDatabase)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountDatabaseStatus) DeepCopyInto(out *DatabaseAccountDatabaseStatus) {
+	*out = *in
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Extensions != nil {
+		in, out := &in.Extensions, &out.Extensions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Schemas != nil {
+		in, out := &in.Schemas, &out.Schemas
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountDatabaseStatus.
+func (in *DatabaseAccountDatabaseStatus) DeepCopy() *DatabaseAccountDatabaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountDatabaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountExtension) DeepCopyInto(out *DatabaseAccountExtension) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountExtension.
+func (in *DatabaseAccountExtension) DeepCopy() *DatabaseAccountExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountExtension)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountList) DeepCopyInto(out *DatabaseAccountList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DatabaseAccount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountList.
+func (in *DatabaseAccountList) DeepCopy() *DatabaseAccountList {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DatabaseAccountList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountPasswordRotation) DeepCopyInto(out *DatabaseAccountPasswordRotation) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.GracePeriod != nil {
+		in, out := &in.GracePeriod, &out.GracePeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountPasswordRotation.
+func (in *DatabaseAccountPasswordRotation) DeepCopy() *DatabaseAccountPasswordRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountPasswordRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountPreviousSecret) DeepCopyInto(out *DatabaseAccountPreviousSecret) {
+	*out = *in
+	in.DeleteAt.DeepCopyInto(&out.DeleteAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountPreviousSecret.
+func (in *DatabaseAccountPreviousSecret) DeepCopy() *DatabaseAccountPreviousSecret {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountPreviousSecret)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountRelay) DeepCopyInto(out *DatabaseAccountRelay) {
+	*out = *in
+	in.RelaySettings.DeepCopyInto(&out.RelaySettings)
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]corev1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]corev1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(RelayTLS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AllowedClients != nil {
+		in, out := &in.AllowedClients, &out.AllowedClients
+		*out = make([]RelayAllowedClient, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountRelay.
+func (in *DatabaseAccountRelay) DeepCopy() *DatabaseAccountRelay {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountRelay)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountRelayStatus) DeepCopyInto(out *DatabaseAccountRelayStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountRelayStatus.
+func (in *DatabaseAccountRelayStatus) DeepCopy() *DatabaseAccountRelayStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountRelayStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountRole) DeepCopyInto(out *DatabaseAccountRole) {
+	*out = *in
+	if in.Attributes != nil {
+		in, out := &in.Attributes, &out.Attributes
+		*out = new(DatabaseAccountRoleAttributes)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PasswordRotation != nil {
+		in, out := &in.PasswordRotation, &out.PasswordRotation
+		*out = new(DatabaseAccountPasswordRotation)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountRole.
+func (in *DatabaseAccountRole) DeepCopy() *DatabaseAccountRole {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountRoleAttributes) DeepCopyInto(out *DatabaseAccountRoleAttributes) {
+	*out = *in
+	if in.ConnectionLimit != nil {
+		in, out := &in.ConnectionLimit, &out.ConnectionLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ValidUntil != nil {
+		in, out := &in.ValidUntil, &out.ValidUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.Inherit != nil {
+		in, out := &in.Inherit, &out.Inherit
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MemberOf != nil {
+		in, out := &in.MemberOf, &out.MemberOf
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountRoleAttributes.
+func (in *DatabaseAccountRoleAttributes) DeepCopy() *DatabaseAccountRoleAttributes {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountRoleAttributes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountRoleStatus) DeepCopyInto(out *DatabaseAccountRoleStatus) {
+	*out = *in
+	if in.Attributes != nil {
+		in, out := &in.Attributes, &out.Attributes
+		*out = new(DatabaseAccountRoleAttributes)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LastRotated != nil {
+		in, out := &in.LastRotated, &out.LastRotated
+		*out = (*in).DeepCopy()
+	}
+	if in.RevokeAt != nil {
+		in, out := &in.RevokeAt, &out.RevokeAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountRoleStatus.
+func (in *DatabaseAccountRoleStatus) DeepCopy() *DatabaseAccountRoleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountRoleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountSecret) DeepCopyInto(out *DatabaseAccountSecret) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountSecret.
+func (in *DatabaseAccountSecret) DeepCopy() *DatabaseAccountSecret {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountSecret)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountSecretStatus) DeepCopyInto(out *DatabaseAccountSecretStatus) {
+	*out = *in
+	if in.Previous != nil {
+		in, out := &in.Previous, &out.Previous
+		*out = make([]DatabaseAccountPreviousSecret, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountSecretStatus.
+func (in *DatabaseAccountSecretStatus) DeepCopy() *DatabaseAccountSecretStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountSecretStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountServerRef) DeepCopyInto(out *DatabaseAccountServerRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountServerRef.
+func (in *DatabaseAccountServerRef) DeepCopy() *DatabaseAccountServerRef {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountServerRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountSpec) DeepCopyInto(out *DatabaseAccountSpec) {
+	*out = *in
+	if in.ServerRef != nil {
+		in, out := &in.ServerRef, &out.ServerRef
+		*out = new(DatabaseAccountServerRef)
+		**out = **in
+	}
+	in.Role.DeepCopyInto(&out.Role)
+	in.Database.DeepCopyInto(&out.Database)
+	if in.Relay != nil {
+		in, out := &in.Relay, &out.Relay
+		*out = new(DatabaseAccountRelay)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Secret.DeepCopyInto(&out.Secret)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountSpec.
+func (in *DatabaseAccountSpec) DeepCopy() *DatabaseAccountSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DatabaseAccountStatus) DeepCopyInto(out *DatabaseAccountStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Role.DeepCopyInto(&out.Role)
+	in.Database.DeepCopyInto(&out.Database)
+	in.Secret.DeepCopyInto(&out.Secret)
+	out.Relay = in.Relay
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DatabaseAccountStatus.
+func (in *DatabaseAccountStatus) DeepCopy() *DatabaseAccountStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DatabaseAccountStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelayAllowedClient) DeepCopyInto(out *RelayAllowedClient) {
+	*out = *in
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelayAllowedClient.
+func (in *RelayAllowedClient) DeepCopy() *RelayAllowedClient {
+	if in == nil {
+		return nil
+	}
+	out := new(RelayAllowedClient)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelayClientTLS) DeepCopyInto(out *RelayClientTLS) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(corev1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelayClientTLS.
+func (in *RelayClientTLS) DeepCopy() *RelayClientTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(RelayClientTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelayServerTLS) DeepCopyInto(out *RelayServerTLS) {
+	*out = *in
+	if in.CASecretRef != nil {
+		in, out := &in.CASecretRef, &out.CASecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelayServerTLS.
+func (in *RelayServerTLS) DeepCopy() *RelayServerTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(RelayServerTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelaySettings) DeepCopyInto(out *RelaySettings) {
+	*out = *in
+	if in.MaxClientConn != nil {
+		in, out := &in.MaxClientConn, &out.MaxClientConn
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DefaultPoolSize != nil {
+		in, out := &in.DefaultPoolSize, &out.DefaultPoolSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ServerIdleTimeout != nil {
+		in, out := &in.ServerIdleTimeout, &out.ServerIdleTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxDBConnections != nil {
+		in, out := &in.MaxDBConnections, &out.MaxDBConnections
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelaySettings.
+func (in *RelaySettings) DeepCopy() *RelaySettings {
+	if in == nil {
+		return nil
+	}
+	out := new(RelaySettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelayTLS) DeepCopyInto(out *RelayTLS) {
+	*out = *in
+	if in.Client != nil {
+		in, out := &in.Client, &out.Client
+		*out = new(RelayClientTLS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Server != nil {
+		in, out := &in.Server, &out.Server
+		*out = new(RelayServerTLS)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelayTLS.
+func (in *RelayTLS) DeepCopy() *RelayTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(RelayTLS)
+	in.DeepCopyInto(out)
+	return out
+}
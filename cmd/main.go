@@ -27,8 +27,11 @@ import (
 
 	"github.com/dosquad/database-operator/accountsvr"
 	dbov1 "github.com/dosquad/database-operator/api/v1"
+	dbov2 "github.com/dosquad/database-operator/api/v2"
 	"github.com/dosquad/database-operator/internal/controller"
 	"github.com/dosquad/database-operator/internal/helper"
+	webhookv1 "github.com/dosquad/database-operator/internal/webhook/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -54,7 +57,10 @@ var (
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+
 	utilruntime.Must(dbov1.AddToScheme(scheme))
+	utilruntime.Must(dbov2.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -89,7 +95,7 @@ func mainCommand() error {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	var ctrlConfig *dbov1.DatabaseAccountControllerConfig
+	ctrlConfig := &dbov1.DatabaseAccountControllerConfig{}
 	options := ctrl.Options{
 		Scheme: scheme,
 		Metrics: server.Options{
@@ -126,29 +132,51 @@ func mainCommand() error {
 		}
 	}
 
-	var svr *accountsvr.DatabaseServer
-	{
-		var err error
-		svr, err = accountsvr.NewDatabaseServer(context.Background(), ctrlConfig.DatabaseDSN)
+	// the server from the controller configuration is the default for accounts without a serverRef.
+	var svr accountsvr.Server
+	if ctrlConfig.DatabaseDSN != "" {
+		dbSvr, err := accountsvr.NewDatabaseServer(context.Background(), ctrlConfig.DatabaseDSN)
 		if err != nil {
 			setupLog.Error(err, "unable to start database connection")
 			return err
 		}
+		defer dbSvr.Close(context.Background())
+
+		svr = dbSvr
 	}
-	defer svr.Close(context.Background())
+
+	pool := accountsvr.NewDatabaseServerPool()
+	defer pool.Close(context.Background())
 
 	if err := (&controller.DatabaseAccountReconciler{
 		Client:        mgr.GetClient(),
 		Scheme:        mgr.GetScheme(),
 		Recorder:      controller.NewRecorder(mgr.GetEventRecorderFor(controllerName)),
 		AccountServer: svr,
+		Servers:       pool,
 		Config:        ctrlConfig,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DatabaseAccount")
 		return err
 	}
+	// the webhooks need the serving certificate, they can be disabled when running the manager locally.
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err := webhookv1.SetupDatabaseAccountWebhookWithManager(mgr, ctrlConfig); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "DatabaseAccount")
+			return err
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
+	// accounts stored before v2 became the storage version are rewritten in v2.
+	if err := mgr.Add(&controller.StorageVersionMigration{
+		Client: mgr.GetClient(),
+		Reader: mgr.GetAPIReader(),
+	}); err != nil {
+		setupLog.Error(err, "unable to add storage version migration")
+		return err
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		return err
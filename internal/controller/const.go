@@ -10,8 +10,33 @@ const (
 	// defaultRequeueTime is the time to requeue a reconciliation request if there was an error.
 	defaultRequeueTime = 5 * time.Second
 
+	// relayRequeueTime is the time to requeue a reconciliation request while waiting for the relay
+	// pods to be ready.
+	relayRequeueTime = 10 * time.Second
+
 	// secretType is the type used to indicate a secret has been created by this operator.
 	//
 	//nolint:gosec // not credentials.
 	secretType = `dosquad.github.io/database-account`
+
+	// secretReplacedAnnotation is set on a secret that is being deleted so it can be recreated, it is
+	// used to change the data of an immutable secret.
+	//
+	//nolint:gosec // not credentials.
+	secretReplacedAnnotation = "dbo.dosquad.github.io/secret-replaced"
+
+	// secretReplacementSuffix is added to the name of the account secret for the copy of the secret
+	// that is written before it is replaced, the account secret is restored from it when the
+	// replacement was not created.
+	secretReplacementSuffix = "-replacement"
+
+	// secretReplacementType is the type of the copy of the account secret written before it is
+	// replaced, it is not the type of the account secret so deleting it does not remove the account.
+	//
+	//nolint:gosec // not credentials.
+	secretReplacementType = `dosquad.github.io/database-account-replacement`
+
+	// relayConfigChecksumAnnotation is set on the relay pod template to the checksum of the mounted
+	// relay configuration, the relay pods are rolled when the configuration changes.
+	relayConfigChecksumAnnotation = "dbo.dosquad.github.io/config-checksum"
 )
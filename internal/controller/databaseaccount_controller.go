@@ -20,19 +20,28 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"math/rand/v2"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/dosquad/database-operator/accountsvr"
 	dbov1 "github.com/dosquad/database-operator/api/v1"
 	"github.com/dosquad/database-operator/internal/helper"
+	"github.com/dosquad/database-operator/internal/valid"
 	"github.com/go-logr/logr"
 	"github.com/oklog/ulid/v2"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -49,20 +58,30 @@ const (
 // DatabaseAccountReconciler reconciles a DatabaseAccount object.
 type DatabaseAccountReconciler struct {
 	client.Client
-	Scheme        *runtime.Scheme
-	Recorder      Recorder
+	Scheme   *runtime.Scheme
+	Recorder Recorder
+	// AccountServer is the server used for accounts that do not specify a serverRef.
 	AccountServer accountsvr.Server
-	Config        *dbov1.DatabaseAccountControllerConfig
+	// Servers is the pool of servers used for accounts that specify a serverRef.
+	Servers *accountsvr.Pool
+	Config  *dbov1.DatabaseAccountControllerConfig
+	// Clock is used when scheduling password rotation, the real clock is used if not set.
+	Clock clock.PassiveClock
 }
 
 //+kubebuilder:rbac:groups=dbo.dosquad.github.io,resources=databaseaccounts,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=dbo.dosquad.github.io,resources=databaseaccounts/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=dbo.dosquad.github.io,resources=databaseaccounts/finalizers,verbs=update
+//+kubebuilder:rbac:groups=dbo.dosquad.github.io,resources=databaseservers,verbs=get;list;watch
 //+kubebuilder:rbac:groups=dbo.dosquad.github.io,resources=events,verbs=create;patch
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="apps",resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="policy",resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="networking.k8s.io",resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="discovery.k8s.io",resources=endpointslices,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -96,10 +115,20 @@ func (r *DatabaseAccountReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 		return ctrl.Result{}, nil
 	}
 
+	// the finalizer is handled before the server is resolved so an account with a missing server
+	// can still be deleted.
 	if ok, err := r.handleFinalizers(ctx, &dbAccount); ok {
 		return ctrl.Result{}, err
 	}
 
+	svr, svrErr := r.accountServer(ctx, &dbAccount)
+	if svrErr != nil {
+		logger.Error(svrErr, "unable to retrieve database server", "serverRef", dbAccount.GetSpecServerRef())
+		r.Recorder.WarningEvent(&dbAccount, ReasonServer, fmt.Sprintf("Unable to retrieve database server: %s", svrErr))
+
+		return ctrl.Result{}, svrErr
+	}
+
 	logger.V(1).Info("entering switch",
 		"stage", dbAccount.Status.Stage,
 		"resourceVersion", dbAccount.ResourceVersion,
@@ -118,19 +147,19 @@ func (r *DatabaseAccountReconciler) Reconcile(ctx context.Context, req ctrl.Requ
 	case dbov1.UnknownStage:
 		return r.stageZero(ctx, &dbAccount)
 	case dbov1.InitStage:
-		return r.stageInit(ctx, &dbAccount)
+		return r.stageInit(ctx, svr, &dbAccount)
 	case dbov1.UserCreateStage:
-		return r.stageUserCreate(ctx, &dbAccount)
+		return r.stageUserCreate(ctx, svr, &dbAccount)
 	case dbov1.DatabaseCreateStage:
-		return r.stageDatabaseCreate(ctx, &dbAccount)
+		return r.stageDatabaseCreate(ctx, svr, &dbAccount)
 	case dbov1.RelayCreateStage:
-		return r.stageRelayCreate(ctx, &dbAccount)
+		return r.stageRelayCreate(ctx, svr, &dbAccount)
 	case dbov1.ReadyStage:
-		return r.stageReady(ctx, &dbAccount)
+		return r.stageReady(ctx, svr, &dbAccount)
 	case dbov1.ErrorStage:
 		return r.stageError(ctx, &dbAccount)
 	case dbov1.TerminatingStage:
-		return r.stageTerminating(ctx, &dbAccount)
+		return r.stageTerminating(ctx, svr, &dbAccount)
 	default:
 		logger.Error(nil, "Unknown error type", "stage_value", dbAccount.Status.Stage)
 	}
@@ -188,32 +217,64 @@ func (r *DatabaseAccountReconciler) deleteExternalResources(
 ) error {
 	logger := log.FromContext(ctx)
 
+	if dbAccount.GetSpecRelay().IsShared() {
+		if err := r.releaseSharedRelay(ctx, dbAccount); err != nil {
+			return err
+		}
+	}
+
 	switch dbAccount.GetSpecOnDelete() {
 	case dbov1.OnDeleteDelete:
-		name, err := dbAccount.GetDatabaseName()
-		if err != nil {
+		svr, err := r.accountServer(ctx, dbAccount)
+		if isServerUnresolved(err) {
+			// the account is removed without the database so it does not block the deletion forever.
+			logger.Info("Unable to retrieve database server, skipping delete",
+				"serverRef", dbAccount.GetSpecServerRef(), "error", err.Error())
+			r.Recorder.WarningEvent(dbAccount, ReasonServer,
+				fmt.Sprintf("Unable to retrieve database server, database and role not deleted: %s", err))
+
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := r.deleteLoginRoles(ctx, svr, dbAccount); err != nil {
 			return err
 		}
 
-		return r.deleteDatabase(ctx, name)
+		return r.deleteDatabase(ctx, svr, dbAccount)
 	case dbov1.OnDeleteRetain:
 		logger.Info("Database record marked for retention, skipping delete")
 	}
 	return nil
 }
 
-// deleteDatabase takes the database name and removes it from the database server.
-func (r *DatabaseAccountReconciler) deleteDatabase(ctx context.Context, name string) error {
+// deleteDatabase removes the database and role of the DatabaseAccount from the database server.
+func (r *DatabaseAccountReconciler) deleteDatabase(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) error {
 	logger := log.FromContext(ctx)
 
-	logger.Info("Database record marked for delete, deleting", "databaseName", name)
+	name, err := dbAccount.GetDatabaseName()
+	if err != nil {
+		return err
+	}
+
+	roleName, err := dbAccount.GetRoleName()
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Database record marked for delete, deleting", "databaseName", name, "roleName", roleName)
 
-	dbName, ok, err := r.AccountServer.IsDatabase(ctx, name)
+	dbName, ok, err := svr.IsDatabase(ctx, name)
 	switch {
 	case err == nil && ok:
 		logger.V(1).Info("Database exists, deleting")
 
-		if err = r.AccountServer.Delete(ctx, name); err != nil {
+		if err = svr.Delete(ctx, name, roleName); err != nil {
 			logger.Error(err, "Unable to delete database and/or user")
 
 			return err
@@ -231,6 +292,137 @@ func (r *DatabaseAccountReconciler) deleteDatabase(ctx context.Context, name str
 	return nil
 }
 
+// accountServer returns the database server the DatabaseAccount is created on.
+func (r *DatabaseAccountReconciler) accountServer(
+	ctx context.Context,
+	dbAccount *dbov1.DatabaseAccount,
+) (accountsvr.Server, error) {
+	serverRef := dbAccount.GetSpecServerRef()
+	if serverRef == "" {
+		if r.AccountServer == nil {
+			return nil, ErrNoDefaultServer
+		}
+
+		return r.AccountServer, nil
+	}
+
+	if r.Servers == nil {
+		return nil, ErrNoServerPool
+	}
+
+	dsn, err := DatabaseServerDSNGet(ctx, r, serverRef)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Servers.Get(ctx, serverRef, dsn)
+}
+
+// isServerUnresolved returns true if the error is from looking up the database server of an account
+// and not from connecting to it, the lookup does not succeed by retrying.
+func isServerUnresolved(err error) bool {
+	return errors.Is(err, ErrNoDefaultServer) || errors.Is(err, ErrNoServerPool) ||
+		errors.Is(err, ErrMissingServerDSN) || apierrors.IsNotFound(err)
+}
+
+// setFailedCondition marks the condition as failed and the account as degraded, the status update
+// is best effort as the error is returned to the caller for the request to be retried.
+func (r *DatabaseAccountReconciler) setFailedCondition(
+	ctx context.Context,
+	dbAccount *dbov1.DatabaseAccount,
+	conditionType string,
+	failure error,
+) {
+	logger := log.FromContext(ctx)
+
+	dbAccount.SetCondition(conditionType, metav1.ConditionFalse, dbov1.ConditionReasonFailed, failure.Error())
+	dbAccount.SetCondition(dbov1.ConditionDegraded, metav1.ConditionTrue, dbov1.ConditionReasonFailed, failure.Error())
+	if err := r.Status().Update(ctx, dbAccount); err != nil {
+		logger.V(1).Error(err, "Unable to update DatabaseAccount status")
+	}
+}
+
+// setRelayPendingCondition sets the relay condition depending on if a relay is requested.
+func setRelayPendingCondition(dbAccount *dbov1.DatabaseAccount) {
+	if dbAccount.GetSpecCreateRelay() {
+		dbAccount.SetCondition(dbov1.ConditionRelayReady, metav1.ConditionFalse, dbov1.ConditionReasonPending,
+			"Waiting for relay to be created")
+		return
+	}
+
+	dbAccount.SetCondition(dbov1.ConditionRelayReady, metav1.ConditionTrue, dbov1.ConditionReasonNotRequired,
+		"Relay not requested")
+}
+
+// setReadyConditions marks every condition as complete and the account as ready.
+func setReadyConditions(dbAccount *dbov1.DatabaseAccount) {
+	if !dbAccount.IsConditionTrue(dbov1.ConditionUserReady) {
+		dbAccount.SetCondition(dbov1.ConditionUserReady, metav1.ConditionTrue, dbov1.ConditionReasonCreated,
+			"User created")
+	}
+	if !dbAccount.IsConditionTrue(dbov1.ConditionDatabaseReady) {
+		dbAccount.SetCondition(dbov1.ConditionDatabaseReady, metav1.ConditionTrue, dbov1.ConditionReasonCreated,
+			"Database created")
+	}
+	if !dbAccount.GetSpecCreateRelay() {
+		if !dbAccount.IsConditionTrue(dbov1.ConditionRelayReady) {
+			setRelayPendingCondition(dbAccount)
+		}
+	} else if dbAccount.GetCondition(dbov1.ConditionRelayReady) == nil {
+		// accounts created before the relay readiness was observed, it is checked on the next reconcile.
+		dbAccount.SetCondition(dbov1.ConditionRelayReady, metav1.ConditionTrue, dbov1.ConditionReasonCreated,
+			"Relay created")
+	}
+
+	if c := dbAccount.GetCondition(dbov1.ConditionRelayReady); c.Status != metav1.ConditionTrue {
+		// the account is not ready until the relay is serving.
+		setRelayNotReadyConditions(dbAccount, c.Reason, c.Message)
+
+		return
+	}
+
+	dbAccount.SetCondition(dbov1.ConditionDegraded, metav1.ConditionFalse, dbov1.ConditionReasonAsExpected, "")
+	dbAccount.SetCondition(dbov1.ConditionReady, metav1.ConditionTrue, dbov1.ConditionReasonAvailable,
+		"Ready to use")
+}
+
+// setRelayNotReadyConditions marks the account as not ready while the relay is not serving, the account
+// is only degraded when the relay pods are failing.
+func setRelayNotReadyConditions(dbAccount *dbov1.DatabaseAccount, reason, message string) {
+	if reason == dbov1.ConditionReasonRelayDegraded {
+		dbAccount.SetCondition(dbov1.ConditionDegraded, metav1.ConditionTrue, reason, message)
+	} else {
+		dbAccount.SetCondition(dbov1.ConditionDegraded, metav1.ConditionFalse, dbov1.ConditionReasonAsExpected, "")
+	}
+	dbAccount.SetCondition(dbov1.ConditionReady, metav1.ConditionFalse, reason, message)
+}
+
+// setRelayStatusCondition sets the relay condition from the observed relay status, true is returned
+// if the condition changed.
+func setRelayStatusCondition(dbAccount *dbov1.DatabaseAccount, relayStatus RelayStatus) bool {
+	status := metav1.ConditionFalse
+	if relayStatus.Ready {
+		status = metav1.ConditionTrue
+	}
+
+	return dbAccount.SetCondition(dbov1.ConditionRelayReady, status, relayStatus.Reason, relayStatus.Message)
+}
+
+// recordRelayStatus records an event for the relay status, a warning is used when the relay is degraded.
+func (r *DatabaseAccountReconciler) recordRelayStatus(
+	dbAccount *dbov1.DatabaseAccount,
+	reason RecorderReason,
+	relayStatus RelayStatus,
+) {
+	if relayStatus.Reason == dbov1.ConditionReasonRelayDegraded {
+		r.Recorder.WarningEvent(dbAccount, reason, relayStatus.Message)
+
+		return
+	}
+
+	r.Recorder.NormalEvent(dbAccount, reason, relayStatus.Message)
+}
+
 func (r *DatabaseAccountReconciler) stageZero(
 	ctx context.Context,
 	dbAccount *dbov1.DatabaseAccount,
@@ -258,6 +450,15 @@ func (r *DatabaseAccountReconciler) stageZero(
 		dbAccount.Status.Name = NewDatabaseAccountName(ctx)
 	}
 
+	dbAccount.SetCondition(dbov1.ConditionReady, metav1.ConditionFalse, dbov1.ConditionReasonPending,
+		"Waiting for account to be created")
+	dbAccount.SetCondition(dbov1.ConditionUserReady, metav1.ConditionFalse, dbov1.ConditionReasonPending,
+		"Waiting for user to be created")
+	dbAccount.SetCondition(dbov1.ConditionDatabaseReady, metav1.ConditionFalse, dbov1.ConditionReasonPending,
+		"Waiting for database to be created")
+	setRelayPendingCondition(dbAccount)
+	dbAccount.SetCondition(dbov1.ConditionDegraded, metav1.ConditionFalse, dbov1.ConditionReasonAsExpected, "")
+
 	if err := dbAccount.UpdateStatus(ctx, r); err != nil {
 		logger.V(1).Error(err, "unable to update DatabaseAccount Status")
 
@@ -270,35 +471,69 @@ func (r *DatabaseAccountReconciler) stageZero(
 
 func (r *DatabaseAccountReconciler) stageInit(
 	ctx context.Context,
+	svr accountsvr.Server,
 	dbAccount *dbov1.DatabaseAccount,
 ) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
-	secretErr := SecretRun(ctx, r, r, r.AccountServer, dbAccount, func(secret *corev1.Secret) error {
-		if secret.Immutable != nil && *secret.Immutable {
-			return ErrSecretImmutable
+	if dbAccount.Spec.Username != "" {
+		username, err := valid.PGIdentifier(dbAccount.Spec.Username).Validate()
+		if err == nil && username != dbAccount.Spec.Username {
+			err = fmt.Errorf("%w: invalid characters", valid.ErrInvalidName)
 		}
-
-		name, err := dbAccount.GetDatabaseName()
 		if err != nil {
-			return err
+			msg := fmt.Sprintf("Invalid username[%s]: %s", dbAccount.Spec.Username, err)
+			r.Recorder.WarningEvent(dbAccount, ReasonQueued, msg)
+			dbAccount.SetDegraded(dbov1.ConditionReasonInvalidUsername, msg)
+			dbAccount.Status.Stage = dbov1.ErrorStage
+
+			if err := dbAccount.UpdateStatus(ctx, r); err != nil {
+				logger.V(1).Error(err, "Unable to update DatabaseAccount status")
+
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{}, nil
 		}
 
-		r.AccountServer.CopyInitConfigToSecret(dbAccount, secret)
-		SetSecretKV(secret, accountsvr.DatabaseKeyUsername, name)
-		if dbAccount.GetSpecOnDelete() != dbov1.OnDeleteRetain {
-			SecretAddOwnerRefs(secret, dbAccount)
+		dbAccount.Status.Username = username
+	}
+
+	if dbAccount.GetSpecCreateRelay() {
+		if err := r.Config.ValidateRelay(dbAccount); err != nil {
+			msg := fmt.Sprintf("Invalid relay settings: %s", err)
+			r.Recorder.WarningEvent(dbAccount, ReasonQueued, msg)
+			dbAccount.SetDegraded(dbov1.ConditionReasonInvalidRelaySettings, msg)
+			dbAccount.Status.Stage = dbov1.ErrorStage
+
+			if err := dbAccount.UpdateStatus(ctx, r); err != nil {
+				logger.V(1).Error(err, "Unable to update DatabaseAccount status")
+
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{}, nil
 		}
-		controllerutil.AddFinalizer(secret, finalizerName)
+	}
 
-		return nil
-	})
+	secretErr := r.initSecret(ctx, svr, dbAccount)
 
 	switch {
 	case secretErr != nil && errors.Is(secretErr, ErrSecretImmutable):
 		r.Recorder.WarningEvent(dbAccount, ReasonQueued, "Secret already exists and is immutable")
-		dbAccount.Status.Error = true
-		dbAccount.Status.ErrorMessage = "Secret already exists and is immutable"
+		dbAccount.SetDegraded(dbov1.ConditionReasonSecretImmutable, "Secret already exists and is immutable")
+		dbAccount.Status.Stage = dbov1.ErrorStage
+
+		if secretErr = dbAccount.UpdateStatus(ctx, r); secretErr != nil {
+			logger.V(1).Error(secretErr, "Unable to update DatabaseAccount status")
+
+			return ctrl.Result{}, secretErr
+		}
+
+		return ctrl.Result{}, nil
+	case secretErr != nil && errors.Is(secretErr, ErrSecretAdopt):
+		r.Recorder.WarningEvent(dbAccount, ReasonQueued, fmt.Sprintf("Unable to adopt secret: %s", secretErr))
+		dbAccount.SetDegraded(dbov1.ConditionReasonSecretAdoptFailed, secretErr.Error())
 		dbAccount.Status.Stage = dbov1.ErrorStage
 
 		if secretErr = dbAccount.UpdateStatus(ctx, r); secretErr != nil {
@@ -328,44 +563,208 @@ func (r *DatabaseAccountReconciler) stageInit(
 	return ctrl.Result{}, nil
 }
 
+// initSecret writes the username of the account to the secret, an existing secret is handled by
+// the secret policy of the account. Fail returns ErrSecretImmutable for an immutable secret, Adopt
+// and Replace delete it and create it again as a mutable secret, for Adopt only once the
+// credentials in it have been verified.
+func (r *DatabaseAccountReconciler) initSecret(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) error {
+	policy := dbAccount.GetSpecSecretPolicy()
+	f := func(secret *corev1.Secret) error {
+		if policy == dbov1.SecretPolicyAdopt {
+			if err := adoptSecret(ctx, svr, dbAccount, secret); err != nil {
+				return err
+			}
+		}
+
+		name, err := dbAccount.GetRoleName()
+		if err != nil {
+			return err
+		}
+
+		svr.CopyInitConfigToSecret(dbAccount, secret)
+		SetSecretKV(secret, accountsvr.DatabaseKeyUsername, name)
+		if dbAccount.GetSpecOnDelete() != dbov1.OnDeleteRetain {
+			SecretAddOwnerRefs(secret, dbAccount)
+		}
+		controllerutil.AddFinalizer(secret, finalizerName)
+
+		return nil
+	}
+
+	switch policy {
+	case dbov1.SecretPolicyAdopt, dbov1.SecretPolicyReplace:
+		secret, err := SecretGetByName(ctx, r, dbAccount.GetSecretName())
+		if err == nil && secret.Immutable != nil && *secret.Immutable {
+			if err := SecretReplace(ctx, r, secret, f); err != nil {
+				return err
+			}
+
+			r.Recorder.NormalEvent(dbAccount, ReasonQueued,
+				fmt.Sprintf("Secret was immutable, recreated it for secret policy %s", policy))
+
+			return nil
+		} else if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+
+		return SecretRun(ctx, r, r, svr, dbAccount, f)
+	default:
+		return SecretRun(ctx, r, r, svr, dbAccount, func(secret *corev1.Secret) error {
+			if secret.Immutable != nil && *secret.Immutable {
+				return ErrSecretImmutable
+			}
+
+			return f(secret)
+		})
+	}
+}
+
+// adoptSecret uses the username in an existing secret for the account when the username and
+// password in the secret can login to the server, a secret without credentials is not adopted.
+func adoptSecret(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+	secret *corev1.Secret,
+) error {
+	username := GetSecretKV(secret, accountsvr.DatabaseKeyUsername)
+	password := GetSecretKV(secret, accountsvr.DatabaseKeyPassword)
+	if username == "" || password == "" {
+		return nil
+	}
+
+	if name, err := valid.PGIdentifier(username).Validate(); err != nil || name != username {
+		return fmt.Errorf("%w: invalid username[%s]", ErrSecretAdopt, username)
+	}
+
+	if dbAccount.Spec.Username != "" && dbAccount.Spec.Username != username {
+		return fmt.Errorf("%w: username[%s] does not match the spec username[%s]",
+			ErrSecretAdopt, username, dbAccount.Spec.Username)
+	}
+
+	ok, err := svr.VerifyRolePassword(ctx, username, password)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: login failed for username[%s]", ErrSecretAdopt, username)
+	}
+
+	if err := checkAdoptedRole(ctx, svr, dbAccount, username); err != nil {
+		return err
+	}
+
+	dbAccount.Status.Username = username
+
+	return nil
+}
+
+// checkAdoptedRole returns ErrSecretAdopt when the role was not created by the operator and the
+// account does not retain it, an adopted role is otherwise dropped when the account is deleted.
+func checkAdoptedRole(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+	name string,
+) error {
+	if dbAccount.GetSpecOnDelete() == dbov1.OnDeleteRetain {
+		return nil
+	}
+
+	managed, err := svr.IsManagedRole(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if !managed {
+		return fmt.Errorf("%w: role[%s] is not managed by the operator, onDelete must be %s",
+			ErrSecretAdopt, name, dbov1.OnDeleteRetain)
+	}
+
+	return nil
+}
+
 func (r *DatabaseAccountReconciler) stageUserCreate(
 	ctx context.Context,
+	svr accountsvr.Server,
 	dbAccount *dbov1.DatabaseAccount,
 ) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
 	r.Recorder.NormalEvent(dbAccount, ReasonUserCreate, "Creating database user")
 
-	if err := SecretRun(ctx, r, r, r.AccountServer, dbAccount, func(secret *corev1.Secret) error {
+	if err := r.checkRoleAllowed(dbAccount); err != nil {
+		r.Recorder.WarningEvent(dbAccount, ReasonUserCreate, fmt.Sprintf("Failed to create user: %s", err))
+		dbAccount.SetCondition(dbov1.ConditionUserReady, metav1.ConditionFalse,
+			dbov1.ConditionReasonBypassRLSNotAllowed, err.Error())
+		dbAccount.SetDegraded(dbov1.ConditionReasonBypassRLSNotAllowed, err.Error())
+		dbAccount.Status.Stage = dbov1.ErrorStage
+
+		if err := dbAccount.UpdateStatus(ctx, r); err != nil {
+			logger.V(1).Error(err, "Unable to update DatabaseAccount status")
+
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, nil
+	}
+
+	if err := SecretRun(ctx, r, r, svr, dbAccount, func(secret *corev1.Secret) error {
 		var name string
 		{
 			var err error
-			name, err = dbAccount.GetDatabaseName()
+			name, err = dbAccount.GetRoleName()
 			if err != nil {
 				return err
 			}
 		}
 
 		{
-			usr, pw, err := r.AccountServer.CreateRole(ctx, name)
+			reason := dbov1.ConditionReasonCreated
+			creds, err := svr.CreateRole(ctx, name)
 			if errors.Is(err, accountsvr.ErrRoleExists) {
-				r.Recorder.WarningEvent(dbAccount, ReasonUserCreate, "User exists, creating new password")
-				usr, pw, err = r.AccountServer.UpdateRolePassword(ctx, name)
+				reason = dbov1.ConditionReasonExists
+				creds, err = r.existingRole(ctx, svr, dbAccount, secret, name)
+			}
+			if reason, ok := roleNotUsableReason(err); ok {
+				r.Recorder.WarningEvent(dbAccount, ReasonUserCreate, fmt.Sprintf("Failed to create user: %s", err))
+				dbAccount.SetCondition(dbov1.ConditionUserReady, metav1.ConditionFalse, reason, err.Error())
+				dbAccount.SetDegraded(reason, err.Error())
+				dbAccount.Status.Stage = dbov1.ErrorStage
+
+				return err
+			}
+			if err == nil && !dbAccount.IsRoleSynced() {
+				err = r.syncRole(ctx, svr, dbAccount)
 			}
 			if err != nil {
 				r.Recorder.WarningEvent(dbAccount, ReasonUserCreate, fmt.Sprintf("Failed to create user: %s", err))
+				r.setFailedCondition(ctx, dbAccount, dbov1.ConditionUserReady, err)
 
 				return err
 			}
 
 			r.Recorder.NormalEvent(dbAccount, ReasonUserCreate, "User created")
+			dbAccount.SetCondition(dbov1.ConditionUserReady, metav1.ConditionTrue, reason, "User created")
 
-			SetSecretKV(secret, accountsvr.DatabaseKeyUsername, usr)
-			SetSecretKV(secret, accountsvr.DatabaseKeyPassword, pw)
+			setSecretLogin(secret, creds)
 		}
 
 		return nil
-	}); err != nil {
+	}); errors.Is(err, accountsvr.ErrRoleNotManaged) || errors.Is(err, ErrSecretAdopt) {
+		if err := dbAccount.UpdateStatus(ctx, r); err != nil {
+			logger.V(1).Error(err, "Unable to update DatabaseAccount status")
+
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{}, nil
+	} else if err != nil {
 		logger.V(1).Error(err, "Unable to create/retrieve secret")
 
 		return ctrl.Result{}, err
@@ -384,8 +783,69 @@ func (r *DatabaseAccountReconciler) stageUserCreate(
 	return ctrl.Result{}, nil
 }
 
+// roleNotUsableReason returns the condition reason of an existing role that the account can not use,
+// the account is failed instead of retried for these errors.
+func roleNotUsableReason(err error) (string, bool) {
+	switch {
+	case errors.Is(err, accountsvr.ErrRoleNotManaged):
+		return dbov1.ConditionReasonRoleNotManaged, true
+	case errors.Is(err, ErrSecretAdopt):
+		return dbov1.ConditionReasonSecretAdoptFailed, true
+	}
+
+	return "", false
+}
+
+// existingRole returns the credentials for a role that already exists, the credentials adopted from
+// the secret are kept when they can still login and the role is managed or retained by the account,
+// otherwise only roles created by the operator are reused and are given a new password.
+func (r *DatabaseAccountReconciler) existingRole(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+	secret *corev1.Secret,
+	name string,
+) (accountsvr.Credentials, error) {
+	pw := GetSecretKV(secret, accountsvr.DatabaseKeyPassword)
+	if dbAccount.GetSpecSecretPolicy() == dbov1.SecretPolicyAdopt && pw != "" &&
+		GetSecretKV(secret, accountsvr.DatabaseKeyUsername) == name {
+		ok, err := svr.VerifyRolePassword(ctx, name, pw)
+		if err != nil {
+			return accountsvr.Credentials{}, err
+		}
+
+		if ok {
+			if err := checkAdoptedRole(ctx, svr, dbAccount, name); err != nil {
+				return accountsvr.Credentials{}, err
+			}
+
+			r.Recorder.NormalEvent(dbAccount, ReasonUserCreate, "User exists, adopted credentials from secret")
+
+			return accountsvr.Credentials{
+				Username: name,
+				Password: pw,
+				Verifier: GetSecretKV(secret, accountsvr.DatabaseKeyVerifier),
+			}, nil
+		}
+	}
+
+	managed, err := svr.IsManagedRole(ctx, name)
+	if err != nil {
+		return accountsvr.Credentials{}, err
+	}
+
+	if !managed {
+		return accountsvr.Credentials{}, fmt.Errorf("%w: %s", accountsvr.ErrRoleNotManaged, name)
+	}
+
+	r.Recorder.WarningEvent(dbAccount, ReasonUserCreate, "User exists, creating new password")
+
+	return svr.UpdateRolePassword(ctx, name)
+}
+
 func (r *DatabaseAccountReconciler) stageDatabaseCreate(
 	ctx context.Context,
+	svr accountsvr.Server,
 	dbAccount *dbov1.DatabaseAccount,
 ) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -399,7 +859,19 @@ func (r *DatabaseAccountReconciler) stageDatabaseCreate(
 		}
 	}
 
-	dbName, ok, dbErr := r.AccountServer.IsDatabase(ctx, name)
+	var relayCA []byte
+	if dbAccount.GetSpecCreateRelay() {
+		var err error
+		relayCA, err = r.reconcileRelayTLS(ctx, dbAccount)
+		if err != nil {
+			r.Recorder.WarningEvent(dbAccount, ReasonRelayCreate, fmt.Sprintf("Failed to create relay TLS: %s", err))
+			r.setFailedCondition(ctx, dbAccount, dbov1.ConditionRelayReady, err)
+
+			return ctrl.Result{}, err
+		}
+	}
+
+	dbName, ok, dbErr := svr.IsDatabase(ctx, name)
 	switch {
 	case dbErr != nil:
 		r.Recorder.WarningEvent(
@@ -407,22 +879,14 @@ func (r *DatabaseAccountReconciler) stageDatabaseCreate(
 			ReasonDatabaseCreate,
 			fmt.Sprintf("Failed to init check for create database: %s", dbErr),
 		)
+		r.setFailedCondition(ctx, dbAccount, dbov1.ConditionDatabaseReady, dbErr)
 
 		return ctrl.Result{}, dbErr
 	case ok:
 		r.Recorder.WarningEvent(dbAccount, ReasonDatabaseCreate, "Database already exists")
-		if err := SecretRun(ctx, r, r, r.AccountServer, dbAccount, func(secret *corev1.Secret) error {
-			SetSecretKV(secret, accountsvr.DatabaseKeyDatabase, dbName)
-			SetSecretKV(secret, accountsvr.DatabaseKeyDSN, accountsvr.GenerateDSN(secret))
-			if dbAccount.GetSpecCreateRelay() {
-				AddPGBouncerConf(r.AccountServer, secret)
-				SetSecretKV(secret, accountsvr.DatabaseKeyHost, dbAccount.GetSecretName().Name)
-			}
-
-			boolTrue := true
-			secret.Immutable = &boolTrue
-			return nil
-		}); err != nil {
+		dbAccount.SetCondition(dbov1.ConditionDatabaseReady, metav1.ConditionTrue, dbov1.ConditionReasonExists,
+			"Database already exists")
+		if err := r.writeDatabaseSecret(ctx, svr, dbAccount, dbName, relayCA); err != nil {
 			logger.V(1).Error(err, "Unable to update secret")
 
 			return ctrl.Result{}, err
@@ -430,46 +894,71 @@ func (r *DatabaseAccountReconciler) stageDatabaseCreate(
 	default:
 		{
 			var err error
-			dbName, err = r.AccountServer.CreateDatabase(ctx, name, name)
+			var roleName string
+			if roleName, err = dbAccount.GetRoleName(); err == nil {
+				dbName, err = svr.CreateDatabase(ctx, name, roleName)
+			}
 			if err != nil {
 				r.Recorder.WarningEvent(dbAccount, ReasonDatabaseCreate,
 					fmt.Sprintf("Failed to create database: %s", err),
 				)
+				r.setFailedCondition(ctx, dbAccount, dbov1.ConditionDatabaseReady, err)
+
 				return ctrl.Result{}, err
 			}
 		}
 
-		if err := SecretRun(ctx, r, r, r.AccountServer, dbAccount, func(secret *corev1.Secret) error {
-			SetSecretKV(secret, accountsvr.DatabaseKeyDatabase, dbName)
-			SetSecretKV(secret, accountsvr.DatabaseKeyDSN, accountsvr.GenerateDSN(secret))
-			if dbAccount.GetSpecCreateRelay() {
-				AddPGBouncerConf(r.AccountServer, secret)
-				SetSecretKV(secret, accountsvr.DatabaseKeyHost, dbAccount.GetSecretName().Name)
-			}
-
-			boolTrue := true
-			secret.Immutable = &boolTrue
-			return nil
-		}); err != nil {
+		if err := r.writeDatabaseSecret(ctx, svr, dbAccount, dbName, relayCA); err != nil {
 			logger.V(1).Error(err, "Unable to update secret")
 
 			return ctrl.Result{}, err
 		}
 
 		r.Recorder.NormalEvent(dbAccount, ReasonDatabaseCreate, "Database created")
+		dbAccount.SetCondition(dbov1.ConditionDatabaseReady, metav1.ConditionTrue, dbov1.ConditionReasonCreated,
+			"Database created")
 	}
 
-	if !dbAccount.GetSpecCreateRelay() {
-		dbAccount.Status.Stage = dbov1.ReadyStage
-		dbAccount.Status.Ready = true
-	} else {
-		dbAccount.Status.Stage = dbov1.RelayCreateStage
+	if !dbAccount.IsSchemasSynced() {
+		if err := r.syncSchemas(ctx, svr, dbAccount); err != nil {
+			r.Recorder.WarningEvent(dbAccount, ReasonDatabaseCreate, fmt.Sprintf("Failed to create schemas: %s", err))
+			r.setFailedCondition(ctx, dbAccount, dbov1.ConditionDatabaseReady, err)
+
+			return ctrl.Result{}, err
+		}
 	}
 
-	if err := r.Status().Update(ctx, dbAccount); err != nil {
-		logger.V(1).Error(err, "Unable to update DatabaseAccount")
+	// settings that are not allowed are reported once the account is ready.
+	if !dbAccount.IsSettingsSynced() && r.Config.ValidateSettings(dbAccount) == nil {
+		if err := r.syncSettings(ctx, svr, dbAccount); err != nil {
+			r.Recorder.WarningEvent(dbAccount, ReasonDatabaseCreate, fmt.Sprintf("Failed to apply settings: %s", err))
+			r.setFailedCondition(ctx, dbAccount, dbov1.ConditionDatabaseReady, err)
 
-		return ctrl.Result{}, err
+			return ctrl.Result{}, err
+		}
+	}
+
+	// extensions that are not allowed are reported once the account is ready.
+	if !dbAccount.IsExtensionsSynced() && r.checkExtensionsAllowed(dbAccount) == nil {
+		if err := r.syncExtensions(ctx, svr, dbAccount); err != nil {
+			r.Recorder.WarningEvent(dbAccount, ReasonDatabaseCreate, fmt.Sprintf("Failed to install extensions: %s", err))
+			r.setFailedCondition(ctx, dbAccount, dbov1.ConditionDatabaseReady, err)
+
+			return ctrl.Result{}, err
+		}
+	}
+
+	if !dbAccount.GetSpecCreateRelay() {
+		dbAccount.Status.Stage = dbov1.ReadyStage
+		setReadyConditions(dbAccount)
+	} else {
+		dbAccount.Status.Stage = dbov1.RelayCreateStage
+	}
+
+	if err := r.Status().Update(ctx, dbAccount); err != nil {
+		logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+		return ctrl.Result{}, err
 	}
 
 	if !dbAccount.GetSpecCreateRelay() {
@@ -486,8 +975,39 @@ func (r *DatabaseAccountReconciler) stageDatabaseCreate(
 	return ctrl.Result{}, nil
 }
 
+// writeDatabaseSecret writes the database and DSN of the account to the secret and creates the
+// immutable secret of the version of the credentials.
+func (r *DatabaseAccountReconciler) writeDatabaseSecret(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+	dbName string,
+	relayCA []byte,
+) error {
+	var current *corev1.Secret
+	if err := SecretRun(ctx, r, r, svr, dbAccount, func(secret *corev1.Secret) error {
+		SetSecretKV(secret, accountsvr.DatabaseKeyDatabase, dbName)
+		SetSecretKV(secret, accountsvr.DatabaseKeyDSN, accountsvr.GenerateDSN(secret))
+		SetSecretSchema(dbAccount, secret)
+		if err := SetSecretRelay(ctx, svr, r.Config, dbAccount, secret); err != nil {
+			return err
+		}
+		if dbAccount.GetSpecCreateRelay() {
+			SetSecretRelayTLS(dbAccount, secret, relayCA)
+		}
+		current = secret
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return r.writeSecretVersion(ctx, dbAccount, current)
+}
+
 func (r *DatabaseAccountReconciler) stageRelayCreate(
 	ctx context.Context,
+	svr accountsvr.Server,
 	dbAccount *dbov1.DatabaseAccount,
 ) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -497,34 +1017,41 @@ func (r *DatabaseAccountReconciler) stageRelayCreate(
 	// 	return ctrl.Result{}, nameErr
 	// }
 
-	statefulSet, statefulSetErr := StatefulSetGet(ctx, r, r.Config, dbAccount)
-	if errors.Is(statefulSetErr, ErrNewStatefulSet) {
-		if err := r.Create(ctx, statefulSet); err != nil {
-			logger.V(1).Error(err, "unable to create StatefulSet")
+	if dbAccount.GetSpecRelay().IsShared() {
+		if err := r.reconcileSharedRelay(ctx, svr, dbAccount); err != nil {
+			logger.V(1).Error(err, "unable to add account to shared relay")
+			r.setFailedCondition(ctx, dbAccount, dbov1.ConditionRelayReady, err)
 
 			return ctrl.Result{}, err
 		}
-	} else if statefulSetErr != nil {
-		return ctrl.Result{}, statefulSetErr
+	} else if err := r.createRelay(ctx, svr, dbAccount); err != nil {
+		return ctrl.Result{}, err
 	}
+	dbAccount.Status.RelayMode = dbAccount.GetSpecRelayMode()
 
-	r.Recorder.NormalEvent(dbAccount, ReasonRelayCreate, "Created StatefulSet")
+	relayStatus, relayStatusErr := RelayStatusGet(ctx, r, dbAccount)
+	if relayStatusErr != nil {
+		return ctrl.Result{}, relayStatusErr
+	}
 
-	service, serviceErr := ServiceGet(ctx, r, dbAccount)
-	if errors.Is(serviceErr, ErrNewService) {
-		if err := r.Create(ctx, service); err != nil {
-			logger.V(1).Error(err, "unable to create Service")
+	changed := setRelayStatusCondition(dbAccount, relayStatus)
+	if !relayStatus.Ready {
+		setRelayNotReadyConditions(dbAccount, relayStatus.Reason, relayStatus.Message)
+		if err := r.Status().Update(ctx, dbAccount); err != nil {
+			logger.V(1).Error(err, "Unable to update DatabaseAccount")
 
 			return ctrl.Result{}, err
 		}
-	} else if serviceErr != nil {
-		return ctrl.Result{}, serviceErr
-	}
 
-	r.Recorder.NormalEvent(dbAccount, ReasonRelayCreate, "Created Service")
+		if changed {
+			r.recordRelayStatus(dbAccount, ReasonRelayCreate, relayStatus)
+		}
+
+		return ctrl.Result{RequeueAfter: relayRequeueTime}, nil
+	}
 
 	dbAccount.Status.Stage = dbov1.ReadyStage
-	dbAccount.Status.Ready = true
+	setReadyConditions(dbAccount)
 	if err := r.Status().Update(ctx, dbAccount); err != nil {
 		logger.V(1).Error(err, "Unable to update DatabaseAccount")
 
@@ -541,29 +1068,106 @@ func (r *DatabaseAccountReconciler) stageRelayCreate(
 	return ctrl.Result{}, nil
 }
 
+// createRelay creates the relay resources of the account that do not exist.
+func (r *DatabaseAccountReconciler) createRelay(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) error {
+	logger := log.FromContext(ctx)
+
+	if err := r.applyRelayNetworkPolicy(ctx, dbAccount, relayObjectMeta(dbAccount),
+		dbAccount.GetSpecRelay().GetAllowedClients(), accountRelayServer(svr)); err != nil {
+		logger.V(1).Error(err, "unable to create NetworkPolicy")
+		r.setFailedCondition(ctx, dbAccount, dbov1.ConditionRelayReady, err)
+
+		return err
+	}
+
+	statefulSet, statefulSetErr := StatefulSetGet(ctx, r, r.Config, dbAccount)
+	if errors.Is(statefulSetErr, ErrNewStatefulSet) {
+		if err := r.Create(ctx, statefulSet); err != nil {
+			logger.V(1).Error(err, "unable to create StatefulSet")
+			r.setFailedCondition(ctx, dbAccount, dbov1.ConditionRelayReady, err)
+
+			return err
+		}
+
+		r.Recorder.NormalEvent(dbAccount, ReasonRelayCreate, "Created StatefulSet")
+	} else if statefulSetErr != nil {
+		return statefulSetErr
+	}
+
+	service, serviceErr := ServiceGet(ctx, r, dbAccount)
+	if errors.Is(serviceErr, ErrNewService) {
+		if err := r.Create(ctx, service); err != nil {
+			logger.V(1).Error(err, "unable to create Service")
+			r.setFailedCondition(ctx, dbAccount, dbov1.ConditionRelayReady, err)
+
+			return err
+		}
+
+		r.Recorder.NormalEvent(dbAccount, ReasonRelayCreate, "Created Service")
+	} else if serviceErr != nil {
+		return serviceErr
+	}
+
+	if dbAccount.GetSpecRelay().GetReplicas() > 1 {
+		pdb, pdbErr := PodDisruptionBudgetGet(ctx, r, dbAccount)
+		if errors.Is(pdbErr, ErrNewPodDisruptionBudget) {
+			if err := r.Create(ctx, pdb); err != nil {
+				logger.V(1).Error(err, "unable to create PodDisruptionBudget")
+				r.setFailedCondition(ctx, dbAccount, dbov1.ConditionRelayReady, err)
+
+				return err
+			}
+
+			r.Recorder.NormalEvent(dbAccount, ReasonRelayCreate, "Created PodDisruptionBudget")
+		} else if pdbErr != nil {
+			return pdbErr
+		}
+	}
+
+	return nil
+}
+
 func (r *DatabaseAccountReconciler) stageReady(
 	ctx context.Context,
+	svr accountsvr.Server,
 	dbAccount *dbov1.DatabaseAccount,
 ) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
 	logger.V(1).Info("Record is marked as ready, nothing to do")
 
-	if _, secretErr := SecretGetByName(ctx, r, dbAccount.GetSecretName()); apierrors.IsNotFound(secretErr) {
-		// secret has been deleted, probably sent here from reconcile trigger in secret delete.
-		logger.Info("Secret has been deleted, remove DatabaseAccount")
+	if _, secretErr := SecretGetByName(ctx, r, dbAccount.GetSecretName()); apierrors.IsNotFound(secretErr) &&
+		!isPasswordRotating(dbAccount) {
+		// the secret was deleted by the controller to replace it, it is not a request to remove the account.
+		restored, err := SecretRestore(ctx, r, r, dbAccount.GetSecretName())
+		if err != nil {
+			return ctrl.Result{}, err
+		}
 
-		if err := r.Delete(ctx, dbAccount); err != nil {
-			logger.Error(err, "Unable to delete DatabaseAccount in reaction to secret deletion")
+		if restored {
+			r.Recorder.NormalEvent(dbAccount, ReasonQueued, "Secret was deleted while it was replaced, restored it")
+		} else {
+			// secret has been deleted, probably sent here from reconcile trigger in secret delete.
+			logger.Info("Secret has been deleted, remove DatabaseAccount")
 
-			return ctrl.Result{RequeueAfter: defaultRequeueTime}, nil
-		}
+			if err := r.Delete(ctx, dbAccount); err != nil {
+				logger.Error(err, "Unable to delete DatabaseAccount in reaction to secret deletion")
 
-		return ctrl.Result{}, nil
+				return ctrl.Result{RequeueAfter: defaultRequeueTime}, nil
+			}
+
+			return ctrl.Result{}, nil
+		}
 	}
 
 	onDeleteUpdate := false
-	if err := SecretRun(ctx, r, r, r.AccountServer, dbAccount, func(secret *corev1.Secret) error {
+	var current *corev1.Secret
+	if err := SecretRun(ctx, r, r, svr, dbAccount, func(secret *corev1.Secret) error {
+		current = secret
 		// logger.V(1).Info("Checking database account spec")
 		// logger.V(1).Info("Database account spec", "dbAccount.Spec.OnDelete", dbAccount.GetSpecOnDelete())
 		switch dbAccount.GetSpecOnDelete() {
@@ -592,71 +1196,998 @@ func (r *DatabaseAccountReconciler) stageReady(
 		logger.Info("Database account onDelete changed, updated secret")
 	}
 
+	// accounts that were ready before the secret was versioned do not have a current version.
+	if dbAccount.Status.CurrentSecretName == "" && GetSecretKV(current, accountsvr.DatabaseKeyPassword) != "" {
+		if err := r.writeSecretVersion(ctx, dbAccount, current); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := r.Status().Update(ctx, dbAccount); err != nil {
+			logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+			return ctrl.Result{}, err
+		}
+	}
+
+	requeueAfter, relayErr := r.stageReadyRelay(ctx, svr, dbAccount)
+	if relayErr != nil {
+		return ctrl.Result{}, relayErr
+	}
+
+	// the previous role is revoked first as a DualRole rotation waits for it.
+	revokeAfter, revokeErr := r.revokePreviousRole(ctx, svr, dbAccount)
+	if revokeErr != nil {
+		return ctrl.Result{}, revokeErr
+	}
+	requeueAfter = minRequeue(requeueAfter, revokeAfter)
+
+	rotateAfter, rotateErr := r.rotatePassword(ctx, svr, dbAccount)
+	if rotateErr != nil {
+		return ctrl.Result{}, rotateErr
+	}
+	requeueAfter = minRequeue(requeueAfter, rotateAfter)
+
+	deleteAfter, deleteErr := r.deleteSecretVersions(ctx, dbAccount)
+	if deleteErr != nil {
+		return ctrl.Result{}, deleteErr
+	}
+	requeueAfter = minRequeue(requeueAfter, deleteAfter)
+
+	if !dbAccount.IsRoleSynced() {
+		if err := r.checkRoleAllowed(dbAccount); err != nil {
+			r.Recorder.WarningEvent(dbAccount, ReasonRoleSync, fmt.Sprintf("Failed to update role: %s", err))
+			dbAccount.SetDegraded(dbov1.ConditionReasonBypassRLSNotAllowed, err.Error())
+			if err := r.Status().Update(ctx, dbAccount); err != nil {
+				logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
+
+		if err := r.syncRole(ctx, svr, dbAccount); err != nil {
+			r.Recorder.WarningEvent(dbAccount, ReasonRoleSync, fmt.Sprintf("Failed to update role: %s", err))
+
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.NormalEvent(dbAccount, ReasonRoleSync, "Role updated")
+		setReadyConditions(dbAccount)
+		if err := r.Status().Update(ctx, dbAccount); err != nil {
+			logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+			return ctrl.Result{}, err
+		}
+	}
+
+	if !dbAccount.IsSchemasSynced() {
+		if err := r.syncSchemas(ctx, svr, dbAccount); err != nil {
+			r.Recorder.WarningEvent(dbAccount, ReasonSchemaSync, fmt.Sprintf("Failed to update schemas: %s", err))
+
+			return ctrl.Result{}, err
+		}
+
+		var current *corev1.Secret
+		if err := r.secretUpdate(ctx, svr, dbAccount, func(secret *corev1.Secret) error {
+			SetSecretSchema(dbAccount, secret)
+			current = secret
+
+			return nil
+		}); err != nil {
+			logger.V(1).Error(err, "Unable to update secret")
+
+			return ctrl.Result{}, err
+		}
+
+		if err := r.writeSecretVersion(ctx, dbAccount, current); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.NormalEvent(dbAccount, ReasonSchemaSync, "Schemas updated")
+		setReadyConditions(dbAccount)
+		if err := r.Status().Update(ctx, dbAccount); err != nil {
+			logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+			return ctrl.Result{}, err
+		}
+	}
+
+	if !dbAccount.IsSettingsSynced() {
+		if err := r.syncSettings(ctx, svr, dbAccount); errors.Is(err, dbov1.ErrSettingNotAllowed) {
+			r.Recorder.WarningEvent(dbAccount, ReasonSettingsSync, fmt.Sprintf("Failed to update settings: %s", err))
+			dbAccount.SetDegraded(dbov1.ConditionReasonSettingNotAllowed, err.Error())
+			if err := r.Status().Update(ctx, dbAccount); err != nil {
+				logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		} else if err != nil {
+			r.Recorder.WarningEvent(dbAccount, ReasonSettingsSync, fmt.Sprintf("Failed to update settings: %s", err))
+
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.NormalEvent(dbAccount, ReasonSettingsSync, "Settings updated")
+		setReadyConditions(dbAccount)
+		if err := r.Status().Update(ctx, dbAccount); err != nil {
+			logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+			return ctrl.Result{}, err
+		}
+	}
+
+	if !dbAccount.IsExtensionsSynced() {
+		if err := r.syncExtensions(ctx, svr, dbAccount); errors.Is(err, ErrExtensionNotAllowed) {
+			r.Recorder.WarningEvent(dbAccount, ReasonExtensionSync, fmt.Sprintf("Failed to update extensions: %s", err))
+			dbAccount.SetDegraded(dbov1.ConditionReasonExtensionNotAllowed, err.Error())
+			if err := r.Status().Update(ctx, dbAccount); err != nil {
+				logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		} else if err != nil {
+			r.Recorder.WarningEvent(dbAccount, ReasonExtensionSync, fmt.Sprintf("Failed to update extensions: %s", err))
+
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.NormalEvent(dbAccount, ReasonExtensionSync, "Extensions updated")
+		setReadyConditions(dbAccount)
+		if err := r.Status().Update(ctx, dbAccount); err != nil {
+			logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+			return ctrl.Result{}, err
+		}
+	}
+
+	relayReady := !dbAccount.GetSpecCreateRelay() || dbAccount.IsConditionTrue(dbov1.ConditionRelayReady)
+	if relayReady && (!dbAccount.IsConditionTrue(dbov1.ConditionReady) ||
+		dbAccount.Status.ObservedGeneration != dbAccount.GetGeneration()) {
+		// accounts created before status conditions were added need them populated.
+		setReadyConditions(dbAccount)
+		if err := r.Status().Update(ctx, dbAccount); err != nil {
+			logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+			return ctrl.Result{}, err
+		}
+	}
+
 	logger.Info("Record is marked as ready",
 		"databaseUsername", dbAccount.Status.Name,
 		"secretName", dbAccount.GetSecretName(),
 	)
 
 	// logger.V(1).Info("return result[ok]")
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
-func (r *DatabaseAccountReconciler) stageError(
+// stageReadyRelay updates the relay configuration, corrects drift in the relay resources and updates
+// the relay condition from the relay pods, the request is requeued while the relay is not serving.
+func (r *DatabaseAccountReconciler) stageReadyRelay(
 	ctx context.Context,
-	_ *dbov1.DatabaseAccount,
-) (ctrl.Result, error) {
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) (time.Duration, error) {
 	logger := log.FromContext(ctx)
 
-	logger.Info("Record is marked as error, nothing to do")
+	if dbAccount.GetSpecCreateRelay() {
+		if err := r.reissueRelayPassword(ctx, svr, dbAccount); err != nil {
+			r.Recorder.WarningEvent(dbAccount, ReasonPasswordRotate,
+				fmt.Sprintf("Failed to reissue password for relay: %s", err))
 
-	return ctrl.Result{}, nil
+			return 0, err
+		}
+	}
+
+	if err := r.releaseRelay(ctx, svr, dbAccount); err != nil {
+		r.Recorder.WarningEvent(dbAccount, ReasonRelayUpdate, fmt.Sprintf("Failed to remove relay: %s", err))
+
+		return 0, err
+	}
+
+	if !dbAccount.GetSpecCreateRelay() {
+		return 0, nil
+	}
+
+	if dbAccount.GetSpecRelay().IsShared() {
+		if err := r.reconcileSharedRelay(ctx, svr, dbAccount); err != nil {
+			r.Recorder.WarningEvent(dbAccount, ReasonRelayUpdate,
+				fmt.Sprintf("Failed to reconcile shared relay: %s", err))
+
+			return 0, err
+		}
+	} else if err := r.reconcileAccountRelay(ctx, svr, dbAccount); err != nil {
+		return 0, err
+	}
+
+	relayStatus, err := RelayStatusGet(ctx, r, dbAccount)
+	if err != nil {
+		return 0, err
+	}
+
+	modeChanged := dbAccount.Status.RelayMode != dbAccount.GetSpecRelayMode()
+	dbAccount.Status.RelayMode = dbAccount.GetSpecRelayMode()
+	if changed := setRelayStatusCondition(dbAccount, relayStatus); changed || modeChanged {
+		if changed {
+			r.recordRelayStatus(dbAccount, ReasonRelayUpdate, relayStatus)
+		}
+		setReadyConditions(dbAccount)
+		if err := r.Status().Update(ctx, dbAccount); err != nil {
+			logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+			return 0, err
+		}
+	}
+
+	if !relayStatus.Ready {
+		return relayRequeueTime, nil
+	}
+
+	return r.relayResolveAfter(svr, dbAccount), nil
 }
 
-func (r *DatabaseAccountReconciler) stageTerminating(
+// releaseRelay removes the relay that was last deployed for the account when the spec has changed to
+// another relay mode or no relay, and points the account secret at the new relay or the server.
+func (r *DatabaseAccountReconciler) releaseRelay(
 	ctx context.Context,
+	svr accountsvr.Server,
 	dbAccount *dbov1.DatabaseAccount,
-) (ctrl.Result, error) {
-	dbName := dbAccount.Status.Name
-	if err := r.deleteDatabase(ctx, dbName.String()); err != nil {
-		return ctrl.Result{}, err
+) error {
+	logger := log.FromContext(ctx)
+
+	previous := dbAccount.Status.RelayMode
+	if previous == "" || previous == dbAccount.GetSpecRelayMode() {
+		return nil
 	}
 
-	return ctrl.Result{}, nil
-}
+	logger.Info("Relay mode changed", "previous", previous, "mode", dbAccount.GetSpecRelayMode())
 
-// reconcileSecret handles secrets created along side databaseaccount resources.
-func (r *DatabaseAccountReconciler) reconcileSecret(ctx context.Context, secretObj client.Object) []reconcile.Request {
-	logger := log.FromContext(ctx)
-	callID := ulid.Make().String()
-	logger = logger.WithValues("callID", callID)
-	ctx = log.IntoContext(ctx, logger)
-	requests := []reconcile.Request{}
+	if previous == dbov1.RelayModeShared {
+		if err := r.releaseSharedRelay(ctx, dbAccount); err != nil {
+			return err
+		}
+	} else if err := r.deleteAccountRelay(ctx, dbAccount); err != nil {
+		return err
+	}
 
-	name := types.NamespacedName{
-		Name:      secretObj.GetName(),
-		Namespace: secretObj.GetNamespace(),
+	var current *corev1.Secret
+	if err := r.secretUpdate(ctx, svr, dbAccount, func(secret *corev1.Secret) error {
+		current = secret
+		svr.CopyInitConfigToSecret(dbAccount, secret)
+		if !dbAccount.GetSpecCreateRelay() {
+			setSecretRelayFiles(secret, nil)
+			SetSecretRelayTLS(dbAccount, secret, nil)
+		}
+		if err := SetSecretRelay(ctx, svr, r.Config, dbAccount, secret); err != nil {
+			return err
+		}
+		SetSecretKV(secret, accountsvr.DatabaseKeyDSN, accountsvr.GenerateDSN(secret))
+
+		return nil
+	}); err != nil {
+		return err
 	}
-	secret, secretErr := SecretGetByName(ctx, r, name)
-	if secretErr != nil {
-		return requests
+
+	if err := r.writeSecretVersion(ctx, dbAccount, current); err != nil {
+		return err
 	}
 
-	if !strings.EqualFold(string(secret.Type), secretType) {
-		return requests
+	dbAccount.Status.RelayMode = ""
+	if !dbAccount.GetSpecCreateRelay() {
+		setRelayPendingCondition(dbAccount)
 	}
+	setReadyConditions(dbAccount)
+	if err := r.Status().Update(ctx, dbAccount); err != nil {
+		logger.V(1).Error(err, "Unable to update DatabaseAccount")
 
-	logger.V(1).Info("call():reconcileSecret",
-		"name", secretObj.GetName(),
-		"namespace", secretObj.GetNamespace(),
-		"managedFields", secretObj.GetManagedFields(),
-		"secretType", secret.Type,
-	)
+		return err
+	}
 
-	if secret.ObjectMeta.DeletionTimestamp.IsZero() {
+	return nil
+}
+
+// reissueRelayPassword sets a new password on the role of an account secret without the verifier of
+// its password, the relay logs in to the server with the verifier and secrets written before it was
+// stored with the credentials do not have it. The new credentials are written to the secret with a
+// new secret version, and the status of the account is updated.
+func (r *DatabaseAccountReconciler) reissueRelayPassword(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) error {
+	logger := log.FromContext(ctx)
+
+	secret, err := SecretGetByName(ctx, r, dbAccount.GetSecretName())
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	username := GetSecretKV(secret, accountsvr.DatabaseKeyUsername)
+	password := GetSecretKV(secret, accountsvr.DatabaseKeyPassword)
+	if username == "" || password == "" ||
+		helper.ScramSHA256VerifierMatches(GetSecretKV(secret, accountsvr.DatabaseKeyVerifier), password) {
+		return nil
+	}
+
+	logger.Info("Secret has no password verifier, reissuing password", "username", username)
+
+	creds, err := svr.UpdateRolePassword(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	var current *corev1.Secret
+	if err := r.secretUpdate(ctx, svr, dbAccount, func(secret *corev1.Secret) error {
+		current = secret
+
+		return SetSecretCredentials(ctx, svr, r.Config, dbAccount, secret, creds)
+	}); err != nil {
+		logger.V(1).Error(err, "Unable to update secret")
+
+		return err
+	}
+
+	r.Recorder.NormalEvent(dbAccount, ReasonPasswordRotate, "Password reissued for relay")
+
+	if err := r.writeSecretVersion(ctx, dbAccount, current); err != nil {
+		return err
+	}
+
+	if err := r.Status().Update(ctx, dbAccount); err != nil {
+		logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+		return err
+	}
+
+	return nil
+}
+
+// reconcileAccountRelay updates the relay configuration in the account secret and then reconciles the
+// relay resources of the account.
+func (r *DatabaseAccountReconciler) reconcileAccountRelay(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) error {
+	logger := log.FromContext(ctx)
+
+	relayCA, err := r.reconcileRelayTLS(ctx, dbAccount)
+	if err != nil {
+		r.Recorder.WarningEvent(dbAccount, ReasonRelayUpdate, fmt.Sprintf("Failed to reconcile relay TLS: %s", err))
+
+		return err
+	}
+
+	currentSecretName := dbAccount.Status.CurrentSecretName
+	if updated, err := r.syncRelaySecret(ctx, svr, dbAccount, relayCA); err != nil {
+		logger.V(1).Error(err, "Unable to update secret")
+
+		return err
+	} else if updated {
+		r.Recorder.NormalEvent(dbAccount, ReasonRelayUpdate, "Relay configuration updated")
+	}
+
+	if dbAccount.Status.CurrentSecretName != currentSecretName {
+		if err := r.Status().Update(ctx, dbAccount); err != nil {
+			logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+			return err
+		}
+	}
+
+	// the relay resources are reconciled after the secrets so the pods roll to the new configuration.
+	if err := r.reconcileRelay(ctx, svr, dbAccount); err != nil {
+		r.Recorder.WarningEvent(dbAccount, ReasonRelayUpdate, fmt.Sprintf("Failed to reconcile relay: %s", err))
+
+		return err
+	}
+
+	return nil
+}
+
+// isPasswordRotating returns true if the password rotation was started and has not completed, the
+// secret may be missing if it was being replaced.
+func isPasswordRotating(dbAccount *dbov1.DatabaseAccount) bool {
+	c := dbAccount.GetCondition(dbov1.ConditionReady)
+
+	return c != nil && c.Reason == dbov1.ConditionReasonPasswordRotating
+}
+
+// passwordRotationDue returns true if the password is due to be rotated on the schedule or by a
+// rotate-at request, otherwise the time until it is next due is returned, zero if it is not.
+func (r *DatabaseAccountReconciler) passwordRotationDue(dbAccount *dbov1.DatabaseAccount) (bool, time.Duration) {
+	now := r.now()
+	due := false
+	var wait time.Duration
+
+	if at, ok := dbAccount.GetPasswordRotationRequest(); ok {
+		if at.After(now) {
+			wait = minRequeue(wait, at.Sub(now))
+		} else {
+			due = true
+		}
+	}
+
+	if next, ok, err := dbAccount.GetNextPasswordRotation(); err != nil {
+		// retrying will not help, the request is reconciled again when the spec is changed.
+		r.Recorder.WarningEvent(dbAccount, ReasonPasswordRotate, fmt.Sprintf("Invalid password rotation: %s", err))
+	} else if ok {
+		if until := next.Sub(now); until > 0 {
+			wait = minRequeue(wait, until)
+		} else {
+			due = true
+		}
+	}
+
+	return due, wait
+}
+
+// minRequeue returns the shortest of the requeue durations, zero is no requeue.
+func minRequeue(a, b time.Duration) time.Duration {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+
+	return a
+}
+
+// rotatePassword rotates the password of the account if it is due, the time until the next rotation
+// is returned so the request can be requeued.
+func (r *DatabaseAccountReconciler) rotatePassword(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) (time.Duration, error) {
+	logger := log.FromContext(ctx)
+
+	if due, wait := r.passwordRotationDue(dbAccount); !due {
+		logger.V(1).Info("Password rotation is not due", "wait", wait)
+
+		return wait, nil
+	}
+
+	if wait, ok := r.passwordRotationDeferred(dbAccount); ok {
+		// the next login role is the previous role, its password can not change during the grace period.
+		logger.Info("Password rotation deferred until the previous role is revoked",
+			"previousRole", dbAccount.Status.PreviousRole, "wait", wait)
+
+		return wait, nil
+	}
+
+	name, err := dbAccount.GetRoleName()
+	if err != nil {
+		return 0, err
+	}
+
+	dbAccount.SetCondition(dbov1.ConditionReady, metav1.ConditionFalse, dbov1.ConditionReasonPasswordRotating,
+		"Password is being rotated")
+	if err := r.Status().Update(ctx, dbAccount); err != nil {
+		logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+		return 0, err
+	}
+
+	loginRole, err := dbAccount.GetLoginRoleName()
+	if err != nil {
+		return 0, err
+	}
+
+	var creds accountsvr.Credentials
+	if dbAccount.GetSpecRotationMode() == dbov1.RotationModeDualRole {
+		var nextRole string
+		if nextRole, err = dbAccount.GetNextLoginRoleName(); err == nil {
+			creds, err = svr.CreateMemberRole(ctx, nextRole, name)
+		}
+		if err == nil {
+			err = r.applyLoginRole(ctx, svr, dbAccount, creds.Username)
+		}
+	} else {
+		creds, err = svr.UpdateRolePassword(ctx, loginRole)
+	}
+	if err != nil {
+		r.Recorder.WarningEvent(dbAccount, ReasonPasswordRotate, fmt.Sprintf("Failed to rotate password: %s", err))
+		r.setFailedCondition(ctx, dbAccount, dbov1.ConditionUserReady, err)
+
+		return 0, err
+	}
+
+	var current *corev1.Secret
+	secretFunc := func(secret *corev1.Secret) error {
+		current = secret
+
+		return SetSecretCredentials(ctx, svr, r.Config, dbAccount, secret, creds)
+	}
+
+	if secretErr := r.secretUpdate(ctx, svr, dbAccount, secretFunc); secretErr != nil {
+		logger.V(1).Error(secretErr, "Unable to update secret")
+
+		return 0, secretErr
+	}
+
+	if err := r.writeSecretVersion(ctx, dbAccount, current); err != nil {
+		return 0, err
+	}
+
+	dbAccount.Status.LastRotated = ptr.To(metav1.NewTime(r.now()))
+	if dbAccount.GetSpecRotationMode() == dbov1.RotationModeDualRole {
+		// the previous login role keeps working until pods have picked up the new secret.
+		dbAccount.Status.ActiveRole = creds.Username
+		dbAccount.Status.PreviousRole = loginRole
+		dbAccount.Status.RevokeAt = ptr.To(metav1.NewTime(r.now().Add(dbAccount.GetSpecRotationGracePeriod())))
+	}
+	if at, ok := dbAccount.GetPasswordRotationRequest(); ok && !at.After(r.now()) {
+		// acknowledge the request so it is not handled again.
+		dbAccount.Status.RotateAtHandled = dbAccount.GetAnnotations()[dbov1.AnnotationRotateAt]
+	}
+	setReadyConditions(dbAccount)
+	if err := r.Status().Update(ctx, dbAccount); err != nil {
+		logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+		return 0, err
+	}
+
+	r.Recorder.NormalEvent(dbAccount, ReasonPasswordRotate, "Password rotated")
+
+	_, wait := r.passwordRotationDue(dbAccount)
+	if revokeAfter, ok := r.passwordRotationDeferred(dbAccount); ok {
+		// the login of the previous role is revoked at the end of the grace period.
+		wait = minRequeue(wait, revokeAfter)
+	}
+
+	return wait, nil
+}
+
+// passwordRotationDeferred returns true with the time until the grace period of the previous login
+// role ends when a DualRole rotation has to wait for it.
+func (r *DatabaseAccountReconciler) passwordRotationDeferred(dbAccount *dbov1.DatabaseAccount) (time.Duration, bool) {
+	if dbAccount.GetSpecRotationMode() != dbov1.RotationModeDualRole ||
+		dbAccount.Status.PreviousRole == "" || dbAccount.Status.RevokeAt == nil {
+		return 0, false
+	}
+
+	wait := dbAccount.Status.RevokeAt.Sub(r.now())
+
+	return wait, wait > 0
+}
+
+// revokePreviousRole revokes the login of the previous role once the DualRole rotation grace period
+// has ended, the time until it is due is returned so the request can be requeued.
+func (r *DatabaseAccountReconciler) revokePreviousRole(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) (time.Duration, error) {
+	logger := log.FromContext(ctx)
+
+	if dbAccount.Status.PreviousRole == "" || dbAccount.Status.RevokeAt == nil {
+		return 0, nil
+	}
+
+	if wait := dbAccount.Status.RevokeAt.Sub(r.now()); wait > 0 {
+		return wait, nil
+	}
+
+	if dbAccount.Status.PreviousRole != dbAccount.Status.ActiveRole {
+		if err := svr.SetRoleLogin(ctx, dbAccount.Status.PreviousRole, false); err != nil {
+			r.Recorder.WarningEvent(dbAccount, ReasonPasswordRotate,
+				fmt.Sprintf("Failed to revoke login of previous role: %s", err),
+			)
+
+			return 0, err
+		}
+	}
+
+	previousRole := dbAccount.Status.PreviousRole
+	dbAccount.Status.PreviousRole = ""
+	dbAccount.Status.RevokeAt = nil
+	if err := r.Status().Update(ctx, dbAccount); err != nil {
+		logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+		return 0, err
+	}
+
+	r.Recorder.NormalEvent(dbAccount, ReasonPasswordRotate, fmt.Sprintf("Revoked login of role %s", previousRole))
+
+	return 0, nil
+}
+
+// deleteLoginRoles removes the login roles created by DualRole rotation.
+func (r *DatabaseAccountReconciler) deleteLoginRoles(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) error {
+	if dbAccount.Status.ActiveRole == "" {
+		return nil
+	}
+
+	name, err := dbAccount.GetRoleName()
+	if err != nil {
+		return err
+	}
+
+	for _, suffix := range []string{dbov1.DualRoleSuffixA, dbov1.DualRoleSuffixB} {
+		if err := svr.DeleteRole(ctx, name+suffix); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkRoleAllowed returns an error if the role in the spec requests attributes that are not
+// allowed by the controller configuration.
+func (r *DatabaseAccountReconciler) checkRoleAllowed(dbAccount *dbov1.DatabaseAccount) error {
+	if role := dbAccount.GetSpecRole(); role != nil && role.BypassRLS && !r.Config.AllowBypassRLS {
+		return ErrBypassRLSNotAllowed
+	}
+
+	return nil
+}
+
+// loginRoleNames returns the DualRole login roles of the account that exist on the server, the
+// sessions of the account log in as these roles so the login attributes and settings are applied to
+// them as well as the owner role.
+func (r *DatabaseAccountReconciler) loginRoleNames(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) ([]string, error) {
+	if dbAccount.Status.ActiveRole == "" {
+		return nil, nil
+	}
+
+	name, err := dbAccount.GetRoleName()
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []string{}
+	for _, suffix := range []string{dbov1.DualRoleSuffixA, dbov1.DualRoleSuffixB} {
+		ok, err := svr.IsRole(ctx, name+suffix)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			roles = append(roles, name+suffix)
+		}
+	}
+
+	return roles, nil
+}
+
+// applyLoginRole applies the role attributes and settings of the account to a new DualRole login
+// role, the existing login roles are kept in sync by syncRole and syncSettings.
+func (r *DatabaseAccountReconciler) applyLoginRole(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+	loginRole string,
+) error {
+	if dbAccount.Status.Role != nil {
+		if err := svr.AlterRole(ctx, loginRole, dbAccount.Status.Role); err != nil {
+			return err
+		}
+	}
+
+	if len(dbAccount.Status.RoleSettings) > 0 {
+		if err := svr.SetRoleSettings(ctx, loginRole, dbAccount.Status.RoleSettings, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncRole applies the role attributes and memberships in the spec to the account role, memberships
+// that were previously granted and have been removed from the spec are revoked. The attributes are
+// also applied to the login roles, they are members of the account role so the memberships are not.
+func (r *DatabaseAccountReconciler) syncRole(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) error {
+	name, err := dbAccount.GetRoleName()
+	if err != nil {
+		return err
+	}
+
+	loginRoles, err := r.loginRoleNames(ctx, svr, dbAccount)
+	if err != nil {
+		return err
+	}
+
+	role := dbAccount.GetSpecRole()
+	for _, roleName := range append([]string{name}, loginRoles...) {
+		if err := svr.AlterRole(ctx, roleName, role); err != nil {
+			return err
+		}
+	}
+
+	previous := dbAccount.Status.Role.GetMemberOf()
+	revoke := []string{}
+	for _, v := range previous {
+		if !slices.Contains(role.GetMemberOf(), v) {
+			revoke = append(revoke, v)
+		}
+	}
+
+	grant := []string{}
+	for _, v := range role.GetMemberOf() {
+		if !slices.Contains(previous, v) {
+			grant = append(grant, v)
+		}
+	}
+
+	if err := svr.RevokeRoles(ctx, name, revoke); err != nil {
+		return err
+	}
+
+	if err := svr.GrantRoles(ctx, name, grant); err != nil {
+		return err
+	}
+
+	dbAccount.Status.Role = role.DeepCopy()
+
+	return nil
+}
+
+// syncSettings applies the role and database settings in the spec, settings that were previously
+// applied and have been removed from the spec are reset. The role settings are applied to the login
+// roles as well as the account role as they are read when a session logs in.
+func (r *DatabaseAccountReconciler) syncSettings(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) error {
+	if err := r.Config.ValidateSettings(dbAccount); err != nil {
+		return err
+	}
+
+	roleName, err := dbAccount.GetRoleName()
+	if err != nil {
+		return err
+	}
+
+	loginRoles, err := r.loginRoleNames(ctx, svr, dbAccount)
+	if err != nil {
+		return err
+	}
+
+	dbName, err := dbAccount.GetDatabaseName()
+	if err != nil {
+		return err
+	}
+
+	roleSettings := dbAccount.GetRoleSettings()
+	set, reset := diffSettings(dbAccount.Status.RoleSettings, roleSettings)
+	if len(set) > 0 || len(reset) > 0 {
+		for _, name := range append([]string{roleName}, loginRoles...) {
+			if err := svr.SetRoleSettings(ctx, name, set, reset); err != nil {
+				return err
+			}
+		}
+	}
+	dbAccount.Status.RoleSettings = maps.Clone(roleSettings)
+
+	set, reset = diffSettings(dbAccount.Status.DatabaseSettings, dbAccount.Spec.DatabaseSettings)
+	if len(set) > 0 || len(reset) > 0 {
+		if err := svr.SetDatabaseSettings(ctx, dbName, set, reset); err != nil {
+			return err
+		}
+	}
+	dbAccount.Status.DatabaseSettings = maps.Clone(dbAccount.Spec.DatabaseSettings)
+
+	return nil
+}
+
+// diffSettings returns the settings that have been added or changed and the names of the settings
+// that have been removed.
+func diffSettings(applied, settings map[string]string) (map[string]string, []string) {
+	set := map[string]string{}
+	for k, v := range settings {
+		if current, ok := applied[k]; !ok || current != v {
+			set[k] = v
+		}
+	}
+
+	reset := []string{}
+	for k := range applied {
+		if _, ok := settings[k]; !ok {
+			reset = append(reset, k)
+		}
+	}
+
+	return set, reset
+}
+
+// checkExtensionsAllowed returns an error if the spec requests an extension that is not in the allowed
+// extensions of the controller configuration.
+func (r *DatabaseAccountReconciler) checkExtensionsAllowed(dbAccount *dbov1.DatabaseAccount) error {
+	for _, ext := range dbAccount.Spec.Extensions {
+		if !slices.Contains(r.Config.AllowedExtensions, ext.Name) {
+			return fmt.Errorf("%w: %s", ErrExtensionNotAllowed, ext.Name)
+		}
+	}
+
+	return nil
+}
+
+// syncExtensions installs or updates the extensions in the spec and records the installed versions.
+func (r *DatabaseAccountReconciler) syncExtensions(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) error {
+	if err := r.checkExtensionsAllowed(dbAccount); err != nil {
+		return err
+	}
+
+	dbName, err := dbAccount.GetDatabaseName()
+	if err != nil {
+		return err
+	}
+
+	versions, err := svr.SyncExtensions(ctx, dbName, dbAccount.Spec.Extensions)
+	if err != nil {
+		return err
+	}
+
+	dbAccount.Status.Extensions = versions
+
+	return nil
+}
+
+// syncSchemas creates the schemas in the spec that have not been created in the account database.
+func (r *DatabaseAccountReconciler) syncSchemas(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) error {
+	roleName, err := dbAccount.GetRoleName()
+	if err != nil {
+		return err
+	}
+
+	dbName, err := dbAccount.GetDatabaseName()
+	if err != nil {
+		return err
+	}
+
+	schemas := dbAccount.GetSchemas()
+	for _, schema := range schemas {
+		if slices.Contains(dbAccount.Status.Schemas, schema) {
+			continue
+		}
+
+		if err := svr.CreateSchema(ctx, dbName, schema, roleName); err != nil {
+			return err
+		}
+	}
+
+	dbAccount.Status.Schemas = schemas
+
+	return nil
+}
+
+// secretUpdate runs the function on the account secret, an immutable secret is replaced.
+func (r *DatabaseAccountReconciler) secretUpdate(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+	f SecretFunc,
+) error {
+	if secret, err := SecretGetByName(ctx, r, dbAccount.GetSecretName()); err == nil &&
+		secret.Immutable != nil && *secret.Immutable {
+		return SecretReplace(ctx, r, secret, f)
+	}
+
+	return SecretRun(ctx, r, r, svr, dbAccount, f)
+}
+
+// now returns the current time from the reconciler clock.
+func (r *DatabaseAccountReconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now()
+	}
+
+	return r.Clock.Now()
+}
+
+func (r *DatabaseAccountReconciler) stageError(
+	ctx context.Context,
+	dbAccount *dbov1.DatabaseAccount,
+) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if !dbAccount.IsConditionTrue(dbov1.ConditionDegraded) {
+		// accounts marked as error before status conditions were added need them populated.
+		dbAccount.SetDegraded(dbov1.ConditionReasonFailed, dbAccount.Status.ErrorMessage)
+		if err := r.Status().Update(ctx, dbAccount); err != nil {
+			logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+			return ctrl.Result{}, err
+		}
+	}
+
+	logger.Info("Record is marked as error, nothing to do")
+
+	return ctrl.Result{}, nil
+}
+
+func (r *DatabaseAccountReconciler) stageTerminating(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) (ctrl.Result, error) {
+	if dbAccount.SetCondition(dbov1.ConditionReady, metav1.ConditionFalse, dbov1.ConditionReasonTerminating,
+		"Account is being removed") {
+		if err := r.Status().Update(ctx, dbAccount); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if dbAccount.GetSpecRelay().IsShared() {
+		if err := r.releaseSharedRelay(ctx, dbAccount); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if err := r.deleteLoginRoles(ctx, svr, dbAccount); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.deleteDatabase(ctx, svr, dbAccount); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// reconcileSecret handles secrets created along side databaseaccount resources.
+func (r *DatabaseAccountReconciler) reconcileSecret(ctx context.Context, secretObj client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+	callID := ulid.Make().String()
+	logger = logger.WithValues("callID", callID)
+	ctx = log.IntoContext(ctx, logger)
+	requests := []reconcile.Request{}
+
+	name := types.NamespacedName{
+		Name:      secretObj.GetName(),
+		Namespace: secretObj.GetNamespace(),
+	}
+	secret, secretErr := SecretGetByName(ctx, r, name)
+	if secretErr != nil {
+		return requests
+	}
+
+	if !strings.EqualFold(string(secret.Type), secretType) {
+		return requests
+	}
+
+	logger.V(1).Info("call():reconcileSecret",
+		"name", secretObj.GetName(),
+		"namespace", secretObj.GetNamespace(),
+		"managedFields", secretObj.GetManagedFields(),
+		"secretType", secret.Type,
+	)
+
+	if secret.ObjectMeta.DeletionTimestamp.IsZero() {
 		// object is not being deleted, return early
 		return requests
 	}
 
+	if _, ok := secret.GetAnnotations()[secretReplacedAnnotation]; ok {
+		// object is being replaced by the controller, the account is not being removed.
+		return requests
+	}
+
 	// object is being deleted
 	if controllerutil.ContainsFinalizer(secret, finalizerName) {
 		// our finalizer is present, so lets handle any external dependency
@@ -756,6 +2287,10 @@ func (r *DatabaseAccountReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Named("database_operator").
 		For(&dbov1.DatabaseAccount{}).
 		Owns(&corev1.Secret{}).
+		Owns(&appsv1.StatefulSet{}).
+		Owns(&corev1.Service{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&networkingv1.NetworkPolicy{}).
 		WithLogConstructor(logCtr).
 		Watches(
 			&corev1.Secret{},
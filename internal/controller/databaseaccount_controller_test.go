@@ -2,12 +2,25 @@ package controller_test
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"slices"
 	"testing"
+	"time"
 
+	"github.com/dosquad/database-operator/accountsvr"
+	accountsvrtest "github.com/dosquad/database-operator/accountsvr/test"
 	v1 "github.com/dosquad/database-operator/api/v1"
 	v1test "github.com/dosquad/database-operator/api/v1/test"
 	"github.com/dosquad/database-operator/internal/controller"
 	controllertest "github.com/dosquad/database-operator/internal/controller/test"
+	"github.com/dosquad/database-operator/internal/helper"
+	"github.com/dosquad/database-operator/internal/testhelp"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -63,6 +76,7 @@ func TestReconcile_Stage_Unknown_WithFinalizer(t *testing.T) {
 	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
 		controllertest.ReconcileWantStage(v1.InitStage),
 		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantPendingConditions,
 	}
 	// ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
 	// 	controllertest.ReconcileWantSecretInit,
@@ -71,12 +85,154 @@ func TestReconcile_Stage_Unknown_WithFinalizer(t *testing.T) {
 	testReconcileResultsTestSet(ts, expect)
 }
 
+func TestReconcile_Delete_Retain_NoServer(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":    1,
+			"MockClientWriter.Update": 1,
+		},
+		expectServerCallMap:   map[string]int{},
+		expectRecorderCallMap: map[string]int{},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)":    1,
+			"MockClientWriter.Update(*v1.DatabaseAccount)": 1,
+		},
+		expectNormalMessage:  []v1test.MockRecorderMessage{},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDeleting,
+			controllertest.ReconcileWantOnDelete(v1.OnDeleteRetain),
+		},
+		nil,
+	)
+	// the database is retained so the account is removed without a database server.
+	ts.rec.AccountServer = nil
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantNoDBFinalizer,
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Delete_ServerNotFound(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":    2,
+			"MockClientWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{},
+		expectRecorderCallMap: map[string]int{
+			"WarningEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)":    1,
+			"MockClientReader.Get(*v1.DatabaseServer)":     1,
+			"MockClientWriter.Update(*v1.DatabaseAccount)": 1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{},
+		expectWarningMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonServer,
+				"Unable to retrieve database server, database and role not deleted: "+
+					"databaseaccounts \"testaccount\" not found"),
+		},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDeleting,
+			controllertest.ReconcileWantOnDelete(v1.OnDeleteDelete),
+			controllertest.ReconcileWantServerRef("missing"),
+		},
+		nil,
+	)
+	ts.rec.Servers = accountsvr.NewPool(nil)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantNoDBFinalizer,
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_UserCreate_ServerRef(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     4,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		// the default server is not used.
+		expectServerCallMap: map[string]int{},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 3,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.DatabaseServer)":  1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.UserCreateStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantServerRef("primary"),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+		},
+	)
+	ts.ctr.InitDatabaseServer("primary", accountsvrtest.TestDSN)
+
+	var (
+		routed    = accountsvrtest.NewMockServer(accountsvrtest.TestDSN)
+		routedDSN v1.PostgreSQLDSN
+	)
+	ts.rec.Servers = accountsvr.NewPool(func(_ context.Context, dsn v1.PostgreSQLDSN) (accountsvr.Server, error) {
+		routedDSN = dsn
+
+		return routed, nil
+	})
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.DatabaseCreateStage),
+		controllertest.ReconcileWantCondition(v1.ConditionUserReady, metav1.ConditionTrue, v1.ConditionReasonCreated),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretNamePassword,
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+
+	if routedDSN != accountsvrtest.TestDSN {
+		testhelp.Errorf(t, ts.start, "rec.Reconcile(): server DSN, got '%s', want '%s'",
+			routedDSN, accountsvrtest.TestDSN)
+	}
+
+	if diff := cmp.Diff(routed.CallCountMap(), map[string]int{"CreateRole": 1}); diff != "" {
+		testhelp.Errorf(t, ts.start, "rec.Reconcile(): serverRef server called functions -got +want:\n%s", diff)
+	}
+}
+
 func TestReconcile_Stage_Init(t *testing.T) {
 	t.Parallel()
 	expect := expectSet{
 		expectResult: reconcile.Result{},
 		expectClientCallMap: map[string]int{
-			"MockClientReader.Get":                     2,
+			"MockClientReader.Get":                     3,
 			"MockClientWriter.Create":                  1,
 			"MockClientWriter.Update":                  1,
 			"MockStatusClient.Status":                  1,
@@ -92,7 +248,7 @@ func TestReconcile_Stage_Init(t *testing.T) {
 		},
 		expectTestCallMap: map[string]int{
 			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
-			"MockClientReader.Get(*v1.Secret)":          1,
+			"MockClientReader.Get(*v1.Secret)":          2,
 			"MockClientWriter.Create(*v1.Secret)":       1,
 			"MockClientWriter.Update(*v1.Secret)":       1,
 		},
@@ -161,6 +317,7 @@ func TestReconcile_Stage_UserCreate(t *testing.T) {
 	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
 		controllertest.ReconcileWantStage(v1.DatabaseCreateStage),
 		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantCondition(v1.ConditionUserReady, metav1.ConditionTrue, v1.ConditionReasonCreated),
 	}
 	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
 		controllertest.ReconcileWantSecretNamePassword,
@@ -169,143 +326,1192 @@ func TestReconcile_Stage_UserCreate(t *testing.T) {
 	testReconcileResultsTestSet(ts, expect)
 }
 
-func TestReconcile_Stage_DatabaseCreate(t *testing.T) {
+func TestReconcile_Stage_Init_Username(t *testing.T) {
 	t.Parallel()
 	expect := expectSet{
 		expectResult: reconcile.Result{},
 		expectClientCallMap: map[string]int{
-			"MockClientReader.Get":                     2,
+			"MockClientReader.Get":                     3,
+			"MockClientWriter.Create":                  1,
 			"MockClientWriter.Update":                  1,
 			"MockStatusClient.Status":                  1,
 			"MockStatusClient.TestStatusWriter.Update": 1,
 		},
 		expectServerCallMap: map[string]int{
-			"CreateDatabase": 1,
-			"IsDatabase":     1,
+			"CopyInitConfigToSecret": 2,
+			"GetDatabaseHost":        2,
+			"GetDatabaseHostConfig":  2,
 		},
 		expectRecorderCallMap: map[string]int{
-			"NormalEvent": 2,
+			"NormalEvent": 1,
 		},
 		expectTestCallMap: map[string]int{
 			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
-			"MockClientReader.Get(*v1.Secret)":          1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Create(*v1.Secret)":       1,
 			"MockClientWriter.Update(*v1.Secret)":       1,
 		},
 		expectNormalMessage: []v1test.MockRecorderMessage{
-			v1test.NewMockRecorderMessage(controller.ReasonDatabaseCreate, ""),
-			v1test.NewMockRecorderMessage(controller.ReasonReady, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonQueued, ""),
 		},
 		expectWarningMessage: []v1test.MockRecorderMessage{},
 	}
 	ts := newTestSet(
-		t, v1.DatabaseCreateStage,
+		t, v1.InitStage,
 		[]controllertest.ReconcileModDBFunc{
-			controllertest.ReconcileWantDBFinalizer,
 			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantSpecUsername("legacy_app"),
 		},
-		[]controllertest.ReconcileModSecretFunc{
-			controllertest.ReconcileWantSecretInit,
-			controllertest.ReconcileWantSecretNamePassword,
-		},
+		nil,
 	)
 	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
-		controllertest.ReconcileWantStage(v1.ReadyStage),
-		controllertest.ReconcileWantReady(true),
+		controllertest.ReconcileWantStage(v1.UserCreateStage),
 		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantUsername("legacy_app"),
 	}
 	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
-		controllertest.ReconcileWantSecretDatabaseDSN,
-		controllertest.ReconcileWantSecretNamePassword,
+		controllertest.ReconcileWantSecretInit,
+		controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyUsername, "legacy_app"),
 	}
 
-	ts.svr.OnIsDatabase = func(_ context.Context, dbName string) (string, bool, error) {
-		return dbName, false, nil
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Init_InvalidUsername(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{},
+		expectRecorderCallMap: map[string]int{
+			"WarningEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{},
+		expectWarningMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonQueued, ""),
+		},
+	}
+	ts := newTestSet(
+		t, v1.InitStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantSpecUsername("postgres"),
+		},
+		nil,
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ErrorStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantSpecUsername("postgres"),
+		controllertest.ReconcileWantDegraded(v1.ConditionReasonInvalidUsername,
+			"Invalid username[postgres]: invalid name"),
 	}
 
 	testReconcileResultsTestSet(ts, expect)
 }
 
-func TestReconcile_Stage_DatabaseCreate_DatabaseExists(t *testing.T) {
+func TestReconcile_Stage_Init_InvalidRelaySettings(t *testing.T) {
 	t.Parallel()
 	expect := expectSet{
 		expectResult: reconcile.Result{},
 		expectClientCallMap: map[string]int{
-			"MockClientReader.Get":                     2,
-			"MockClientWriter.Update":                  1,
+			"MockClientReader.Get":                     1,
 			"MockStatusClient.Status":                  1,
 			"MockStatusClient.TestStatusWriter.Update": 1,
 		},
-		expectServerCallMap: map[string]int{
-			"IsDatabase": 1,
+		expectServerCallMap: map[string]int{},
+		expectRecorderCallMap: map[string]int{
+			"WarningEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{},
+		expectWarningMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonQueued, ""),
+		},
+	}
+	relay := &v1.DatabaseAccountRelay{RelaySettings: v1.RelaySettings{DefaultPoolSize: ptr.To[int32](50)}}
+	ts := newTestSet(
+		t, v1.InitStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantRelay(relay),
+		},
+		nil,
+	)
+	ts.rec.Config.RelayDefaults = v1.RelaySettings{MaxClientConn: ptr.To[int32](20)}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ErrorStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantRelay(relay),
+		controllertest.ReconcileWantDegraded(v1.ConditionReasonInvalidRelaySettings,
+			"Invalid relay settings: invalid relay settings: defaultPoolSize can not be more than maxClientConn"),
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Init_SecretImmutable(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
 		},
+		expectServerCallMap: map[string]int{},
 		expectRecorderCallMap: map[string]int{
-			"NormalEvent":  1,
 			"WarningEvent": 1,
 		},
 		expectTestCallMap: map[string]int{
 			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
 			"MockClientReader.Get(*v1.Secret)":          1,
-			"MockClientWriter.Update(*v1.Secret)":       1,
-		},
-		expectNormalMessage: []v1test.MockRecorderMessage{
-			v1test.NewMockRecorderMessage(controller.ReasonReady, ""),
 		},
+		expectNormalMessage: []v1test.MockRecorderMessage{},
 		expectWarningMessage: []v1test.MockRecorderMessage{
-			v1test.NewMockRecorderMessage(controller.ReasonDatabaseCreate, "Database already exists"),
+			v1test.NewMockRecorderMessage(controller.ReasonQueued, "Secret already exists and is immutable"),
 		},
 	}
 	ts := newTestSet(
-		t, v1.DatabaseCreateStage,
+		t, v1.InitStage,
 		[]controllertest.ReconcileModDBFunc{
-			controllertest.ReconcileWantDBFinalizer,
 			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantDBFinalizer,
 		},
-		[]controllertest.ReconcileModSecretFunc{
-			controllertest.ReconcileWantSecretInit,
-			controllertest.ReconcileWantSecretNamePassword,
-		},
+		[]controllertest.ReconcileModSecretFunc{},
 	)
 	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
-		controllertest.ReconcileWantStage(v1.ReadyStage),
-		controllertest.ReconcileWantReady(true),
+		controllertest.ReconcileWantStage(v1.ErrorStage),
 		controllertest.ReconcileWantDBFinalizer,
-	}
-	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
-		controllertest.ReconcileWantSecretDatabaseDSN,
-		controllertest.ReconcileWantSecretNamePassword,
+		controllertest.ReconcileWantDegraded(v1.ConditionReasonSecretImmutable,
+			"Secret already exists and is immutable"),
 	}
 
 	testReconcileResultsTestSet(ts, expect)
 }
 
-// func TestReconcile_Stage_RelayCreate(t *testing.T) {
-// 	t.Parallel()
-// 	expect := expectSet{
-// 		expectResult: reconcile.Result{},
-// 		expectClientCallMap: map[string]int{
-// 			"MockClientReader.Get":                     3,
-// 			"MockClientWriter.Create":                  2,
-// 			"MockStatusClient.Status":                  1,
-// 			"MockStatusClient.TestStatusWriter.Update": 1,
-// 		},
-// 		expectServerCallMap: map[string]int{},
-// 		expectRecorderCallMap: map[string]int{
-// 			"NormalEvent": 3,
-// 		},
-// 		expectTestCallMap: map[string]int{
-// 			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
-// 			"MockClientReader.Get(*v1.Service)":         1,
-// 			"MockClientReader.Get(*v1.StatefulSet)":     1,
-// 			"MockClientWriter.Create(*v1.Service)":      1,
-// 			"MockClientWriter.Create(*v1.StatefulSet)":  1,
-// 		},
-// 		expectNormalMessage: []v1test.MockRecorderMessage{
-// 			v1test.NewMockRecorderMessage(controller.ReasonRelayCreate, "Created StatefulSet"),
-// 			v1test.NewMockRecorderMessage(controller.ReasonRelayCreate, "Created Service"),
-// 			v1test.NewMockRecorderMessage(controller.ReasonReady, "Ready to use"),
-// 		},
-// 		expectWarningMessage: []v1test.MockRecorderMessage{},
-// 	}
+func TestReconcile_Stage_Init_SecretPolicyReplace(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockClientWriter.Create":                  2,
+			"MockClientWriter.Delete":                  2,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"CopyInitConfigToSecret": 1,
+			"GetDatabaseHost":        1,
+			"GetDatabaseHostConfig":  1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 2,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          1,
+			"MockClientWriter.Create(*v1.Secret)":       2,
+			"MockClientWriter.Delete(*v1.Secret)":       2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonQueued,
+				"Secret was immutable, recreated it for secret policy Replace"),
+			v1test.NewMockRecorderMessage(controller.ReasonQueued, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.InitStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantSecretPolicy(v1.SecretPolicyReplace),
+		},
+		[]controllertest.ReconcileModSecretFunc{},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.UserCreateStage),
+		controllertest.ReconcileWantDBFinalizer,
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretInit,
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+		controllertest.ReconcileWantSecretFinalizer,
+		controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyUsername,
+			controllertest.NewDatabaseAccountName().String()),
+		controllertest.ReconcileWantSecretReplaced,
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Init_SecretPolicyAdopt(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockClientWriter.Create":                  2,
+			"MockClientWriter.Delete":                  2,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"IsManagedRole":          1,
+			"VerifyRolePassword":     1,
+			"CopyInitConfigToSecret": 1,
+			"GetDatabaseHost":        1,
+			"GetDatabaseHostConfig":  1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 2,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          1,
+			"MockClientWriter.Create(*v1.Secret)":       2,
+			"MockClientWriter.Delete(*v1.Secret)":       2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonQueued,
+				"Secret was immutable, recreated it for secret policy Adopt"),
+			v1test.NewMockRecorderMessage(controller.ReasonQueued, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.InitStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantSecretPolicy(v1.SecretPolicyAdopt),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyUsername, "imported_app"),
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyPassword, "importedpassword"),
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.UserCreateStage),
+		controllertest.ReconcileWantDBFinalizer,
+		func(want *v1.DatabaseAccount) {
+			want.Status.Username = "imported_app"
+		},
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretInit,
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+		controllertest.ReconcileWantSecretFinalizer,
+		controllertest.ReconcileWantSecretReplaced,
+	}
+
+	ts.svr.OnVerifyRolePassword = func(_ context.Context, roleName, password string) (bool, error) {
+		return roleName == "imported_app" && password == "importedpassword", nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Init_SecretPolicyAdopt_LoginFailed(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"VerifyRolePassword": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"WarningEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{},
+		expectWarningMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonQueued, ""),
+		},
+	}
+	ts := newTestSet(
+		t, v1.InitStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantSecretPolicy(v1.SecretPolicyAdopt),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyUsername, "imported_app"),
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyPassword, "wrongpassword"),
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ErrorStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantDegraded(v1.ConditionReasonSecretAdoptFailed,
+			"secret credentials can not be adopted: login failed for username[imported_app]"),
+	}
+
+	ts.svr.OnVerifyRolePassword = func(_ context.Context, _, _ string) (bool, error) {
+		return false, nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Init_SecretPolicyAdopt_MutableSecret(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     3,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"IsManagedRole":          1,
+			"VerifyRolePassword":     1,
+			"CopyInitConfigToSecret": 1,
+			"GetDatabaseHost":        1,
+			"GetDatabaseHostConfig":  1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonQueued, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.InitStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantSecretPolicy(v1.SecretPolicyAdopt),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretImmutable(false),
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyUsername, "imported_app"),
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyPassword, "importedpassword"),
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.UserCreateStage),
+		controllertest.ReconcileWantDBFinalizer,
+		func(want *v1.DatabaseAccount) {
+			want.Status.Username = "imported_app"
+		},
+	}
+	// the secret is updated in place, only an immutable secret is recreated.
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretInit,
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+		controllertest.ReconcileWantSecretFinalizer,
+	}
+
+	ts.svr.OnVerifyRolePassword = func(_ context.Context, roleName, password string) (bool, error) {
+		return roleName == "imported_app" && password == "importedpassword", nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Init_SecretPolicyAdopt_RoleNotManaged(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"IsManagedRole":      1,
+			"VerifyRolePassword": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"WarningEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{},
+		expectWarningMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonQueued, ""),
+		},
+	}
+	ts := newTestSet(
+		t, v1.InitStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantSecretPolicy(v1.SecretPolicyAdopt),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyUsername, "imported_app"),
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyPassword, "importedpassword"),
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ErrorStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantDegraded(v1.ConditionReasonSecretAdoptFailed,
+			"secret credentials can not be adopted: role[imported_app] is not managed by the operator, "+
+				"onDelete must be retain"),
+	}
+
+	ts.svr.OnVerifyRolePassword = func(_ context.Context, _, _ string) (bool, error) {
+		return true, nil
+	}
+	ts.svr.OnIsManagedRole = func(_ context.Context, _ string) (bool, error) {
+		return false, nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_UserCreate_RoleExists(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"CreateRole":         1,
+			"IsManagedRole":      1,
+			"UpdateRolePassword": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent":  3,
+			"WarningEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          1,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonDatabaseCreate, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, ""),
+		},
+	}
+	ts := newTestSet(
+		t, v1.UserCreateStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantUsername("legacy_app"),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.DatabaseCreateStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantUsername("legacy_app"),
+		controllertest.ReconcileWantCondition(v1.ConditionUserReady, metav1.ConditionTrue, v1.ConditionReasonExists),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyUsername, "legacy_app"),
+		controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyPassword, "newpassword"),
+	}
+
+	ts.svr.OnCreateRole = func(_ context.Context, _ string) (accountsvr.Credentials, error) {
+		return accountsvr.Credentials{}, accountsvr.ErrRoleExists
+	}
+	ts.svr.OnUpdateRolePassword = func(_ context.Context, roleName string) (accountsvr.Credentials, error) {
+		return accountsvr.Credentials{Username: roleName, Password: "newpassword"}, nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_UserCreate_RoleNotManaged(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"CreateRole":    1,
+			"IsManagedRole": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent":  1,
+			"WarningEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, ""),
+		},
+	}
+	ts := newTestSet(
+		t, v1.UserCreateStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantUsername("legacy_app"),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ErrorStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantUsername("legacy_app"),
+		controllertest.ReconcileWantCondition(v1.ConditionUserReady, metav1.ConditionFalse,
+			v1.ConditionReasonRoleNotManaged),
+		controllertest.ReconcileWantDegraded(v1.ConditionReasonRoleNotManaged,
+			"role exists and is not managed by the operator: legacy_app"),
+	}
+
+	ts.svr.OnCreateRole = func(_ context.Context, _ string) (accountsvr.Credentials, error) {
+		return accountsvr.Credentials{}, accountsvr.ErrRoleExists
+	}
+	ts.svr.OnIsManagedRole = func(_ context.Context, _ string) (bool, error) {
+		return false, nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_UserCreate_SecretPolicyAdopt(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"CreateRole":         1,
+			"IsManagedRole":      1,
+			"VerifyRolePassword": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 4,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, "User exists, adopted credentials from secret"),
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonDatabaseCreate, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.UserCreateStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantSecretPolicy(v1.SecretPolicyAdopt),
+			func(want *v1.DatabaseAccount) {
+				want.Status.Username = "imported_app"
+			},
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyUsername, "imported_app"),
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyPassword, "importedpassword"),
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.DatabaseCreateStage),
+		controllertest.ReconcileWantCondition(v1.ConditionUserReady, metav1.ConditionTrue, v1.ConditionReasonExists),
+	}
+
+	ts.svr.OnCreateRole = func(_ context.Context, _ string) (accountsvr.Credentials, error) {
+		return accountsvr.Credentials{}, accountsvr.ErrRoleExists
+	}
+	ts.svr.OnVerifyRolePassword = func(_ context.Context, roleName, password string) (bool, error) {
+		return roleName == "imported_app" && password == "importedpassword", nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_UserCreate_SecretPolicyAdopt_Retain(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"CreateRole":         1,
+			"VerifyRolePassword": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 4,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, "User exists, adopted credentials from secret"),
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonDatabaseCreate, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.UserCreateStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantSecretPolicy(v1.SecretPolicyAdopt),
+			controllertest.ReconcileWantOnDelete(v1.OnDeleteRetain),
+			func(want *v1.DatabaseAccount) {
+				want.Status.Username = "imported_app"
+			},
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyUsername, "imported_app"),
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyPassword, "importedpassword"),
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.DatabaseCreateStage),
+		controllertest.ReconcileWantCondition(v1.ConditionUserReady, metav1.ConditionTrue, v1.ConditionReasonExists),
+	}
+
+	ts.svr.OnCreateRole = func(_ context.Context, _ string) (accountsvr.Credentials, error) {
+		return accountsvr.Credentials{}, accountsvr.ErrRoleExists
+	}
+	ts.svr.OnVerifyRolePassword = func(_ context.Context, roleName, password string) (bool, error) {
+		return roleName == "imported_app" && password == "importedpassword", nil
+	}
+	// the role is retained when the account is deleted so it does not need to be managed.
+	ts.svr.OnIsManagedRole = func(_ context.Context, _ string) (bool, error) {
+		return false, nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_UserCreate_Role(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"CreateRole":  1,
+			"AlterRole":   1,
+			"GrantRoles":  1,
+			"RevokeRoles": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 3,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          1,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonDatabaseCreate, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	role := &v1.DatabaseAccountRole{
+		ConnectionLimit: ptr.To[int32](5),
+		MemberOf:        []string{"pg_monitor"},
+	}
+	ts := newTestSet(
+		t, v1.UserCreateStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantRole(role),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.DatabaseCreateStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantRole(role),
+		controllertest.ReconcileWantRoleApplied(role),
+		controllertest.ReconcileWantCondition(v1.ConditionUserReady, metav1.ConditionTrue, v1.ConditionReasonCreated),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretNamePassword,
+	}
+
+	granted := []string{}
+	ts.svr.OnGrantRoles = func(_ context.Context, _ string, memberOf []string) error {
+		granted = append(granted, memberOf...)
+
+		return nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+
+	if diff := cmp.Diff(granted, role.MemberOf); diff != "" {
+		testhelp.Errorf(t, ts.start, "GrantRoles(): roles -got +want:\n%s", diff)
+	}
+}
+
+func TestReconcile_Stage_UserCreate_BypassRLSNotAllowed(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent":  1,
+			"WarningEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonUserCreate, ""),
+		},
+	}
+	role := &v1.DatabaseAccountRole{BypassRLS: true}
+	ts := newTestSet(
+		t, v1.UserCreateStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantRole(role),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ErrorStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantRole(role),
+		controllertest.ReconcileWantCondition(v1.ConditionUserReady, metav1.ConditionFalse,
+			v1.ConditionReasonBypassRLSNotAllowed),
+		controllertest.ReconcileWantDegraded(v1.ConditionReasonBypassRLSNotAllowed,
+			controller.ErrBypassRLSNotAllowed.Error()),
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_DatabaseCreate(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+			"MockClientWriter.Create":                  1,
+		},
+		expectServerCallMap: map[string]int{
+			"CreateDatabase": 1,
+			"IsDatabase":     1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 2,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          1,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+			"MockClientWriter.Create(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonDatabaseCreate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonReady, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.DatabaseCreateStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantReady(true),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+		ts.wantSecretVersion,
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretDatabaseDSN,
+		controllertest.ReconcileWantSecretNamePassword,
+	}
+
+	ts.svr.OnIsDatabase = func(_ context.Context, dbName string) (string, bool, error) {
+		return dbName, false, nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_DatabaseCreate_Settings(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+			"MockClientWriter.Create":                  1,
+		},
+		expectServerCallMap: map[string]int{
+			"CreateDatabase":      1,
+			"IsDatabase":          1,
+			"SetRoleSettings":     1,
+			"SetDatabaseSettings": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 2,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          1,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+			"MockClientWriter.Create(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonDatabaseCreate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonReady, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	roleSettings := map[string]string{"statement_timeout": "30s"}
+	databaseSettings := map[string]string{"work_mem": "64MB"}
+	ts := newTestSet(
+		t, v1.DatabaseCreateStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantSettings(roleSettings, databaseSettings),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.rec.Config.AllowedSettings = []string{"statement_timeout", "work_mem"}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantReady(true),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+		controllertest.ReconcileWantSettings(roleSettings, databaseSettings),
+		controllertest.ReconcileWantSettingsApplied(roleSettings, databaseSettings),
+		ts.wantSecretVersion,
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretDatabaseDSN,
+		controllertest.ReconcileWantSecretNamePassword,
+	}
+
+	ts.svr.OnIsDatabase = func(_ context.Context, dbName string) (string, bool, error) {
+		return dbName, false, nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_DatabaseCreate_Schemas(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+			"MockClientWriter.Create":                  1,
+		},
+		expectServerCallMap: map[string]int{
+			"CreateDatabase":  1,
+			"CreateSchema":    2,
+			"IsDatabase":      1,
+			"SetRoleSettings": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 2,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          1,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+			"MockClientWriter.Create(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonDatabaseCreate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonReady, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	schemas := []string{"app_data", "public"}
+	ts := newTestSet(
+		t, v1.DatabaseCreateStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantSchemas(schemas, ""),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantReady(true),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+		controllertest.ReconcileWantSchemas(schemas, ""),
+		controllertest.ReconcileWantSchemasCreated(schemas),
+		controllertest.ReconcileWantSettingsApplied(map[string]string{"search_path": "app_data, public"}, nil),
+		ts.wantSecretVersion,
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretDatabaseDSN,
+		controllertest.ReconcileWantSecretNamePassword,
+		controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeySchema, "app_data"),
+	}
+
+	dbName := controllertest.NewDatabaseAccountName().String()
+	ts.svr.OnIsDatabase = func(_ context.Context, dbName string) (string, bool, error) {
+		return dbName, false, nil
+	}
+	ts.svr.OnCreateSchema = func(_ context.Context, db, schema, role string) error {
+		if db != dbName || role != dbName || !slices.Contains(schemas, schema) {
+			testhelp.Errorf(t, ts.start, "svr.CreateSchema(): got '%s' '%s' '%s'", db, schema, role)
+		}
+
+		return nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_DatabaseCreate_DatabaseExists(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     2,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+			"MockClientWriter.Create":                  1,
+		},
+		expectServerCallMap: map[string]int{
+			"IsDatabase": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent":  1,
+			"WarningEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          1,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+			"MockClientWriter.Create(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonReady, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonDatabaseCreate, "Database already exists"),
+		},
+	}
+	ts := newTestSet(
+		t, v1.DatabaseCreateStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantReady(true),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+		controllertest.ReconcileWantCondition(v1.ConditionDatabaseReady, metav1.ConditionTrue, v1.ConditionReasonExists),
+		ts.wantSecretVersion,
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretDatabaseDSN,
+		controllertest.ReconcileWantSecretNamePassword,
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+// func TestReconcile_Stage_RelayCreate(t *testing.T) {
+// 	t.Parallel()
+// 	expect := expectSet{
+// 		expectResult: reconcile.Result{},
+// 		expectClientCallMap: map[string]int{
+// 			"MockClientReader.Get":                     3,
+// 			"MockClientWriter.Create":                  2,
+// 			"MockStatusClient.Status":                  1,
+// 			"MockStatusClient.TestStatusWriter.Update": 1,
+// 		},
+// 		expectServerCallMap: map[string]int{},
+// 		expectRecorderCallMap: map[string]int{
+// 			"NormalEvent": 3,
+// 		},
+// 		expectTestCallMap: map[string]int{
+// 			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+// 			"MockClientReader.Get(*v1.Service)":         1,
+// 			"MockClientReader.Get(*v1.StatefulSet)":     1,
+// 			"MockClientWriter.Create(*v1.Service)":      1,
+// 			"MockClientWriter.Create(*v1.StatefulSet)":  1,
+// 		},
+// 		expectNormalMessage: []v1test.MockRecorderMessage{
+// 			v1test.NewMockRecorderMessage(controller.ReasonRelayCreate, "Created StatefulSet"),
+// 			v1test.NewMockRecorderMessage(controller.ReasonRelayCreate, "Created Service"),
+// 			v1test.NewMockRecorderMessage(controller.ReasonReady, "Ready to use"),
+// 		},
+// 		expectWarningMessage: []v1test.MockRecorderMessage{},
+// 	}
+// 	ts := newTestSet(
+// 		t, v1.RelayCreateStage,
+// 		[]controllertest.ReconcileModDBFunc{
+// 			controllertest.ReconcileWantDBFinalizer,
+// 			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName())},
+// 		[]controllertest.ReconcileModSecretFunc{
+// 			controllertest.ReconcileWantSecretInit,
+// 			controllertest.ReconcileWantSecretNamePassword,
+// 		},
+// 	)
+// 	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+// 		controllertest.ReconcileWantStage(v1.ReadyStage),
+// 		controllertest.ReconcileWantReady(true),
+// 		controllertest.ReconcileWantDBFinalizer,
+// 	}
+// 	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+// 		controllertest.ReconcileWantSecretNamePassword,
+// 	}
+
+// 	ts.svr.OnIsDatabase = func(ctx context.Context, dbName string) (string, bool, error) {
+// 		return dbName, false, nil
+// 	}
+
+// 	testReconcileResultsTestSet(ts, expect)
+// }
+
+// func TestReconcile_Stage_RelayCreate_TwoRounds(t *testing.T) {
+// 	t.Parallel()
+// 	expect := expectSet{
+// 		expectResult: reconcile.Result{},
+// 		expectClientCallMap: map[string]int{
+// 			"MockClientReader.Get":                     6,
+// 			"MockClientWriter.Create":                  2,
+// 			"MockStatusClient.Status":                  1,
+// 			"MockStatusClient.Update":                  1,
+// 			"MockStatusClient.TestStatusWriter.Update": 1,
+// 		},
+// 		expectServerCallMap: map[string]int{},
+// 		expectRecorderCallMap: map[string]int{
+// 			"NormalEvent": 3,
+// 		},
+// 		expectTestCallMap: map[string]int{
+// 			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+// 			"MockClientReader.Get(*v1.Service)":         1,
+// 			"MockClientReader.Get(*v1.StatefulSet)":     1,
+// 			"MockClientWriter.Create(*v1.Service)":      1,
+// 			"MockClientWriter.Create(*v1.StatefulSet)":  1,
+// 		},
+// 		expectNormalMessage: []v1test.MockRecorderMessage{
+// 			v1test.NewMockRecorderMessage(controller.ReasonRelayCreate, "Created StatefulSet"),
+// 			v1test.NewMockRecorderMessage(controller.ReasonRelayCreate, "Created Service"),
+// 			v1test.NewMockRecorderMessage(controller.ReasonReady, "Ready to use"),
+// 		},
+// 		expectWarningMessage: []v1test.MockRecorderMessage{},
+// 	}
 // 	ts := newTestSet(
 // 		t, v1.RelayCreateStage,
 // 		[]controllertest.ReconcileModDBFunc{
@@ -324,72 +1530,1502 @@ func TestReconcile_Stage_DatabaseCreate_DatabaseExists(t *testing.T) {
 // 	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
 // 		controllertest.ReconcileWantSecretNamePassword,
 // 	}
+// 	ts.reconcileLoop = 2
+
+// 	ts.svr.OnIsDatabase = func(ctx context.Context, dbName string) (string, bool, error) {
+// 		return dbName, false, nil
+// 	}
+
+// 	testReconcileResultsTestSet(ts, expect)
+// }
+
+func TestReconcile_Stage_Ready(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     3,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap:   map[string]int{},
+		expectRecorderCallMap: map[string]int{},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage:  []v1test.MockRecorderMessage{},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
+
+	// ts.svr.OnIsDatabase = func(ctx context.Context, dbName string) (string, bool, error) {
+	// 	return dbName, false, nil
+	// }
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Ready_SecretRestored(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     4,
+			"MockClientWriter.Create":                  1,
+			"MockClientWriter.Delete":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          3,
+			"MockClientWriter.Create(*v1.Secret)":       1,
+			"MockClientWriter.Delete(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonQueued, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+			controllertest.ReconcileWantSecretOwnerRefs(v1test.NewDatabaseAccount()),
+		},
+	)
+	// the secret was deleted while it was replaced and the replacement was not created.
+	staged := ts.ctr.Secret.DeepCopy()
+	staged.Name += "-replacement"
+	staged.Finalizers = nil
+	staged.Type = "dosquad.github.io/database-account-replacement"
+	ts.ctr.SecretVersions[staged.Name] = staged
+	ts.ctr.Secret = nil
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantReadyConditions,
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretReplaced,
+		controllertest.ReconcileWantSecretFinalizer,
+		func(want *corev1.Secret) {
+			want.StringData = nil
+		},
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+
+	if _, ok := ts.ctr.SecretVersions[staged.Name]; ok {
+		testhelp.Errorf(t, ts.start, "rec.Reconcile(): replacement secret '%s' not deleted", staged.Name)
+	}
+}
+
+func TestReconcile_Stage_Ready_SecretVersionRetention(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	expect := expectSet{
+		expectResult: reconcile.Result{RequeueAfter: 30 * time.Minute},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     3,
+			"MockClientWriter.Delete":                  1,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  2,
+			"MockStatusClient.TestStatusWriter.Update": 2,
+		},
+		expectServerCallMap:   map[string]int{},
+		expectRecorderCallMap: map[string]int{},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Delete(*v1.Secret)":       1,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage:  []v1test.MockRecorderMessage{},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantSecretVersions("testaccount-cccccccccc"),
+			controllertest.ReconcileWantPreviousSecret("testaccount-aaaaaaaaaa", now.Add(-time.Minute)),
+			controllertest.ReconcileWantPreviousSecret("testaccount-bbbbbbbbbb", now.Add(30*time.Minute)),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.ctr.SecretVersions["testaccount-aaaaaaaaaa"] = &corev1.Secret{}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+		func(want *v1.DatabaseAccount) {
+			want.Status.PreviousSecrets = want.Status.PreviousSecrets[1:]
+		},
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+
+	if _, ok := ts.ctr.SecretVersions["testaccount-aaaaaaaaaa"]; ok {
+		testhelp.Errorf(t, ts.start, "rec.Reconcile(): secret version 'testaccount-aaaaaaaaaa' not deleted")
+	}
+}
+
+func TestReconcile_Stage_Ready_SecretVersionBackfill(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     3,
+			"MockClientWriter.Create":                  1,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  2,
+			"MockStatusClient.TestStatusWriter.Update": 2,
+		},
+		expectServerCallMap:   map[string]int{},
+		expectRecorderCallMap: map[string]int{},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Create(*v1.Secret)":       1,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage:  []v1test.MockRecorderMessage{},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	// the account was ready before the secret was versioned.
+	ts.ctr.DBAccount.Status.CurrentSecretName = ""
+	ts.ctr.OriginalDBAccount.Status.CurrentSecretName = ""
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantReadyConditions,
+		ts.wantSecretVersion,
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Ready_RoleSync(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     3,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"AlterRole":   1,
+			"GrantRoles":  1,
+			"RevokeRoles": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonRoleSync, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	applied := &v1.DatabaseAccountRole{MemberOf: []string{"pg_monitor", "pg_read_all_data"}}
+	role := &v1.DatabaseAccountRole{MemberOf: []string{"pg_read_all_data", "pg_write_all_data"}}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantRole(role),
+			controllertest.ReconcileWantRoleApplied(applied),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+		controllertest.ReconcileWantRole(role),
+		controllertest.ReconcileWantRoleApplied(role),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
+
+	granted, revoked := []string{}, []string{}
+	ts.svr.OnGrantRoles = func(_ context.Context, _ string, memberOf []string) error {
+		granted = append(granted, memberOf...)
+
+		return nil
+	}
+	ts.svr.OnRevokeRoles = func(_ context.Context, _ string, memberOf []string) error {
+		revoked = append(revoked, memberOf...)
+
+		return nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+
+	if diff := cmp.Diff(granted, []string{"pg_write_all_data"}); diff != "" {
+		testhelp.Errorf(t, ts.start, "GrantRoles(): roles -got +want:\n%s", diff)
+	}
+
+	if diff := cmp.Diff(revoked, []string{"pg_monitor"}); diff != "" {
+		testhelp.Errorf(t, ts.start, "RevokeRoles(): roles -got +want:\n%s", diff)
+	}
+}
+
+func TestReconcile_Stage_Ready_SettingsSync(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     3,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"SetRoleSettings": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonSettingsSync, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	applied := map[string]string{"statement_timeout": "30s", "lock_timeout": "5s"}
+	roleSettings := map[string]string{"statement_timeout": "60s", "work_mem": "64MB"}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantSettings(roleSettings, nil),
+			controllertest.ReconcileWantSettingsApplied(applied, nil),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.rec.Config.AllowedSettings = []string{"lock_timeout", "statement_timeout", "work_mem"}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+		controllertest.ReconcileWantSettings(roleSettings, nil),
+		controllertest.ReconcileWantSettingsApplied(roleSettings, nil),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
+
+	var gotSet map[string]string
+	var gotReset []string
+	ts.svr.OnSetRoleSettings = func(_ context.Context, _ string, settings map[string]string, reset []string) error {
+		gotSet, gotReset = settings, reset
+
+		return nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+
+	if diff := cmp.Diff(gotSet, roleSettings); diff != "" {
+		testhelp.Errorf(t, ts.start, "SetRoleSettings(): settings -got +want:\n%s", diff)
+	}
+
+	if diff := cmp.Diff(gotReset, []string{"lock_timeout"}); diff != "" {
+		testhelp.Errorf(t, ts.start, "SetRoleSettings(): reset -got +want:\n%s", diff)
+	}
+}
+
+func TestReconcile_Stage_Ready_SettingsSync_DualRole(t *testing.T) {
+	t.Parallel()
+	name := controllertest.NewDatabaseAccountName().String()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     3,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"IsRole":          2,
+			"SetRoleSettings": 3,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonSettingsSync, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	roleSettings := map[string]string{"statement_timeout": "60s"}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantSettings(roleSettings, nil),
+			controllertest.ReconcileWantLoginRoles(name+v1.DualRoleSuffixB, "", time.Time{}),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.rec.Config.AllowedSettings = []string{"statement_timeout"}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+		controllertest.ReconcileWantSettingsApplied(roleSettings, nil),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
+
+	// sessions log in as the login roles so the settings are applied to both of them.
+	roles := []string{}
+	ts.svr.OnSetRoleSettings = func(_ context.Context, roleName string, _ map[string]string, _ []string) error {
+		roles = append(roles, roleName)
+
+		return nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+
+	if diff := cmp.Diff(roles, []string{name, name + v1.DualRoleSuffixA, name + v1.DualRoleSuffixB}); diff != "" {
+		testhelp.Errorf(t, ts.start, "SetRoleSettings(): roles -got +want:\n%s", diff)
+	}
+}
+
+func TestReconcile_Stage_Ready_RoleSync_DualRole(t *testing.T) {
+	t.Parallel()
+	name := controllertest.NewDatabaseAccountName().String()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     3,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"IsRole":      2,
+			"AlterRole":   2,
+			"GrantRoles":  1,
+			"RevokeRoles": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonRoleSync, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	role := &v1.DatabaseAccountRole{ConnectionLimit: ptr.To[int32](5)}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantRole(role),
+			controllertest.ReconcileWantLoginRoles(name+v1.DualRoleSuffixA, name, time.Time{}),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+		controllertest.ReconcileWantRoleApplied(role),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
+
+	// only the first login role has been created.
+	ts.svr.OnIsRole = func(_ context.Context, roleName string) (bool, error) {
+		return roleName == name+v1.DualRoleSuffixA, nil
+	}
+	roles := []string{}
+	ts.svr.OnAlterRole = func(_ context.Context, roleName string, got *v1.DatabaseAccountRole) error {
+		roles = append(roles, roleName)
+		if got.GetConnectionLimit() != 5 {
+			t.Errorf("AlterRole(): connection limit, got '%d', want '5'", got.GetConnectionLimit())
+		}
+
+		return nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+
+	if diff := cmp.Diff(roles, []string{name, name + v1.DualRoleSuffixA}); diff != "" {
+		testhelp.Errorf(t, ts.start, "AlterRole(): roles -got +want:\n%s", diff)
+	}
+}
+
+func TestReconcile_Stage_Ready_SchemaSync(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     4,
+			"MockClientWriter.Create":                  3,
+			"MockClientWriter.Delete":                  2,
+			"MockClientWriter.Update":                  2,
+			"MockStatusClient.Status":                  2,
+			"MockStatusClient.TestStatusWriter.Update": 2,
+		},
+		expectServerCallMap: map[string]int{
+			"CreateSchema":    1,
+			"SetRoleSettings": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 2,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          3,
+			"MockClientWriter.Create(*v1.Secret)":       3,
+			"MockClientWriter.Delete(*v1.Secret)":       2,
+			"MockClientWriter.Update(*v1.Secret)":       2,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonSchemaSync, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonSettingsSync, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	schemas := []string{"app_data", "reporting"}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantSchemas(schemas, "reporting"),
+			controllertest.ReconcileWantSchemasCreated([]string{"app_data"}),
+			controllertest.ReconcileWantSettingsApplied(map[string]string{"search_path": "app_data"}, nil),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeySchema, "app_data"),
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+		controllertest.ReconcileWantSchemas(schemas, "reporting"),
+		controllertest.ReconcileWantSchemasCreated([]string{"reporting", "app_data"}),
+		controllertest.ReconcileWantSettingsApplied(map[string]string{"search_path": "reporting, app_data"}, nil),
+		ts.wantSecretVersion,
+		ts.wantPreviousSecretVersion(now.Add(24 * time.Hour)),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+		controllertest.ReconcileWantSecretReplaced,
+		controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeySchema, "reporting"),
+	}
+
+	ts.svr.OnCreateSchema = func(_ context.Context, _, schema, _ string) error {
+		if schema != "reporting" {
+			testhelp.Errorf(t, ts.start, "svr.CreateSchema(): schema, got '%s', want 'reporting'", schema)
+		}
+
+		return nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Ready_SchemaSync_DualRole(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	name := controllertest.NewDatabaseAccountName().String()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     4,
+			"MockClientWriter.Create":                  3,
+			"MockClientWriter.Delete":                  2,
+			"MockClientWriter.Update":                  2,
+			"MockStatusClient.Status":                  2,
+			"MockStatusClient.TestStatusWriter.Update": 2,
+		},
+		expectServerCallMap: map[string]int{
+			"CreateSchema":    1,
+			"IsRole":          2,
+			"SetRoleSettings": 3,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 2,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          3,
+			"MockClientWriter.Create(*v1.Secret)":       3,
+			"MockClientWriter.Delete(*v1.Secret)":       2,
+			"MockClientWriter.Update(*v1.Secret)":       2,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonSchemaSync, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonSettingsSync, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	schemas := []string{"app", "v1"}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantSchemas(schemas, "v1"),
+			controllertest.ReconcileWantSchemasCreated([]string{"app"}),
+			controllertest.ReconcileWantSettingsApplied(map[string]string{"search_path": "app"}, nil),
+			controllertest.ReconcileWantLoginRoles(name+v1.DualRoleSuffixB, "", time.Time{}),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeySchema, "app"),
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+		controllertest.ReconcileWantSchemas(schemas, "v1"),
+		controllertest.ReconcileWantSchemasCreated([]string{"v1", "app"}),
+		controllertest.ReconcileWantSettingsApplied(map[string]string{"search_path": "v1, app"}, nil),
+		ts.wantSecretVersion,
+		ts.wantPreviousSecretVersion(now.Add(24 * time.Hour)),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+		controllertest.ReconcileWantSecretReplaced,
+		controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeySchema, "v1"),
+	}
+
+	// sessions log in as the login roles so the search_path is set on both of them.
+	searchPaths := map[string]string{}
+	ts.svr.OnSetRoleSettings = func(_ context.Context, roleName string, settings map[string]string, _ []string) error {
+		searchPaths[roleName] = settings["search_path"]
+
+		return nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+
+	if diff := cmp.Diff(searchPaths, map[string]string{
+		name:                      "v1, app",
+		name + v1.DualRoleSuffixA: "v1, app",
+		name + v1.DualRoleSuffixB: "v1, app",
+	}); diff != "" {
+		testhelp.Errorf(t, ts.start, "SetRoleSettings(): search_path -got +want:\n%s", diff)
+	}
+}
+
+func TestReconcile_Stage_Ready_Extensions(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     3,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"SyncExtensions": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonExtensionSync, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	extensions := []v1.DatabaseAccountExtension{{Name: "pgcrypto"}, {Name: "pg_trgm", Version: "1.6"}}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantExtensions(extensions),
+			controllertest.ReconcileWantExtensionsInstalled(map[string]string{"pgcrypto": "1.3", "pg_trgm": "1.5"}),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.rec.Config.AllowedExtensions = []string{"pgcrypto", "pg_trgm"}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+		controllertest.ReconcileWantExtensions(extensions),
+		controllertest.ReconcileWantExtensionsInstalled(map[string]string{"pgcrypto": "1.3", "pg_trgm": "1.6"}),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
+
+	ts.svr.OnSyncExtensions = func(
+		_ context.Context,
+		_ string,
+		_ []v1.DatabaseAccountExtension,
+	) (map[string]string, error) {
+		return map[string]string{"pgcrypto": "1.3", "pg_trgm": "1.6"}, nil
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Ready_ExtensionNotAllowed(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     3,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{},
+		expectRecorderCallMap: map[string]int{
+			"WarningEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{},
+		expectWarningMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonExtensionSync, ""),
+		},
+	}
+	extensions := []v1.DatabaseAccountExtension{{Name: "postgis"}}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantExtensions(extensions),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.rec.Config.AllowedExtensions = []string{"pgcrypto"}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantExtensions(extensions),
+		controllertest.ReconcileWantDegraded(v1.ConditionReasonExtensionNotAllowed,
+			"extension is not allowed: postgis"),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Ready_SettingNotAllowed(t *testing.T) {
+	t.Parallel()
+	expect := expectSet{
+		expectResult: reconcile.Result{},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     3,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{},
+		expectRecorderCallMap: map[string]int{
+			"WarningEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{},
+		expectWarningMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonSettingsSync, ""),
+		},
+	}
+	roleSettings := map[string]string{"statement_timeout": "30s", "log_statement": "none"}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantSettings(roleSettings, nil),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.rec.Config.AllowedSettings = []string{"statement_timeout"}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantSettings(roleSettings, nil),
+		controllertest.ReconcileWantDegraded(v1.ConditionReasonSettingNotAllowed,
+			"setting is not allowed: log_statement"),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
 
-// 	ts.svr.OnIsDatabase = func(ctx context.Context, dbName string) (string, bool, error) {
-// 		return dbName, false, nil
-// 	}
+	testReconcileResultsTestSet(ts, expect)
+}
 
-// 	testReconcileResultsTestSet(ts, expect)
-// }
+func TestReconcile_Stage_Ready_RelayRemoved(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	expect := expectSet{
+		expectResult: reconcile.Result{RequeueAfter: 24 * time.Hour},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     5,
+			"MockClientWriter.Create":                  3,
+			"MockClientWriter.Delete":                  6,
+			"MockClientWriter.Update":                  2,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"CopyInitConfigToSecret": 1,
+			"GetDatabaseHost":        1,
+			"GetDatabaseHostConfig":  1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)":        1,
+			"MockClientReader.Get(*v1.Secret)":                 4,
+			"MockClientWriter.Create(*v1.Secret)":              3,
+			"MockClientWriter.Delete(*v1.Secret)":              2,
+			"MockClientWriter.Delete(*v1.StatefulSet)":         1,
+			"MockClientWriter.Delete(*v1.Service)":             1,
+			"MockClientWriter.Delete(*v1.PodDisruptionBudget)": 1,
+			"MockClientWriter.Delete(*v1.NetworkPolicy)":       1,
+			"MockClientWriter.Update(*v1.Secret)":              2,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonRelayUpdate, "Deleted relay"),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantRelayMode(v1.RelayModeDedicated),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyHost, "testaccount-relay"),
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantStage(v1.ReadyStage),
+		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantReadyConditions,
+		controllertest.ReconcileWantRelayMode(""),
+		ts.wantSecretVersion,
+		ts.wantPreviousSecretVersion(now.Add(24 * time.Hour)),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+		controllertest.ReconcileWantSecretReplaced,
+		controllertest.ReconcileWantSecretInit,
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Ready_RelayTLSSecretVersion(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	expect := expectSet{
+		expectResult: reconcile.Result{RequeueAfter: 10 * time.Second},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     12,
+			"MockClientWriter.Create":                  3,
+			"MockClientWriter.Delete":                  2,
+			"MockClientWriter.Update":                  2,
+			"MockStatusClient.Status":                  2,
+			"MockStatusClient.TestStatusWriter.Update": 2,
+		},
+		expectServerCallMap: map[string]int{
+			"GetDatabaseHostConfig": 3,
+			"GetDatabasePortConfig": 3,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 6,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)":        1,
+			"MockClientReader.Get(*v1.Secret)":                 8,
+			"MockClientReader.Get(*v1.Service)":                1,
+			"MockClientReader.Get(*v1.StatefulSet)":            2,
+			"MockClientWriter.Create(*v1.Secret)":              1,
+			"MockClientWriter.Create(*v1.Service)":             1,
+			"MockClientWriter.Create(*v1.StatefulSet)":         1,
+			"MockClientWriter.Delete(*v1.NetworkPolicy)":       1,
+			"MockClientWriter.Delete(*v1.PodDisruptionBudget)": 1,
+			"MockClientWriter.Update(*v1.Secret)":              2,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonRelayUpdate, "Relay configuration updated"),
+			v1test.NewMockRecorderMessage(controller.ReasonRelayUpdate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonRelayUpdate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonRelayUpdate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonRelayUpdate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonRelayUpdate, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantRelayMode(v1.RelayModeDedicated),
+			func(want *v1.DatabaseAccount) {
+				want.Spec.CreateRelay = true
+				want.Spec.Relay = &v1.DatabaseAccountRelay{
+					TLS: &v1.RelayTLS{Client: &v1.RelayClientTLS{SSLMode: v1.RelayTLSModeDisable}},
+				}
+			},
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+			controllertest.ReconcileWantSecretVerifier,
+			controllertest.ReconcileWantSecretImmutable(false),
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantCondition(v1.ConditionUserReady, metav1.ConditionTrue, v1.ConditionReasonCreated),
+		controllertest.ReconcileWantCondition(v1.ConditionDatabaseReady, metav1.ConditionTrue,
+			v1.ConditionReasonCreated),
+		controllertest.ReconcileWantCondition(v1.ConditionDegraded, metav1.ConditionFalse,
+			v1.ConditionReasonAsExpected),
+		controllertest.ReconcileWantCondition(v1.ConditionRelayReady, metav1.ConditionFalse,
+			v1.ConditionReasonRelayProgressing),
+		controllertest.ReconcileWantCondition(v1.ConditionReady, metav1.ConditionFalse,
+			v1.ConditionReasonRelayProgressing),
+		controllertest.ReconcileWantReady(false),
+		ts.wantSecretVersion,
+		ts.wantPreviousSecretVersion(now.Add(24 * time.Hour)),
+	}
+	dbAccount := ts.ctr.GetDatabaseAccount()
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(dbAccount),
+		controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeySSLMode, v1.RelayTLSModeDisable.String()),
+		func(want *corev1.Secret) {
+			// the relay admin password is generated, the expected configuration uses the stored one.
+			got := ts.ctr.GetSecret()
+			for _, key := range []string{
+				accountsvr.DatabaseKeyRelayAdminPassword, accountsvr.DatabaseKeyRelayAdminVerifier,
+			} {
+				controller.SetSecretKV(want, key, controller.GetSecretKV(&got, key))
+			}
+			if err := controller.AddRelayConf(t.Context(), ts.svr, ts.rec.Config, &dbAccount, want); err != nil {
+				testhelp.Errorf(t, ts.start, "controller.AddRelayConf(): error, got '%s', want 'nil'", err)
+			}
+		},
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+
+	// the version has the sslmode applications use to connect to the relay.
+	account := ts.ctr.GetDatabaseAccount()
+	if version, ok := ts.ctr.SecretVersions[account.Status.CurrentSecretName]; !ok {
+		testhelp.Errorf(t, ts.start, "rec.Reconcile(): secret version '%s' not created",
+			account.Status.CurrentSecretName)
+	} else if got := controller.GetSecretKV(version, accountsvr.DatabaseKeySSLMode); got != "disable" {
+		testhelp.Errorf(t, ts.start, "rec.Reconcile(): secret version sslmode, got '%s', want 'disable'", got)
+	}
+}
+
+func TestReconcile_Stage_Ready_RelayReissuePassword(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	verifier, _ := helper.ScramSHA256VerifierWithSalt("newpassword", []byte("mocksalt"), 4096)
+	expect := expectSet{
+		expectResult: reconcile.Result{RequeueAfter: 10 * time.Second},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     12,
+			"MockClientWriter.Create":                  3,
+			"MockClientWriter.Delete":                  2,
+			"MockClientWriter.Update":                  2,
+			"MockStatusClient.Status":                  2,
+			"MockStatusClient.TestStatusWriter.Update": 2,
+		},
+		expectServerCallMap: map[string]int{
+			"UpdateRolePassword":     1,
+			"CopyInitConfigToSecret": 1,
+			"GetDatabaseHost":        1,
+			"GetDatabaseHostConfig":  3,
+			"GetDatabasePortConfig":  3,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 6,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)":        1,
+			"MockClientReader.Get(*v1.Secret)":                 8,
+			"MockClientReader.Get(*v1.Service)":                1,
+			"MockClientReader.Get(*v1.StatefulSet)":            2,
+			"MockClientWriter.Create(*v1.Secret)":              1,
+			"MockClientWriter.Create(*v1.Service)":             1,
+			"MockClientWriter.Create(*v1.StatefulSet)":         1,
+			"MockClientWriter.Delete(*v1.NetworkPolicy)":       1,
+			"MockClientWriter.Delete(*v1.PodDisruptionBudget)": 1,
+			"MockClientWriter.Update(*v1.Secret)":              2,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonPasswordRotate, "Password reissued for relay"),
+			v1test.NewMockRecorderMessage(controller.ReasonRelayUpdate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonRelayUpdate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonRelayUpdate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonRelayUpdate, ""),
+			v1test.NewMockRecorderMessage(controller.ReasonRelayUpdate, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantRelayMode(v1.RelayModeDedicated),
+			func(want *v1.DatabaseAccount) {
+				want.Spec.CreateRelay = true
+				want.Spec.Relay = &v1.DatabaseAccountRelay{
+					TLS: &v1.RelayTLS{Client: &v1.RelayClientTLS{SSLMode: v1.RelayTLSModeDisable}},
+				}
+			},
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+			controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeySSLMode, v1.RelayTLSModeDisable.String()),
+			controllertest.ReconcileWantSecretImmutable(false),
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.svr.OnUpdateRolePassword = func(_ context.Context, roleName string) (accountsvr.Credentials, error) {
+		return accountsvr.Credentials{Username: roleName, Password: "newpassword", Verifier: verifier}, nil
+	}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantCondition(v1.ConditionUserReady, metav1.ConditionTrue, v1.ConditionReasonCreated),
+		controllertest.ReconcileWantCondition(v1.ConditionDatabaseReady, metav1.ConditionTrue,
+			v1.ConditionReasonCreated),
+		controllertest.ReconcileWantCondition(v1.ConditionDegraded, metav1.ConditionFalse,
+			v1.ConditionReasonAsExpected),
+		controllertest.ReconcileWantCondition(v1.ConditionRelayReady, metav1.ConditionFalse,
+			v1.ConditionReasonRelayProgressing),
+		controllertest.ReconcileWantCondition(v1.ConditionReady, metav1.ConditionFalse,
+			v1.ConditionReasonRelayProgressing),
+		controllertest.ReconcileWantReady(false),
+		ts.wantSecretVersion,
+		ts.wantPreviousSecretVersion(now.Add(24 * time.Hour)),
+	}
+	dbAccount := ts.ctr.GetDatabaseAccount()
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(dbAccount),
+		controllertest.ReconcileWantSecretPassword("newpassword"),
+		controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyVerifier, verifier),
+		// the credentials are written with the relay as the host.
+		controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyHost, dbAccount.GetRelayName().Name),
+		controllertest.ReconcileWantSecretDataValue(accountsvr.DatabaseKeyDSN, fmt.Sprintf(
+			"postgres://%[1]s:newpassword@%[2]s:5432/%[1]s",
+			controllertest.NewDatabaseAccountName(), dbAccount.GetRelayName().Name,
+		)),
+		func(want *corev1.Secret) {
+			// the relay admin password is generated, the expected configuration uses the stored one.
+			got := ts.ctr.GetSecret()
+			for _, key := range []string{
+				accountsvr.DatabaseKeyRelayAdminPassword, accountsvr.DatabaseKeyRelayAdminVerifier,
+			} {
+				controller.SetSecretKV(want, key, controller.GetSecretKV(&got, key))
+			}
+			if err := controller.AddRelayConf(t.Context(), ts.svr, ts.rec.Config, &dbAccount, want); err != nil {
+				testhelp.Errorf(t, ts.start, "controller.AddRelayConf(): error, got '%s', want 'nil'", err)
+			}
+		},
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Ready_PasswordRotationNotDue(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rotation := &v1.DatabaseAccountPasswordRotation{Interval: &metav1.Duration{Duration: time.Hour}}
+	expect := expectSet{
+		expectResult: reconcile.Result{RequeueAfter: 45 * time.Minute},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":    3,
+			"MockClientWriter.Update": 1,
+		},
+		expectServerCallMap:   map[string]int{},
+		expectRecorderCallMap: map[string]int{},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage:  []v1test.MockRecorderMessage{},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReadyConditions,
+			controllertest.ReconcileWantPasswordRotation(rotation),
+			controllertest.ReconcileWantLastRotated(now.Add(-15 * time.Minute)),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Ready_PasswordRotation(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rotation := &v1.DatabaseAccountPasswordRotation{Interval: &metav1.Duration{Duration: time.Hour}}
+	expect := expectSet{
+		expectResult: reconcile.Result{RequeueAfter: time.Hour},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     5,
+			"MockClientWriter.Update":                  2,
+			"MockStatusClient.Status":                  2,
+			"MockStatusClient.TestStatusWriter.Update": 2,
+			"MockClientWriter.Create":                  1,
+		},
+		expectServerCallMap: map[string]int{
+			"UpdateRolePassword":     1,
+			"CopyInitConfigToSecret": 1,
+			"GetDatabaseHost":        1,
+			"GetDatabaseHostConfig":  1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          4,
+			"MockClientWriter.Update(*v1.Secret)":       2,
+			"MockClientWriter.Create(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonPasswordRotate, "Password rotated"),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReadyConditions,
+			controllertest.ReconcileWantPasswordRotation(rotation),
+			controllertest.ReconcileWantLastRotated(now.Add(-2 * time.Hour)),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+			controllertest.ReconcileWantSecretImmutable(false),
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.svr.OnUpdateRolePassword = func(_ context.Context, roleName string) (accountsvr.Credentials, error) {
+		return accountsvr.Credentials{Username: roleName, Password: "rotatedpassword"}, nil
+	}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantLastRotated(now),
+		ts.wantSecretVersion,
+		ts.wantPreviousSecretVersion(now.Add(24 * time.Hour)),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+		controllertest.ReconcileWantSecretPassword("rotatedpassword"),
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Ready_PasswordRotation_SecretVersion(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rotation := &v1.DatabaseAccountPasswordRotation{Interval: &metav1.Duration{Duration: time.Hour}}
+	expect := expectSet{
+		expectResult: reconcile.Result{RequeueAfter: time.Hour},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     5,
+			"MockClientWriter.Update":                  2,
+			"MockStatusClient.Status":                  2,
+			"MockStatusClient.TestStatusWriter.Update": 2,
+			"MockClientWriter.Create":                  1,
+		},
+		expectServerCallMap: map[string]int{
+			"UpdateRolePassword":     1,
+			"CopyInitConfigToSecret": 1,
+			"GetDatabaseHost":        1,
+			"GetDatabaseHostConfig":  1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          4,
+			"MockClientWriter.Update(*v1.Secret)":       2,
+			"MockClientWriter.Create(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonPasswordRotate, "Password rotated"),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReadyConditions,
+			controllertest.ReconcileWantPasswordRotation(rotation),
+			controllertest.ReconcileWantLastRotated(now.Add(-2 * time.Hour)),
+			controllertest.ReconcileWantSecretVersions("testaccount-0123456789"),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+			controllertest.ReconcileWantSecretImmutable(false),
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.rec.Config.SecretVersionRetention = &metav1.Duration{Duration: 2 * time.Hour}
+	ts.svr.OnUpdateRolePassword = func(_ context.Context, roleName string) (accountsvr.Credentials, error) {
+		return accountsvr.Credentials{Username: roleName, Password: "rotatedpassword"}, nil
+	}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantLastRotated(now),
+		ts.wantSecretVersion,
+		controllertest.ReconcileWantPreviousSecret("testaccount-0123456789", now.Add(2*time.Hour)),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+		controllertest.ReconcileWantSecretPassword("rotatedpassword"),
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+
+	// the version has the credentials of the account secret and can not be changed.
+	account := ts.ctr.GetDatabaseAccount()
+	version, ok := ts.ctr.SecretVersions[account.Status.CurrentSecretName]
+	if !ok {
+		testhelp.Errorf(t, ts.start, "rec.Reconcile(): secret version '%s' not created",
+			account.Status.CurrentSecretName)
+	} else if !ptr.Deref(version.Immutable, false) ||
+		string(version.Data[accountsvr.DatabaseKeyPassword]) != "rotatedpassword" {
+		testhelp.Errorf(t, ts.start, "rec.Reconcile(): secret version, got '%v', want immutable with the new password",
+			version)
+	}
+}
+
+func TestReconcile_Stage_Ready_PasswordRotation_SecretImmutable(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rotation := &v1.DatabaseAccountPasswordRotation{Schedule: "0 0 * * *"}
+	expect := expectSet{
+		expectResult: reconcile.Result{RequeueAfter: 20*time.Hour + 55*time.Minute + 55*time.Second},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     4,
+			"MockClientWriter.Create":                  3,
+			"MockClientWriter.Delete":                  2,
+			"MockClientWriter.Update":                  2,
+			"MockStatusClient.Status":                  2,
+			"MockStatusClient.TestStatusWriter.Update": 2,
+		},
+		expectServerCallMap: map[string]int{
+			"UpdateRolePassword":     1,
+			"CopyInitConfigToSecret": 1,
+			"GetDatabaseHost":        1,
+			"GetDatabaseHostConfig":  1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          3,
+			"MockClientWriter.Create(*v1.Secret)":       3,
+			"MockClientWriter.Delete(*v1.Secret)":       2,
+			"MockClientWriter.Update(*v1.Secret)":       2,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonPasswordRotate, "Password rotated"),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReadyConditions,
+			controllertest.ReconcileWantPasswordRotation(rotation),
+			controllertest.ReconcileWantLastRotated(now.Add(-24 * time.Hour)),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+			controllertest.ReconcileWantSecretFinalizer,
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.svr.OnUpdateRolePassword = func(_ context.Context, roleName string) (accountsvr.Credentials, error) {
+		return accountsvr.Credentials{Username: roleName, Password: "rotatedpassword"}, nil
+	}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantLastRotated(now),
+		ts.wantSecretVersion,
+		ts.wantPreviousSecretVersion(now.Add(24 * time.Hour)),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+		controllertest.ReconcileWantSecretPassword("rotatedpassword"),
+		controllertest.ReconcileWantSecretReplaced,
+	}
 
-// func TestReconcile_Stage_RelayCreate_TwoRounds(t *testing.T) {
-// 	t.Parallel()
-// 	expect := expectSet{
-// 		expectResult: reconcile.Result{},
-// 		expectClientCallMap: map[string]int{
-// 			"MockClientReader.Get":                     6,
-// 			"MockClientWriter.Create":                  2,
-// 			"MockStatusClient.Status":                  1,
-// 			"MockStatusClient.Update":                  1,
-// 			"MockStatusClient.TestStatusWriter.Update": 1,
-// 		},
-// 		expectServerCallMap: map[string]int{},
-// 		expectRecorderCallMap: map[string]int{
-// 			"NormalEvent": 3,
-// 		},
-// 		expectTestCallMap: map[string]int{
-// 			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
-// 			"MockClientReader.Get(*v1.Service)":         1,
-// 			"MockClientReader.Get(*v1.StatefulSet)":     1,
-// 			"MockClientWriter.Create(*v1.Service)":      1,
-// 			"MockClientWriter.Create(*v1.StatefulSet)":  1,
-// 		},
-// 		expectNormalMessage: []v1test.MockRecorderMessage{
-// 			v1test.NewMockRecorderMessage(controller.ReasonRelayCreate, "Created StatefulSet"),
-// 			v1test.NewMockRecorderMessage(controller.ReasonRelayCreate, "Created Service"),
-// 			v1test.NewMockRecorderMessage(controller.ReasonReady, "Ready to use"),
-// 		},
-// 		expectWarningMessage: []v1test.MockRecorderMessage{},
-// 	}
-// 	ts := newTestSet(
-// 		t, v1.RelayCreateStage,
-// 		[]controllertest.ReconcileModDBFunc{
-// 			controllertest.ReconcileWantDBFinalizer,
-// 			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName())},
-// 		[]controllertest.ReconcileModSecretFunc{
-// 			controllertest.ReconcileWantSecretInit,
-// 			controllertest.ReconcileWantSecretNamePassword,
-// 		},
-// 	)
-// 	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
-// 		controllertest.ReconcileWantStage(v1.ReadyStage),
-// 		controllertest.ReconcileWantReady(true),
-// 		controllertest.ReconcileWantDBFinalizer,
-// 	}
-// 	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
-// 		controllertest.ReconcileWantSecretNamePassword,
-// 	}
-// 	ts.reconcileLoop = 2
+	testReconcileResultsTestSet(ts, expect)
+}
 
-// 	ts.svr.OnIsDatabase = func(ctx context.Context, dbName string) (string, bool, error) {
-// 		return dbName, false, nil
-// 	}
+func TestReconcile_Stage_Ready_RotateAt(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rotateAt := "2024-01-02T03:00:00Z"
+	expect := expectSet{
+		expectResult: reconcile.Result{RequeueAfter: 24 * time.Hour},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     5,
+			"MockClientWriter.Update":                  2,
+			"MockStatusClient.Status":                  2,
+			"MockStatusClient.TestStatusWriter.Update": 2,
+			"MockClientWriter.Create":                  1,
+		},
+		expectServerCallMap: map[string]int{
+			"UpdateRolePassword":     1,
+			"CopyInitConfigToSecret": 1,
+			"GetDatabaseHost":        1,
+			"GetDatabaseHostConfig":  1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          4,
+			"MockClientWriter.Update(*v1.Secret)":       2,
+			"MockClientWriter.Create(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonPasswordRotate, "Password rotated"),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReadyConditions,
+			controllertest.ReconcileWantRotateAt(rotateAt),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+			controllertest.ReconcileWantSecretImmutable(false),
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.svr.OnUpdateRolePassword = func(_ context.Context, roleName string) (accountsvr.Credentials, error) {
+		return accountsvr.Credentials{Username: roleName, Password: "rotatedpassword"}, nil
+	}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantLastRotated(now),
+		controllertest.ReconcileWantRotateAtHandled(rotateAt),
+		ts.wantSecretVersion,
+		ts.wantPreviousSecretVersion(now.Add(24 * time.Hour)),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+		controllertest.ReconcileWantSecretPassword("rotatedpassword"),
+	}
 
-// 	testReconcileResultsTestSet(ts, expect)
-// }
+	testReconcileResultsTestSet(ts, expect)
+}
 
-func TestReconcile_Stage_Ready(t *testing.T) {
+func TestReconcile_Stage_Ready_RotateAt_Handled(t *testing.T) {
 	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rotateAt := "2024-01-02T03:00:00Z"
 	expect := expectSet{
 		expectResult: reconcile.Result{},
 		expectClientCallMap: map[string]int{
@@ -411,25 +3047,226 @@ func TestReconcile_Stage_Ready(t *testing.T) {
 		[]controllertest.ReconcileModDBFunc{
 			controllertest.ReconcileWantDBFinalizer,
 			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
-			controllertest.ReconcileWantReady(true),
+			controllertest.ReconcileWantReadyConditions,
+			controllertest.ReconcileWantRotateAt(rotateAt),
+			controllertest.ReconcileWantRotateAtHandled(rotateAt),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Ready_PasswordRotation_DualRole(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	name := controllertest.NewDatabaseAccountName().String()
+	rotation := &v1.DatabaseAccountPasswordRotation{
+		Interval:    &metav1.Duration{Duration: time.Hour},
+		Mode:        v1.RotationModeDualRole,
+		GracePeriod: &metav1.Duration{Duration: 30 * time.Minute},
+	}
+	roleSettings := map[string]string{"statement_timeout": "60s"}
+	expect := expectSet{
+		expectResult: reconcile.Result{RequeueAfter: 30 * time.Minute},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     5,
+			"MockClientWriter.Update":                  2,
+			"MockStatusClient.Status":                  2,
+			"MockStatusClient.TestStatusWriter.Update": 2,
+			"MockClientWriter.Create":                  1,
+		},
+		expectServerCallMap: map[string]int{
+			"CreateMemberRole":       1,
+			"SetRoleSettings":        1,
+			"CopyInitConfigToSecret": 1,
+			"GetDatabaseHost":        1,
+			"GetDatabaseHostConfig":  1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          4,
+			"MockClientWriter.Update(*v1.Secret)":       2,
+			"MockClientWriter.Create(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonPasswordRotate, "Password rotated"),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReadyConditions,
+			controllertest.ReconcileWantPasswordRotation(rotation),
+			controllertest.ReconcileWantLastRotated(now.Add(-2 * time.Hour)),
+			controllertest.ReconcileWantSettings(roleSettings, nil),
+			controllertest.ReconcileWantSettingsApplied(roleSettings, nil),
 		},
 		[]controllertest.ReconcileModSecretFunc{
 			controllertest.ReconcileWantSecretInit,
 			controllertest.ReconcileWantSecretDatabaseDSN,
 			controllertest.ReconcileWantSecretNamePassword,
+			controllertest.ReconcileWantSecretImmutable(false),
 		},
 	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.svr.OnSetRoleSettings = func(_ context.Context, roleName string, settings map[string]string, _ []string) error {
+		// the new login role gets the settings of the account role.
+		if roleName != name+v1.DualRoleSuffixA || !maps.Equal(settings, roleSettings) {
+			t.Errorf("SetRoleSettings(): got '%s' '%v', want '%s' '%v'", roleName, settings,
+				name+v1.DualRoleSuffixA, roleSettings)
+		}
+
+		return nil
+	}
+	ts.svr.OnCreateMemberRole = func(_ context.Context, roleName, ownerRole string) (accountsvr.Credentials, error) {
+		if roleName != name+v1.DualRoleSuffixA || ownerRole != name {
+			t.Errorf("CreateMemberRole(): got '%s' '%s', want '%s' '%s'", roleName, ownerRole, name+v1.DualRoleSuffixA, name)
+		}
+
+		return accountsvr.Credentials{Username: roleName, Password: "rolepassword"}, nil
+	}
 	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
-		controllertest.ReconcileWantStage(v1.ReadyStage),
-		controllertest.ReconcileWantDBFinalizer,
+		controllertest.ReconcileWantLastRotated(now),
+		controllertest.ReconcileWantLoginRoles(name+v1.DualRoleSuffixA, name, now.Add(30*time.Minute)),
+		ts.wantSecretVersion,
+		ts.wantPreviousSecretVersion(now.Add(24 * time.Hour)),
 	}
 	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
 		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+		controllertest.ReconcileWantSecretLogin(name+v1.DualRoleSuffixA, "rolepassword"),
 	}
 
-	// ts.svr.OnIsDatabase = func(ctx context.Context, dbName string) (string, bool, error) {
-	// 	return dbName, false, nil
-	// }
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Ready_PasswordRotation_DualRoleGracePeriod(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	name := controllertest.NewDatabaseAccountName().String()
+	rotation := &v1.DatabaseAccountPasswordRotation{
+		Interval: &metav1.Duration{Duration: time.Hour},
+		Mode:     v1.RotationModeDualRole,
+	}
+	expect := expectSet{
+		// the rotation is due and waits for the grace period of the previous role.
+		expectResult: reconcile.Result{RequeueAfter: 20 * time.Minute},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":    3,
+			"MockClientWriter.Update": 1,
+		},
+		expectServerCallMap:   map[string]int{},
+		expectRecorderCallMap: map[string]int{},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage:  []v1test.MockRecorderMessage{},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReadyConditions,
+			controllertest.ReconcileWantPasswordRotation(rotation),
+			controllertest.ReconcileWantLastRotated(now.Add(-2 * time.Hour)),
+			controllertest.ReconcileWantLoginRoles(name+v1.DualRoleSuffixB, name+v1.DualRoleSuffixA,
+				now.Add(20*time.Minute)),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
+
+	testReconcileResultsTestSet(ts, expect)
+}
+
+func TestReconcile_Stage_Ready_PasswordRotation_DualRoleRevoke(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	name := controllertest.NewDatabaseAccountName().String()
+	rotation := &v1.DatabaseAccountPasswordRotation{
+		Interval: &metav1.Duration{Duration: time.Hour},
+		Mode:     v1.RotationModeDualRole,
+	}
+	expect := expectSet{
+		expectResult: reconcile.Result{RequeueAfter: 50 * time.Minute},
+		expectClientCallMap: map[string]int{
+			"MockClientReader.Get":                     3,
+			"MockClientWriter.Update":                  1,
+			"MockStatusClient.Status":                  1,
+			"MockStatusClient.TestStatusWriter.Update": 1,
+		},
+		expectServerCallMap: map[string]int{
+			"SetRoleLogin": 1,
+		},
+		expectRecorderCallMap: map[string]int{
+			"NormalEvent": 1,
+		},
+		expectTestCallMap: map[string]int{
+			"MockClientReader.Get(*v1.DatabaseAccount)": 1,
+			"MockClientReader.Get(*v1.Secret)":          2,
+			"MockClientWriter.Update(*v1.Secret)":       1,
+		},
+		expectNormalMessage: []v1test.MockRecorderMessage{
+			v1test.NewMockRecorderMessage(controller.ReasonPasswordRotate, ""),
+		},
+		expectWarningMessage: []v1test.MockRecorderMessage{},
+	}
+	ts := newTestSet(
+		t, v1.ReadyStage,
+		[]controllertest.ReconcileModDBFunc{
+			controllertest.ReconcileWantDBFinalizer,
+			controllertest.ReconcileWantDBName(controllertest.NewDatabaseAccountName()),
+			controllertest.ReconcileWantReadyConditions,
+			controllertest.ReconcileWantPasswordRotation(rotation),
+			controllertest.ReconcileWantLastRotated(now.Add(-10 * time.Minute)),
+			controllertest.ReconcileWantLoginRoles(name+v1.DualRoleSuffixB, name+v1.DualRoleSuffixA, now.Add(-time.Minute)),
+		},
+		[]controllertest.ReconcileModSecretFunc{
+			controllertest.ReconcileWantSecretInit,
+			controllertest.ReconcileWantSecretDatabaseDSN,
+			controllertest.ReconcileWantSecretNamePassword,
+		},
+	)
+	ts.rec.Clock = clocktesting.NewFakePassiveClock(now)
+	ts.svr.OnSetRoleLogin = func(_ context.Context, roleName string, login bool) error {
+		if roleName != name+v1.DualRoleSuffixA || login {
+			t.Errorf("SetRoleLogin(): got '%s' '%t', want '%s' 'false'", roleName, login, name+v1.DualRoleSuffixA)
+		}
+
+		return nil
+	}
+	ts.reconcileModDBAccount = []controllertest.ReconcileModDBFunc{
+		controllertest.ReconcileWantLoginRoles(name+v1.DualRoleSuffixB, "", time.Time{}),
+	}
+	ts.reconcileModSecret = []controllertest.ReconcileModSecretFunc{
+		controllertest.ReconcileWantSecretOwnerRefs(ts.ctr.GetDatabaseAccount()),
+	}
 
 	testReconcileResultsTestSet(ts, expect)
 }
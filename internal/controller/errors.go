@@ -15,6 +15,45 @@ var (
 	// so a new service was generated.
 	ErrNewService = errors.New("creating new service")
 
+	// ErrNewPodDisruptionBudget is returned when a poddisruptionbudget was not able to be retrieved
+	// and so a new poddisruptionbudget was generated.
+	ErrNewPodDisruptionBudget = errors.New("creating new poddisruptionbudget")
+
 	// ErrSecretImmutable is returned when a secret is immutable and can not be changed.
 	ErrSecretImmutable = errors.New("secret is immutable")
+
+	// ErrSecretAdopt is returned when the credentials in an existing secret can not be adopted.
+	ErrSecretAdopt = errors.New("secret credentials can not be adopted")
+
+	// ErrBypassRLSNotAllowed is returned when a DatabaseAccount role requests BYPASSRLS and it is
+	// not allowed by the controller configuration.
+	ErrBypassRLSNotAllowed = errors.New("role attribute BYPASSRLS is not allowed")
+
+	// ErrExtensionNotAllowed is returned when a DatabaseAccount requests an extension that is not
+	// in the allowed extensions of the controller configuration.
+	ErrExtensionNotAllowed = errors.New("extension is not allowed")
+
+	// ErrNoDefaultServer is returned when a DatabaseAccount does not specify a serverRef and
+	// there is no database server configured in the controller configuration.
+	ErrNoDefaultServer = errors.New("no default database server configured")
+
+	// ErrNoServerPool is returned when a DatabaseAccount specifies a serverRef and the
+	// reconciler has no server pool.
+	ErrNoServerPool = errors.New("no database server pool configured")
+
+	// ErrMissingServerDSN is returned when the secret referenced by a DatabaseServer does
+	// not contain the DSN key.
+	ErrMissingServerDSN = errors.New("database server secret is missing dsn")
+
+	// ErrMissingSecretCredentials is returned when the relay configuration of an account is generated
+	// before the database and credentials are in the account secret.
+	ErrMissingSecretCredentials = errors.New("account secret is missing the database or credentials")
+
+	// ErrMissingSecretVerifier is returned when the relay configuration of an account is generated
+	// from a secret without the SCRAM-SHA-256 verifier of the password.
+	ErrMissingSecretVerifier = errors.New("account secret is missing the password verifier")
+
+	// ErrRelayHostUnresolved is returned when the host of a database server of a relay can not be
+	// resolved for the relay NetworkPolicy.
+	ErrRelayHostUnresolved = errors.New("unable to resolve database host")
 )
@@ -5,9 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"hash/crc32"
-	"strconv"
 	"strings"
-	"text/template"
 
 	"github.com/dosquad/database-operator/accountsvr"
 	dbov1 "github.com/dosquad/database-operator/api/v1"
@@ -15,13 +13,12 @@ import (
 	"github.com/oklog/ulid/v2"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -63,61 +60,29 @@ func GetSecretKV(secret *corev1.Secret, key string) string {
 	return ""
 }
 
-const (
-	pgBouncerConfTemplate = `[databases]
-* = host={{.Host}} port=5432 user={{.User}} password={{.Password}}
-
-[pgbouncer]
-listen_addr = 0.0.0.0
-listen_port = {{.Port}}
-unix_socket_dir =
-user = postgres
-auth_file = /etc/pgbouncer/userlist.txt
-auth_type = md5
-ignore_startup_parameters = extra_float_digits
-
-# Log settings
-admin_users = {{.User}}
-stats_users = {{.User}}
-`
-)
-
-func AddPGBouncerConf(
+// AddRelayConf sets the configuration of the account relay in the secret, the configuration of the
+// other relay types is removed. The relay settings of the account are used with the relay defaults
+// from the controller configuration.
+func AddRelayConf(
+	ctx context.Context,
 	accountSvr accountsvr.Server,
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	dbAccount *dbov1.DatabaseAccount,
 	secret *corev1.Secret,
-) {
-	var tmpl *template.Template
-	{
-		var err error
-		tmpl, err = template.New("").Parse(pgBouncerConfTemplate)
-		if err != nil {
-			return
-		}
-	}
-
-	data := struct {
-		Host, Port, User, Password string
-	}{
-		Host:     accountSvr.GetDatabaseHostConfig(),
-		Port:     strconv.Itoa(defaultPostgresqlPort),
-		User:     GetSecretKV(secret, accountsvr.DatabaseKeyUsername),
-		Password: GetSecretKV(secret, accountsvr.DatabaseKeyPassword),
+) error {
+	conf, err := accountRelayConfig(ctx, accountSvr, ctrlConfig, dbAccount, secret)
+	if err != nil {
+		return err
 	}
 
-	sb := &strings.Builder{}
-	if err := tmpl.Execute(sb, data); err != nil {
-		return
+	files, err := RelayDriverFor(ctrlConfig.GetRelayType(dbAccount)).Config(conf)
+	if err != nil {
+		return err
 	}
 
-	SetSecretKV(secret, accountsvr.DatabaseKeyPGBouncerConf, sb.String())
+	setSecretRelayFiles(secret, files)
 
-	SetSecretKV(
-		secret, accountsvr.DatabaseKeyPGBouncerUsers,
-		fmt.Sprintf("\"%s\" \"%s\"\n",
-			GetSecretKV(secret, accountsvr.DatabaseKeyUsername),
-			GetSecretKV(secret, accountsvr.DatabaseKeyPassword),
-		),
-	)
+	return nil
 }
 
 type SecretFunc func(secret *corev1.Secret) error
@@ -133,6 +98,15 @@ func SecretRun(
 	logger := log.FromContext(ctx)
 
 	secret, secretErr := SecretGet(ctx, r, dbAccount)
+	if errors.Is(secretErr, ErrNewSecret) {
+		// a secret that was being replaced is restored instead of creating new credentials.
+		if restored, err := SecretRestore(ctx, r, w, dbAccount.GetSecretName()); err != nil {
+			return err
+		} else if restored {
+			secret, secretErr = SecretGet(ctx, r, dbAccount)
+		}
+	}
+
 	if errors.Is(secretErr, ErrNewSecret) {
 		accountSvr.CopyInitConfigToSecret(dbAccount, secret)
 		if err := w.Create(ctx, secret); err != nil {
@@ -170,6 +144,221 @@ func SecretRun(
 	return nil
 }
 
+// SecretReplace deletes the secret and creates it again with the changes made by the function, it is
+// used to change the data of an immutable secret. The replacement secret is not immutable. The
+// replacement is first written to a copy of the secret so it is restored by SecretRestore if it was
+// deleted and the replacement could not be created.
+func SecretReplace(
+	ctx context.Context,
+	w client.Writer,
+	secret *corev1.Secret,
+	f SecretFunc,
+) error {
+	logger := log.FromContext(ctx)
+
+	current := secret.DeepCopy()
+	replacement := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            current.GetName(),
+			Namespace:       current.GetNamespace(),
+			Annotations:     current.GetAnnotations(),
+			Labels:          current.GetLabels(),
+			OwnerReferences: current.GetOwnerReferences(),
+			Finalizers:      current.GetFinalizers(),
+		},
+		Data: current.Data,
+		Type: current.Type,
+	}
+
+	if err := f(replacement); err != nil {
+		return err
+	}
+
+	staged := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            secretReplacementName(client.ObjectKeyFromObject(current)).Name,
+			Namespace:       current.GetNamespace(),
+			Annotations:     replacement.GetAnnotations(),
+			Labels:          replacement.GetLabels(),
+			OwnerReferences: replacement.GetOwnerReferences(),
+		},
+		Data: replacement.Data,
+		Type: secretReplacementType,
+	}
+	if err := w.Create(ctx, staged); apierrors.IsAlreadyExists(err) {
+		err = w.Update(ctx, staged)
+		if err != nil {
+			logger.V(1).Error(err, "unable to update replacement secret")
+
+			return err
+		}
+	} else if err != nil {
+		logger.V(1).Error(err, "unable to create replacement secret")
+
+		return err
+	}
+
+	// the finalizer is removed so the secret is deleted straight away.
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[secretReplacedAnnotation] = "true"
+	controllerutil.RemoveFinalizer(secret, finalizerName)
+	if err := w.Update(ctx, secret); err != nil {
+		logger.V(1).Error(err, "unable to update secret")
+
+		return err
+	}
+
+	if err := w.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+		logger.V(1).Error(err, "unable to delete secret")
+
+		return err
+	}
+
+	if err := w.Create(ctx, replacement); err != nil {
+		logger.V(1).Error(err, "unable to create secret")
+
+		return err
+	}
+
+	if err := w.Delete(ctx, staged); err != nil && !apierrors.IsNotFound(err) {
+		logger.V(1).Error(err, "unable to delete replacement secret")
+
+		return err
+	}
+
+	*secret = *replacement
+
+	return nil
+}
+
+// secretReplacementName returns the name of the copy of the account secret written before it is
+// replaced.
+func secretReplacementName(name types.NamespacedName) types.NamespacedName {
+	name.Name += secretReplacementSuffix
+
+	return name
+}
+
+// SecretRestore creates the account secret from the copy written by SecretReplace when the secret was
+// deleted and the replacement was not created, false is returned when there is no copy.
+func SecretRestore(ctx context.Context, r client.Reader, w client.Writer, name types.NamespacedName) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	staged, err := SecretGetByName(ctx, r, secretReplacementName(name))
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name.Name,
+			Namespace:       name.Namespace,
+			Annotations:     staged.GetAnnotations(),
+			Labels:          staged.GetLabels(),
+			OwnerReferences: staged.GetOwnerReferences(),
+			Finalizers:      []string{finalizerName},
+		},
+		Data: staged.Data,
+		Type: secretType,
+	}
+	if err := w.Create(ctx, secret); err != nil && !apierrors.IsAlreadyExists(err) {
+		logger.V(1).Error(err, "unable to restore secret")
+
+		return false, err
+	}
+
+	if err := w.Delete(ctx, staged); err != nil && !apierrors.IsNotFound(err) {
+		logger.V(1).Error(err, "unable to delete replacement secret")
+
+		return false, err
+	}
+
+	logger.Info("Restored secret that was being replaced", "secret", name.Name)
+
+	return true, nil
+}
+
+// SetSecretSchema sets the default schema of the account in the secret, the key is removed if the
+// account has no schemas.
+func SetSecretSchema(dbAccount *dbov1.DatabaseAccount, secret *corev1.Secret) {
+	schema := dbAccount.GetDefaultSchema()
+	if schema == "" {
+		delete(secret.Data, accountsvr.DatabaseKeySchema)
+
+		return
+	}
+
+	SetSecretKV(secret, accountsvr.DatabaseKeySchema, schema)
+}
+
+// SetSecretCredentials sets the username and password in the secret along with the keys generated
+// from them.
+func SetSecretCredentials(
+	ctx context.Context,
+	accountSvr accountsvr.Server,
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	dbAccount *dbov1.DatabaseAccount,
+	secret *corev1.Secret,
+	creds accountsvr.Credentials,
+) error {
+	name, err := dbAccount.GetDatabaseName()
+	if err != nil {
+		return err
+	}
+
+	accountSvr.CopyInitConfigToSecret(dbAccount, secret)
+	setSecretLogin(secret, creds)
+	if GetSecretKV(secret, accountsvr.DatabaseKeyDatabase) == "" {
+		SetSecretKV(secret, accountsvr.DatabaseKeyDatabase, name)
+	}
+	SetSecretKV(secret, accountsvr.DatabaseKeyDSN, accountsvr.GenerateDSN(secret))
+
+	return SetSecretRelay(ctx, accountSvr, ctrlConfig, dbAccount, secret)
+}
+
+// setSecretLogin sets the username and password of the credentials in the secret, the verifier is
+// removed when the server did not return one so a stale verifier is not used for a new password.
+func setSecretLogin(secret *corev1.Secret, creds accountsvr.Credentials) {
+	SetSecretKV(secret, accountsvr.DatabaseKeyUsername, creds.Username)
+	SetSecretKV(secret, accountsvr.DatabaseKeyPassword, creds.Password)
+	if creds.Verifier != "" {
+		SetSecretKV(secret, accountsvr.DatabaseKeyVerifier, creds.Verifier)
+	} else {
+		delete(secret.Data, accountsvr.DatabaseKeyVerifier)
+	}
+}
+
+// SetSecretRelay points the host of the secret at the relay of the account. The relay configuration is
+// written to the secret for a dedicated relay, the shared relay has the configuration of every
+// account in the shared relay secret.
+func SetSecretRelay(
+	ctx context.Context,
+	accountSvr accountsvr.Server,
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	dbAccount *dbov1.DatabaseAccount,
+	secret *corev1.Secret,
+) error {
+	if !dbAccount.GetSpecCreateRelay() {
+		return nil
+	}
+
+	if dbAccount.GetSpecRelay().IsShared() {
+		setSecretRelayFiles(secret, nil)
+		delete(secret.Data, accountsvr.DatabaseKeyRelayAdminPassword)
+		delete(secret.Data, accountsvr.DatabaseKeyRelayAdminVerifier)
+	} else if err := AddRelayConf(ctx, accountSvr, ctrlConfig, dbAccount, secret); err != nil {
+		return err
+	}
+
+	SetSecretKV(secret, accountsvr.DatabaseKeyHost, dbAccount.GetRelayName().Name)
+
+	return nil
+}
+
 func SecretGetByName(ctx context.Context, r client.Reader, name types.NamespacedName) (*corev1.Secret, error) {
 	secret := &corev1.Secret{}
 
@@ -212,6 +401,26 @@ func SecretGet(ctx context.Context, r client.Reader, dbAccount *dbov1.DatabaseAc
 	return secret, nil
 }
 
+// DatabaseServerDSNGet returns the admin DSN for the named DatabaseServer.
+func DatabaseServerDSNGet(ctx context.Context, r client.Reader, name string) (dbov1.PostgreSQLDSN, error) {
+	dbServer := &dbov1.DatabaseServer{}
+	if err := r.Get(ctx, types.NamespacedName{Name: name}, dbServer); err != nil {
+		return "", err
+	}
+
+	secret, err := SecretGetByName(ctx, r, dbServer.GetDSNSecretName())
+	if err != nil {
+		return "", err
+	}
+
+	dsn := GetSecretKV(secret, dbServer.GetDSNSecretKey())
+	if dsn == "" {
+		return "", fmt.Errorf("%w: %s", ErrMissingServerDSN, dbServer.GetDSNSecretName())
+	}
+
+	return dbov1.PostgreSQLDSN(dsn), nil
+}
+
 func SecretAddOwnerRefs(secret *corev1.Secret, dbAccount *dbov1.DatabaseAccount) {
 	secret.OwnerReferences = []metav1.OwnerReference{*dbAccount.GetReference()}
 }
@@ -228,68 +437,13 @@ func StatefulSetGet(
 	if err := r.Get(ctx, dbAccount.GetStatefulSetName(), statefulSet); apierrors.IsNotFound(err) {
 		logger.V(1).Info("call:StatefulSetGet()")
 
-		podSpec := corev1.PodSpec{
-			Containers: []corev1.Container{
-				{
-					Image: ctrlConfig.GetRelayImage(),
-					Name:  dbAccount.GetStatefulSetName().Name,
-					Ports: []corev1.ContainerPort{
-						{
-							Name:          defaultPostgresqlPortName,
-							ContainerPort: defaultPostgresqlPort,
-							Protocol:      corev1.ProtocolTCP,
-						},
-					},
-					ImagePullPolicy: "IfNotPresent",
-					VolumeMounts: []corev1.VolumeMount{
-						{
-							Name:      dbAccount.GetSecretName().Name,
-							MountPath: "/etc/pgbouncer/",
-						},
-					},
-				},
-			},
-			Volumes: []corev1.Volume{
-				{
-					Name: dbAccount.GetSecretName().Name,
-					VolumeSource: corev1.VolumeSource{
-						Secret: &corev1.SecretVolumeSource{
-							SecretName: dbAccount.GetSecretName().Name,
-						},
-					},
-				},
-			},
+		checksum, err := RelayConfigChecksum(ctx, r, ctrlConfig, dbAccount)
+		if err != nil {
+			return statefulSet, err
 		}
 
-		l := labels.Merge(dbAccount.Spec.SecretTemplate.Labels, map[string]string{
-			labelNamePartOf:  defaultPartOf,
-			databaseOwnerKey: dbAccount.GetStatefulSetName().Name,
-		})
-
-		statefulSet = &appsv1.StatefulSet{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:            dbAccount.GetStatefulSetName().Name,
-				Namespace:       dbAccount.Namespace,
-				Annotations:     dbAccount.Spec.SecretTemplate.Annotations,
-				Labels:          l,
-				OwnerReferences: []metav1.OwnerReference{*dbAccount.GetReference()},
-			},
-			Spec: appsv1.StatefulSetSpec{
-				Replicas: ptr.To[int32](1),
-				Selector: &metav1.LabelSelector{
-					MatchLabels: l,
-				},
-				Template: corev1.PodTemplateSpec{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:        dbAccount.GetStatefulSetName().Name,
-						Namespace:   dbAccount.Namespace,
-						Annotations: dbAccount.Spec.SecretTemplate.Annotations,
-						Labels:      l,
-					},
-					Spec: podSpec,
-				},
-			},
-		}
+		statefulSet = RelayStatefulSet(ctrlConfig, dbAccount)
+		setRelayConfigChecksum(statefulSet, checksum)
 
 		return statefulSet, ErrNewStatefulSet
 	} else if err != nil {
@@ -320,35 +474,7 @@ func ServiceGet(
 	if err := r.Get(ctx, dbAccount.GetStatefulSetName(), service); apierrors.IsNotFound(err) {
 		logger.V(1).Info("call:ServiceGet()")
 
-		l := labels.Merge(dbAccount.Spec.SecretTemplate.Labels, map[string]string{
-			labelNamePartOf:  defaultPartOf,
-			databaseOwnerKey: dbAccount.GetStatefulSetName().Name,
-		})
-
-		service = &corev1.Service{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:            dbAccount.GetStatefulSetName().Name,
-				Namespace:       dbAccount.Namespace,
-				Annotations:     dbAccount.Spec.SecretTemplate.Annotations,
-				Labels:          l,
-				OwnerReferences: []metav1.OwnerReference{*dbAccount.GetReference()},
-			},
-			Spec: corev1.ServiceSpec{
-				Ports: []corev1.ServicePort{
-					{
-						Name:       defaultPostgresqlPortName,
-						Port:       defaultPostgresqlPort,
-						TargetPort: intstr.FromString(defaultPostgresqlPortName),
-						Protocol:   corev1.ProtocolTCP,
-					},
-				},
-				Selector: map[string]string{
-					databaseOwnerKey: dbAccount.GetStatefulSetName().Name,
-				},
-			},
-		}
-
-		return service, ErrNewService
+		return RelayService(dbAccount), ErrNewService
 	} else if err != nil {
 		return service, err
 	}
@@ -356,6 +482,27 @@ func ServiceGet(
 	return service, nil
 }
 
+// PodDisruptionBudgetGet returns the PodDisruptionBudget of the relay pods, a new
+// PodDisruptionBudget is returned with ErrNewPodDisruptionBudget if it does not exist.
+func PodDisruptionBudgetGet(
+	ctx context.Context,
+	r client.Reader,
+	dbAccount *dbov1.DatabaseAccount,
+) (*policyv1.PodDisruptionBudget, error) {
+	logger := log.FromContext(ctx)
+
+	pdb := &policyv1.PodDisruptionBudget{}
+	if err := r.Get(ctx, dbAccount.GetStatefulSetName(), pdb); apierrors.IsNotFound(err) {
+		logger.V(1).Info("call:PodDisruptionBudgetGet()")
+
+		return RelayPodDisruptionBudget(dbAccount), ErrNewPodDisruptionBudget
+	} else if err != nil {
+		return pdb, err
+	}
+
+	return pdb, nil
+}
+
 // func stringOnly(in string, _ bool) string {
 // 	return in
 // }
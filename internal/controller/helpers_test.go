@@ -0,0 +1,377 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	accountsvrtest "github.com/dosquad/database-operator/accountsvr/test"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	v1test "github.com/dosquad/database-operator/api/v1/test"
+	"github.com/dosquad/database-operator/internal/controller"
+	"github.com/dosquad/database-operator/internal/helper"
+	"github.com/dosquad/database-operator/internal/testhelp"
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestAddRelayConf(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		relay    *dbov1.DatabaseAccountRelay
+		defaults dbov1.RelaySettings
+		expect   []string
+	}{
+		{
+			"Defaults", &dbov1.DatabaseAccountRelay{}, dbov1.RelaySettings{},
+			[]string{
+				"* = host=databasehost port=6543\n",
+				"auth_type = scram-sha-256\n",
+				"admin_users = dbo_relay_admin\n",
+				"pool_mode = session\n",
+				"max_client_conn = 100\n",
+				"default_pool_size = 20\n",
+				"server_idle_timeout = 600\n",
+				"max_db_connections = 0\n",
+			},
+		},
+		{
+			"Spec",
+			&dbov1.DatabaseAccountRelay{RelaySettings: dbov1.RelaySettings{
+				PoolMode:          dbov1.RelayPoolModeTransaction,
+				MaxClientConn:     ptr.To[int32](500),
+				DefaultPoolSize:   ptr.To[int32](10),
+				ServerIdleTimeout: &metav1.Duration{Duration: time.Minute},
+				MaxDBConnections:  ptr.To[int32](50),
+			}},
+			dbov1.RelaySettings{PoolMode: dbov1.RelayPoolModeStatement, MaxClientConn: ptr.To[int32](200)},
+			[]string{
+				"pool_mode = transaction\n",
+				"max_client_conn = 500\n",
+				"default_pool_size = 10\n",
+				"server_idle_timeout = 60\n",
+				"max_db_connections = 50\n",
+			},
+		},
+		{
+			"ControllerDefaults",
+			&dbov1.DatabaseAccountRelay{RelaySettings: dbov1.RelaySettings{DefaultPoolSize: ptr.To[int32](5)}},
+			dbov1.RelaySettings{PoolMode: dbov1.RelayPoolModeTransaction, MaxClientConn: ptr.To[int32](200)},
+			[]string{
+				"pool_mode = transaction\n",
+				"max_client_conn = 200\n",
+				"default_pool_size = 5\n",
+			},
+		},
+		{
+			"TLS",
+			&dbov1.DatabaseAccountRelay{TLS: &dbov1.RelayTLS{
+				Client: &dbov1.RelayClientTLS{},
+				Server: &dbov1.RelayServerTLS{
+					SSLMode: dbov1.RelayTLSModeVerifyFull,
+					CASecretRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "db-ca"}, Key: "bundle.pem",
+					},
+				},
+			}},
+			dbov1.RelaySettings{},
+			[]string{
+				"client_tls_sslmode = require\n",
+				"client_tls_cert_file = /etc/pgbouncer-tls/tls.crt\n",
+				"client_tls_key_file = /etc/pgbouncer-tls/tls.key\n",
+				"server_tls_sslmode = verify-full\n",
+				"server_tls_ca_file = /etc/pgbouncer-server-ca/ca.crt\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start := time.Now()
+
+			svr := accountsvrtest.NewMockServer(accountsvrtest.TestDSN)
+			svr.OnGetDatabasePortConfig = func() uint16 { return 6543 }
+			ctrlConfig := &dbov1.DatabaseAccountControllerConfig{RelayDefaults: tt.defaults}
+			dbAccount := v1test.NewDatabaseAccount()
+			dbAccount.Spec.Relay = tt.relay
+			verifier, _ := helper.ScramSHA256Verifier("dbpass")
+			secret := &corev1.Secret{Data: map[string][]byte{
+				accountsvr.DatabaseKeyUsername: []byte("dbuser"),
+				accountsvr.DatabaseKeyPassword: []byte("dbpass"),
+				accountsvr.DatabaseKeyVerifier: []byte(verifier),
+			}}
+
+			if err := controller.AddRelayConf(t.Context(), svr, ctrlConfig, &dbAccount, secret); err != nil {
+				testhelp.Errorf(t, start, "controller.AddRelayConf(): error, got '%v', want 'nil'", err)
+			}
+
+			conf := controller.GetSecretKV(secret, accountsvr.DatabaseKeyPGBouncerConf)
+			for _, line := range tt.expect {
+				if !strings.Contains(conf, line) {
+					testhelp.Errorf(t, start, "controller.AddRelayConf(): missing '%s' in:\n%s",
+						strings.TrimSpace(line), conf)
+				}
+			}
+
+			users := controller.GetSecretKV(secret, accountsvr.DatabaseKeyPGBouncerUsers)
+			if strings.Contains(conf, "dbpass") || strings.Contains(users, "dbpass") {
+				testhelp.Errorf(t, start, "controller.AddRelayConf(): plaintext password in relay configuration")
+			}
+
+			// the admin of the relay is a generated user and not the account user.
+			adminVerifier := controller.GetSecretKV(secret, accountsvr.DatabaseKeyRelayAdminVerifier)
+			if !helper.ScramSHA256VerifierMatches(
+				adminVerifier, controller.GetSecretKV(secret, accountsvr.DatabaseKeyRelayAdminPassword),
+			) {
+				testhelp.Errorf(t, start, "controller.AddRelayConf(): admin verifier does not match the admin password")
+			}
+
+			expect := fmt.Sprintf("\"dbuser\" \"%s\"\n\"dbo_relay_admin\" \"%s\"\n", verifier, adminVerifier)
+			if users != expect {
+				testhelp.Errorf(t, start, "controller.AddRelayConf(): users, got '%s', want '%s'", users, expect)
+			}
+		})
+	}
+}
+
+func TestAddRelayConf_MissingCredentials(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	svr := accountsvrtest.NewMockServer(accountsvrtest.TestDSN)
+	dbAccount := v1test.NewDatabaseAccount()
+	dbAccount.Spec.Relay = &dbov1.DatabaseAccountRelay{}
+	secret := &corev1.Secret{Data: map[string][]byte{
+		accountsvr.DatabaseKeyPGBouncerConf: []byte("[databases]"),
+	}}
+
+	err := controller.AddRelayConf(t.Context(), svr, &dbov1.DatabaseAccountControllerConfig{}, &dbAccount, secret)
+	if !errors.Is(err, controller.ErrMissingSecretCredentials) {
+		testhelp.Errorf(t, start, "controller.AddRelayConf(): error, got '%v', want '%v'",
+			err, controller.ErrMissingSecretCredentials)
+	}
+
+	if v := controller.GetSecretKV(secret, accountsvr.DatabaseKeyPGBouncerConf); v != "[databases]" {
+		testhelp.Errorf(t, start, "controller.AddRelayConf(): secret updated, got '%s'", v)
+	}
+
+	err = controller.SetSecretRelay(t.Context(), svr, &dbov1.DatabaseAccountControllerConfig{}, &dbAccount, secret)
+	if !errors.Is(err, controller.ErrMissingSecretCredentials) {
+		testhelp.Errorf(t, start, "controller.SetSecretRelay(): error, got '%v', want '%v'",
+			err, controller.ErrMissingSecretCredentials)
+	}
+}
+
+func TestSetSecretCredentials_Verifier(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	svr := accountsvrtest.NewMockServer(accountsvrtest.TestDSN)
+	ctrlConfig := &dbov1.DatabaseAccountControllerConfig{}
+	dbAccount := v1test.NewDatabaseAccount()
+	dbAccount.Spec.Relay = &dbov1.DatabaseAccountRelay{}
+	secret := &corev1.Secret{}
+
+	verifier, _ := helper.ScramSHA256Verifier("dbpass")
+	creds := accountsvr.Credentials{Username: "dbuser", Password: "dbpass", Verifier: verifier}
+	if err := controller.SetSecretCredentials(t.Context(), svr, ctrlConfig, &dbAccount, secret, creds); err != nil {
+		t.Fatalf("controller.SetSecretCredentials(): error, got '%v', want 'nil'", err)
+	}
+
+	if got := controller.GetSecretKV(secret, accountsvr.DatabaseKeyVerifier); got != verifier {
+		testhelp.Errorf(t, start, "controller.SetSecretCredentials(): verifier, got '%s', want '%s'", got, verifier)
+	}
+
+	// the relay logs in to the server with the verifier the server stored.
+	users := controller.GetSecretKV(secret, accountsvr.DatabaseKeyPGBouncerUsers)
+	if expect := fmt.Sprintf("\"dbuser\" \"%s\"\n", verifier); !strings.HasPrefix(users, expect) {
+		testhelp.Errorf(t, start, "controller.SetSecretCredentials(): users, got '%s', want '%s'", users, expect)
+	}
+
+	// a stale verifier is removed when the new credentials do not have one, the relay can not log in
+	// to the server without it.
+	creds = accountsvr.Credentials{Username: "dbuser", Password: "newpass"}
+	err := controller.SetSecretCredentials(t.Context(), svr, ctrlConfig, &dbAccount, secret, creds)
+	if !errors.Is(err, controller.ErrMissingSecretVerifier) {
+		testhelp.Errorf(t, start, "controller.SetSecretCredentials(): error, got '%v', want '%v'",
+			err, controller.ErrMissingSecretVerifier)
+	}
+
+	if _, ok := secret.Data[accountsvr.DatabaseKeyVerifier]; ok {
+		testhelp.Errorf(t, start, "controller.SetSecretCredentials(): verifier not removed")
+	}
+}
+
+func TestStatefulSetGet_Relay(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	c := v1test.NewMockClient()
+	c.OnGet = func(_ context.Context, key client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+		return apierrors.NewNotFound(appsv1.Resource("statefulsets"), key.Name)
+	}
+
+	dbAccount := v1test.NewDatabaseAccount()
+	dbAccount.Spec.Relay = &dbov1.DatabaseAccountRelay{
+		Replicas: ptr.To[int32](3),
+		Resources: &corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+		},
+		NodeSelector:     map[string]string{"pool": "relay"},
+		Tolerations:      []corev1.Toleration{{Key: "relay", Operator: corev1.TolerationOpExists}},
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+		TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+			{MaxSkew: 1, TopologyKey: "kubernetes.io/hostname", WhenUnsatisfiable: corev1.ScheduleAnyway},
+		},
+	}
+
+	statefulSet, err := controller.StatefulSetGet(t.Context(), c, &dbov1.DatabaseAccountControllerConfig{}, &dbAccount)
+	if !errors.Is(err, controller.ErrNewStatefulSet) {
+		testhelp.Errorf(t, start, "controller.StatefulSetGet(): error, got '%v', want '%v'",
+			err, controller.ErrNewStatefulSet)
+	}
+
+	if v := ptr.Deref(statefulSet.Spec.Replicas, 0); v != 3 {
+		testhelp.Errorf(t, start, "controller.StatefulSetGet(): replicas, got '%d', want '3'", v)
+	}
+
+	podSpec := statefulSet.Spec.Template.Spec
+	if diff := cmp.Diff(podSpec.NodeSelector, dbAccount.Spec.Relay.NodeSelector); diff != "" {
+		testhelp.Errorf(t, start, "controller.StatefulSetGet(): node selector -got +want:\n%s", diff)
+	}
+
+	if diff := cmp.Diff(podSpec.Tolerations, dbAccount.Spec.Relay.Tolerations); diff != "" {
+		testhelp.Errorf(t, start, "controller.StatefulSetGet(): tolerations -got +want:\n%s", diff)
+	}
+
+	if diff := cmp.Diff(podSpec.ImagePullSecrets, dbAccount.Spec.Relay.ImagePullSecrets); diff != "" {
+		testhelp.Errorf(t, start, "controller.StatefulSetGet(): image pull secrets -got +want:\n%s", diff)
+	}
+
+	if diff := cmp.Diff(
+		podSpec.TopologySpreadConstraints, dbAccount.Spec.Relay.TopologySpreadConstraints,
+	); diff != "" {
+		testhelp.Errorf(t, start, "controller.StatefulSetGet(): topology spread -got +want:\n%s", diff)
+	}
+
+	container := podSpec.Containers[0]
+	if !container.Resources.Requests.Cpu().Equal(resource.MustParse("100m")) {
+		testhelp.Errorf(t, start, "controller.StatefulSetGet(): cpu request, got '%s', want '100m'",
+			container.Resources.Requests.Cpu())
+	}
+
+	if container.ReadinessProbe == nil || container.ReadinessProbe.TCPSocket == nil ||
+		container.LivenessProbe == nil || container.LivenessProbe.TCPSocket == nil {
+		testhelp.Errorf(t, start, "controller.StatefulSetGet(): missing readiness or liveness probe")
+	}
+}
+
+func TestPodDisruptionBudgetGet(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	c := v1test.NewMockClient()
+	c.OnGet = func(_ context.Context, key client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+		return apierrors.NewNotFound(policyv1.Resource("poddisruptionbudgets"), key.Name)
+	}
+
+	dbAccount := v1test.NewDatabaseAccount()
+	dbAccount.Spec.Relay = &dbov1.DatabaseAccountRelay{Replicas: ptr.To[int32](2)}
+
+	pdb, err := controller.PodDisruptionBudgetGet(t.Context(), c, &dbAccount)
+	if !errors.Is(err, controller.ErrNewPodDisruptionBudget) {
+		testhelp.Errorf(t, start, "controller.PodDisruptionBudgetGet(): error, got '%v', want '%v'",
+			err, controller.ErrNewPodDisruptionBudget)
+	}
+
+	if pdb.Name != dbAccount.GetStatefulSetName().Name {
+		testhelp.Errorf(t, start, "controller.PodDisruptionBudgetGet(): name, got '%s', want '%s'",
+			pdb.Name, dbAccount.GetStatefulSetName().Name)
+	}
+
+	if v := pdb.Spec.MaxUnavailable; v == nil || v.IntValue() != 1 {
+		testhelp.Errorf(t, start, "controller.PodDisruptionBudgetGet(): maxUnavailable, got '%v', want '1'", v)
+	}
+}
+
+func TestSecretReplace_CreateFailed(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	secrets := map[string]*corev1.Secret{}
+	c := v1test.NewMockClient()
+	c.OnGet = func(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+		secret, ok := secrets[key.Name]
+		if !ok {
+			return apierrors.NewNotFound(corev1.Resource("secrets"), key.Name)
+		}
+		secret.DeepCopyInto(obj.(*corev1.Secret))
+
+		return nil
+	}
+	c.OnDelete = func(_ context.Context, obj client.Object, _ ...client.DeleteOption) error {
+		delete(secrets, obj.GetName())
+
+		return nil
+	}
+	createErr := errors.New("create failed")
+	c.OnCreate = func(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+		if createErr != nil && obj.GetName() == v1test.SecretName {
+			return createErr
+		}
+		secrets[obj.GetName()] = obj.(*corev1.Secret).DeepCopy()
+
+		return nil
+	}
+
+	secret := v1test.NewSecret()
+	secrets[secret.GetName()] = secret.DeepCopy()
+	name := client.ObjectKeyFromObject(&secret)
+
+	err := controller.SecretReplace(t.Context(), c, &secret, func(secret *corev1.Secret) error {
+		controller.SetSecretKV(secret, accountsvr.DatabaseKeyPassword, "replacedpassword")
+
+		return nil
+	})
+	if !errors.Is(err, createErr) {
+		testhelp.Errorf(t, start, "controller.SecretReplace(): error, got '%v', want '%v'", err, createErr)
+	}
+
+	if _, ok := secrets[name.Name]; ok {
+		testhelp.Errorf(t, start, "controller.SecretReplace(): secret '%s' not deleted", name.Name)
+	}
+
+	// the account secret is restored with the replaced data once it can be created.
+	createErr = nil
+	restored, err := controller.SecretRestore(t.Context(), c, c, name)
+	if err != nil || !restored {
+		testhelp.Errorf(t, start, "controller.SecretRestore(): got '%t' '%v', want 'true' 'nil'", restored, err)
+	}
+
+	if got := controller.GetSecretKV(secrets[name.Name], accountsvr.DatabaseKeyPassword); got != "replacedpassword" {
+		testhelp.Errorf(t, start, "controller.SecretRestore(): password, got '%s', want 'replacedpassword'", got)
+	}
+
+	if len(secrets) != 1 {
+		testhelp.Errorf(t, start, "controller.SecretRestore(): secrets, got '%d', want the account secret only",
+			len(secrets))
+	}
+
+	if restored, err := controller.SecretRestore(t.Context(), c, c, name); err != nil || restored {
+		testhelp.Errorf(t, start, "controller.SecretRestore(): got '%t' '%v', want 'false' 'nil'", restored, err)
+	}
+}
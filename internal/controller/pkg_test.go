@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/dosquad/database-operator/accountsvr"
 	accountsvrtest "github.com/dosquad/database-operator/accountsvr/test"
 	dbov1 "github.com/dosquad/database-operator/api/v1"
 	v1test "github.com/dosquad/database-operator/api/v1/test"
@@ -77,9 +78,32 @@ func newTestSet(
 		})
 	}
 
+	// ready accounts with credentials have written the version of their secret.
+	if stage == dbov1.ReadyStage && ts.ctr.DBAccount.Status.CurrentSecretName == "" && ts.ctr.Secret != nil &&
+		controller.GetSecretKV(ts.ctr.Secret, accountsvr.DatabaseKeyPassword) != "" {
+		name := controller.NewSecretVersion(ts.ctr.DBAccount, ts.ctr.Secret).GetName()
+		ts.ctr.DBAccount.Status.CurrentSecretName = name
+		ts.ctr.OriginalDBAccount.Status.CurrentSecretName = name
+	}
+
 	return ts
 }
 
+// wantSecretVersion returns the mod for the current secret version of the expected secret, the
+// expected secret is read when the account is compared.
+func (ts *testSet) wantSecretVersion(want *dbov1.DatabaseAccount) {
+	secret := ts.ctr.GetExpectSecret(ts.reconcileModSecret...)
+	want.Status.CurrentSecretName = controller.NewSecretVersion(want, &secret).GetName()
+}
+
+// wantPreviousSecretVersion returns the mod for the version the account started with being kept as
+// a previous version until deleteAt.
+func (ts *testSet) wantPreviousSecretVersion(deleteAt time.Time) controllertest.ReconcileModDBFunc {
+	return controllertest.ReconcileWantPreviousSecret(
+		ts.ctr.GetDatabaseAccountOriginal().Status.CurrentSecretName, deleteAt,
+	)
+}
+
 func newReconciler(_ *testing.T, _ time.Time) (
 	*v1test.MockClient,
 	*v1test.MockRecorder,
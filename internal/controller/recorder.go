@@ -87,5 +87,12 @@ const (
 	ReasonUserCreate     RecorderReason = "UserCreate"
 	ReasonDatabaseCreate RecorderReason = "DatabaseCreate"
 	ReasonRelayCreate    RecorderReason = "RelayCreate"
+	ReasonRelayUpdate    RecorderReason = "RelayUpdate"
 	ReasonReady          RecorderReason = "Ready"
+	ReasonServer         RecorderReason = "Server"
+	ReasonPasswordRotate RecorderReason = "PasswordRotate"
+	ReasonRoleSync       RecorderReason = "RoleSync"
+	ReasonSettingsSync   RecorderReason = "SettingsSync"
+	ReasonExtensionSync  RecorderReason = "ExtensionSync"
+	ReasonSchemaSync     RecorderReason = "SchemaSync"
 )
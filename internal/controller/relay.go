@@ -0,0 +1,586 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// relayLabels returns the labels of the relay resources, the StatefulSet selector uses them so they
+// can not be changed once the relay is created.
+func relayLabels(dbAccount *dbov1.DatabaseAccount) map[string]string {
+	return labels.Merge(dbAccount.Spec.SecretTemplate.Labels, map[string]string{
+		labelNamePartOf:  defaultPartOf,
+		databaseOwnerKey: dbAccount.GetStatefulSetName().Name,
+	})
+}
+
+// relayObjectMeta returns the metadata of the relay resources, they are controlled by the account.
+func relayObjectMeta(dbAccount *dbov1.DatabaseAccount) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:            dbAccount.GetStatefulSetName().Name,
+		Namespace:       dbAccount.Namespace,
+		Annotations:     dbAccount.Spec.SecretTemplate.Annotations,
+		Labels:          relayLabels(dbAccount),
+		OwnerReferences: []metav1.OwnerReference{*dbAccount.GetControllerReference()},
+	}
+}
+
+// accountRelayServer returns the database server the relay of the account connects to.
+func accountRelayServer(svr accountsvr.Server) RelayServer {
+	return RelayServer{Host: svr.GetDatabaseHostConfig(), Port: svr.GetDatabasePortConfig()}
+}
+
+// relayInstance is a relay deployment, either the relay of an account or the shared relay of a namespace.
+type relayInstance struct {
+	meta   metav1.ObjectMeta
+	relay  *dbov1.DatabaseAccountRelay
+	driver RelayDriver
+	volume corev1.Volume
+	// tlsVolumes and tlsMounts are the certificates mounted in the relay container.
+	tlsVolumes []corev1.Volume
+	tlsMounts  []corev1.VolumeMount
+}
+
+// accountRelayInstance returns the relay dedicated to the account, only the relay configuration is
+// mounted from the secret of the account so the relay pods do not get the password.
+func accountRelayInstance(
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	dbAccount *dbov1.DatabaseAccount,
+) relayInstance {
+	tlsVolumes, tlsMounts := relayTLSVolumes(dbAccount)
+	driver := RelayDriverFor(ctrlConfig.GetRelayType(dbAccount))
+
+	return relayInstance{
+		meta:   relayObjectMeta(dbAccount),
+		relay:  dbAccount.GetSpecRelay(),
+		driver: driver,
+		volume: corev1.Volume{
+			Name: dbAccount.GetSecretName().Name,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  dbAccount.GetSecretName().Name,
+					Items:       relaySecretItems(driver),
+					DefaultMode: ptr.To(corev1.SecretVolumeSourceDefaultMode),
+				},
+			},
+		},
+		tlsVolumes: tlsVolumes,
+		tlsMounts:  tlsMounts,
+	}
+}
+
+// relaySecretItems returns the keys of the relay configuration files mounted from a secret.
+func relaySecretItems(driver RelayDriver) []corev1.KeyToPath {
+	items := make([]corev1.KeyToPath, 0, len(driver.SecretKeys()))
+	for _, key := range driver.SecretKeys() {
+		items = append(items, corev1.KeyToPath{Key: key, Path: key})
+	}
+
+	return items
+}
+
+// RelayStatefulSet returns the desired StatefulSet of the relay pods.
+func RelayStatefulSet(
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	dbAccount *dbov1.DatabaseAccount,
+) *appsv1.StatefulSet {
+	return relayStatefulSet(ctrlConfig, accountRelayInstance(ctrlConfig, dbAccount))
+}
+
+// relayStatefulSet returns the desired StatefulSet of the relay instance.
+func relayStatefulSet(ctrlConfig *dbov1.DatabaseAccountControllerConfig, instance relayInstance) *appsv1.StatefulSet {
+	relay := instance.relay
+	driver := instance.driver
+
+	podSpec := corev1.PodSpec{
+		Containers: []corev1.Container{
+			driver.Container(instance.meta.Name, ctrlConfig.GetRelayImageFor(driver.Type()), instance.volume.Name),
+		},
+		Volumes: []corev1.Volume{instance.volume},
+	}
+
+	podSpec.Volumes = append(podSpec.Volumes, instance.tlsVolumes...)
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, instance.tlsMounts...)
+
+	if relay != nil {
+		if relay.Resources != nil {
+			podSpec.Containers[0].Resources = *relay.Resources
+		}
+		podSpec.NodeSelector = relay.NodeSelector
+		podSpec.Tolerations = relay.Tolerations
+		podSpec.Affinity = relay.Affinity
+		podSpec.TopologySpreadConstraints = relay.TopologySpreadConstraints
+		podSpec.ImagePullSecrets = relay.ImagePullSecrets
+	}
+
+	return &appsv1.StatefulSet{
+		ObjectMeta: instance.meta,
+		Spec: appsv1.StatefulSetSpec{
+			Replicas: ptr.To(relay.GetReplicas()),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: maps.Clone(instance.meta.Labels),
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        instance.meta.Name,
+					Namespace:   instance.meta.Namespace,
+					Annotations: maps.Clone(instance.meta.Annotations),
+					Labels:      maps.Clone(instance.meta.Labels),
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+}
+
+// relayMountedSecret is a secret mounted in the relay pods and the keys the relay reads from it.
+type relayMountedSecret struct {
+	name types.NamespacedName
+	keys []string
+}
+
+// RelayConfigChecksum returns a checksum of the configuration and certificates mounted in the relay
+// pods, secrets that do not exist yet are skipped.
+func RelayConfigChecksum(
+	ctx context.Context,
+	r client.Reader,
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	dbAccount *dbov1.DatabaseAccount,
+) (string, error) {
+	mounted := []relayMountedSecret{
+		{dbAccount.GetSecretName(), RelayDriverFor(ctrlConfig.GetRelayType(dbAccount)).SecretKeys()},
+	}
+
+	tls := dbAccount.GetSpecRelay().GetTLS()
+	if tls.GetClient() != nil {
+		mounted = append(mounted, relayMountedSecret{
+			relayTLSSecretName(dbAccount), []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey},
+		})
+	}
+
+	if ref := tls.GetServer().GetCASecretRef(); ref != nil {
+		mounted = append(mounted, relayMountedSecret{
+			types.NamespacedName{Namespace: dbAccount.Namespace, Name: ref.Name}, []string{ref.Key},
+		})
+	}
+
+	hash := sha256.New()
+	for _, v := range mounted {
+		secret, err := SecretGetByName(ctx, r, v.name)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+
+		for _, key := range v.keys {
+			fmt.Fprintf(hash, "%s/%s=%s\n", v.name.Name, key, GetSecretKV(secret, key))
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// setRelayConfigChecksum sets the configuration checksum on the pod template, changing the checksum
+// rolls the relay pods.
+func setRelayConfigChecksum(statefulSet *appsv1.StatefulSet, checksum string) {
+	if statefulSet.Spec.Template.Annotations == nil {
+		statefulSet.Spec.Template.Annotations = map[string]string{}
+	}
+	statefulSet.Spec.Template.Annotations[relayConfigChecksumAnnotation] = checksum
+}
+
+// RelayService returns the desired Service of the relay pods.
+func RelayService(dbAccount *dbov1.DatabaseAccount) *corev1.Service {
+	return relayService(relayObjectMeta(dbAccount))
+}
+
+// relayService returns the desired Service of the relay with the metadata.
+func relayService(meta metav1.ObjectMeta) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: meta,
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:       defaultPostgresqlPortName,
+					Port:       defaultPostgresqlPort,
+					TargetPort: intstr.FromString(defaultPostgresqlPortName),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+			Selector: map[string]string{
+				databaseOwnerKey: meta.Name,
+			},
+		},
+	}
+}
+
+// RelayPodDisruptionBudget returns the desired PodDisruptionBudget of the relay pods.
+func RelayPodDisruptionBudget(dbAccount *dbov1.DatabaseAccount) *policyv1.PodDisruptionBudget {
+	return relayPodDisruptionBudget(relayObjectMeta(dbAccount))
+}
+
+// relayPodDisruptionBudget returns the desired PodDisruptionBudget of the relay with the metadata.
+func relayPodDisruptionBudget(meta metav1.ObjectMeta) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: meta,
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: ptr.To(intstr.FromInt32(1)),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: maps.Clone(meta.Labels),
+			},
+		},
+	}
+}
+
+// mutateObjectMeta adds the desired labels, annotations and owner to the object, labels and
+// annotations added by others are kept.
+func mutateObjectMeta(current, desired *metav1.ObjectMeta) {
+	current.Labels = labels.Merge(current.Labels, desired.Labels)
+	current.Annotations = labels.Merge(current.Annotations, desired.Annotations)
+	current.OwnerReferences = desired.OwnerReferences
+}
+
+// MutateRelayStatefulSet patches the fields of the StatefulSet that have drifted from the desired
+// StatefulSet, fields defaulted by the API server and the immutable selector are not changed.
+func MutateRelayStatefulSet(current, desired *appsv1.StatefulSet) {
+	if current.CreationTimestamp.IsZero() && current.ResourceVersion == "" {
+		desired.Spec.DeepCopyInto(&current.Spec)
+	}
+
+	mutateObjectMeta(&current.ObjectMeta, &desired.ObjectMeta)
+	current.Spec.Replicas = desired.Spec.Replicas
+
+	template := &current.Spec.Template
+	template.Labels = labels.Merge(template.Labels, desired.Spec.Template.Labels)
+	template.Annotations = labels.Merge(template.Annotations, desired.Spec.Template.Annotations)
+
+	want := desired.Spec.Template.Spec
+	spec := &template.Spec
+	spec.NodeSelector = want.NodeSelector
+	spec.Tolerations = want.Tolerations
+	spec.Affinity = want.Affinity
+	spec.TopologySpreadConstraints = want.TopologySpreadConstraints
+	spec.ImagePullSecrets = want.ImagePullSecrets
+	spec.Volumes = want.Volumes
+
+	for _, wantContainer := range want.Containers {
+		idx := -1
+		for i := range spec.Containers {
+			if spec.Containers[i].Name == wantContainer.Name {
+				idx = i
+			}
+		}
+
+		if idx == -1 {
+			spec.Containers = append(spec.Containers, wantContainer)
+
+			continue
+		}
+
+		container := &spec.Containers[idx]
+		container.Image = wantContainer.Image
+		container.Command = wantContainer.Command
+		container.Args = wantContainer.Args
+		container.ImagePullPolicy = wantContainer.ImagePullPolicy
+		container.Ports = wantContainer.Ports
+		container.VolumeMounts = wantContainer.VolumeMounts
+		container.Resources = wantContainer.Resources
+		container.ReadinessProbe = wantContainer.ReadinessProbe
+		container.LivenessProbe = wantContainer.LivenessProbe
+		container.SecurityContext = wantContainer.SecurityContext
+	}
+}
+
+// MutateRelayService patches the ports and selector of the Service, the cluster IP and other fields
+// defaulted by the API server are not changed.
+func MutateRelayService(current, desired *corev1.Service) {
+	mutateObjectMeta(&current.ObjectMeta, &desired.ObjectMeta)
+	current.Spec.Ports = desired.Spec.Ports
+	current.Spec.Selector = desired.Spec.Selector
+}
+
+// MutateRelayPodDisruptionBudget patches the PodDisruptionBudget to the desired state.
+func MutateRelayPodDisruptionBudget(current, desired *policyv1.PodDisruptionBudget) {
+	mutateObjectMeta(&current.ObjectMeta, &desired.ObjectMeta)
+	current.Spec.MaxUnavailable = desired.Spec.MaxUnavailable
+	current.Spec.MinAvailable = nil
+	current.Spec.Selector = desired.Spec.Selector
+}
+
+// reconcileRelay creates the relay resources that are missing and patches the resources that have
+// drifted from the desired state, an event is recorded for each resource that is changed.
+func (r *DatabaseAccountReconciler) reconcileRelay(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) error {
+	// the NetworkPolicy is applied first so new relay pods are never reachable by other clients.
+	if err := r.applyRelayNetworkPolicy(ctx, dbAccount, relayObjectMeta(dbAccount),
+		dbAccount.GetSpecRelay().GetAllowedClients(), accountRelayServer(svr)); err != nil {
+		return err
+	}
+
+	checksum, err := RelayConfigChecksum(ctx, r, r.Config, dbAccount)
+	if err != nil {
+		return err
+	}
+
+	desiredStatefulSet := RelayStatefulSet(r.Config, dbAccount)
+	setRelayConfigChecksum(desiredStatefulSet, checksum)
+
+	return r.applyRelay(ctx, dbAccount, desiredStatefulSet, RelayService(dbAccount),
+		RelayPodDisruptionBudget(dbAccount))
+}
+
+// deleteAccountRelay deletes the relay resources of the account and the relay certificate generated by
+// the operator, a certificate referenced by the spec is not deleted.
+func (r *DatabaseAccountReconciler) deleteAccountRelay(ctx context.Context, dbAccount *dbov1.DatabaseAccount) error {
+	meta := relayObjectMeta(dbAccount)
+	objs := []client.Object{
+		&appsv1.StatefulSet{ObjectMeta: meta},
+		&corev1.Service{ObjectMeta: meta},
+		&policyv1.PodDisruptionBudget{ObjectMeta: meta},
+		&networkingv1.NetworkPolicy{ObjectMeta: meta},
+	}
+
+	tlsSecret := &corev1.Secret{}
+	if err := r.Get(ctx, generatedRelayTLSSecretName(dbAccount), tlsSecret); err == nil &&
+		metav1.IsControlledBy(tlsSecret, dbAccount) {
+		objs = append(objs, tlsSecret)
+	} else if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	r.Recorder.NormalEvent(dbAccount, ReasonRelayUpdate, "Deleted relay")
+
+	return nil
+}
+
+// applyRelay creates or patches the relay resources to the desired state, the PodDisruptionBudget is
+// removed when the StatefulSet has a single replica. Events are recorded on the account.
+func (r *DatabaseAccountReconciler) applyRelay(
+	ctx context.Context,
+	dbAccount *dbov1.DatabaseAccount,
+	desiredStatefulSet *appsv1.StatefulSet,
+	desiredService *corev1.Service,
+	desiredPDB *policyv1.PodDisruptionBudget,
+) error {
+	logger := log.FromContext(ctx)
+
+	statefulSet := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+		Name: desiredStatefulSet.Name, Namespace: desiredStatefulSet.Namespace,
+	}}
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+		Name: desiredService.Name, Namespace: desiredService.Namespace,
+	}}
+
+	relayObjects := []struct {
+		kind   string
+		obj    client.Object
+		mutate controllerutil.MutateFn
+	}{
+		{"StatefulSet", statefulSet, func() error {
+			MutateRelayStatefulSet(statefulSet, desiredStatefulSet)
+
+			return nil
+		}},
+		{"Service", service, func() error {
+			MutateRelayService(service, desiredService)
+
+			return nil
+		}},
+	}
+
+	for _, v := range relayObjects {
+		result, err := controllerutil.CreateOrUpdate(ctx, r.Client, v.obj, v.mutate)
+		if err != nil {
+			logger.V(1).Error(err, "Unable to reconcile relay", "kind", v.kind)
+
+			return err
+		}
+
+		if result != controllerutil.OperationResultNone {
+			logger.Info("Relay drift corrected", "kind", v.kind, "result", result)
+			r.Recorder.NormalEvent(dbAccount, ReasonRelayUpdate, fmt.Sprintf("Relay %s %s", v.kind, result))
+		}
+	}
+
+	pdb := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{
+		Name: desiredPDB.Name, Namespace: desiredPDB.Namespace,
+	}}
+
+	if ptr.Deref(desiredStatefulSet.Spec.Replicas, 1) <= 1 {
+		// a single replica can not be disrupted without downtime, the budget would block node drains.
+		if err := r.Delete(ctx, pdb); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		} else if err == nil {
+			r.Recorder.NormalEvent(dbAccount, ReasonRelayUpdate, "Relay PodDisruptionBudget deleted")
+		}
+
+		return nil
+	}
+
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, pdb, func() error {
+		MutateRelayPodDisruptionBudget(pdb, desiredPDB)
+
+		return nil
+	})
+	if err != nil {
+		logger.V(1).Error(err, "Unable to reconcile relay", "kind", "PodDisruptionBudget")
+
+		return err
+	}
+
+	if result != controllerutil.OperationResultNone {
+		r.Recorder.NormalEvent(dbAccount, ReasonRelayUpdate, fmt.Sprintf("Relay PodDisruptionBudget %s", result))
+	}
+
+	return nil
+}
+
+// relayPodFailureReasons are the container waiting reasons that mean the relay pods will not become
+// ready without a change to the spec or the cluster.
+var relayPodFailureReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// RelayStatus is the observed state of the relay pods.
+type RelayStatus struct {
+	Ready   bool
+	Reason  string
+	Message string
+}
+
+// RelayStatusGet returns the relay as ready once the StatefulSet has all its replicas ready and the
+// Service has a ready endpoint, otherwise the relay is progressing or degraded when a pod is failing.
+func RelayStatusGet(ctx context.Context, r client.Reader, dbAccount *dbov1.DatabaseAccount) (RelayStatus, error) {
+	if dbAccount.GetSpecRelay().IsShared() {
+		return relayStatusGet(ctx, r, dbAccount.GetRelayName(), sharedRelayLabels())
+	}
+
+	return relayStatusGet(ctx, r, dbAccount.GetStatefulSetName(), relayLabels(dbAccount))
+}
+
+// relayStatusGet returns the status of the relay StatefulSet and Service with the name, the pods are
+// selected with the labels.
+func relayStatusGet(
+	ctx context.Context,
+	r client.Reader,
+	name types.NamespacedName,
+	podLabels map[string]string,
+) (RelayStatus, error) {
+	statefulSet := &appsv1.StatefulSet{}
+	if err := r.Get(ctx, name, statefulSet); apierrors.IsNotFound(err) {
+		return relayProgressing("Waiting for StatefulSet to be created"), nil
+	} else if err != nil {
+		return RelayStatus{}, err
+	}
+
+	replicas := ptr.Deref(statefulSet.Spec.Replicas, 1)
+	if statefulSet.Status.ObservedGeneration < statefulSet.Generation ||
+		statefulSet.Status.ReadyReplicas < replicas {
+		return relayPodStatusGet(ctx, r, name.Namespace, podLabels, fmt.Sprintf(
+			"Waiting for relay pods to be ready (%d/%d)", statefulSet.Status.ReadyReplicas, replicas,
+		))
+	}
+
+	// the pods of the previous revision are ready while a rollout has not completed.
+	if statefulSet.Status.UpdatedReplicas < replicas ||
+		statefulSet.Status.CurrentRevision != statefulSet.Status.UpdateRevision {
+		return relayPodStatusGet(ctx, r, name.Namespace, podLabels, fmt.Sprintf(
+			"Waiting for relay pods to be updated (%d/%d)", statefulSet.Status.UpdatedReplicas, replicas,
+		))
+	}
+
+	endpointSlices := &discoveryv1.EndpointSliceList{}
+	if err := r.List(ctx, endpointSlices,
+		client.InNamespace(name.Namespace),
+		client.MatchingLabels{discoveryv1.LabelServiceName: name.Name},
+	); err != nil {
+		return RelayStatus{}, err
+	}
+
+	for _, slice := range endpointSlices.Items {
+		for _, endpoint := range slice.Endpoints {
+			if ptr.Deref(endpoint.Conditions.Ready, true) {
+				return RelayStatus{Ready: true, Reason: dbov1.ConditionReasonAvailable, Message: "Relay is serving"}, nil
+			}
+		}
+	}
+
+	return relayProgressing("Waiting for Service endpoints to be ready"), nil
+}
+
+// relayPodStatusGet returns the relay as degraded with the reason of the first failing container of the
+// relay pods, otherwise the relay is progressing with the message.
+func relayPodStatusGet(
+	ctx context.Context,
+	r client.Reader,
+	namespace string,
+	podLabels map[string]string,
+	progressing string,
+) (RelayStatus, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods,
+		client.InNamespace(namespace),
+		client.MatchingLabels(podLabels),
+	); err != nil {
+		return RelayStatus{}, err
+	}
+
+	for _, pod := range pods.Items {
+		statuses := append(slices.Clone(pod.Status.InitContainerStatuses), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if status.State.Waiting == nil || !relayPodFailureReasons[status.State.Waiting.Reason] {
+				continue
+			}
+
+			message := fmt.Sprintf("Pod %s: %s", pod.Name, status.State.Waiting.Reason)
+			if status.State.Waiting.Message != "" {
+				message = fmt.Sprintf("%s: %s", message, status.State.Waiting.Message)
+			}
+
+			return RelayStatus{Reason: dbov1.ConditionReasonRelayDegraded, Message: message}, nil
+		}
+	}
+
+	return relayProgressing(progressing), nil
+}
+
+func relayProgressing(message string) RelayStatus {
+	return RelayStatus{Reason: dbov1.ConditionReasonRelayProgressing, Message: message}
+}
@@ -0,0 +1,278 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	"github.com/dosquad/database-operator/internal/helper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+// relayAdminName is the admin user of the relays, the password is generated and stored with its
+// verifier in the secret holding the relay configuration.
+const relayAdminName = "dbo_relay_admin"
+
+// RelayDriver generates the configuration and container of a connection pooler used for relays.
+type RelayDriver interface {
+	// Type returns the relay type of the driver.
+	Type() dbov1.RelayType
+
+	// SecretKeys returns the keys of the configuration files in the secret mounted in the relay pods.
+	SecretKeys() []string
+
+	// PlaintextPassword returns true if the relay needs the password of the users, otherwise only the
+	// SCRAM-SHA-256 verifiers are used.
+	PlaintextPassword() bool
+
+	// Config returns the configuration files of the relay by secret key.
+	Config(conf RelayConfig) (map[string]string, error)
+
+	// Container returns the relay container with the configuration mounted from the volume.
+	Container(name, image, volume string) corev1.Container
+
+	// Probe returns the health probe of the relay container.
+	Probe() *corev1.Probe
+}
+
+// RelayConfig is the configuration of a relay independent of the connection pooler.
+type RelayConfig struct {
+	Databases []RelayDatabase
+	Users     []RelayUser
+
+	// Admin is the user allowed to use the admin console of the relay.
+	Admin RelayUser
+
+	Settings  dbov1.RelaySettings
+	ClientTLS *dbov1.RelayClientTLS
+	ServerTLS *dbov1.RelayServerTLS
+}
+
+// RelayDatabase is a database served by the relay.
+type RelayDatabase struct {
+	// Name is the database clients connect to, empty is any database.
+	Name string `json:"name,omitempty"`
+
+	Host string `json:"host"`
+	Port uint16 `json:"port"`
+
+	// DBName is the database on the server.
+	DBName string `json:"dbname"`
+
+	// User is the role the relay logs in to the server with, empty uses the role of the client.
+	User string `json:"user,omitempty"`
+
+	// Users are the users that connect to the database, for relays that configure users for each
+	// database.
+	Users []string `json:"users,omitempty"`
+
+	// Settings are the pool settings of the database, settings that are not set use the relay settings.
+	Settings dbov1.RelaySettings `json:"settings,omitempty"`
+}
+
+// RelayUser is a user that can log in to the relay.
+type RelayUser struct {
+	Name string `json:"name"`
+
+	// Password is only set for relays that need the plaintext password.
+	Password string `json:"password,omitempty"`
+
+	// Verifier is the SCRAM-SHA-256 verifier of the password.
+	Verifier string `json:"verifier"`
+}
+
+// relayUserGet returns the user with the name, false is returned if the user is not in the list.
+func relayUserGet(users []RelayUser, name string) (RelayUser, bool) {
+	for _, user := range users {
+		if user.Name == name {
+			return user, true
+		}
+	}
+
+	return RelayUser{}, false
+}
+
+// relayDrivers are the drivers of the relay types.
+var relayDrivers = map[dbov1.RelayType]RelayDriver{
+	dbov1.RelayTypePgBouncer: pgBouncerDriver{},
+	dbov1.RelayTypePgCat:     pgCatDriver{},
+	dbov1.RelayTypeOdyssey:   odysseyDriver{},
+}
+
+// RelayDriverFor returns the driver of the relay type, the PgBouncer driver is returned for an unknown
+// type as the relay type is validated when the account is created.
+func RelayDriverFor(relayType dbov1.RelayType) RelayDriver {
+	if driver, ok := relayDrivers[relayType]; ok {
+		return driver
+	}
+
+	return relayDrivers[dbov1.RelayTypePgBouncer]
+}
+
+// relayConfigKeys returns the secret keys of the configuration files of every relay type.
+func relayConfigKeys() []string {
+	return []string{
+		accountsvr.DatabaseKeyPGBouncerConf,
+		accountsvr.DatabaseKeyPGBouncerUsers,
+		accountsvr.DatabaseKeyPgCatConf,
+		accountsvr.DatabaseKeyOdysseyConf,
+	}
+}
+
+// setSecretRelayFiles replaces the relay configuration files in the secret.
+func setSecretRelayFiles(secret *corev1.Secret, files map[string]string) {
+	for _, key := range relayConfigKeys() {
+		delete(secret.Data, key)
+	}
+
+	for key, value := range files {
+		SetSecretKV(secret, key, value)
+	}
+}
+
+// relayAdminFromSecret returns the admin user of the relay, the password and verifier are generated if
+// the secret does not have them in the keys yet.
+func relayAdminFromSecret(
+	ctx context.Context,
+	secret *corev1.Secret,
+	passwordKey, verifierKey string,
+) (RelayUser, error) {
+	password := GetSecretKV(secret, passwordKey)
+	if password == "" {
+		password = helper.GeneratePassword(ctx)
+		SetSecretKV(secret, passwordKey, password)
+	}
+
+	verifier := GetSecretKV(secret, verifierKey)
+	if !helper.ScramSHA256VerifierMatches(verifier, password) {
+		var err error
+		if verifier, err = helper.ScramSHA256Verifier(password); err != nil {
+			return RelayUser{}, err
+		}
+		SetSecretKV(secret, verifierKey, verifier)
+	}
+
+	return RelayUser{Name: relayAdminName, Password: password, Verifier: verifier}, nil
+}
+
+// relayUserFromSecret returns the relay user of the credentials in the secret, the verifier stored
+// with the credentials must match the password.
+func relayUserFromSecret(secret *corev1.Secret) (RelayUser, error) {
+	user := RelayUser{
+		Name:     GetSecretKV(secret, accountsvr.DatabaseKeyUsername),
+		Password: GetSecretKV(secret, accountsvr.DatabaseKeyPassword),
+		Verifier: GetSecretKV(secret, accountsvr.DatabaseKeyVerifier),
+	}
+
+	if !helper.ScramSHA256VerifierMatches(user.Verifier, user.Password) {
+		return user, fmt.Errorf("%w: %s", ErrMissingSecretVerifier, user.Name)
+	}
+
+	return user, nil
+}
+
+// accountRelayConfig returns the configuration of the relay dedicated to the account, the relay serves
+// any database and logs in to the server as the client user. The admin of the relay is a generated
+// user so the account user has no access to the admin console.
+func accountRelayConfig(
+	ctx context.Context,
+	accountSvr accountsvr.Server,
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	dbAccount *dbov1.DatabaseAccount,
+	secret *corev1.Secret,
+) (RelayConfig, error) {
+	user, err := relayUserFromSecret(secret)
+	if user.Name == "" || user.Password == "" {
+		return RelayConfig{}, fmt.Errorf("%w: %s", ErrMissingSecretCredentials, dbAccount.GetSecretName())
+	} else if err != nil {
+		return RelayConfig{}, err
+	}
+
+	admin, err := relayAdminFromSecret(
+		ctx, secret, accountsvr.DatabaseKeyRelayAdminPassword, accountsvr.DatabaseKeyRelayAdminVerifier,
+	)
+	if err != nil {
+		return RelayConfig{}, err
+	}
+
+	tls := dbAccount.GetSpecRelay().GetTLS()
+
+	return RelayConfig{
+		Databases: []RelayDatabase{
+			{
+				Host:   accountSvr.GetDatabaseHostConfig(),
+				Port:   accountSvr.GetDatabasePortConfig(),
+				DBName: GetSecretKV(secret, accountsvr.DatabaseKeyDatabase),
+				Users:  []string{user.Name},
+			},
+		},
+		Users:     []RelayUser{user},
+		Admin:     admin,
+		Settings:  ctrlConfig.GetRelaySettings(dbAccount),
+		ClientTLS: tls.GetClient(),
+		ServerTLS: tls.GetServer(),
+	}, nil
+}
+
+// relayTCPProbe returns a probe connecting to the relay port, the defaults are set so the desired
+// state matches what the API server stores.
+func relayTCPProbe() *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{
+				Port: intstr.FromString(defaultPostgresqlPortName),
+			},
+		},
+		PeriodSeconds:    10,
+		TimeoutSeconds:   1,
+		SuccessThreshold: 1,
+		FailureThreshold: 3,
+	}
+}
+
+// relayContainer returns the relay container listening on the postgresql port with the volume mounted
+// at the path.
+func relayContainer(name, image, volume, mountPath string, probe *corev1.Probe) corev1.Container {
+	return corev1.Container{
+		Image: image,
+		Name:  name,
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          defaultPostgresqlPortName,
+				ContainerPort: defaultPostgresqlPort,
+				Protocol:      corev1.ProtocolTCP,
+			},
+		},
+		ImagePullPolicy: "IfNotPresent",
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      volume,
+				MountPath: mountPath,
+			},
+		},
+		ReadinessProbe: probe,
+		LivenessProbe:  probe.DeepCopy(),
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: ptr.To(false),
+			Capabilities: &corev1.Capabilities{
+				Drop: []corev1.Capability{"ALL"},
+			},
+			SeccompProfile: &corev1.SeccompProfile{
+				Type: corev1.SeccompProfileTypeRuntimeDefault,
+			},
+		},
+	}
+}
+
+// relayDatabaseName returns the name clients use for the database, the server database is used by
+// relays that can not serve any database.
+func relayDatabaseName(db RelayDatabase) string {
+	if db.Name == "" {
+		return db.DBName
+	}
+
+	return db.Name
+}
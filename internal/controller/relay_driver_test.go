@@ -0,0 +1,131 @@
+package controller_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	accountsvrtest "github.com/dosquad/database-operator/accountsvr/test"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	v1test "github.com/dosquad/database-operator/api/v1/test"
+	"github.com/dosquad/database-operator/internal/controller"
+	"github.com/dosquad/database-operator/internal/helper"
+	"github.com/dosquad/database-operator/internal/testhelp"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestAddRelayConf_Type(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		relayType dbov1.RelayType
+		key       string
+		expect    []string
+		command   []string
+		mountPath string
+	}{
+		{
+			"PgCat",
+			dbov1.RelayTypePgCat,
+			accountsvr.DatabaseKeyPgCatConf,
+			[]string{
+				"port = 5432\n",
+				"admin_username = \"dbo_relay_admin\"\n",
+				"[pools.\"k8s_db\"]\npool_mode = \"session\"\n",
+				"username = \"dbuser\"\npassword = \"dbpass\"\n",
+				"servers = [[\"databasehost\", 6543, \"primary\"]]\ndatabase = \"k8s_db\"\n",
+			},
+			[]string{"pgcat", "/etc/pgcat/pgcat.toml"},
+			"/etc/pgcat/",
+		},
+		{
+			"Odyssey",
+			dbov1.RelayTypeOdyssey,
+			accountsvr.DatabaseKeyOdysseyConf,
+			[]string{
+				"listen {\n\thost \"*\"\n\tport 5432\n}\n",
+				"storage \"server_0\" {\n\ttype \"remote\"\n\thost \"databasehost\"\n\tport 6543\n}\n",
+				"database default {\n\tuser \"dbuser\" {\n",
+				"\t\tpassword \"dbpass\"\n",
+				"\t\tstorage_user \"dbuser\"\n\t\tstorage_password \"dbpass\"\n",
+				"database \"console\" {\n\tuser \"dbo_relay_admin\" {\n",
+			},
+			[]string{"odyssey", "/etc/odyssey/odyssey.conf"},
+			"/etc/odyssey/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start := time.Now()
+
+			svr := accountsvrtest.NewMockServer(accountsvrtest.TestDSN)
+			svr.OnGetDatabasePortConfig = func() uint16 { return 6543 }
+			ctrlConfig := &dbov1.DatabaseAccountControllerConfig{
+				RelayImages: map[dbov1.RelayType]string{tt.relayType: "relay:" + tt.relayType.String()},
+			}
+			dbAccount := v1test.NewDatabaseAccount()
+			dbAccount.Spec.Relay = &dbov1.DatabaseAccountRelay{Type: tt.relayType}
+			verifier, _ := helper.ScramSHA256Verifier("dbpass")
+			secret := &corev1.Secret{Data: map[string][]byte{
+				accountsvr.DatabaseKeyUsername:      []byte("dbuser"),
+				accountsvr.DatabaseKeyPassword:      []byte("dbpass"),
+				accountsvr.DatabaseKeyVerifier:      []byte(verifier),
+				accountsvr.DatabaseKeyDatabase:      []byte("k8s_db"),
+				accountsvr.DatabaseKeyPGBouncerConf: []byte("[databases]"),
+			}}
+
+			if err := controller.AddRelayConf(t.Context(), svr, ctrlConfig, &dbAccount, secret); err != nil {
+				testhelp.Errorf(t, start, "controller.AddRelayConf(): error, got '%v', want 'nil'", err)
+			}
+
+			conf := controller.GetSecretKV(secret, tt.key)
+			for _, line := range tt.expect {
+				if !strings.Contains(conf, line) {
+					testhelp.Errorf(t, start, "controller.AddRelayConf(): missing '%s' in:\n%s",
+						strings.TrimSpace(line), conf)
+				}
+			}
+
+			if _, ok := secret.Data[accountsvr.DatabaseKeyPGBouncerConf]; ok {
+				testhelp.Errorf(t, start, "controller.AddRelayConf(): %s not removed",
+					accountsvr.DatabaseKeyPGBouncerConf)
+			}
+
+			container := controller.RelayStatefulSet(ctrlConfig, &dbAccount).Spec.Template.Spec.Containers[0]
+			if expect := "relay:" + tt.relayType.String(); container.Image != expect {
+				testhelp.Errorf(t, start, "controller.RelayStatefulSet(): image, got '%s', want '%s'",
+					container.Image, expect)
+			}
+
+			if diff := cmp.Diff(container.Command, tt.command); diff != "" {
+				testhelp.Errorf(t, start, "controller.RelayStatefulSet(): command -got +want:\n%s", diff)
+			}
+
+			if v := container.VolumeMounts[0].MountPath; v != tt.mountPath {
+				testhelp.Errorf(t, start, "controller.RelayStatefulSet(): mount path, got '%s', want '%s'",
+					v, tt.mountPath)
+			}
+		})
+	}
+}
+
+func TestRelayDriverFor(t *testing.T) {
+	t.Parallel()
+
+	for _, relayType := range []dbov1.RelayType{
+		dbov1.RelayTypePgBouncer, dbov1.RelayTypePgCat, dbov1.RelayTypeOdyssey,
+	} {
+		if v := controller.RelayDriverFor(relayType).Type(); v != relayType {
+			t.Errorf("controller.RelayDriverFor(%s): got '%s'", relayType, v)
+		}
+	}
+
+	if v := controller.RelayDriverFor("unknown").Type(); v != dbov1.RelayTypePgBouncer {
+		t.Errorf("controller.RelayDriverFor(unknown): got '%s', want '%s'", v, dbov1.RelayTypePgBouncer)
+	}
+}
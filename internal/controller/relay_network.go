@@ -0,0 +1,353 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// dnsPort is the port of the cluster DNS the relay resolves the database server with.
+	dnsPort = 53
+
+	// dnsPodLabel and dnsPodLabelValue select the cluster DNS pods.
+	dnsPodLabel      = "k8s-app"
+	dnsPodLabelValue = "kube-dns"
+)
+
+// RelayServer is a database server the relay connects to.
+type RelayServer struct {
+	Host string
+	Port uint16
+}
+
+// relayHostCIDRs returns the addresses of the host as CIDRs of a single address, true is returned if
+// the host is a name that the relay resolves with the cluster DNS.
+func relayHostCIDRs(ctx context.Context, host string) ([]string, bool, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{relayIPCIDR(ip)}, false, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, true, fmt.Errorf("%w %s: %w", ErrRelayHostUnresolved, host, err)
+	}
+
+	cidrs := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		cidrs = append(cidrs, relayIPCIDR(addr.IP))
+	}
+
+	return cidrs, true, nil
+}
+
+// relayIPCIDR returns the CIDR of the single address.
+func relayIPCIDR(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.String() + "/32"
+	}
+
+	return ip.String() + "/128"
+}
+
+// relayServiceName returns the Service of a host in the form <service>.<namespace>.svc with an
+// optional cluster domain, false is returned if the host is not a cluster Service name.
+func relayServiceName(host string) (types.NamespacedName, bool) {
+	parts := strings.Split(strings.TrimSuffix(host, "."), ".")
+	if len(parts) < 3 || parts[2] != "svc" || parts[0] == "" || parts[1] == "" {
+		return types.NamespacedName{}, false
+	}
+
+	return types.NamespacedName{Namespace: parts[1], Name: parts[0]}, true
+}
+
+// relayServiceRule returns the egress rule allowing the relay to connect to the pods of the Service
+// the server host names, NetworkPolicies match the pod addresses so the address of the Service can
+// not be used. False is returned if the Service is not found or has no selector.
+func relayServiceRule(
+	ctx context.Context,
+	r client.Reader,
+	server RelayServer,
+) (networkingv1.NetworkPolicyEgressRule, bool, error) {
+	name, ok := relayServiceName(server.Host)
+	if !ok {
+		return networkingv1.NetworkPolicyEgressRule{}, false, nil
+	}
+
+	svc := &corev1.Service{}
+	if err := r.Get(ctx, name, svc); err != nil {
+		return networkingv1.NetworkPolicyEgressRule{}, false, client.IgnoreNotFound(err)
+	}
+
+	if len(svc.Spec.Selector) == 0 {
+		return networkingv1.NetworkPolicyEgressRule{}, false, nil
+	}
+
+	port := intstr.FromInt32(int32(server.Port))
+	for _, svcPort := range svc.Spec.Ports {
+		if svcPort.Port == int32(server.Port) && svcPort.TargetPort != (intstr.IntOrString{}) {
+			port = svcPort.TargetPort
+		}
+	}
+
+	return networkingv1.NetworkPolicyEgressRule{
+		Ports: []networkingv1.NetworkPolicyPort{{Protocol: ptr.To(corev1.ProtocolTCP), Port: &port}},
+		To: []networkingv1.NetworkPolicyPeer{
+			{
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{corev1.LabelMetadataName: name.Namespace},
+				},
+				PodSelector: &metav1.LabelSelector{MatchLabels: maps.Clone(svc.Spec.Selector)},
+			},
+		},
+	}, true, nil
+}
+
+// RelayEgressRules returns the egress rules allowing the relay to connect to the database servers,
+// the cluster DNS is allowed when a server is a host name. A host that is a cluster Service name with a
+// selector is matched by the pods of the Service, other host names are resolved and the addresses
+// are sorted so the rules are stable while the DNS records do not change. The rules of the servers
+// that could be resolved are returned with an ErrRelayHostUnresolved error for the others.
+func RelayEgressRules(
+	ctx context.Context,
+	r client.Reader,
+	servers ...RelayServer,
+) ([]networkingv1.NetworkPolicyEgressRule, error) {
+	var (
+		rules []networkingv1.NetworkPolicyEgressRule
+		errs  []error
+		dns   bool
+	)
+
+	for _, server := range servers {
+		rule, ok, err := relayServiceRule(ctx, r, server)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			dns = true
+			rules = append(rules, rule)
+
+			continue
+		}
+
+		cidrs, resolved, err := relayHostCIDRs(ctx, server.Host)
+		dns = dns || resolved
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		slices.Sort(cidrs)
+
+		rule = networkingv1.NetworkPolicyEgressRule{
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(int32(server.Port)))},
+			},
+		}
+		for _, cidr := range slices.Compact(cidrs) {
+			rule.To = append(rule.To, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+		}
+
+		rules = append(rules, rule)
+	}
+
+	if dns {
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+			To: []networkingv1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: &metav1.LabelSelector{},
+					PodSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{dnsPodLabel: dnsPodLabelValue},
+					},
+				},
+			},
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: ptr.To(corev1.ProtocolUDP), Port: ptr.To(intstr.FromInt32(dnsPort))},
+				{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(dnsPort))},
+			},
+		})
+	}
+
+	return rules, errors.Join(errs...)
+}
+
+// relayResolveAfter returns the time until the relay NetworkPolicy of the account is reconciled
+// again to resolve the database host, zero if the policy does not have rules for a host name. The
+// shared relay is resolved again by each of its accounts for the database server of the account.
+func (r *DatabaseAccountReconciler) relayResolveAfter(
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) time.Duration {
+	clients := dbAccount.GetSpecRelay().GetAllowedClients()
+	if dbAccount.GetSpecRelay().IsShared() {
+		clients = r.Config.GetSharedRelay().GetAllowedClients()
+	}
+
+	if !relayEgressResolves(r.Config, clients, accountRelayServer(svr)) {
+		return 0
+	}
+
+	return r.Config.GetRelayResolveInterval()
+}
+
+// relayEgressResolves returns true if the relay NetworkPolicy has egress rules for a server host
+// name, the rules are updated periodically as the addresses of the host can change.
+func relayEgressResolves(
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	clients []dbov1.RelayAllowedClient,
+	servers ...RelayServer,
+) bool {
+	if len(clients) == 0 || ctrlConfig.RelayEgress != nil {
+		return false
+	}
+
+	return slices.ContainsFunc(servers, func(server RelayServer) bool {
+		return net.ParseIP(server.Host) == nil
+	})
+}
+
+// RelayNetworkPolicy returns the desired NetworkPolicy of the relay with the metadata, only the
+// allowed clients can connect to the postgresql port and the relay can only connect with the egress
+// rules.
+func RelayNetworkPolicy(
+	meta metav1.ObjectMeta,
+	clients []dbov1.RelayAllowedClient,
+	egress []networkingv1.NetworkPolicyEgressRule,
+) *networkingv1.NetworkPolicy {
+	from := make([]networkingv1.NetworkPolicyPeer, 0, len(clients))
+	for _, client := range clients {
+		from = append(from, networkingv1.NetworkPolicyPeer{
+			PodSelector:       client.PodSelector.DeepCopy(),
+			NamespaceSelector: client.NamespaceSelector.DeepCopy(),
+		})
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: meta,
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{databaseOwnerKey: meta.Name},
+			},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					From: from,
+					Ports: []networkingv1.NetworkPolicyPort{
+						{
+							Protocol: ptr.To(corev1.ProtocolTCP),
+							Port:     ptr.To(intstr.FromString(defaultPostgresqlPortName)),
+						},
+					},
+				},
+			},
+			Egress:      egress,
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+}
+
+// MutateRelayNetworkPolicy patches the NetworkPolicy to the desired state.
+func MutateRelayNetworkPolicy(current, desired *networkingv1.NetworkPolicy) {
+	mutateObjectMeta(&current.ObjectMeta, &desired.ObjectMeta)
+	desired.Spec.DeepCopyInto(&current.Spec)
+}
+
+// applyRelayNetworkPolicy creates or patches the NetworkPolicy of the relay with the metadata, the
+// policy is deleted when no clients are set as every client is allowed.
+func (r *DatabaseAccountReconciler) applyRelayNetworkPolicy(
+	ctx context.Context,
+	dbAccount *dbov1.DatabaseAccount,
+	meta metav1.ObjectMeta,
+	clients []dbov1.RelayAllowedClient,
+	servers ...RelayServer,
+) error {
+	logger := log.FromContext(ctx)
+
+	policy := &networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: meta.Name, Namespace: meta.Namespace}}
+
+	if len(clients) == 0 {
+		if err := r.Delete(ctx, policy); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		} else if err == nil {
+			r.Recorder.NormalEvent(dbAccount, ReasonRelayUpdate, "Relay NetworkPolicy deleted")
+		}
+
+		return nil
+	}
+
+	egress, keepEgress, err := r.relayEgress(ctx, dbAccount, servers...)
+	if err != nil {
+		return err
+	}
+
+	desired := RelayNetworkPolicy(meta, clients, egress)
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, policy, func() error {
+		// the egress rules of an existing policy are kept until the database hosts can be resolved.
+		if keepEgress && !policy.CreationTimestamp.IsZero() {
+			desired.Spec.Egress = policy.Spec.Egress
+		}
+		MutateRelayNetworkPolicy(policy, desired)
+
+		return nil
+	})
+	if err != nil {
+		logger.V(1).Error(err, "Unable to reconcile relay", "kind", "NetworkPolicy")
+
+		return err
+	}
+
+	if result != controllerutil.OperationResultNone {
+		r.Recorder.NormalEvent(dbAccount, ReasonRelayUpdate, fmt.Sprintf("Relay NetworkPolicy %s", result))
+	}
+
+	return nil
+}
+
+// relayEgress returns the egress rules of the relay NetworkPolicy, relayEgress of the controller
+// configuration replaces the rules of the database servers. When a database host can not be resolved
+// a warning is recorded and true is returned so the egress rules of an existing policy are kept
+// rather than failing the reconcile of the relay.
+func (r *DatabaseAccountReconciler) relayEgress(
+	ctx context.Context,
+	dbAccount *dbov1.DatabaseAccount,
+	servers ...RelayServer,
+) ([]networkingv1.NetworkPolicyEgressRule, bool, error) {
+	logger := log.FromContext(ctx)
+
+	if r.Config.RelayEgress != nil {
+		egress := make([]networkingv1.NetworkPolicyEgressRule, 0, len(r.Config.RelayEgress))
+		for _, rule := range r.Config.RelayEgress {
+			egress = append(egress, *rule.DeepCopy())
+		}
+
+		return egress, false, nil
+	}
+
+	egress, err := RelayEgressRules(ctx, r, servers...)
+	if errors.Is(err, ErrRelayHostUnresolved) {
+		logger.Info("Unable to resolve relay egress", "error", err.Error())
+		r.Recorder.WarningEvent(dbAccount, ReasonRelayUpdate, fmt.Sprintf("Failed to resolve relay egress: %s", err))
+
+		return egress, true, nil
+	}
+
+	return egress, false, err
+}
@@ -0,0 +1,187 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	v1test "github.com/dosquad/database-operator/api/v1/test"
+	"github.com/dosquad/database-operator/internal/controller"
+	"github.com/dosquad/database-operator/internal/testhelp"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRelayEgressRules(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	rules, err := controller.RelayEgressRules(t.Context(), v1test.NewMockClient(),
+		controller.RelayServer{Host: "10.0.0.5", Port: 5432},
+		controller.RelayServer{Host: "fd00::5", Port: 6432},
+	)
+	if err != nil {
+		t.Fatalf("controller.RelayEgressRules(): error, got '%v', want 'nil'", err)
+	}
+
+	expect := []networkingv1.NetworkPolicyEgressRule{
+		{
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(5432))},
+			},
+			To: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.5/32"}}},
+		},
+		{
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(6432))},
+			},
+			To: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "fd00::5/128"}}},
+		},
+	}
+
+	// the database servers are addresses so the relay does not need the cluster DNS.
+	if diff := cmp.Diff(rules, expect); diff != "" {
+		testhelp.Errorf(t, start, "controller.RelayEgressRules(): -got +want:\n%s", diff)
+	}
+}
+
+func TestRelayEgressRules_Service(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	c := v1test.NewMockClient()
+	c.MockClientReader.OnGet = func(
+		_ context.Context, key client.ObjectKey,
+		obj client.Object, _ ...client.GetOption,
+	) error {
+		if key != (types.NamespacedName{Namespace: "db", Name: "postgres"}) {
+			return apierrors.NewNotFound(schema.GroupResource{Resource: "services"}, key.Name)
+		}
+
+		if svc, ok := obj.(*corev1.Service); ok {
+			svc.Spec.Selector = map[string]string{"app": "postgres"}
+			svc.Spec.Ports = []corev1.ServicePort{{Port: 5432, TargetPort: intstr.FromString("postgresql")}}
+		}
+
+		return nil
+	}
+
+	rules, err := controller.RelayEgressRules(t.Context(), c,
+		controller.RelayServer{Host: "postgres.db.svc.cluster.local", Port: 5432},
+	)
+	if err != nil {
+		t.Fatalf("controller.RelayEgressRules(): error, got '%v', want 'nil'", err)
+	}
+
+	expect := []networkingv1.NetworkPolicyEgressRule{
+		{
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromString("postgresql"))},
+			},
+			To: []networkingv1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"kubernetes.io/metadata.name": "db"},
+					},
+					PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "postgres"}},
+				},
+			},
+		},
+		{
+			To: []networkingv1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: &metav1.LabelSelector{},
+					PodSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"k8s-app": "kube-dns"},
+					},
+				},
+			},
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: ptr.To(corev1.ProtocolUDP), Port: ptr.To(intstr.FromInt32(53))},
+				{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(53))},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(rules, expect); diff != "" {
+		testhelp.Errorf(t, start, "controller.RelayEgressRules(): -got +want:\n%s", diff)
+	}
+}
+
+func TestRelayEgressRules_Unresolved(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	rules, err := controller.RelayEgressRules(t.Context(), v1test.NewMockClient(),
+		controller.RelayServer{Host: "10.0.0.5", Port: 5432},
+		controller.RelayServer{Host: "missing.invalid", Port: 5432},
+	)
+	if !errors.Is(err, controller.ErrRelayHostUnresolved) {
+		testhelp.Errorf(t, start, "controller.RelayEgressRules(): error, got '%v', want '%v'",
+			err, controller.ErrRelayHostUnresolved)
+	}
+
+	// the rules of the servers that were resolved are returned with the cluster DNS.
+	if len(rules) != 2 || rules[0].To[0].IPBlock == nil || rules[0].To[0].IPBlock.CIDR != "10.0.0.5/32" {
+		testhelp.Errorf(t, start, "controller.RelayEgressRules(): rules, got '%+v'", rules)
+	}
+}
+
+func TestRelayNetworkPolicy(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	dbAccount := v1test.NewDatabaseAccount()
+	clients := []dbov1.RelayAllowedClient{
+		{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "testapp"}}},
+		{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "data"}}},
+	}
+	egress := []networkingv1.NetworkPolicyEgressRule{
+		{To: []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.5/32"}}}},
+	}
+
+	meta := controller.RelayService(&dbAccount).ObjectMeta
+	policy := controller.RelayNetworkPolicy(meta, clients, egress)
+
+	if policy.Name != meta.Name || len(policy.OwnerReferences) != 1 {
+		testhelp.Errorf(t, start, "controller.RelayNetworkPolicy(): metadata, got '%v'", policy.ObjectMeta)
+	}
+
+	if v := policy.Spec.PodSelector.MatchLabels; len(v) != 1 || v["dba-name"] != meta.Name {
+		testhelp.Errorf(t, start, "controller.RelayNetworkPolicy(): pod selector, got '%v'", v)
+	}
+
+	expectIngress := []networkingv1.NetworkPolicyIngressRule{
+		{
+			From: []networkingv1.NetworkPolicyPeer{
+				{PodSelector: clients[0].PodSelector},
+				{NamespaceSelector: clients[1].NamespaceSelector},
+			},
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromString("postgresql"))},
+			},
+		},
+	}
+	if diff := cmp.Diff(policy.Spec.Ingress, expectIngress); diff != "" {
+		testhelp.Errorf(t, start, "controller.RelayNetworkPolicy(): ingress -got +want:\n%s", diff)
+	}
+
+	if diff := cmp.Diff(policy.Spec.Egress, egress); diff != "" {
+		testhelp.Errorf(t, start, "controller.RelayNetworkPolicy(): egress -got +want:\n%s", diff)
+	}
+
+	expectTypes := []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress}
+	if diff := cmp.Diff(policy.Spec.PolicyTypes, expectTypes); diff != "" {
+		testhelp.Errorf(t, start, "controller.RelayNetworkPolicy(): policy types -got +want:\n%s", diff)
+	}
+}
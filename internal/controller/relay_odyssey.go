@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// odysseyConfPath is the path of the Odyssey configuration in the relay container.
+const odysseyConfPath = "/etc/odyssey/"
+
+// odysseyDriver is the Odyssey relay. Odyssey configures the users of each database and logs in to the
+// server with the plaintext password of the users.
+type odysseyDriver struct{}
+
+func (odysseyDriver) Type() dbov1.RelayType {
+	return dbov1.RelayTypeOdyssey
+}
+
+func (odysseyDriver) SecretKeys() []string {
+	return []string{accountsvr.DatabaseKeyOdysseyConf}
+}
+
+func (odysseyDriver) PlaintextPassword() bool {
+	return true
+}
+
+// odysseyTLSMode returns the Odyssey name of the TLS mode, Odyssey has no prefer mode.
+func odysseyTLSMode(mode dbov1.RelayTLSMode) string {
+	if mode == dbov1.RelayTLSModePrefer {
+		return "allow"
+	}
+
+	return strings.ReplaceAll(mode.String(), "-", "_")
+}
+
+// odysseyListen writes the listen block of the relay port with the client TLS settings.
+func odysseyListen(sb *strings.Builder, clientTLS *dbov1.RelayClientTLS) {
+	sb.WriteString("\nlisten {\n")
+	sb.WriteString("\thost \"*\"\n")
+	fmt.Fprintf(sb, "\tport %d\n", defaultPostgresqlPort)
+	if clientTLS != nil {
+		fmt.Fprintf(sb, "\ttls %s\n", strconv.Quote(odysseyTLSMode(clientTLS.GetSSLMode())))
+		if clientTLS.GetSSLMode() != dbov1.RelayTLSModeDisable {
+			fmt.Fprintf(sb, "\ttls_cert_file %s\n", strconv.Quote(relayTLSMountPath+corev1.TLSCertKey))
+			fmt.Fprintf(sb, "\ttls_key_file %s\n", strconv.Quote(relayTLSMountPath+corev1.TLSPrivateKeyKey))
+		}
+	}
+	sb.WriteString("}\n")
+}
+
+// odysseyStorage writes the storage block of the database server.
+func odysseyStorage(sb *strings.Builder, storage string, db RelayDatabase, serverTLS *dbov1.RelayServerTLS) {
+	fmt.Fprintf(sb, "\nstorage %s {\n", storage)
+	sb.WriteString("\ttype \"remote\"\n")
+	fmt.Fprintf(sb, "\thost %s\n", strconv.Quote(db.Host))
+	fmt.Fprintf(sb, "\tport %d\n", db.Port)
+	if serverTLS != nil {
+		fmt.Fprintf(sb, "\ttls %s\n", strconv.Quote(odysseyTLSMode(serverTLS.GetSSLMode())))
+		if serverTLS.GetCASecretRef() != nil {
+			fmt.Fprintf(sb, "\ttls_ca_file %s\n", strconv.Quote(relayServerCAMountPath+accountsvr.DatabaseKeyCACert))
+		}
+	}
+	sb.WriteString("}\n")
+}
+
+// odysseyDatabase writes the database block with a user block for each user of the database.
+func odysseyDatabase(sb *strings.Builder, conf RelayConfig, storage string, db RelayDatabase) error {
+	settings := db.Settings.WithDefaults(conf.Settings)
+
+	if db.Name == "" {
+		sb.WriteString("\ndatabase default {\n")
+	} else {
+		fmt.Fprintf(sb, "\ndatabase %s {\n", strconv.Quote(db.Name))
+	}
+
+	for _, username := range db.Users {
+		user, ok := relayUserGet(conf.Users, username)
+		if !ok {
+			return fmt.Errorf("relay user %s of database %s not found", username, db.DBName)
+		}
+
+		storageUser := user
+		if db.User != "" && db.User != user.Name {
+			if storageUser, ok = relayUserGet(conf.Users, db.User); !ok {
+				return fmt.Errorf("relay user %s of database %s not found", db.User, db.DBName)
+			}
+		}
+
+		fmt.Fprintf(sb, "\tuser %s {\n", strconv.Quote(user.Name))
+		sb.WriteString("\t\tauthentication \"scram-sha-256\"\n")
+		fmt.Fprintf(sb, "\t\tpassword %s\n", strconv.Quote(user.Password))
+		fmt.Fprintf(sb, "\t\tstorage %s\n", storage)
+		if db.Name != "" {
+			fmt.Fprintf(sb, "\t\tstorage_db %s\n", strconv.Quote(db.DBName))
+		}
+		fmt.Fprintf(sb, "\t\tstorage_user %s\n", strconv.Quote(storageUser.Name))
+		fmt.Fprintf(sb, "\t\tstorage_password %s\n", strconv.Quote(storageUser.Password))
+		fmt.Fprintf(sb, "\t\tpool %s\n", strconv.Quote(settings.GetPoolMode().String()))
+		fmt.Fprintf(sb, "\t\tpool_size %d\n", settings.GetDefaultPoolSize())
+		fmt.Fprintf(sb, "\t\tpool_ttl %d\n", int64(settings.GetServerIdleTimeout().Seconds()))
+		sb.WriteString("\t}\n")
+	}
+	sb.WriteString("}\n")
+
+	return nil
+}
+
+// odysseyConsole writes the admin console database of the admin user.
+func odysseyConsole(sb *strings.Builder, admin RelayUser) {
+	sb.WriteString("\nstorage \"local\" {\n")
+	sb.WriteString("\ttype \"local\"\n")
+	sb.WriteString("}\n")
+	sb.WriteString("\ndatabase \"console\" {\n")
+	fmt.Fprintf(sb, "\tuser %s {\n", strconv.Quote(admin.Name))
+	sb.WriteString("\t\tauthentication \"scram-sha-256\"\n")
+	fmt.Fprintf(sb, "\t\tpassword %s\n", strconv.Quote(admin.Password))
+	sb.WriteString("\t\trole \"admin\"\n")
+	sb.WriteString("\t\tpool \"session\"\n")
+	sb.WriteString("\t\tstorage \"local\"\n")
+	sb.WriteString("\t}\n")
+	sb.WriteString("}\n")
+}
+
+func (odysseyDriver) Config(conf RelayConfig) (map[string]string, error) {
+	sb := &strings.Builder{}
+
+	sb.WriteString("daemonize no\n")
+	sb.WriteString("log_to_stdout yes\n")
+	sb.WriteString("unix_socket_dir \"/tmp\"\n")
+	sb.WriteString("unix_socket_mode \"0644\"\n")
+	fmt.Fprintf(sb, "client_max %d\n", conf.Settings.GetMaxClientConn())
+
+	odysseyListen(sb, conf.ClientTLS)
+
+	for i, db := range conf.Databases {
+		storage := strconv.Quote(fmt.Sprintf("server_%d", i))
+
+		odysseyStorage(sb, storage, db, conf.ServerTLS)
+		if err := odysseyDatabase(sb, conf, storage, db); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Admin.Name != "" && conf.Admin.Password != "" {
+		odysseyConsole(sb, conf.Admin)
+	}
+
+	return map[string]string{accountsvr.DatabaseKeyOdysseyConf: sb.String()}, nil
+}
+
+func (d odysseyDriver) Container(name, image, volume string) corev1.Container {
+	container := relayContainer(name, image, volume, odysseyConfPath, d.Probe())
+	container.Command = []string{"odyssey", odysseyConfPath + accountsvr.DatabaseKeyOdysseyConf}
+
+	return container
+}
+
+func (odysseyDriver) Probe() *corev1.Probe {
+	return relayTCPProbe()
+}
@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	pgBouncerConfTemplate = `[databases]
+{{- range .Databases}}
+{{.}}
+{{- end}}
+
+[pgbouncer]
+listen_addr = 0.0.0.0
+listen_port = {{.Port}}
+unix_socket_dir =
+user = postgres
+auth_file = /etc/pgbouncer/userlist.txt
+auth_type = scram-sha-256
+ignore_startup_parameters = extra_float_digits
+
+# Pool settings
+pool_mode = {{.Settings.GetPoolMode}}
+max_client_conn = {{.Settings.GetMaxClientConn}}
+default_pool_size = {{.Settings.GetDefaultPoolSize}}
+server_idle_timeout = {{.ServerIdleTimeout}}
+max_db_connections = {{.Settings.GetMaxDBConnections}}
+
+{{- with .ClientTLS}}
+
+# Client TLS settings
+client_tls_sslmode = {{.GetSSLMode}}
+{{- if ne .GetSSLMode "disable"}}
+client_tls_cert_file = {{$.TLSPath}}tls.crt
+client_tls_key_file = {{$.TLSPath}}tls.key
+{{- end}}
+{{- end}}
+{{- with .ServerTLS}}
+
+# Server TLS settings
+server_tls_sslmode = {{.GetSSLMode}}
+{{- if .GetCASecretRef}}
+server_tls_ca_file = {{$.ServerCAPath}}ca.crt
+{{- end}}
+{{- end}}
+
+{{- with .User}}
+
+# Log settings
+admin_users = {{.}}
+stats_users = {{.}}
+{{- end}}
+`
+)
+
+// pgBouncerConf is the data of the PgBouncer configuration template.
+type pgBouncerConf struct {
+	Databases             []string
+	Port, User            string
+	ServerIdleTimeout     int64
+	Settings              dbov1.RelaySettings
+	ClientTLS             *dbov1.RelayClientTLS
+	ServerTLS             *dbov1.RelayServerTLS
+	TLSPath, ServerCAPath string
+}
+
+// renderPGBouncerConf returns the PgBouncer configuration for the databases and settings.
+func renderPGBouncerConf(data pgBouncerConf) (string, error) {
+	tmpl, err := template.New("").Parse(pgBouncerConfTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	sb := &strings.Builder{}
+	if err := tmpl.Execute(sb, data); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+// pgBouncerDriver is the PgBouncer relay. The auth file holds the SCRAM-SHA-256 verifiers of the users
+// which PgBouncer also uses to log in to the server, so passwords are not written to the configuration.
+type pgBouncerDriver struct{}
+
+func (pgBouncerDriver) Type() dbov1.RelayType {
+	return dbov1.RelayTypePgBouncer
+}
+
+func (pgBouncerDriver) SecretKeys() []string {
+	return []string{accountsvr.DatabaseKeyPGBouncerConf, accountsvr.DatabaseKeyPGBouncerUsers}
+}
+
+func (pgBouncerDriver) PlaintextPassword() bool {
+	return false
+}
+
+// pgBouncerDatabase returns the [databases] entry of the database, a database without a name is the
+// fallback used for any database.
+func pgBouncerDatabase(db RelayDatabase) string {
+	name := db.Name
+	if name == "" {
+		name = "*"
+	}
+
+	entry := fmt.Sprintf("%s = host=%s port=%d", name, db.Host, db.Port)
+	if db.Name != "" {
+		entry += " dbname=" + db.DBName
+	}
+
+	if db.User != "" {
+		entry += " user=" + db.User
+	}
+
+	if db.Settings.PoolMode != "" {
+		entry += " pool_mode=" + db.Settings.PoolMode.String()
+	}
+
+	if db.Settings.DefaultPoolSize != nil {
+		entry += " pool_size=" + strconv.Itoa(int(*db.Settings.DefaultPoolSize))
+	}
+
+	if db.Settings.MaxDBConnections != nil {
+		entry += " max_db_connections=" + strconv.Itoa(int(*db.Settings.MaxDBConnections))
+	}
+
+	return entry
+}
+
+func (pgBouncerDriver) Config(conf RelayConfig) (map[string]string, error) {
+	databases := make([]string, 0, len(conf.Databases))
+	for _, db := range conf.Databases {
+		databases = append(databases, pgBouncerDatabase(db))
+	}
+
+	ini, err := renderPGBouncerConf(pgBouncerConf{
+		Databases:         databases,
+		Port:              strconv.Itoa(defaultPostgresqlPort),
+		User:              conf.Admin.Name,
+		ServerIdleTimeout: int64(conf.Settings.GetServerIdleTimeout().Seconds()),
+		Settings:          conf.Settings,
+		ClientTLS:         conf.ClientTLS,
+		ServerTLS:         conf.ServerTLS,
+		TLSPath:           relayTLSMountPath,
+		ServerCAPath:      relayServerCAMountPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	users := slices.Clone(conf.Users)
+	if _, ok := relayUserGet(users, conf.Admin.Name); !ok && conf.Admin.Verifier != "" {
+		users = append(users, conf.Admin)
+	}
+
+	sb := &strings.Builder{}
+	for _, user := range users {
+		fmt.Fprintf(sb, "\"%s\" \"%s\"\n", user.Name, user.Verifier)
+	}
+
+	return map[string]string{
+		accountsvr.DatabaseKeyPGBouncerConf:  ini,
+		accountsvr.DatabaseKeyPGBouncerUsers: sb.String(),
+	}, nil
+}
+
+func (d pgBouncerDriver) Container(name, image, volume string) corev1.Container {
+	return relayContainer(name, image, volume, "/etc/pgbouncer/", d.Probe())
+}
+
+func (pgBouncerDriver) Probe() *corev1.Probe {
+	return relayTCPProbe()
+}
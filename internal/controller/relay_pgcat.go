@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// pgCatConfPath is the path of the PgCat configuration in the relay container.
+const pgCatConfPath = "/etc/pgcat/"
+
+// pgCatDriver is the PgCat relay. PgCat configures a pool for each database with the users allowed to
+// connect to it, it needs the plaintext password of the users to log in to the server.
+type pgCatDriver struct{}
+
+func (pgCatDriver) Type() dbov1.RelayType {
+	return dbov1.RelayTypePgCat
+}
+
+func (pgCatDriver) SecretKeys() []string {
+	return []string{accountsvr.DatabaseKeyPgCatConf}
+}
+
+func (pgCatDriver) PlaintextPassword() bool {
+	return true
+}
+
+func (pgCatDriver) Config(conf RelayConfig) (map[string]string, error) {
+	sb := &strings.Builder{}
+
+	sb.WriteString("[general]\n")
+	sb.WriteString("host = \"0.0.0.0\"\n")
+	fmt.Fprintf(sb, "port = %d\n", defaultPostgresqlPort)
+	fmt.Fprintf(sb, "admin_username = %s\n", strconv.Quote(conf.Admin.Name))
+	fmt.Fprintf(sb, "admin_password = %s\n", strconv.Quote(conf.Admin.Password))
+	fmt.Fprintf(sb, "idle_timeout = %d\n", conf.Settings.GetServerIdleTimeout().Milliseconds())
+
+	if clientTLS := conf.ClientTLS; clientTLS != nil && clientTLS.GetSSLMode() != dbov1.RelayTLSModeDisable {
+		fmt.Fprintf(sb, "tls_certificate = %s\n", strconv.Quote(relayTLSMountPath+corev1.TLSCertKey))
+		fmt.Fprintf(sb, "tls_private_key = %s\n", strconv.Quote(relayTLSMountPath+corev1.TLSPrivateKeyKey))
+	}
+
+	if serverTLS := conf.ServerTLS; serverTLS != nil && serverTLS.GetSSLMode() != dbov1.RelayTLSModeDisable {
+		sb.WriteString("server_tls = true\n")
+		fmt.Fprintf(sb, "verify_server_certificate = %t\n", serverTLS.GetSSLMode().IsVerify())
+	}
+
+	for _, db := range conf.Databases {
+		name := strconv.Quote(relayDatabaseName(db))
+		settings := db.Settings.WithDefaults(conf.Settings)
+
+		fmt.Fprintf(sb, "\n[pools.%s]\n", name)
+		fmt.Fprintf(sb, "pool_mode = %s\n", strconv.Quote(settings.GetPoolMode().String()))
+
+		for i, username := range db.Users {
+			user, ok := relayUserGet(conf.Users, username)
+			if !ok {
+				return nil, fmt.Errorf("relay user %s of database %s not found", username, db.DBName)
+			}
+
+			fmt.Fprintf(sb, "\n[pools.%s.users.%d]\n", name, i)
+			fmt.Fprintf(sb, "username = %s\n", strconv.Quote(user.Name))
+			fmt.Fprintf(sb, "password = %s\n", strconv.Quote(user.Password))
+			fmt.Fprintf(sb, "pool_size = %d\n", settings.GetDefaultPoolSize())
+		}
+
+		fmt.Fprintf(sb, "\n[pools.%s.shards.0]\n", name)
+		fmt.Fprintf(sb, "servers = [[%s, %d, \"primary\"]]\n", strconv.Quote(db.Host), db.Port)
+		fmt.Fprintf(sb, "database = %s\n", strconv.Quote(db.DBName))
+	}
+
+	return map[string]string{accountsvr.DatabaseKeyPgCatConf: sb.String()}, nil
+}
+
+func (d pgCatDriver) Container(name, image, volume string) corev1.Container {
+	container := relayContainer(name, image, volume, pgCatConfPath, d.Probe())
+	container.Command = []string{"pgcat", pgCatConfPath + accountsvr.DatabaseKeyPgCatConf}
+
+	return container
+}
+
+func (pgCatDriver) Probe() *corev1.Probe {
+	return relayTCPProbe()
+}
@@ -0,0 +1,374 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// sharedRelayAccountPrefix prefixes the account name in the keys of the shared relay secret holding
+	// the database and user of each account.
+	sharedRelayAccountPrefix = "account."
+
+	// sharedRelayAdminPasswordKey is the key of the password of the shared relay admin user, the
+	// password is generated when the shared relay secret is created and stored with its verifier in the
+	// sharedRelayAdminVerifierKey key.
+	sharedRelayAdminPasswordKey = "admin-password"
+	sharedRelayAdminVerifierKey = "admin-verifier"
+)
+
+// sharedRelayAccount is the database and user of an account in the shared relay secret.
+type sharedRelayAccount struct {
+	Database RelayDatabase `json:"database"`
+	User     RelayUser     `json:"user"`
+}
+
+// sharedRelayName returns the name of the shared relay resources of the namespace.
+func sharedRelayName(namespace string) types.NamespacedName {
+	return types.NamespacedName{Namespace: namespace, Name: dbov1.SharedRelayName}
+}
+
+// sharedRelayLabels returns the labels of the shared relay resources.
+func sharedRelayLabels() map[string]string {
+	return map[string]string{
+		labelNamePartOf:  defaultPartOf,
+		databaseOwnerKey: dbov1.SharedRelayName,
+	}
+}
+
+// sharedRelayObjectMeta returns the metadata of the shared relay resources, they are not owned by an
+// account and are removed when the last account leaves the shared relay.
+func sharedRelayObjectMeta(namespace string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      dbov1.SharedRelayName,
+		Namespace: namespace,
+		Labels:    sharedRelayLabels(),
+	}
+}
+
+// sharedRelayInstance returns the shared relay of the namespace, only the generated configuration is
+// mounted from the shared relay secret.
+func sharedRelayInstance(ctrlConfig *dbov1.DatabaseAccountControllerConfig, namespace string) relayInstance {
+	driver := RelayDriverFor(ctrlConfig.GetSharedRelayType())
+
+	return relayInstance{
+		meta:   sharedRelayObjectMeta(namespace),
+		relay:  ctrlConfig.GetSharedRelay(),
+		driver: driver,
+		volume: corev1.Volume{
+			Name: dbov1.SharedRelayName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  dbov1.SharedRelayName,
+					Items:       relaySecretItems(driver),
+					DefaultMode: ptr.To(corev1.SecretVolumeSourceDefaultMode),
+				},
+			},
+		},
+	}
+}
+
+// SharedRelayStatefulSet returns the desired StatefulSet of the shared relay pods of the namespace.
+func SharedRelayStatefulSet(
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	namespace string,
+) *appsv1.StatefulSet {
+	return relayStatefulSet(ctrlConfig, sharedRelayInstance(ctrlConfig, namespace))
+}
+
+// SharedRelayService returns the desired Service of the shared relay pods of the namespace.
+func SharedRelayService(namespace string) *corev1.Service {
+	return relayService(sharedRelayObjectMeta(namespace))
+}
+
+// SharedRelayPodDisruptionBudget returns the desired PodDisruptionBudget of the shared relay pods.
+func SharedRelayPodDisruptionBudget(namespace string) *policyv1.PodDisruptionBudget {
+	return relayPodDisruptionBudget(sharedRelayObjectMeta(namespace))
+}
+
+// SetSharedRelayAccount adds or updates the database and user of the account in the shared relay
+// secret from the account secret, and regenerates the shared relay configuration. The password of the
+// account is only stored when the shared relay type needs the plaintext password.
+func SetSharedRelayAccount(
+	ctx context.Context,
+	accountSvr accountsvr.Server,
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	dbAccount *dbov1.DatabaseAccount,
+	shared, accountSecret *corev1.Secret,
+) error {
+	database := GetSecretKV(accountSecret, accountsvr.DatabaseKeyDatabase)
+	user, err := relayUserFromSecret(accountSecret)
+	if database == "" || user.Name == "" || user.Password == "" {
+		return fmt.Errorf("%w: %s", ErrMissingSecretCredentials, dbAccount.GetSecretName())
+	} else if err != nil {
+		return err
+	}
+
+	if !RelayDriverFor(ctrlConfig.GetSharedRelayType()).PlaintextPassword() {
+		user.Password = ""
+	}
+
+	entry, err := json.Marshal(sharedRelayAccount{
+		Database: RelayDatabase{
+			Name:     database,
+			Host:     accountSvr.GetDatabaseHostConfig(),
+			Port:     accountSvr.GetDatabasePortConfig(),
+			DBName:   database,
+			Users:    []string{user.Name},
+			Settings: dbAccount.GetSpecRelay().RelaySettings,
+		},
+		User: user,
+	})
+	if err != nil {
+		return err
+	}
+
+	SetSecretKV(shared, sharedRelayAccountPrefix+dbAccount.Name, string(entry))
+
+	return setSharedRelayConf(ctx, ctrlConfig, shared)
+}
+
+// RemoveSharedRelayAccount removes the account from the shared relay secret and regenerates the shared
+// relay configuration, false is returned if the account was not in the secret.
+func RemoveSharedRelayAccount(
+	ctx context.Context,
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	dbAccount *dbov1.DatabaseAccount,
+	shared *corev1.Secret,
+) (bool, error) {
+	key := sharedRelayAccountPrefix + dbAccount.Name
+	if _, ok := shared.Data[key]; !ok {
+		return false, nil
+	}
+
+	delete(shared.Data, key)
+
+	return true, setSharedRelayConf(ctx, ctrlConfig, shared)
+}
+
+// sharedRelayAccountsGet returns the accounts in the shared relay secret sorted by key.
+func sharedRelayAccountsGet(shared *corev1.Secret) ([]sharedRelayAccount, error) {
+	var accounts []sharedRelayAccount
+	for _, key := range slices.Sorted(maps.Keys(shared.Data)) {
+		if !strings.HasPrefix(key, sharedRelayAccountPrefix) {
+			continue
+		}
+
+		account := sharedRelayAccount{}
+		if err := json.Unmarshal(shared.Data[key], &account); err != nil {
+			return nil, fmt.Errorf("shared relay %s: %w", key, err)
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+// sharedRelayServers returns the database servers of the accounts in the shared relay secret.
+func sharedRelayServers(shared *corev1.Secret) ([]RelayServer, error) {
+	accounts, err := sharedRelayAccountsGet(shared)
+	if err != nil {
+		return nil, err
+	}
+
+	var servers []RelayServer
+	for _, account := range accounts {
+		server := RelayServer{Host: account.Database.Host, Port: account.Database.Port}
+		if !slices.Contains(servers, server) {
+			servers = append(servers, server)
+		}
+	}
+
+	return servers, nil
+}
+
+// sharedRelayAccounts returns the number of accounts in the shared relay secret.
+func sharedRelayAccounts(shared *corev1.Secret) int {
+	count := 0
+	for key := range shared.Data {
+		if strings.HasPrefix(key, sharedRelayAccountPrefix) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// setSharedRelayConf generates the configuration of the shared relay from the accounts in the secret,
+// the accounts are sorted so the configuration is stable.
+func setSharedRelayConf(
+	ctx context.Context,
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	shared *corev1.Secret,
+) error {
+	driver := RelayDriverFor(ctrlConfig.GetSharedRelayType())
+
+	admin, err := relayAdminFromSecret(ctx, shared, sharedRelayAdminPasswordKey, sharedRelayAdminVerifierKey)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := sharedRelayAccountsGet(shared)
+	if err != nil {
+		return err
+	}
+
+	conf := RelayConfig{Admin: admin, Settings: ctrlConfig.GetSharedRelaySettings()}
+	for _, account := range accounts {
+		conf.Databases = append(conf.Databases, account.Database)
+		conf.Users = append(conf.Users, account.User)
+	}
+
+	files, err := driver.Config(conf)
+	if err != nil {
+		return err
+	}
+
+	setSecretRelayFiles(shared, files)
+
+	return nil
+}
+
+// sharedRelayConfigChecksum returns a checksum of the configuration mounted in the shared relay pods.
+func sharedRelayConfigChecksum(ctrlConfig *dbov1.DatabaseAccountControllerConfig, shared *corev1.Secret) string {
+	hash := sha256.New()
+	for _, key := range RelayDriverFor(ctrlConfig.GetSharedRelayType()).SecretKeys() {
+		fmt.Fprintf(hash, "%s/%s=%s\n", shared.Name, key, GetSecretKV(shared, key))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// reconcileSharedRelay adds the account to the shared relay of the namespace and creates or patches the
+// shared relay resources, the shared relay pods roll when the configuration of any account changes.
+func (r *DatabaseAccountReconciler) reconcileSharedRelay(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+) error {
+	logger := log.FromContext(ctx)
+
+	accountSecret, err := SecretGetByName(ctx, r, dbAccount.GetSecretName())
+	if err != nil {
+		return err
+	}
+
+	meta := sharedRelayObjectMeta(dbAccount.Namespace)
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: meta.Name, Namespace: meta.Namespace}}
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		mutateObjectMeta(&secret.ObjectMeta, &meta)
+
+		return SetSharedRelayAccount(ctx, svr, r.Config, dbAccount, secret, accountSecret)
+	})
+	if err != nil {
+		logger.V(1).Error(err, "Unable to reconcile shared relay secret")
+
+		return err
+	}
+
+	if result != controllerutil.OperationResultNone {
+		r.Recorder.NormalEvent(dbAccount, ReasonRelayUpdate, fmt.Sprintf("Shared relay configuration %s", result))
+	}
+
+	return r.applySharedRelay(ctx, dbAccount, secret)
+}
+
+// applySharedRelay creates or patches the shared relay resources for the configuration in the secret.
+func (r *DatabaseAccountReconciler) applySharedRelay(
+	ctx context.Context,
+	dbAccount *dbov1.DatabaseAccount,
+	shared *corev1.Secret,
+) error {
+	servers, err := sharedRelayServers(shared)
+	if err != nil {
+		return err
+	}
+
+	// the NetworkPolicy is applied first so new relay pods are never reachable by other clients.
+	if err := r.applyRelayNetworkPolicy(ctx, dbAccount, sharedRelayObjectMeta(shared.Namespace),
+		r.Config.GetSharedRelay().GetAllowedClients(), servers...); err != nil {
+		return err
+	}
+
+	desiredStatefulSet := SharedRelayStatefulSet(r.Config, shared.Namespace)
+	setRelayConfigChecksum(desiredStatefulSet, sharedRelayConfigChecksum(r.Config, shared))
+
+	return r.applyRelay(ctx, dbAccount, desiredStatefulSet, SharedRelayService(shared.Namespace),
+		SharedRelayPodDisruptionBudget(shared.Namespace))
+}
+
+// releaseSharedRelay removes the account from the shared relay of the namespace, the shared relay is
+// deleted once no accounts are left in it.
+func (r *DatabaseAccountReconciler) releaseSharedRelay(ctx context.Context, dbAccount *dbov1.DatabaseAccount) error {
+	secret, err := SecretGetByName(ctx, r, sharedRelayName(dbAccount.Namespace))
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if removed, err := RemoveSharedRelayAccount(ctx, r.Config, dbAccount, secret); err != nil || !removed {
+		return err
+	}
+
+	if sharedRelayAccounts(secret) == 0 {
+		return r.deleteSharedRelay(ctx, dbAccount, secret)
+	}
+
+	if err := r.Update(ctx, secret); err != nil {
+		return err
+	}
+
+	r.Recorder.NormalEvent(dbAccount, ReasonRelayUpdate, "Removed from shared relay")
+
+	return r.applySharedRelay(ctx, dbAccount, secret)
+}
+
+// deleteSharedRelay deletes the shared relay resources, the secret is deleted first with a precondition
+// so an account added to the shared relay since it was read keeps the shared relay.
+func (r *DatabaseAccountReconciler) deleteSharedRelay(
+	ctx context.Context,
+	dbAccount *dbov1.DatabaseAccount,
+	shared *corev1.Secret,
+) error {
+	if err := r.Delete(ctx, shared, client.Preconditions{ResourceVersion: &shared.ResourceVersion}); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	meta := sharedRelayObjectMeta(shared.Namespace)
+	for _, obj := range []client.Object{
+		&appsv1.StatefulSet{ObjectMeta: meta},
+		&corev1.Service{ObjectMeta: meta},
+		&policyv1.PodDisruptionBudget{ObjectMeta: meta},
+		&networkingv1.NetworkPolicy{ObjectMeta: meta},
+	} {
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	r.Recorder.NormalEvent(dbAccount, ReasonRelayUpdate, "Deleted shared relay")
+
+	return nil
+}
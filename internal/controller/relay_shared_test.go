@@ -0,0 +1,246 @@
+package controller_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	accountsvrtest "github.com/dosquad/database-operator/accountsvr/test"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	v1test "github.com/dosquad/database-operator/api/v1/test"
+	"github.com/dosquad/database-operator/internal/controller"
+	"github.com/dosquad/database-operator/internal/helper"
+	"github.com/dosquad/database-operator/internal/testhelp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+func newSharedRelayAccount(name, database, username string) (dbov1.DatabaseAccount, *corev1.Secret) {
+	dbAccount := v1test.NewDatabaseAccount()
+	dbAccount.Name = name
+	dbAccount.Spec.Relay = &dbov1.DatabaseAccountRelay{Shared: true}
+
+	verifier, _ := helper.ScramSHA256Verifier(username + "pass")
+
+	return dbAccount, &corev1.Secret{Data: map[string][]byte{
+		accountsvr.DatabaseKeyDatabase: []byte(database),
+		accountsvr.DatabaseKeyUsername: []byte(username),
+		accountsvr.DatabaseKeyPassword: []byte(username + "pass"),
+		accountsvr.DatabaseKeyVerifier: []byte(verifier),
+	}}
+}
+
+func TestSetSharedRelayAccount(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	svr := accountsvrtest.NewMockServer(accountsvrtest.TestDSN)
+	svr.OnGetDatabasePortConfig = func() uint16 { return 6543 }
+	ctrlConfig := &dbov1.DatabaseAccountControllerConfig{
+		RelayDefaults: dbov1.RelaySettings{MaxClientConn: ptr.To[int32](1000)},
+		SharedRelay: &dbov1.DatabaseAccountRelay{RelaySettings: dbov1.RelaySettings{
+			PoolMode: dbov1.RelayPoolModeTransaction,
+		}},
+	}
+
+	accountB, secretB := newSharedRelayAccount("account-b", "k8s_b", "user_b")
+	accountB.Spec.Relay.DefaultPoolSize = ptr.To[int32](5)
+	accountB.Spec.Relay.MaxDBConnections = ptr.To[int32](10)
+	accountA, secretA := newSharedRelayAccount("account-a", "k8s_a", "user_a")
+
+	shared := &corev1.Secret{}
+	for _, v := range []struct {
+		dbAccount *dbov1.DatabaseAccount
+		secret    *corev1.Secret
+	}{{&accountB, secretB}, {&accountA, secretA}} {
+		err := controller.SetSharedRelayAccount(t.Context(), svr, ctrlConfig, v.dbAccount, shared, v.secret)
+		if err != nil {
+			t.Fatalf("controller.SetSharedRelayAccount(): error, got '%v', want 'nil'", err)
+		}
+	}
+
+	conf := controller.GetSecretKV(shared, accountsvr.DatabaseKeyPGBouncerConf)
+	expectDatabases := "[databases]\n" +
+		"k8s_a = host=databasehost port=6543 dbname=k8s_a\n" +
+		"k8s_b = host=databasehost port=6543 dbname=k8s_b pool_size=5 max_db_connections=10\n"
+	for _, line := range []string{
+		expectDatabases,
+		"pool_mode = transaction\n",
+		"max_client_conn = 1000\n",
+		"admin_users = dbo_relay_admin\n",
+	} {
+		if !strings.Contains(conf, line) {
+			testhelp.Errorf(t, start, "controller.SetSharedRelayAccount(): missing '%s' in:\n%s",
+				strings.TrimSpace(line), conf)
+		}
+	}
+
+	adminPassword := controller.GetSecretKV(shared, "admin-password")
+	if adminPassword == "" {
+		t.Fatalf("controller.SetSharedRelayAccount(): admin password not generated")
+	}
+
+	verifierA := controller.GetSecretKV(secretA, accountsvr.DatabaseKeyVerifier)
+	verifierB := controller.GetSecretKV(secretB, accountsvr.DatabaseKeyVerifier)
+	verifierAdmin := controller.GetSecretKV(shared, "admin-verifier")
+	if !helper.ScramSHA256VerifierMatches(verifierAdmin, adminPassword) {
+		testhelp.Errorf(t, start, "controller.SetSharedRelayAccount(): admin verifier, got '%s', want verifier of '%s'",
+			verifierAdmin, adminPassword)
+	}
+	users := controller.GetSecretKV(shared, accountsvr.DatabaseKeyPGBouncerUsers)
+	expect := fmt.Sprintf("\"user_a\" \"%s\"\n\"user_b\" \"%s\"\n\"dbo_relay_admin\" \"%s\"\n",
+		verifierA, verifierB, verifierAdmin)
+	if users != expect {
+		testhelp.Errorf(t, start, "controller.SetSharedRelayAccount(): users, got '%s', want '%s'", users, expect)
+	}
+
+	if strings.Contains(string(shared.Data["account.account-a"]), "user_apass") {
+		testhelp.Errorf(t, start, "controller.SetSharedRelayAccount(): password stored for PgBouncer")
+	}
+
+	removed, err := controller.RemoveSharedRelayAccount(t.Context(), ctrlConfig, &accountB, shared)
+	if err != nil || !removed {
+		testhelp.Errorf(t, start, "controller.RemoveSharedRelayAccount(): got '%t, %v', want 'true, nil'", removed, err)
+	}
+
+	conf = controller.GetSecretKV(shared, accountsvr.DatabaseKeyPGBouncerConf)
+	if strings.Contains(conf, "k8s_b") || !strings.Contains(conf, "k8s_a = ") {
+		testhelp.Errorf(t, start, "controller.RemoveSharedRelayAccount(): databases, got:\n%s", conf)
+	}
+
+	users = controller.GetSecretKV(shared, accountsvr.DatabaseKeyPGBouncerUsers)
+	expect = fmt.Sprintf("\"user_a\" \"%s\"\n\"dbo_relay_admin\" \"%s\"\n", verifierA, verifierAdmin)
+	if users != expect {
+		testhelp.Errorf(t, start, "controller.RemoveSharedRelayAccount(): users, got '%s', want '%s'", users, expect)
+	}
+
+	if removed, _ := controller.RemoveSharedRelayAccount(t.Context(), ctrlConfig, &accountB, shared); removed {
+		testhelp.Errorf(t, start, "controller.RemoveSharedRelayAccount(): removed account that is not in the relay")
+	}
+}
+
+func TestSetSharedRelayAccount_PgCat(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	svr := accountsvrtest.NewMockServer(accountsvrtest.TestDSN)
+	ctrlConfig := &dbov1.DatabaseAccountControllerConfig{
+		SharedRelay: &dbov1.DatabaseAccountRelay{Type: dbov1.RelayTypePgCat},
+	}
+	dbAccount, secret := newSharedRelayAccount("account-a", "k8s_a", "user_a")
+	shared := &corev1.Secret{}
+	controller.SetSecretKV(shared, accountsvr.DatabaseKeyPGBouncerConf, "[databases]")
+
+	if err := controller.SetSharedRelayAccount(t.Context(), svr, ctrlConfig, &dbAccount, shared, secret); err != nil {
+		t.Fatalf("controller.SetSharedRelayAccount(): error, got '%v', want 'nil'", err)
+	}
+
+	if _, ok := shared.Data[accountsvr.DatabaseKeyPGBouncerConf]; ok {
+		testhelp.Errorf(t, start, "controller.SetSharedRelayAccount(): %s not removed",
+			accountsvr.DatabaseKeyPGBouncerConf)
+	}
+
+	conf := controller.GetSecretKV(shared, accountsvr.DatabaseKeyPgCatConf)
+	for _, line := range []string{
+		"admin_username = \"dbo_relay_admin\"\n",
+		"[pools.\"k8s_a\"]\n",
+		"username = \"user_a\"\npassword = \"user_apass\"\n",
+	} {
+		if !strings.Contains(conf, line) {
+			testhelp.Errorf(t, start, "controller.SetSharedRelayAccount(): missing '%s' in:\n%s",
+				strings.TrimSpace(line), conf)
+		}
+	}
+
+	if items := controller.SharedRelayStatefulSet(ctrlConfig, "apps").Spec.Template.Spec.Volumes[0].Secret.Items; len(
+		items) != 1 || items[0].Key != accountsvr.DatabaseKeyPgCatConf {
+		testhelp.Errorf(t, start, "controller.SharedRelayStatefulSet(): volume items, got '%v'", items)
+	}
+}
+
+func TestSetSharedRelayAccount_MissingCredentials(t *testing.T) {
+	t.Parallel()
+
+	svr := accountsvrtest.NewMockServer(accountsvrtest.TestDSN)
+	dbAccount, secret := newSharedRelayAccount("account-a", "", "user_a")
+
+	shared := &corev1.Secret{}
+	err := controller.SetSharedRelayAccount(
+		t.Context(), svr, &dbov1.DatabaseAccountControllerConfig{}, &dbAccount, shared, secret,
+	)
+	if err == nil {
+		t.Errorf("controller.SetSharedRelayAccount(): error, got 'nil', want '%v'",
+			controller.ErrMissingSecretCredentials)
+	}
+
+	if len(shared.Data) != 0 {
+		t.Errorf("controller.SetSharedRelayAccount(): secret updated, got '%v'", shared.Data)
+	}
+}
+
+func TestSharedRelayStatefulSet(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	ctrlConfig := &dbov1.DatabaseAccountControllerConfig{
+		SharedRelay: &dbov1.DatabaseAccountRelay{Replicas: ptr.To[int32](3)},
+	}
+
+	statefulSet := controller.SharedRelayStatefulSet(ctrlConfig, "apps")
+	if statefulSet.Name != dbov1.SharedRelayName || statefulSet.Namespace != "apps" {
+		testhelp.Errorf(t, start, "controller.SharedRelayStatefulSet(): name, got '%s/%s', want 'apps/%s'",
+			statefulSet.Namespace, statefulSet.Name, dbov1.SharedRelayName)
+	}
+
+	if len(statefulSet.OwnerReferences) != 0 {
+		testhelp.Errorf(t, start, "controller.SharedRelayStatefulSet(): owner references, got '%v', want none",
+			statefulSet.OwnerReferences)
+	}
+
+	if v := ptr.Deref(statefulSet.Spec.Replicas, 0); v != 3 {
+		testhelp.Errorf(t, start, "controller.SharedRelayStatefulSet(): replicas, got '%d', want '3'", v)
+	}
+
+	volumes := statefulSet.Spec.Template.Spec.Volumes
+	if len(volumes) != 1 || volumes[0].Secret == nil || volumes[0].Secret.SecretName != dbov1.SharedRelayName {
+		t.Fatalf("controller.SharedRelayStatefulSet(): volumes, got '%v'", volumes)
+	}
+
+	// only the generated configuration is mounted, the entries of each account are not.
+	if items := volumes[0].Secret.Items; len(items) != 2 {
+		testhelp.Errorf(t, start, "controller.SharedRelayStatefulSet(): volume items, got '%v'", items)
+	}
+
+	service := controller.SharedRelayService("apps")
+	if v := service.Spec.Selector; len(v) != 1 || v["dba-name"] != dbov1.SharedRelayName {
+		testhelp.Errorf(t, start, "controller.SharedRelayService(): selector, got '%v'", v)
+	}
+}
+
+func TestSetSecretRelay_Shared(t *testing.T) {
+	t.Parallel()
+
+	svr := accountsvrtest.NewMockServer(accountsvrtest.TestDSN)
+	dbAccount, secret := newSharedRelayAccount("account-a", "k8s_a", "user_a")
+	controller.SetSecretKV(secret, accountsvr.DatabaseKeyPGBouncerConf, "[databases]")
+	controller.SetSecretKV(secret, accountsvr.DatabaseKeyRelayAdminPassword, "adminpass")
+
+	ctrlConfig := &dbov1.DatabaseAccountControllerConfig{}
+	if err := controller.SetSecretRelay(t.Context(), svr, ctrlConfig, &dbAccount, secret); err != nil {
+		t.Errorf("controller.SetSecretRelay(): error, got '%v', want 'nil'", err)
+	}
+
+	if v := controller.GetSecretKV(secret, accountsvr.DatabaseKeyHost); v != dbov1.SharedRelayName {
+		t.Errorf("controller.SetSecretRelay(): host, got '%s', want '%s'", v, dbov1.SharedRelayName)
+	}
+
+	if _, ok := secret.Data[accountsvr.DatabaseKeyPGBouncerConf]; ok {
+		t.Errorf("controller.SetSecretRelay(): %s set for shared relay", accountsvr.DatabaseKeyPGBouncerConf)
+	}
+
+	if _, ok := secret.Data[accountsvr.DatabaseKeyRelayAdminPassword]; ok {
+		t.Errorf("controller.SetSecretRelay(): %s set for shared relay", accountsvr.DatabaseKeyRelayAdminPassword)
+	}
+}
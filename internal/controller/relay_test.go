@@ -0,0 +1,359 @@
+package controller_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	v1test "github.com/dosquad/database-operator/api/v1/test"
+	"github.com/dosquad/database-operator/internal/controller"
+	"github.com/dosquad/database-operator/internal/testhelp"
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestMutateRelayStatefulSet(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	dbAccount := v1test.NewDatabaseAccount()
+	dbAccount.Spec.Relay = &dbov1.DatabaseAccountRelay{}
+	ctrlConfig := &dbov1.DatabaseAccountControllerConfig{}
+	desired := controller.RelayStatefulSet(ctrlConfig, &dbAccount)
+
+	current := desired.DeepCopy()
+	current.ResourceVersion = "1"
+	current.Labels["team"] = "payments"
+	current.Spec.Selector.MatchLabels["extra"] = "selector"
+	current.Spec.Template.Spec.DNSPolicy = corev1.DNSClusterFirst
+	container := &current.Spec.Template.Spec.Containers[0]
+	container.Image = "example/drifted:latest"
+	container.Ports[0].ContainerPort = 6543
+	container.TerminationMessagePath = corev1.TerminationMessagePathDefault
+	current.Spec.Template.Spec.Volumes = nil
+
+	controller.MutateRelayStatefulSet(current, controller.RelayStatefulSet(ctrlConfig, &dbAccount))
+
+	got := current.Spec.Template.Spec
+	want := desired.Spec.Template.Spec
+	if diff := cmp.Diff(got.Containers[0].Image, want.Containers[0].Image); diff != "" {
+		testhelp.Errorf(t, start, "controller.MutateRelayStatefulSet(): image -got +want:\n%s", diff)
+	}
+
+	if diff := cmp.Diff(got.Containers[0].Ports, want.Containers[0].Ports); diff != "" {
+		testhelp.Errorf(t, start, "controller.MutateRelayStatefulSet(): ports -got +want:\n%s", diff)
+	}
+
+	if diff := cmp.Diff(got.Volumes, want.Volumes); diff != "" {
+		testhelp.Errorf(t, start, "controller.MutateRelayStatefulSet(): volumes -got +want:\n%s", diff)
+	}
+
+	if v := current.Spec.Selector.MatchLabels["extra"]; v != "selector" {
+		testhelp.Errorf(t, start, "controller.MutateRelayStatefulSet(): selector changed, got '%v'",
+			current.Spec.Selector.MatchLabels)
+	}
+
+	if v := current.Labels["team"]; v != "payments" {
+		testhelp.Errorf(t, start, "controller.MutateRelayStatefulSet(): label removed, got '%v'", current.Labels)
+	}
+
+	if got.DNSPolicy != corev1.DNSClusterFirst ||
+		got.Containers[0].TerminationMessagePath != corev1.TerminationMessagePathDefault {
+		testhelp.Errorf(t, start, "controller.MutateRelayStatefulSet(): server defaults were changed")
+	}
+
+	before := current.DeepCopy()
+	controller.MutateRelayStatefulSet(current, controller.RelayStatefulSet(ctrlConfig, &dbAccount))
+	if !equality.Semantic.DeepEqual(before, current) {
+		testhelp.Errorf(t, start, "controller.MutateRelayStatefulSet(): not idempotent -before +after:\n%s",
+			cmp.Diff(before, current))
+	}
+}
+
+func TestRelayStatefulSet_SecretItems(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	tests := []struct {
+		relayType dbov1.RelayType
+		expect    []string
+	}{
+		{
+			dbov1.RelayTypePgBouncer,
+			[]string{accountsvr.DatabaseKeyPGBouncerConf, accountsvr.DatabaseKeyPGBouncerUsers},
+		},
+		{dbov1.RelayTypePgCat, []string{accountsvr.DatabaseKeyPgCatConf}},
+		{dbov1.RelayTypeOdyssey, []string{accountsvr.DatabaseKeyOdysseyConf}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.relayType), func(t *testing.T) {
+			t.Parallel()
+
+			dbAccount := v1test.NewDatabaseAccount()
+			dbAccount.Spec.Relay = &dbov1.DatabaseAccountRelay{Type: tt.relayType}
+			statefulSet := controller.RelayStatefulSet(&dbov1.DatabaseAccountControllerConfig{}, &dbAccount)
+
+			volume := statefulSet.Spec.Template.Spec.Volumes[0]
+			keys := []string{}
+			for _, item := range volume.Secret.Items {
+				keys = append(keys, item.Key)
+			}
+
+			if diff := cmp.Diff(keys, tt.expect); diff != "" {
+				testhelp.Errorf(t, start, "controller.RelayStatefulSet(): secret items -got +want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMutateRelayService(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	dbAccount := v1test.NewDatabaseAccount()
+	desired := controller.RelayService(&dbAccount)
+
+	current := desired.DeepCopy()
+	current.ResourceVersion = "1"
+	current.Spec.ClusterIP = "10.0.0.10"
+	current.Spec.Ports[0].Port = 6543
+	current.Spec.Ports[0].TargetPort = intstr.FromInt32(6543)
+	current.Spec.Selector = map[string]string{"app": "other"}
+
+	controller.MutateRelayService(current, controller.RelayService(&dbAccount))
+
+	if diff := cmp.Diff(current.Spec.Ports, desired.Spec.Ports); diff != "" {
+		testhelp.Errorf(t, start, "controller.MutateRelayService(): ports -got +want:\n%s", diff)
+	}
+
+	if diff := cmp.Diff(current.Spec.Selector, desired.Spec.Selector); diff != "" {
+		testhelp.Errorf(t, start, "controller.MutateRelayService(): selector -got +want:\n%s", diff)
+	}
+
+	if current.Spec.ClusterIP != "10.0.0.10" {
+		testhelp.Errorf(t, start, "controller.MutateRelayService(): cluster IP changed, got '%s'",
+			current.Spec.ClusterIP)
+	}
+}
+
+func TestRelayStatusGet(t *testing.T) {
+	t.Parallel()
+
+	readyStatefulSet := &appsv1.StatefulSet{
+		Spec: appsv1.StatefulSetSpec{Replicas: ptr.To[int32](2)},
+		Status: appsv1.StatefulSetStatus{
+			ReadyReplicas:   2,
+			UpdatedReplicas: 2,
+			CurrentRevision: "relay-2",
+			UpdateRevision:  "relay-2",
+		},
+	}
+	updatingStatefulSet := &appsv1.StatefulSet{
+		Spec: appsv1.StatefulSetSpec{Replicas: ptr.To[int32](2)},
+		Status: appsv1.StatefulSetStatus{
+			ReadyReplicas:   2,
+			UpdatedReplicas: 1,
+			CurrentRevision: "relay-1",
+			UpdateRevision:  "relay-2",
+		},
+	}
+	startingStatefulSet := &appsv1.StatefulSet{
+		Spec:   appsv1.StatefulSetSpec{Replicas: ptr.To[int32](2)},
+		Status: appsv1.StatefulSetStatus{ReadyReplicas: 1},
+	}
+	waitingPod := func(reason string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "relay-0"},
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}}},
+				},
+			},
+		}
+	}
+	endpoint := func(ready bool) discoveryv1.EndpointSlice {
+		return discoveryv1.EndpointSlice{
+			Endpoints: []discoveryv1.Endpoint{{Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(ready)}}},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		statefulSet *appsv1.StatefulSet
+		pods        []corev1.Pod
+		slices      []discoveryv1.EndpointSlice
+		expect      controller.RelayStatus
+	}{
+		{
+			"NotFound", nil, nil, nil,
+			controller.RelayStatus{
+				Reason:  dbov1.ConditionReasonRelayProgressing,
+				Message: "Waiting for StatefulSet to be created",
+			},
+		},
+		{
+			"Starting", startingStatefulSet, []corev1.Pod{waitingPod("ContainerCreating")}, nil,
+			controller.RelayStatus{
+				Reason:  dbov1.ConditionReasonRelayProgressing,
+				Message: "Waiting for relay pods to be ready (1/2)",
+			},
+		},
+		{
+			"ImagePullBackOff", startingStatefulSet, []corev1.Pod{waitingPod("ImagePullBackOff")}, nil,
+			controller.RelayStatus{
+				Reason:  dbov1.ConditionReasonRelayDegraded,
+				Message: "Pod relay-0: ImagePullBackOff",
+			},
+		},
+		{
+			"CrashLoopBackOff", startingStatefulSet, []corev1.Pod{waitingPod("CrashLoopBackOff")}, nil,
+			controller.RelayStatus{
+				Reason:  dbov1.ConditionReasonRelayDegraded,
+				Message: "Pod relay-0: CrashLoopBackOff",
+			},
+		},
+		{
+			"Updating", updatingStatefulSet, nil, []discoveryv1.EndpointSlice{endpoint(true)},
+			controller.RelayStatus{
+				Reason:  dbov1.ConditionReasonRelayProgressing,
+				Message: "Waiting for relay pods to be updated (1/2)",
+			},
+		},
+		{
+			"UpdatingCrashLoopBackOff", updatingStatefulSet, []corev1.Pod{waitingPod("CrashLoopBackOff")}, nil,
+			controller.RelayStatus{
+				Reason:  dbov1.ConditionReasonRelayDegraded,
+				Message: "Pod relay-0: CrashLoopBackOff",
+			},
+		},
+		{
+			"NoEndpoints", readyStatefulSet, nil, []discoveryv1.EndpointSlice{endpoint(false)},
+			controller.RelayStatus{
+				Reason:  dbov1.ConditionReasonRelayProgressing,
+				Message: "Waiting for Service endpoints to be ready",
+			},
+		},
+		{
+			"Ready", readyStatefulSet, nil, []discoveryv1.EndpointSlice{endpoint(false), endpoint(true)},
+			controller.RelayStatus{
+				Ready:   true,
+				Reason:  dbov1.ConditionReasonAvailable,
+				Message: "Relay is serving",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start := time.Now()
+
+			c := v1test.NewMockClient()
+			c.OnGet = func(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+				if v, ok := obj.(*appsv1.StatefulSet); ok && tt.statefulSet != nil {
+					tt.statefulSet.DeepCopyInto(v)
+
+					return nil
+				}
+
+				return apierrors.NewNotFound(appsv1.Resource("statefulsets"), key.Name)
+			}
+			c.OnList = func(_ context.Context, list client.ObjectList, _ ...client.ListOption) error {
+				switch v := list.(type) {
+				case *corev1.PodList:
+					v.Items = tt.pods
+				case *discoveryv1.EndpointSliceList:
+					v.Items = tt.slices
+				}
+
+				return nil
+			}
+
+			dbAccount := v1test.NewDatabaseAccount()
+			got, err := controller.RelayStatusGet(t.Context(), c, &dbAccount)
+			if err != nil {
+				testhelp.Errorf(t, start, "controller.RelayStatusGet(): error, got '%v', want 'nil'", err)
+			}
+
+			if diff := cmp.Diff(got, tt.expect); diff != "" {
+				testhelp.Errorf(t, start, "controller.RelayStatusGet(): -got +want:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRelayConfigChecksum(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	dbAccount := v1test.NewDatabaseAccount()
+	dbAccount.Spec.Relay = &dbov1.DatabaseAccountRelay{TLS: &dbov1.RelayTLS{Client: &dbov1.RelayClientTLS{
+		SecretRef: &corev1.LocalObjectReference{Name: "relay-cert"},
+	}}}
+
+	secrets := map[string]*corev1.Secret{
+		dbAccount.GetSecretName().Name: {Data: map[string][]byte{
+			accountsvr.DatabaseKeyPGBouncerConf:  []byte("[pgbouncer]\n"),
+			accountsvr.DatabaseKeyPGBouncerUsers: []byte("\"dbuser\" \"SCRAM-SHA-256$...\"\n"),
+			accountsvr.DatabaseKeyDSN:            []byte("postgres://"),
+		}},
+		"relay-cert": {Data: map[string][]byte{corev1.TLSCertKey: []byte("cert")}},
+	}
+
+	c := v1test.NewMockClient()
+	c.OnGet = func(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+		if v, ok := obj.(*corev1.Secret); ok && secrets[key.Name] != nil {
+			secrets[key.Name].DeepCopyInto(v)
+
+			return nil
+		}
+
+		return apierrors.NewNotFound(corev1.Resource("secrets"), key.Name)
+	}
+
+	checksum := func() string {
+		v, err := controller.RelayConfigChecksum(t.Context(), c, &dbov1.DatabaseAccountControllerConfig{}, &dbAccount)
+		if err != nil {
+			testhelp.Errorf(t, start, "controller.RelayConfigChecksum(): error, got '%v', want 'nil'", err)
+		}
+
+		return v
+	}
+
+	base := checksum()
+	if v := checksum(); v != base {
+		testhelp.Errorf(t, start, "controller.RelayConfigChecksum(): not stable, got '%s', want '%s'", v, base)
+	}
+
+	secrets[dbAccount.GetSecretName().Name].Data[accountsvr.DatabaseKeyDSN] = []byte("postgres://changed")
+	if v := checksum(); v != base {
+		testhelp.Errorf(t, start, "controller.RelayConfigChecksum(): changed by a key the relay does not read")
+	}
+
+	secrets[dbAccount.GetSecretName().Name].Data[accountsvr.DatabaseKeyPGBouncerConf] = []byte("[pgbouncer]\n#\n")
+	changed := checksum()
+	if changed == base {
+		testhelp.Errorf(t, start, "controller.RelayConfigChecksum(): not changed by pgbouncer.ini")
+	}
+
+	secrets["relay-cert"].Data[corev1.TLSCertKey] = []byte("renewed")
+	if v := checksum(); v == changed {
+		testhelp.Errorf(t, start, "controller.RelayConfigChecksum(): not changed by the relay certificate")
+	}
+
+	statefulSet, _ := controller.StatefulSetGet(t.Context(), c, &dbov1.DatabaseAccountControllerConfig{}, &dbAccount)
+	if v := statefulSet.Spec.Template.Annotations["dbo.dosquad.github.io/config-checksum"]; v != checksum() {
+		testhelp.Errorf(t, start, "controller.StatefulSetGet(): checksum annotation, got '%s', want '%s'", v, checksum())
+	}
+}
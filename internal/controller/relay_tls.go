@@ -0,0 +1,285 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	"github.com/dosquad/database-operator/internal/helper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// relayTLSSecretSuffix is added to the secret name of the account for the secret holding the
+	// generated relay certificate.
+	//
+	//nolint:gosec // not credentials.
+	relayTLSSecretSuffix = "-relay-tls"
+
+	// relayTLSCAKey is the key of the generated CA private key, it is not mounted in the relay pods.
+	relayTLSCAKey = "ca.key"
+
+	relayTLSVolumeName      = "relay-tls"
+	relayTLSMountPath       = "/etc/pgbouncer-tls/"
+	relayServerCAVolumeName = "relay-server-ca"
+	relayServerCAMountPath  = "/etc/pgbouncer-server-ca/"
+
+	relayTLSCAValidity   = 10 * 365 * 24 * time.Hour
+	relayTLSCertValidity = 365 * 24 * time.Hour
+	relayTLSRenewBefore  = 30 * 24 * time.Hour
+)
+
+// relayTLSSecretName returns the name of the secret with the relay certificate, this is the referenced
+// secret when one is set otherwise the secret generated by the operator.
+func relayTLSSecretName(dbAccount *dbov1.DatabaseAccount) types.NamespacedName {
+	name := dbAccount.GetSecretName()
+	if ref := dbAccount.GetSpecRelay().GetTLS().GetClient().SecretRef; ref != nil {
+		name.Name = ref.Name
+
+		return name
+	}
+
+	return generatedRelayTLSSecretName(dbAccount)
+}
+
+// generatedRelayTLSSecretName returns the name of the secret with the relay certificate generated by the
+// operator.
+func generatedRelayTLSSecretName(dbAccount *dbov1.DatabaseAccount) types.NamespacedName {
+	name := dbAccount.GetSecretName()
+	name.Name += relayTLSSecretSuffix
+
+	return name
+}
+
+// relayDNSNames returns the DNS names of the relay Service.
+func relayDNSNames(dbAccount *dbov1.DatabaseAccount) []string {
+	name := dbAccount.GetStatefulSetName()
+
+	return []string{
+		name.Name,
+		fmt.Sprintf("%s.%s", name.Name, name.Namespace),
+		fmt.Sprintf("%s.%s.svc", name.Name, name.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", name.Name, name.Namespace),
+	}
+}
+
+// relayTLSVolumes returns the volumes and mounts of the relay certificate and the CA bundle of the
+// database server.
+func relayTLSVolumes(dbAccount *dbov1.DatabaseAccount) ([]corev1.Volume, []corev1.VolumeMount) {
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+
+	tls := dbAccount.GetSpecRelay().GetTLS()
+	if tls.GetClient() != nil {
+		source := &corev1.SecretVolumeSource{
+			SecretName:  relayTLSSecretName(dbAccount).Name,
+			DefaultMode: ptr.To(corev1.SecretVolumeSourceDefaultMode),
+		}
+		if tls.Client.SecretRef == nil {
+			// the CA private key of the generated secret is not mounted.
+			source.Items = []corev1.KeyToPath{
+				{Key: corev1.TLSCertKey, Path: corev1.TLSCertKey},
+				{Key: corev1.TLSPrivateKeyKey, Path: corev1.TLSPrivateKeyKey},
+			}
+		}
+
+		volumes = append(volumes, corev1.Volume{
+			Name:         relayTLSVolumeName,
+			VolumeSource: corev1.VolumeSource{Secret: source},
+		})
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      relayTLSVolumeName,
+			MountPath: relayTLSMountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	if ref := tls.GetServer().GetCASecretRef(); ref != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: relayServerCAVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  ref.Name,
+					Items:       []corev1.KeyToPath{{Key: ref.Key, Path: accountsvr.DatabaseKeyCACert}},
+					DefaultMode: ptr.To(corev1.SecretVolumeSourceDefaultMode),
+				},
+			},
+		})
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      relayServerCAVolumeName,
+			MountPath: relayServerCAMountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	return volumes, mounts
+}
+
+// MutateRelayTLSSecret generates the CA and the relay certificate in the secret, the CA is only
+// replaced when it is missing or expiring and the certificate is replaced when it is expiring, is not
+// for the relay DNS names or is not signed by the CA.
+func MutateRelayTLSSecret(secret *corev1.Secret, dbAccount *dbov1.DatabaseAccount, now time.Time) error {
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Type = corev1.SecretTypeTLS
+
+	renewAt := now.Add(relayTLSRenewBefore)
+	caCert, caKey := secret.Data[accountsvr.DatabaseKeyCACert], secret.Data[relayTLSCAKey]
+	if len(caKey) == 0 || !helper.CertificateValid(caCert, nil, nil, renewAt) {
+		var err error
+		commonName := dbAccount.GetStatefulSetName().Name + " relay CA"
+		caCert, caKey, err = helper.GenerateCA(commonName, now, relayTLSCAValidity)
+		if err != nil {
+			return err
+		}
+		secret.Data[accountsvr.DatabaseKeyCACert] = caCert
+		secret.Data[relayTLSCAKey] = caKey
+	}
+
+	dnsNames := relayDNSNames(dbAccount)
+	if !helper.CertificateValid(secret.Data[corev1.TLSCertKey], caCert, dnsNames, renewAt) {
+		cert, key, err := helper.GenerateCertificate(caCert, caKey, dnsNames, now, relayTLSCertValidity)
+		if err != nil {
+			return err
+		}
+		secret.Data[corev1.TLSCertKey] = cert
+		secret.Data[corev1.TLSPrivateKeyKey] = key
+	}
+
+	return nil
+}
+
+// reconcileRelayTLS creates or renews the generated relay certificate and returns the CA bundle that
+// applications use to verify the relay, nil is returned when client TLS is not configured.
+func (r *DatabaseAccountReconciler) reconcileRelayTLS(
+	ctx context.Context,
+	dbAccount *dbov1.DatabaseAccount,
+) ([]byte, error) {
+	logger := log.FromContext(ctx)
+
+	clientTLS := dbAccount.GetSpecRelay().GetTLS().GetClient()
+	if clientTLS == nil || clientTLS.GetSSLMode() == dbov1.RelayTLSModeDisable {
+		return nil, nil
+	}
+
+	secret := &corev1.Secret{}
+	if clientTLS.SecretRef != nil {
+		if err := r.Get(ctx, relayTLSSecretName(dbAccount), secret); err != nil {
+			return nil, fmt.Errorf("relay TLS secret[%s]: %w", clientTLS.SecretRef.Name, err)
+		}
+
+		return secret.Data[accountsvr.DatabaseKeyCACert], nil
+	}
+
+	desired := relayObjectMeta(dbAccount)
+	desired.Name = relayTLSSecretName(dbAccount).Name
+	secret.ObjectMeta = metav1.ObjectMeta{Name: desired.Name, Namespace: desired.Namespace}
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+		mutateObjectMeta(&secret.ObjectMeta, &desired)
+
+		return MutateRelayTLSSecret(secret, dbAccount, r.now())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result != controllerutil.OperationResultNone {
+		logger.V(1).Info("Relay TLS secret reconciled", "result", result)
+		r.Recorder.NormalEvent(dbAccount, ReasonRelayUpdate, fmt.Sprintf("Relay TLS Secret %s", result))
+	}
+
+	return secret.Data[accountsvr.DatabaseKeyCACert], nil
+}
+
+// SetSecretRelayTLS publishes the CA of the relay and the sslmode applications should use in the
+// secret, the keys are removed when client TLS is not configured.
+func SetSecretRelayTLS(dbAccount *dbov1.DatabaseAccount, secret *corev1.Secret, caPEM []byte) {
+	clientTLS := dbAccount.GetSpecRelay().GetTLS().GetClient()
+	if !dbAccount.GetSpecCreateRelay() || clientTLS == nil {
+		delete(secret.Data, accountsvr.DatabaseKeyCACert)
+		delete(secret.Data, accountsvr.DatabaseKeySSLMode)
+
+		return
+	}
+
+	sslMode := dbov1.RelayTLSModeRequire
+	switch {
+	case clientTLS.GetSSLMode() == dbov1.RelayTLSModeDisable:
+		sslMode = dbov1.RelayTLSModeDisable
+	case len(caPEM) > 0:
+		sslMode = dbov1.RelayTLSModeVerifyFull
+	}
+
+	if len(caPEM) > 0 && sslMode != dbov1.RelayTLSModeDisable {
+		SetSecretKV(secret, accountsvr.DatabaseKeyCACert, string(caPEM))
+	} else {
+		delete(secret.Data, accountsvr.DatabaseKeyCACert)
+	}
+	SetSecretKV(secret, accountsvr.DatabaseKeySSLMode, sslMode.String())
+}
+
+// secretRelayKeys returns the keys of the account secret generated from the relay configuration.
+func secretRelayKeys() []string {
+	return append(relayConfigKeys(), accountsvr.DatabaseKeyCACert, accountsvr.DatabaseKeySSLMode)
+}
+
+// relaySecretChanged returns true if the relay keys of the secrets are different.
+func relaySecretChanged(current, desired *corev1.Secret) bool {
+	for _, key := range secretRelayKeys() {
+		if GetSecretKV(current, key) != GetSecretKV(desired, key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// syncRelaySecret updates the relay configuration in the account secret when the relay spec or the
+// relay CA has changed, a new secret version is written when the CA or SSL mode changed it. The
+// status of the account is updated by the caller.
+func (r *DatabaseAccountReconciler) syncRelaySecret(
+	ctx context.Context,
+	svr accountsvr.Server,
+	dbAccount *dbov1.DatabaseAccount,
+	caPEM []byte,
+) (bool, error) {
+	secret, err := SecretGetByName(ctx, r, dbAccount.GetSecretName())
+	if err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	var current *corev1.Secret
+	update := func(secret *corev1.Secret) error {
+		if err := AddRelayConf(ctx, svr, r.Config, dbAccount, secret); err != nil {
+			return err
+		}
+		SetSecretRelayTLS(dbAccount, secret, caPEM)
+		current = secret
+
+		return nil
+	}
+
+	desired := secret.DeepCopy()
+	if err := update(desired); err != nil {
+		return false, err
+	}
+	if !relaySecretChanged(secret, desired) {
+		return false, nil
+	}
+
+	if err := r.secretUpdate(ctx, svr, dbAccount, update); err != nil {
+		return false, err
+	}
+
+	// the version is unchanged when only the relay configuration changed.
+	return true, r.writeSecretVersion(ctx, dbAccount, current)
+}
@@ -0,0 +1,167 @@
+package controller_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	v1test "github.com/dosquad/database-operator/api/v1/test"
+	"github.com/dosquad/database-operator/internal/controller"
+	"github.com/dosquad/database-operator/internal/helper"
+	"github.com/dosquad/database-operator/internal/testhelp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestMutateRelayTLSSecret(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	dbAccount := v1test.NewDatabaseAccount()
+	dbAccount.Spec.Relay = &dbov1.DatabaseAccountRelay{TLS: &dbov1.RelayTLS{Client: &dbov1.RelayClientTLS{}}}
+	name := dbAccount.GetStatefulSetName()
+	dnsName := name.Name + "." + name.Namespace + ".svc"
+
+	secret := &corev1.Secret{}
+	if err := controller.MutateRelayTLSSecret(secret, &dbAccount, start); err != nil {
+		t.Fatalf("controller.MutateRelayTLSSecret(): error, got '%v', want 'nil'", err)
+	}
+
+	if secret.Type != corev1.SecretTypeTLS {
+		testhelp.Errorf(t, start, "controller.MutateRelayTLSSecret(): type, got '%s', want '%s'",
+			secret.Type, corev1.SecretTypeTLS)
+	}
+
+	caCert := secret.Data[accountsvr.DatabaseKeyCACert]
+	cert := secret.Data[corev1.TLSCertKey]
+	if !helper.CertificateValid(cert, caCert, nil, start) {
+		testhelp.Errorf(t, start, "controller.MutateRelayTLSSecret(): certificate not signed by the CA")
+	}
+
+	parsed, err := helper.ParseCertificatePEM(cert)
+	if err != nil || !slices.Contains(parsed.DNSNames, dnsName) {
+		testhelp.Errorf(t, start, "controller.MutateRelayTLSSecret(): certificate names missing '%s'", dnsName)
+	}
+
+	if err := controller.MutateRelayTLSSecret(secret, &dbAccount, start.Add(time.Hour)); err != nil {
+		testhelp.Errorf(t, start, "controller.MutateRelayTLSSecret(): error, got '%v', want 'nil'", err)
+	}
+
+	if string(secret.Data[corev1.TLSCertKey]) != string(cert) {
+		testhelp.Errorf(t, start, "controller.MutateRelayTLSSecret(): valid certificate was replaced")
+	}
+
+	if err := controller.MutateRelayTLSSecret(secret, &dbAccount, start.Add(360*24*time.Hour)); err != nil {
+		testhelp.Errorf(t, start, "controller.MutateRelayTLSSecret(): error, got '%v', want 'nil'", err)
+	}
+
+	if string(secret.Data[corev1.TLSCertKey]) == string(cert) {
+		testhelp.Errorf(t, start, "controller.MutateRelayTLSSecret(): expiring certificate was not renewed")
+	}
+
+	if string(secret.Data[accountsvr.DatabaseKeyCACert]) != string(caCert) {
+		testhelp.Errorf(t, start, "controller.MutateRelayTLSSecret(): valid CA was replaced")
+	}
+}
+
+func TestSetSecretRelayTLS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		relay        *dbov1.DatabaseAccountRelay
+		ca           []byte
+		expectMode   string
+		expectCACert string
+	}{
+		{"NoRelay", nil, []byte("ca"), "", ""},
+		{"NoTLS", &dbov1.DatabaseAccountRelay{}, []byte("ca"), "", ""},
+		{
+			"GeneratedCA",
+			&dbov1.DatabaseAccountRelay{TLS: &dbov1.RelayTLS{Client: &dbov1.RelayClientTLS{}}},
+			[]byte("ca"), "verify-full", "ca",
+		},
+		{
+			"SecretWithoutCA",
+			&dbov1.DatabaseAccountRelay{TLS: &dbov1.RelayTLS{Client: &dbov1.RelayClientTLS{
+				SecretRef: &corev1.LocalObjectReference{Name: "relay-cert"},
+			}}},
+			nil, "require", "",
+		},
+		{
+			"Disabled",
+			&dbov1.DatabaseAccountRelay{TLS: &dbov1.RelayTLS{Client: &dbov1.RelayClientTLS{
+				SSLMode: dbov1.RelayTLSModeDisable,
+			}}},
+			[]byte("ca"), "disable", "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			start := time.Now()
+
+			dbAccount := v1test.NewDatabaseAccount()
+			dbAccount.Spec.Relay = tt.relay
+			secret := &corev1.Secret{Data: map[string][]byte{
+				accountsvr.DatabaseKeyCACert:  []byte("old"),
+				accountsvr.DatabaseKeySSLMode: []byte("old"),
+			}}
+
+			controller.SetSecretRelayTLS(&dbAccount, secret, tt.ca)
+
+			if v := controller.GetSecretKV(secret, accountsvr.DatabaseKeySSLMode); v != tt.expectMode {
+				testhelp.Errorf(t, start, "controller.SetSecretRelayTLS(): sslmode, got '%s', want '%s'", v, tt.expectMode)
+			}
+
+			if v := controller.GetSecretKV(secret, accountsvr.DatabaseKeyCACert); v != tt.expectCACert {
+				testhelp.Errorf(t, start, "controller.SetSecretRelayTLS(): ca.crt, got '%s', want '%s'",
+					v, tt.expectCACert)
+			}
+		})
+	}
+}
+
+func TestRelayStatefulSet_TLS(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	dbAccount := v1test.NewDatabaseAccount()
+	dbAccount.Spec.Relay = &dbov1.DatabaseAccountRelay{TLS: &dbov1.RelayTLS{
+		Client: &dbov1.RelayClientTLS{},
+		Server: &dbov1.RelayServerTLS{
+			CASecretRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "db-ca"}, Key: "bundle.pem",
+			},
+		},
+	}}
+
+	statefulSet := controller.RelayStatefulSet(&dbov1.DatabaseAccountControllerConfig{}, &dbAccount)
+	volumes := map[string]corev1.Volume{}
+	for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+		volumes[volume.Name] = volume
+	}
+
+	relayTLS, ok := volumes["relay-tls"]
+	if !ok || relayTLS.Secret.SecretName != dbAccount.GetSecretName().Name+"-relay-tls" {
+		testhelp.Errorf(t, start, "controller.RelayStatefulSet(): relay-tls volume, got '%+v'", relayTLS)
+	} else {
+		for _, item := range relayTLS.Secret.Items {
+			if item.Key == "ca.key" {
+				testhelp.Errorf(t, start, "controller.RelayStatefulSet(): CA private key is mounted")
+			}
+		}
+	}
+
+	serverCA, ok := volumes["relay-server-ca"]
+	if !ok || serverCA.Secret.SecretName != "db-ca" ||
+		len(serverCA.Secret.Items) != 1 || serverCA.Secret.Items[0].Key != "bundle.pem" {
+		testhelp.Errorf(t, start, "controller.RelayStatefulSet(): relay-server-ca volume, got '%+v'", serverCA)
+	}
+
+	if v := len(statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts); v != 3 {
+		testhelp.Errorf(t, start, "controller.RelayStatefulSet(): volume mounts, got '%d', want '3'", v)
+	}
+}
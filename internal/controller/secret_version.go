@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// secretVersionType is the type of the immutable secrets of the versions of the credentials, it is
+	// not the type of the account secret so deleting a version does not remove the account.
+	secretVersionType = `dosquad.github.io/database-account-version`
+
+	// secretVersionHashLength is the length of the hash of the data added to the name of the account
+	// secret for the secret of a version.
+	secretVersionHashLength = 10
+)
+
+// secretVersionKeys are the keys of the account secret that are copied to the secret of a version,
+// the relay configuration is only used by the relay and is not versioned.
+//
+//nolint:gochecknoglobals // constant list of keys.
+var secretVersionKeys = []string{
+	accountsvr.DatabaseKeyDSN,
+	accountsvr.DatabaseKeyUsername,
+	accountsvr.DatabaseKeyPassword,
+	accountsvr.DatabaseKeyHost,
+	accountsvr.DatabaseKeyPort,
+	accountsvr.DatabaseKeyDatabase,
+	accountsvr.DatabaseKeySchema,
+	accountsvr.DatabaseKeySSLMode,
+	accountsvr.DatabaseKeyCACert,
+}
+
+// NewSecretVersion returns the immutable secret of the version of the credentials in the account
+// secret, the name of the secret is the name of the account secret with a hash of the data. Like the
+// account secret, the version is only owned by the account when it is not retained on delete.
+func NewSecretVersion(dbAccount *dbov1.DatabaseAccount, secret *corev1.Secret) *corev1.Secret {
+	data := map[string][]byte{}
+	for _, key := range secretVersionKeys {
+		if v, ok := secret.Data[key]; ok {
+			data[key] = v
+		}
+	}
+
+	hash := sha256.New()
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write(data[key])
+		hash.Write([]byte{0})
+	}
+
+	labels := maps.Clone(dbAccount.Spec.SecretTemplate.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[dbov1.LabelDatabaseAccount] = dbAccount.GetName()
+
+	version := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        secret.GetName() + "-" + hex.EncodeToString(hash.Sum(nil))[:secretVersionHashLength],
+			Namespace:   secret.GetNamespace(),
+			Annotations: dbAccount.Spec.SecretTemplate.Annotations,
+			Labels:      labels,
+		},
+		Data:      data,
+		Type:      secretVersionType,
+		Immutable: ptr.To(true),
+	}
+
+	if dbAccount.GetSpecOnDelete() != dbov1.OnDeleteRetain {
+		SecretAddOwnerRefs(version, dbAccount)
+	}
+
+	return version
+}
+
+// writeSecretVersion creates the immutable secret of the version of the credentials in the account
+// secret when it is not the current version, the previous version is deleted once the retention of
+// the controller configuration has passed. The status of the account is updated by the caller.
+func (r *DatabaseAccountReconciler) writeSecretVersion(
+	ctx context.Context,
+	dbAccount *dbov1.DatabaseAccount,
+	secret *corev1.Secret,
+) error {
+	logger := log.FromContext(ctx)
+
+	version := NewSecretVersion(dbAccount, secret)
+	if version.GetName() == dbAccount.Status.CurrentSecretName {
+		return nil
+	}
+
+	if err := r.Create(ctx, version); err != nil && !apierrors.IsAlreadyExists(err) {
+		logger.V(1).Error(err, "Unable to create secret version")
+
+		return err
+	}
+
+	// a version that is current again is no longer deleted.
+	dbAccount.Status.PreviousSecrets = slices.DeleteFunc(dbAccount.Status.PreviousSecrets,
+		func(previous dbov1.DatabaseAccountPreviousSecret) bool {
+			return previous.Name == version.GetName()
+		},
+	)
+
+	if current := dbAccount.Status.CurrentSecretName; current != "" {
+		dbAccount.Status.PreviousSecrets = append(dbAccount.Status.PreviousSecrets, dbov1.DatabaseAccountPreviousSecret{
+			Name:     current,
+			DeleteAt: metav1.NewTime(r.now().Add(r.Config.GetSecretVersionRetention())),
+		})
+	}
+	dbAccount.Status.CurrentSecretName = version.GetName()
+
+	logger.Info("Created secret version", "secret", version.GetName())
+
+	return nil
+}
+
+// deleteSecretVersions deletes the secrets of the previous versions of the credentials that are due,
+// the time until the next one is due is returned so the request can be requeued.
+func (r *DatabaseAccountReconciler) deleteSecretVersions(
+	ctx context.Context,
+	dbAccount *dbov1.DatabaseAccount,
+) (time.Duration, error) {
+	logger := log.FromContext(ctx)
+
+	if len(dbAccount.Status.PreviousSecrets) == 0 {
+		return 0, nil
+	}
+
+	var (
+		wait    time.Duration
+		kept    []dbov1.DatabaseAccountPreviousSecret
+		deleted bool
+	)
+	for _, previous := range dbAccount.Status.PreviousSecrets {
+		if until := previous.DeleteAt.Sub(r.now()); until > 0 {
+			wait = minRequeue(wait, until)
+			kept = append(kept, previous)
+
+			continue
+		}
+
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Name:      previous.Name,
+			Namespace: dbAccount.GetNamespace(),
+		}}
+		if err := r.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+			logger.V(1).Error(err, "Unable to delete secret version", "secret", previous.Name)
+
+			return 0, err
+		}
+
+		deleted = true
+		logger.Info("Deleted secret version", "secret", previous.Name)
+	}
+
+	if !deleted {
+		return wait, nil
+	}
+
+	dbAccount.Status.PreviousSecrets = kept
+	if err := r.Status().Update(ctx, dbAccount); err != nil {
+		logger.V(1).Error(err, "Unable to update DatabaseAccount")
+
+		return 0, err
+	}
+
+	return wait, nil
+}
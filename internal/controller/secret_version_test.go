@@ -0,0 +1,68 @@
+package controller_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dosquad/database-operator/accountsvr"
+	v1 "github.com/dosquad/database-operator/api/v1"
+	v1test "github.com/dosquad/database-operator/api/v1/test"
+	"github.com/dosquad/database-operator/internal/controller"
+	"github.com/dosquad/database-operator/internal/testhelp"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+)
+
+func TestNewSecretVersion(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	dbAccount := v1test.NewDatabaseAccount()
+	dbAccount.Spec.SecretTemplate.Labels = map[string]string{"team": "data"}
+
+	secret := v1test.NewSecret()
+	secret.Data = map[string][]byte{
+		accountsvr.DatabaseKeyUsername:      []byte("testaccount"),
+		accountsvr.DatabaseKeyPassword:      []byte("testpassword"),
+		accountsvr.DatabaseKeyPGBouncerConf: []byte("[databases]"),
+	}
+
+	version := controller.NewSecretVersion(&dbAccount, &secret)
+
+	if !ptr.Deref(version.Immutable, false) {
+		testhelp.Errorf(t, start, "NewSecretVersion(): immutable, got '%v', want 'true'", version.Immutable)
+	}
+
+	if diff := cmp.Diff(version.Labels, map[string]string{
+		"team":                  "data",
+		v1.LabelDatabaseAccount: dbAccount.GetName(),
+	}); diff != "" {
+		testhelp.Errorf(t, start, "NewSecretVersion(): labels -got +want:\n%s", diff)
+	}
+
+	if len(version.OwnerReferences) != 1 || version.OwnerReferences[0].Name != dbAccount.GetName() {
+		testhelp.Errorf(t, start, "NewSecretVersion(): owner references, got '%v', want '%s'",
+			version.OwnerReferences, dbAccount.GetName())
+	}
+
+	retained := dbAccount.DeepCopy()
+	retained.Spec.OnDelete = v1.OnDeleteRetain
+	if refs := controller.NewSecretVersion(retained, &secret).OwnerReferences; len(refs) != 0 {
+		testhelp.Errorf(t, start, "NewSecretVersion(): owner references, got '%v', want none when retained", refs)
+	}
+
+	// the relay configuration is not part of the version.
+	if _, ok := version.Data[accountsvr.DatabaseKeyPGBouncerConf]; ok {
+		testhelp.Errorf(t, start, "NewSecretVersion(): data, got '%v', want no relay configuration", version.Data)
+	}
+
+	if got := controller.NewSecretVersion(&dbAccount, secret.DeepCopy()).GetName(); got != version.GetName() {
+		testhelp.Errorf(t, start, "NewSecretVersion(): name, got '%s', want '%s'", got, version.GetName())
+	}
+
+	rotated := secret.DeepCopy()
+	rotated.Data[accountsvr.DatabaseKeyPassword] = []byte("rotatedpassword")
+	if got := controller.NewSecretVersion(&dbAccount, rotated).GetName(); got == version.GetName() {
+		testhelp.Errorf(t, start, "NewSecretVersion(): name, got '%s', want a new name for a new password", got)
+	}
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"slices"
+
+	v2 "github.com/dosquad/database-operator/api/v2"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// databaseAccountCRDName is the name of the DatabaseAccount CustomResourceDefinition.
+	databaseAccountCRDName = "databaseaccounts.dbo.dosquad.github.io"
+
+	// storageMigrationPageSize is the number of accounts listed at a time by the migration.
+	storageMigrationPageSize = 100
+)
+
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions/status,verbs=update
+
+// StorageVersionMigration rewrites the stored DatabaseAccounts in the storage version when the CRD
+// has objects stored in a previous version, the previous versions are then removed from the stored
+// versions of the CRD so they can be removed from the CRD in a later release.
+type StorageVersionMigration struct {
+	Client client.Client
+	Reader client.Reader
+}
+
+var _ manager.LeaderElectionRunnable = &StorageVersionMigration{}
+
+// NeedLeaderElection returns true, only the leader migrates the accounts.
+func (m *StorageVersionMigration) NeedLeaderElection() bool {
+	return true
+}
+
+// Start migrates the stored accounts, a failed migration is logged and retried the next time the
+// manager is started so it does not stop the controller.
+func (m *StorageVersionMigration) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("storage-version-migration")
+
+	if err := m.migrate(ctx); err != nil {
+		logger.Error(err, "Unable to migrate stored DatabaseAccounts")
+	}
+
+	return nil
+}
+
+func (m *StorageVersionMigration) migrate(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("storage-version-migration")
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := m.Reader.Get(ctx, client.ObjectKey{Name: databaseAccountCRDName}, crd); err != nil {
+		return err
+	}
+
+	storageVersion := ""
+	for _, version := range crd.Spec.Versions {
+		if version.Storage {
+			storageVersion = version.Name
+		}
+	}
+
+	if storageVersion == "" || slices.Equal(crd.Status.StoredVersions, []string{storageVersion}) {
+		return nil
+	}
+
+	logger.Info("Migrating stored DatabaseAccounts", "storedVersions", crd.Status.StoredVersions,
+		"storageVersion", storageVersion)
+
+	count, err := m.rewriteAccounts(ctx)
+	if err != nil {
+		return err
+	}
+
+	crd.Status.StoredVersions = []string{storageVersion}
+	if err := m.Client.Status().Update(ctx, crd); err != nil {
+		return err
+	}
+
+	logger.Info("Migrated stored DatabaseAccounts", "count", count, "storageVersion", storageVersion)
+
+	return nil
+}
+
+// rewriteAccounts writes every account back unchanged, the API server stores them in the storage
+// version. The number of accounts written is returned.
+func (m *StorageVersionMigration) rewriteAccounts(ctx context.Context) (int, error) {
+	count := 0
+	list := &v2.DatabaseAccountList{}
+
+	for {
+		err := m.Reader.List(ctx, list, client.Limit(storageMigrationPageSize), client.Continue(list.Continue))
+		if err != nil {
+			return count, err
+		}
+
+		for i := range list.Items {
+			err := m.Client.Patch(ctx, &list.Items[i], client.RawPatch(types.MergePatchType, []byte("{}")))
+			if err != nil && !apierrors.IsNotFound(err) {
+				return count, err
+			}
+
+			count++
+		}
+
+		if list.Continue == "" {
+			return count, nil
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package controller_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1test "github.com/dosquad/database-operator/api/v1/test"
+	v2 "github.com/dosquad/database-operator/api/v2"
+	"github.com/dosquad/database-operator/internal/controller"
+	"github.com/dosquad/database-operator/internal/testhelp"
+	"github.com/google/go-cmp/cmp"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newStorageMigrationClient(storedVersions ...string) (*v1test.MockClient, *[]string, *[]string) {
+	var (
+		patched []string
+		updated []string
+	)
+
+	c := v1test.NewMockClient()
+	c.OnGet = func(_ context.Context, _ client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+		if crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition); ok {
+			crd.Spec.Versions = []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1", Served: true},
+				{Name: "v2", Served: true, Storage: true},
+			}
+			crd.Status.StoredVersions = storedVersions
+		}
+
+		return nil
+	}
+	c.OnList = func(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+		listOpts := &client.ListOptions{}
+		listOpts.ApplyOptions(opts)
+
+		// the accounts are returned in two pages.
+		accounts, ok := list.(*v2.DatabaseAccountList)
+		if !ok {
+			return nil
+		}
+
+		if listOpts.Continue == "" {
+			accounts.Items = []v2.DatabaseAccount{{ObjectMeta: metav1.ObjectMeta{Name: "first"}}}
+			accounts.Continue = "next"
+		} else {
+			accounts.Items = []v2.DatabaseAccount{{ObjectMeta: metav1.ObjectMeta{Name: "second"}}}
+			accounts.Continue = ""
+		}
+
+		return nil
+	}
+	c.OnPatch = func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+		patched = append(patched, obj.GetName())
+
+		return nil
+	}
+	c.TestStatusWriter.OnUpdate = func(
+		_ context.Context, obj client.Object, _ ...client.SubResourceUpdateOption,
+	) error {
+		if crd, ok := obj.(*apiextensionsv1.CustomResourceDefinition); ok {
+			updated = crd.Status.StoredVersions
+		}
+
+		return nil
+	}
+
+	return c, &patched, &updated
+}
+
+func TestStorageVersionMigration_Start(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	c, patched, updated := newStorageMigrationClient("v1", "v2")
+	migration := &controller.StorageVersionMigration{Client: c, Reader: c}
+
+	if err := migration.Start(t.Context()); err != nil {
+		t.Fatalf("StorageVersionMigration.Start(): error, got '%v', want 'nil'", err)
+	}
+
+	if diff := cmp.Diff(*patched, []string{"first", "second"}); diff != "" {
+		testhelp.Errorf(t, start, "StorageVersionMigration.Start(): patched -got +want:\n%s", diff)
+	}
+
+	if diff := cmp.Diff(*updated, []string{"v2"}); diff != "" {
+		testhelp.Errorf(t, start, "StorageVersionMigration.Start(): storedVersions -got +want:\n%s", diff)
+	}
+}
+
+func TestStorageVersionMigration_Start_Migrated(t *testing.T) {
+	t.Parallel()
+	start := time.Now()
+
+	c, patched, updated := newStorageMigrationClient("v2")
+	migration := &controller.StorageVersionMigration{Client: c, Reader: c}
+
+	if err := migration.Start(t.Context()); err != nil {
+		t.Fatalf("StorageVersionMigration.Start(): error, got '%v', want 'nil'", err)
+	}
+
+	if len(*patched) != 0 || *updated != nil {
+		testhelp.Errorf(t, start, "StorageVersionMigration.Start(): got patched '%v' updated '%v', want none",
+			*patched, *updated)
+	}
+}
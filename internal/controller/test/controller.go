@@ -13,6 +13,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -23,6 +24,9 @@ type ControllerMockWrapper struct {
 	start                        time.Time
 	calledFunc                   map[string]int
 	Secret, OriginalSecret       *corev1.Secret
+	SecretVersions               map[string]*corev1.Secret
+	DBServer                     *v1.DatabaseServer
+	DBServerSecret               *corev1.Secret
 	DBAccount, OriginalDBAccount *v1.DatabaseAccount
 	Client                       *v1test.MockClient
 }
@@ -71,6 +75,9 @@ func (c *ControllerMockWrapper) initMockClientReaderOnGet() {
 		c.IncCallCount(
 			fmt.Sprintf("MockClientReader.Get(%s)", reflect.TypeOf(obj).String()),
 		)
+		if c.getDatabaseServer(key, obj) {
+			return nil
+		}
 		if key.String() == fmt.Sprintf("%s/%s", c.DBAccount.GetNamespace(), c.DBAccount.GetName()) {
 			switch v := obj.(type) {
 			case *v1.DatabaseAccount:
@@ -87,6 +94,10 @@ func (c *ControllerMockWrapper) initMockClientReaderOnGet() {
 				}
 			}
 		}
+		if v, ok := obj.(*corev1.Secret); ok && c.SecretVersions[key.Name] != nil {
+			*v = *c.SecretVersions[key.Name]
+			return nil
+		}
 
 		return apierrors.NewNotFound(
 			schema.GroupResource{Resource: "databaseaccounts"},
@@ -95,6 +106,29 @@ func (c *ControllerMockWrapper) initMockClientReaderOnGet() {
 	}
 }
 
+// getDatabaseServer returns true if the key is the DatabaseServer or its DSN secret and the
+// object has been set.
+func (c *ControllerMockWrapper) getDatabaseServer(key client.ObjectKey, obj client.Object) bool {
+	if c.DBServer == nil {
+		return false
+	}
+
+	switch v := obj.(type) {
+	case *v1.DatabaseServer:
+		if key.Namespace == "" && key.Name == c.DBServer.GetName() {
+			*v = *c.DBServer
+			return true
+		}
+	case *corev1.Secret:
+		if key == c.DBServer.GetDSNSecretName() {
+			*v = *c.DBServerSecret
+			return true
+		}
+	}
+
+	return false
+}
+
 func (c *ControllerMockWrapper) initMockClientWriterOnCreate() {
 	c.Client.MockClientWriter.OnCreate = func(_ context.Context, obj client.Object, opts ...client.CreateOption) error {
 		testhelp.Logf(c.t, c.start, "MockClientWriter.Create(ctx, '%+v', '%+v')", obj, opts)
@@ -110,6 +144,10 @@ func (c *ControllerMockWrapper) initMockClientWriterOnCreate() {
 		case *corev1.Secret:
 			testhelp.Logf(c.t, c.start, "Object is corev1.Secret: %+v", obj)
 			// c.IncCallCount("MockClientWriter.Create(*corev1.Secret)")
+			if c.isSecretVersion(v) {
+				c.SecretVersions[v.GetName()] = v
+				return nil
+			}
 			c.Secret = v
 			return nil
 		case *appsv1.StatefulSet:
@@ -123,6 +161,23 @@ func (c *ControllerMockWrapper) initMockClientWriterOnCreate() {
 	}
 }
 
+func (c *ControllerMockWrapper) initMockClientWriterOnDelete() {
+	c.Client.MockClientWriter.OnDelete = func(_ context.Context, obj client.Object, opts ...client.DeleteOption) error {
+		testhelp.Logf(c.t, c.start, "MockClientWriter.Delete(ctx, '%+v', '%+v')", obj, opts)
+		c.IncCallCount(
+			fmt.Sprintf("MockClientWriter.Delete(%s)", reflect.TypeOf(obj).String()),
+		)
+		if v, ok := obj.(*corev1.Secret); ok {
+			if c.isSecretVersion(v) {
+				delete(c.SecretVersions, v.GetName())
+				return nil
+			}
+			c.Secret = nil
+		}
+		return nil
+	}
+}
+
 func (c *ControllerMockWrapper) initMockClientWriterOnUpdate() {
 	c.Client.MockClientWriter.OnUpdate = func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
 		testhelp.Logf(c.t, c.start, "MockClientWriter.Update(ctx, obj): %+v", obj)
@@ -169,9 +224,17 @@ func (c *ControllerMockWrapper) initTestStatusWriterOnUpdate() {
 	}
 }
 
+// isSecretVersion returns true if the secret is not the account secret, the secrets of the versions
+// of the credentials are kept separately.
+func (c *ControllerMockWrapper) isSecretVersion(secret *corev1.Secret) bool {
+	return c.DBAccount != nil && secret.GetName() != c.DBAccount.GetSecretName().Name
+}
+
 func (c *ControllerMockWrapper) Init() {
+	c.SecretVersions = map[string]*corev1.Secret{}
 	c.initMockClientReaderOnGet()
 	c.initMockClientWriterOnCreate()
+	c.initMockClientWriterOnDelete()
 	c.initMockClientWriterOnUpdate()
 	c.initTestStatusWriterOnUpdate()
 }
@@ -196,6 +259,20 @@ func (c *ControllerMockWrapper) InitSecrets(f func(secret *corev1.Secret)) {
 	c.Secret.DeepCopyInto(c.OriginalSecret)
 }
 
+// InitDatabaseServer sets the DatabaseServer with the name and its DSN secret.
+func (c *ControllerMockWrapper) InitDatabaseServer(name, dsn string) {
+	c.DBServer = &v1.DatabaseServer{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.DatabaseServerSpec{
+			DSNSecretRef: v1.DatabaseServerSecretRef{Namespace: "dbo-system", Name: name + "-dsn"},
+		},
+	}
+	c.DBServerSecret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "dbo-system", Name: name + "-dsn"},
+		Data:       map[string][]byte{v1.DefaultDatabaseServerDSNKey: []byte(dsn)},
+	}
+}
+
 func (c *ControllerMockWrapper) GetDatabaseAccount() v1.DatabaseAccount {
 	if c.DBAccount != nil {
 		return *c.DBAccount
@@ -1,10 +1,15 @@
 package test
 
 import (
+	"time"
+
 	v1 "github.com/dosquad/database-operator/api/v1"
 	v1test "github.com/dosquad/database-operator/api/v1/test"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 type ReconcilePreModfunc func(obj *v1.DatabaseAccount)
@@ -26,6 +31,184 @@ func ReconcileWantReady(ready bool) ReconcileModDBFunc {
 	}
 }
 
+func ReconcileWantCondition(
+	conditionType string,
+	status metav1.ConditionStatus,
+	reason string,
+) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		meta.SetStatusCondition(&want.Status.Conditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             status,
+			Reason:             reason,
+			ObservedGeneration: want.GetGeneration(),
+		})
+		want.Status.ObservedGeneration = want.GetGeneration()
+	}
+}
+
+func ReconcileWantPendingConditions(want *v1.DatabaseAccount) {
+	ReconcileWantCondition(v1.ConditionReady, metav1.ConditionFalse, v1.ConditionReasonPending)(want)
+	ReconcileWantCondition(v1.ConditionUserReady, metav1.ConditionFalse, v1.ConditionReasonPending)(want)
+	ReconcileWantCondition(v1.ConditionDatabaseReady, metav1.ConditionFalse, v1.ConditionReasonPending)(want)
+	ReconcileWantCondition(v1.ConditionRelayReady, metav1.ConditionTrue, v1.ConditionReasonNotRequired)(want)
+	ReconcileWantCondition(v1.ConditionDegraded, metav1.ConditionFalse, v1.ConditionReasonAsExpected)(want)
+}
+
+func ReconcileWantReadyConditions(want *v1.DatabaseAccount) {
+	ReconcileWantCondition(v1.ConditionUserReady, metav1.ConditionTrue, v1.ConditionReasonCreated)(want)
+	ReconcileWantCondition(v1.ConditionDatabaseReady, metav1.ConditionTrue, v1.ConditionReasonCreated)(want)
+	ReconcileWantCondition(v1.ConditionRelayReady, metav1.ConditionTrue, v1.ConditionReasonNotRequired)(want)
+	ReconcileWantCondition(v1.ConditionDegraded, metav1.ConditionFalse, v1.ConditionReasonAsExpected)(want)
+	ReconcileWantCondition(v1.ConditionReady, metav1.ConditionTrue, v1.ConditionReasonAvailable)(want)
+	want.Status.Ready = true
+}
+
+func ReconcileWantPasswordRotation(rotation *v1.DatabaseAccountPasswordRotation) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Spec.PasswordRotation = rotation
+	}
+}
+
+func ReconcileWantLastRotated(lastRotated time.Time) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Status.LastRotated = ptr.To(metav1.NewTime(lastRotated))
+	}
+}
+
+func ReconcileWantRotateAt(value string) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		if want.Annotations == nil {
+			want.Annotations = map[string]string{}
+		}
+		want.Annotations[v1.AnnotationRotateAt] = value
+	}
+}
+
+func ReconcileWantRotateAtHandled(value string) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Status.RotateAtHandled = value
+	}
+}
+
+func ReconcileWantLoginRoles(active, previous string, revokeAt time.Time) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Status.ActiveRole = active
+		want.Status.PreviousRole = previous
+		want.Status.RevokeAt = nil
+		if !revokeAt.IsZero() {
+			want.Status.RevokeAt = ptr.To(metav1.NewTime(revokeAt))
+		}
+	}
+}
+
+func ReconcileWantDegraded(reason, message string) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		ReconcileWantCondition(v1.ConditionDegraded, metav1.ConditionTrue, reason)(want)
+		ReconcileWantCondition(v1.ConditionReady, metav1.ConditionFalse, reason)(want)
+		want.Status.Ready = false
+		want.Status.Error = true
+		want.Status.ErrorMessage = message
+	}
+}
+
+func ReconcileWantSpecUsername(username string) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Spec.Username = username
+	}
+}
+
+func ReconcileWantUsername(username string) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Spec.Username = username
+		want.Status.Username = username
+	}
+}
+
+func ReconcileWantSecretPolicy(policy v1.DatabaseAccountSecretPolicy) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Spec.SecretPolicy = policy
+	}
+}
+
+func ReconcileWantSecretVersions(current string) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Status.CurrentSecretName = current
+	}
+}
+
+func ReconcileWantPreviousSecret(name string, deleteAt time.Time) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Status.PreviousSecrets = append(want.Status.PreviousSecrets, v1.DatabaseAccountPreviousSecret{
+			Name:     name,
+			DeleteAt: metav1.NewTime(deleteAt),
+		})
+	}
+}
+
+func ReconcileWantRole(role *v1.DatabaseAccountRole) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Spec.Role = role
+	}
+}
+
+func ReconcileWantRoleApplied(role *v1.DatabaseAccountRole) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Status.Role = role
+	}
+}
+
+func ReconcileWantSettings(roleSettings, databaseSettings map[string]string) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Spec.RoleSettings = roleSettings
+		want.Spec.DatabaseSettings = databaseSettings
+	}
+}
+
+func ReconcileWantSettingsApplied(roleSettings, databaseSettings map[string]string) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Status.RoleSettings = roleSettings
+		want.Status.DatabaseSettings = databaseSettings
+	}
+}
+
+func ReconcileWantExtensions(extensions []v1.DatabaseAccountExtension) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Spec.Extensions = extensions
+	}
+}
+
+func ReconcileWantExtensionsInstalled(versions map[string]string) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Status.Extensions = versions
+	}
+}
+
+func ReconcileWantSchemas(schemas []string, defaultSchema string) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Spec.Schemas = schemas
+		want.Spec.DefaultSchema = defaultSchema
+	}
+}
+
+func ReconcileWantSchemasCreated(schemas []string) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Status.Schemas = schemas
+	}
+}
+
+func ReconcileWantRelay(relay *v1.DatabaseAccountRelay) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Spec.Relay = relay
+	}
+}
+
+func ReconcileWantRelayMode(mode v1.RelayMode) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Status.RelayMode = mode
+	}
+}
+
 func ReconcileWantDBName(name v1.PostgreSQLResourceName) ReconcileModDBFunc {
 	return func(want *v1.DatabaseAccount) {
 		want.Status.Name = name
@@ -36,15 +219,44 @@ func ReconcileWantDBFinalizer(want *v1.DatabaseAccount) {
 	want.ObjectMeta.Finalizers = []string{"dbo.dosquad.github.io/database-account-finalizer"}
 }
 
+func ReconcileWantNoDBFinalizer(want *v1.DatabaseAccount) {
+	want.ObjectMeta.Finalizers = []string{}
+}
+
+func ReconcileWantDeleting(want *v1.DatabaseAccount) {
+	want.ObjectMeta.DeletionTimestamp = &metav1.Time{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+}
+
+func ReconcileWantOnDelete(onDelete v1.DatabaseAccountOnDelete) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Spec.OnDelete = onDelete
+	}
+}
+
+func ReconcileWantServerRef(name string) ReconcileModDBFunc {
+	return func(want *v1.DatabaseAccount) {
+		want.Spec.ServerRef = &v1.DatabaseAccountServerRef{Name: name}
+	}
+}
+
 func ReconcileStatusDBUser(want *v1.DatabaseAccount) {
 	want.Status.Name = v1test.DBUser
 }
 
 func CompareAccountsIgnore() cmp.Option {
-	return cmpopts.IgnoreFields(
-		v1.DatabaseAccount{},
-		"Status.Name",
-	)
+	return cmp.Options{
+		cmpopts.IgnoreFields(
+			v1.DatabaseAccount{},
+			"Status.Name",
+		),
+		cmpopts.IgnoreFields(
+			metav1.Condition{},
+			"LastTransitionTime", "Message",
+		),
+		cmpopts.SortSlices(func(a, b metav1.Condition) bool {
+			return a.Type < b.Type
+		}),
+	}
 }
 
 func CompareEventsIgnore() cmp.Option {
@@ -7,6 +7,7 @@ import (
 	"github.com/dosquad/database-operator/accountsvr"
 	accountsvrtest "github.com/dosquad/database-operator/accountsvr/test"
 	v1 "github.com/dosquad/database-operator/api/v1"
+	"github.com/dosquad/database-operator/internal/helper"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -30,6 +31,13 @@ func ReconcileWantSecretNamePassword(want *corev1.Secret) {
 	ReconcileWantSecretDataValue(accountsvr.DatabaseKeyPassword, "mockpassword")(want)
 }
 
+// ReconcileWantSecretVerifier sets the verifier of the mock password, the salt is fixed so the secret
+// matches the expected secret.
+func ReconcileWantSecretVerifier(want *corev1.Secret) {
+	verifier, _ := helper.ScramSHA256VerifierWithSalt("mockpassword", []byte("mocksalt"), 4096)
+	ReconcileWantSecretDataValue(accountsvr.DatabaseKeyVerifier, verifier)(want)
+}
+
 func ReconcileWantSecretInit(want *corev1.Secret) {
 	u, _ := url.Parse(accountsvrtest.TestDSN)
 	ReconcileWantSecretDataValue(accountsvr.DatabaseKeyHost, u.Hostname())(want)
@@ -47,6 +55,41 @@ func ReconcileWantSecretDatabaseDSN(want *corev1.Secret) {
 	ReconcileWantSecretDataValue(accountsvr.DatabaseKeyDSN, dsn)(want)
 }
 
+func ReconcileWantSecretPassword(password string) ReconcileModSecretFunc {
+	return ReconcileWantSecretLogin(NewDatabaseAccountName().String(), password)
+}
+
+func ReconcileWantSecretLogin(username, password string) ReconcileModSecretFunc {
+	return func(want *corev1.Secret) {
+		dbName := NewDatabaseAccountName().String()
+		u, _ := url.Parse(accountsvrtest.TestDSN)
+		dsn := fmt.Sprintf("postgres://%s:%s@%s/%s", username, password, u.Host, dbName)
+		ReconcileWantSecretDataValue(accountsvr.DatabaseKeyUsername, username)(want)
+		ReconcileWantSecretDataValue(accountsvr.DatabaseKeyPassword, password)(want)
+		ReconcileWantSecretDataValue(accountsvr.DatabaseKeyDSN, dsn)(want)
+	}
+}
+
+func ReconcileWantSecretImmutable(immutable bool) ReconcileModSecretFunc {
+	return func(want *corev1.Secret) {
+		want.Immutable = &immutable
+	}
+}
+
+// ReconcileWantSecretReplaced removes the fields that are not copied when a secret is replaced.
+func ReconcileWantSecretReplaced(want *corev1.Secret) {
+	want.ObjectMeta = metav1.ObjectMeta{
+		Name:            want.GetName(),
+		Namespace:       want.GetNamespace(),
+		Annotations:     want.GetAnnotations(),
+		Labels:          want.GetLabels(),
+		OwnerReferences: want.GetOwnerReferences(),
+		Finalizers:      want.GetFinalizers(),
+	}
+	want.TypeMeta = metav1.TypeMeta{}
+	want.Immutable = nil
+}
+
 func ReconcileWantSecretDataValue(key, value string) ReconcileModSecretFunc {
 	return func(want *corev1.Secret) {
 		if want.StringData == nil {
@@ -50,6 +50,10 @@ func LoadConfigFile(configFile string, options ctrl.Options) (
 		}
 	}
 
+	if err := newObj.RelayDefaults.Validate(); err != nil {
+		return options, newObj, fmt.Errorf("relayDefaults: %w", err)
+	}
+
 	options = setLeaderElectionConfig(options, newObj)
 
 	if options.Cache.SyncPeriod == nil && newObj.SyncPeriod != nil {
@@ -0,0 +1,84 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ScramSHA256Prefix is the prefix of a SCRAM-SHA-256 verifier.
+	ScramSHA256Prefix = "SCRAM-SHA-256"
+
+	scramIterations = 4096
+	scramSaltLength = 16
+	scramKeyLength  = sha256.Size
+)
+
+// ScramSHA256Verifier returns the SCRAM-SHA-256 verifier of the password in the format PostgreSQL
+// stores in pg_authid, the salt is random. The verifier is stored with the credentials so the relay
+// auth file uses the same verifier as the role, PgBouncer can only log in to the server with the
+// verifier when both match.
+//
+// Passwords are not normalised with SASLprep, the generated passwords are ASCII which SASLprep does
+// not change.
+func ScramSHA256Verifier(password string) (string, error) {
+	salt := make([]byte, scramSaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	return ScramSHA256VerifierWithSalt(password, salt, scramIterations)
+}
+
+// ScramSHA256VerifierMatches returns true if the verifier was generated from the password.
+func ScramSHA256VerifierMatches(verifier, password string) bool {
+	var iterations int
+	var salt, keys string
+	if _, err := fmt.Sscanf(
+		strings.Replace(verifier, "$", " ", 2), ScramSHA256Prefix+" %d:%s %s", &iterations, &salt, &keys,
+	); err != nil {
+		return false
+	}
+
+	saltBytes, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return false
+	}
+
+	expect, err := ScramSHA256VerifierWithSalt(password, saltBytes, iterations)
+
+	return err == nil && hmac.Equal([]byte(expect), []byte(verifier))
+}
+
+// ScramSHA256VerifierWithSalt returns the SCRAM-SHA-256 verifier of the password using the salt and
+// iteration count.
+func ScramSHA256VerifierWithSalt(password string, salt []byte, iterations int) (string, error) {
+	saltedPassword, err := pbkdf2.Key(sha256.New, password, salt, iterations, scramKeyLength)
+	if err != nil {
+		return "", err
+	}
+
+	clientKey := scramHMAC(saltedPassword, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	serverKey := scramHMAC(saltedPassword, "Server Key")
+
+	return fmt.Sprintf("%s$%d:%s$%s:%s",
+		ScramSHA256Prefix,
+		iterations,
+		base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(storedKey[:]),
+		base64.StdEncoding.EncodeToString(serverKey),
+	), nil
+}
+
+func scramHMAC(key []byte, message string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(message))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,79 @@
+package helper_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/dosquad/database-operator/internal/helper"
+)
+
+func TestScramSHA256VerifierWithSalt(t *testing.T) {
+	t.Parallel()
+
+	salt, err := base64.StdEncoding.DecodeString("W22ZaJ0SNY7soEsUEjb6gQ==")
+	if err != nil {
+		t.Fatalf("base64.DecodeString(): error, got '%v', want 'nil'", err)
+	}
+
+	expect := "SCRAM-SHA-256$4096:W22ZaJ0SNY7soEsUEjb6gQ==" +
+		"$WG5d8oPm3OtcPnkdi4Uo7BkeZkBFzpcXkuLmtbsT4qY=:wfPLwcE6nTWhTAmQ7tl2KeoiWGPlZqQxSrmfPwDl2dU="
+
+	got, err := helper.ScramSHA256VerifierWithSalt("pencil", salt, 4096)
+	if err != nil {
+		t.Errorf("helper.ScramSHA256VerifierWithSalt(): error, got '%v', want 'nil'", err)
+	}
+
+	if got != expect {
+		t.Errorf("helper.ScramSHA256VerifierWithSalt(): got '%s', want '%s'", got, expect)
+	}
+}
+
+func TestScramSHA256Verifier(t *testing.T) {
+	t.Parallel()
+
+	v1, err := helper.ScramSHA256Verifier("dbpass")
+	if err != nil {
+		t.Errorf("helper.ScramSHA256Verifier(): error, got '%v', want 'nil'", err)
+	}
+
+	v2, _ := helper.ScramSHA256Verifier("dbpass")
+	if v1 == v2 {
+		t.Errorf("helper.ScramSHA256Verifier(): verifier should have a random salt: %s == %s", v1, v2)
+	}
+
+	for _, v := range []string{v1, v2} {
+		if !helper.ScramSHA256VerifierMatches(v, "dbpass") {
+			t.Errorf("helper.ScramSHA256VerifierMatches(): got 'false', want 'true' for '%s'", v)
+		}
+	}
+}
+
+func TestScramSHA256VerifierMatches(t *testing.T) {
+	t.Parallel()
+
+	verifier := "SCRAM-SHA-256$4096:W22ZaJ0SNY7soEsUEjb6gQ==" +
+		"$WG5d8oPm3OtcPnkdi4Uo7BkeZkBFzpcXkuLmtbsT4qY=:wfPLwcE6nTWhTAmQ7tl2KeoiWGPlZqQxSrmfPwDl2dU="
+
+	tests := []struct {
+		name     string
+		verifier string
+		password string
+		expect   bool
+	}{
+		{"Match", verifier, "pencil", true},
+		{"WrongPassword", verifier, "pen", false},
+		{"Empty", "", "pencil", false},
+		{"Malformed", "SCRAM-SHA-256$4096:salt", "pencil", false},
+		{"InvalidSalt", "SCRAM-SHA-256$4096:!!$a:b", "pencil", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := helper.ScramSHA256VerifierMatches(tt.verifier, tt.password); got != tt.expect {
+				t.Errorf("helper.ScramSHA256VerifierMatches(): got '%t', want '%t'", got, tt.expect)
+			}
+		})
+	}
+}
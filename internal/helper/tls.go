@@ -0,0 +1,157 @@
+package helper
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"slices"
+	"time"
+)
+
+const (
+	pemTypeCertificate = "CERTIFICATE"
+	pemTypePrivateKey  = "PRIVATE KEY"
+)
+
+// ErrInvalidPEM is returned when PEM data does not contain the expected block.
+var ErrInvalidPEM = errors.New("invalid PEM data")
+
+// GenerateCA returns a self-signed CA certificate and its private key in PEM format.
+func GenerateCA(commonName string, notBefore time.Time, validity time.Duration) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template, err := certificateTemplate(commonName, notBefore, validity)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return encodeCertificate(der, key)
+}
+
+// GenerateCertificate returns a server certificate for the DNS names signed by the CA and its private
+// key in PEM format.
+func GenerateCertificate(
+	caCertPEM, caKeyPEM []byte,
+	dnsNames []string,
+	notBefore time.Time,
+	validity time.Duration,
+) ([]byte, []byte, error) {
+	caCert, err := ParseCertificatePEM(caCertPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	caKey, err := parsePrivateKeyPEM(caKeyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var commonName string
+	if len(dnsNames) > 0 {
+		commonName = dnsNames[0]
+	}
+
+	template, err := certificateTemplate(commonName, notBefore, validity)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.DNSNames = dnsNames
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return encodeCertificate(der, key)
+}
+
+// ParseCertificatePEM returns the first certificate in the PEM data.
+func ParseCertificatePEM(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != pemTypeCertificate {
+		return nil, ErrInvalidPEM
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// CertificateValid returns true if the certificate is signed by the CA, is for the DNS names and does
+// not expire before the renew time.
+func CertificateValid(certPEM, caCertPEM []byte, dnsNames []string, renewAt time.Time) bool {
+	cert, err := ParseCertificatePEM(certPEM)
+	if err != nil {
+		return false
+	}
+
+	if caCertPEM != nil {
+		caCert, err := ParseCertificatePEM(caCertPEM)
+		if err != nil || !bytes.Equal(cert.RawIssuer, caCert.RawSubject) ||
+			cert.CheckSignatureFrom(caCert) != nil {
+			return false
+		}
+	}
+
+	if dnsNames != nil && !slices.Equal(cert.DNSNames, dnsNames) {
+		return false
+	}
+
+	return renewAt.Before(cert.NotAfter)
+}
+
+func certificateTemplate(commonName string, notBefore time.Time, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    notBefore.Add(-time.Hour),
+		NotAfter:     notBefore.Add(validity),
+	}, nil
+}
+
+func encodeCertificate(der []byte, key *ecdsa.PrivateKey) ([]byte, []byte, error) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypeCertificate, Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: pemTypePrivateKey, Bytes: keyDER}),
+		nil
+}
+
+func parsePrivateKeyPEM(keyPEM []byte) (any, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil || block.Type != pemTypePrivateKey {
+		return nil, ErrInvalidPEM
+	}
+
+	return x509.ParsePKCS8PrivateKey(block.Bytes)
+}
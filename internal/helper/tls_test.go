@@ -0,0 +1,75 @@
+package helper_test
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/dosquad/database-operator/internal/helper"
+)
+
+func TestGenerateCertificate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	dnsNames := []string{"relay", "relay.default.svc"}
+
+	caCert, caKey, err := helper.GenerateCA("relay CA", now, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("helper.GenerateCA(): error, got '%v', want 'nil'", err)
+	}
+
+	cert, key, err := helper.GenerateCertificate(caCert, caKey, dnsNames, now, time.Hour)
+	if err != nil {
+		t.Fatalf("helper.GenerateCertificate(): error, got '%v', want 'nil'", err)
+	}
+
+	if len(key) == 0 {
+		t.Errorf("helper.GenerateCertificate(): key, got empty, want PEM")
+	}
+
+	parsed, err := helper.ParseCertificatePEM(cert)
+	if err != nil {
+		t.Fatalf("helper.ParseCertificatePEM(): error, got '%v', want 'nil'", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caCert)
+	if _, err := parsed.Verify(x509.VerifyOptions{DNSName: "relay.default.svc", Roots: roots}); err != nil {
+		t.Errorf("x509.Certificate.Verify(): error, got '%v', want 'nil'", err)
+	}
+
+	otherCA, _, _ := helper.GenerateCA("other CA", now, 24*time.Hour)
+
+	tests := []struct {
+		name     string
+		caCert   []byte
+		dnsNames []string
+		renewAt  time.Time
+		expect   bool
+	}{
+		{"Valid", caCert, dnsNames, now, true},
+		{"AnyNames", caCert, nil, now, true},
+		{"OtherCA", otherCA, dnsNames, now, false},
+		{"OtherNames", caCert, []string{"relay"}, now, false},
+		{"Expiring", caCert, dnsNames, now.Add(2 * time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := helper.CertificateValid(cert, tt.caCert, tt.dnsNames, tt.renewAt); got != tt.expect {
+				t.Errorf("helper.CertificateValid(): got '%t', want '%t'", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestCertificateValid_InvalidPEM(t *testing.T) {
+	t.Parallel()
+
+	if helper.CertificateValid([]byte("not a certificate"), nil, nil, time.Now()) {
+		t.Errorf("helper.CertificateValid(): got 'true', want 'false'")
+	}
+}
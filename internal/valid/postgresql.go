@@ -8,10 +8,16 @@ import (
 )
 
 var (
-	validNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]+$`)
-	nameRegex      = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
-
-	ErrInvalidName = errors.New("invalid name")
+	validNameRegex    = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]+$`)
+	validSchemaRegex  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	nameRegex         = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+	validSettingRegex = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+	validExtRegex     = regexp.MustCompile(`^[a-z_][a-z0-9_-]*$`)
+
+	ErrInvalidName    = errors.New("invalid name")
+	ErrInvalidSchema  = errors.New("invalid schema name")
+	ErrInvalidSetting = errors.New("invalid setting")
+	ErrInvalidExt     = errors.New("invalid extension name")
 )
 
 const (
@@ -64,6 +70,35 @@ func (ident PGIdentifier) Validate() (string, error) {
 	return name, nil
 }
 
+// PGSchemaName a PostgreSQL schema name, unlike account identifiers short names such as "app" are
+// allowed and the reserved "pg_" prefix is rejected.
+type PGSchemaName string
+
+// Validate returns the schema name with invalid characters removed or an error if it is not a valid
+// schema name.
+func (name PGSchemaName) Validate() (string, error) {
+	s := nameRegex.ReplaceAllString(string(name), "")
+
+	if !validSchemaRegex.MatchString(s) {
+		return s, fmt.Errorf("%w: invalid characters", ErrInvalidSchema)
+	}
+
+	if len(s) > PostgreSQLNameDataLen-1 {
+		return s, fmt.Errorf("%w: name too long", ErrInvalidSchema)
+	}
+
+	if strings.HasPrefix(strings.ToLower(s), "pg_") {
+		return s, fmt.Errorf("%w: the pg_ prefix is reserved", ErrInvalidSchema)
+	}
+
+	return s, nil
+}
+
+// Sanitize returns a sanitized string safe for SQL interpolation.
+func (name PGSchemaName) Sanitize() string {
+	return PGIdentifier(name).Sanitize()
+}
+
 // PGValue a PostgreSQL value.
 type PGValue string
 
@@ -81,3 +116,88 @@ func (ident PGValue) String() string {
 	s = strings.ReplaceAll(s, `@`, ``)
 	return s
 }
+
+// PGSettingName a PostgreSQL run-time parameter name, custom parameters are prefixed with the
+// extension or application name such as "myapp.tenant".
+type PGSettingName string
+
+// Validate returns the lower case parameter name or an error if it is not a valid parameter that
+// can be set on a role or database.
+func (name PGSettingName) Validate() (string, error) {
+	s := strings.ToLower(string(name))
+
+	if !validSettingRegex.MatchString(s) {
+		return s, fmt.Errorf("%w: invalid characters", ErrInvalidSetting)
+	}
+
+	if len(s) > PostgreSQLNameDataLen-1 {
+		return s, fmt.Errorf("%w: name too long", ErrInvalidSetting)
+	}
+
+	switch s {
+	case "role", "session_authorization":
+		// these change the identity of the session.
+		return s, fmt.Errorf("%w: %s can not be set", ErrInvalidSetting, s)
+	}
+
+	return s, nil
+}
+
+// IsList returns true if the parameter takes a list of values.
+func (name PGSettingName) IsList() bool {
+	switch strings.ToLower(string(name)) {
+	case "search_path", "temp_tablespaces", "local_preload_libraries", "session_preload_libraries":
+		return true
+	}
+
+	return false
+}
+
+// SanitizeValue returns the value quoted for SQL interpolation, the values of list parameters are
+// split on commas and each element is quoted separately.
+func (name PGSettingName) SanitizeValue(value string) string {
+	if !name.IsList() {
+		return PGValue(value).Sanitize()
+	}
+
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		v = strings.Trim(strings.TrimSpace(v), `"`)
+		if v == "" {
+			continue
+		}
+
+		values = append(values, PGValue(v).Sanitize())
+	}
+
+	if len(values) == 0 {
+		return PGValue("").Sanitize()
+	}
+
+	return strings.Join(values, ", ")
+}
+
+// PGExtensionName a PostgreSQL extension name, unlike identifiers extension names can contain
+// hyphens such as "uuid-ossp".
+type PGExtensionName string
+
+// Validate returns the extension name or an error if it is not a valid extension name.
+func (name PGExtensionName) Validate() (string, error) {
+	s := string(name)
+
+	if !validExtRegex.MatchString(s) {
+		return s, fmt.Errorf("%w: invalid characters", ErrInvalidExt)
+	}
+
+	if len(s) > PostgreSQLNameDataLen-1 {
+		return s, fmt.Errorf("%w: name too long", ErrInvalidExt)
+	}
+
+	return s, nil
+}
+
+// Sanitize returns a sanitized string safe for SQL interpolation.
+func (name PGExtensionName) Sanitize() string {
+	s := strings.ReplaceAll(string(name), string([]byte{0}), "")
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -116,3 +116,134 @@ func TestPGValue_String(t *testing.T) {
 		})
 	}
 }
+
+func TestPGSettingName_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value       string
+		expect      string
+		expectError error
+	}{
+		{"statement_timeout", "statement_timeout", nil},
+		{"Work_Mem", "work_mem", nil},
+		{"myapp.tenant", "myapp.tenant", nil},
+		{"search_path;drop", "search_path;drop", valid.ErrInvalidSetting},
+		{"myapp.tenant.id", "myapp.tenant.id", valid.ErrInvalidSetting},
+		{"1timeout", "1timeout", valid.ErrInvalidSetting},
+		{"role", "role", valid.ErrInvalidSetting},
+		{"session_authorization", "session_authorization", valid.ErrInvalidSetting},
+		{strings.Repeat("a", valid.PostgreSQLNameDataLen), strings.Repeat("a", valid.PostgreSQLNameDataLen),
+			valid.ErrInvalidSetting},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			v, err := valid.PGSettingName(tt.value).Validate()
+			if !errors.Is(err, tt.expectError) {
+				t.Errorf("Validate(): error got:'%v' want:'%v'", err, tt.expectError)
+			}
+
+			if v != tt.expect {
+				t.Errorf("Validate(): got:'%s' want:'%s'", v, tt.expect)
+			}
+		})
+	}
+}
+
+func TestPGSettingName_SanitizeValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		value  string
+		expect string
+	}{
+		{"statement_timeout", "30s", `'30s'`},
+		{"application_name", "it's, mine", `'it''s, mine'`},
+		{"search_path", `"$user", public`, `'$user', 'public'`},
+		{"search_path", "app,,public", `'app', 'public'`},
+		{"search_path", "", `''`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"="+tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			if v := valid.PGSettingName(tt.name).SanitizeValue(tt.value); v != tt.expect {
+				t.Errorf("SanitizeValue(): got:'%s' want:'%s'", v, tt.expect)
+			}
+		})
+	}
+}
+
+func TestPGSchemaName_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value       string
+		expectError error
+		expectSQL   string
+	}{
+		{"app", nil, `"app"`},
+		{"v1", nil, `"v1"`},
+		{"_", nil, `"_"`},
+		{"app_data", nil, `"app_data"`},
+		{"app-data", nil, `"appdata"`},
+		{"1app", valid.ErrInvalidSchema, `"1app"`},
+		{"", valid.ErrInvalidSchema, `""`},
+		{"pg_app", valid.ErrInvalidSchema, `"pg_app"`},
+		{"PG_app", valid.ErrInvalidSchema, `"PG_app"`},
+		{strings.Repeat("a", valid.PostgreSQLNameDataLen), valid.ErrInvalidSchema,
+			`"` + strings.Repeat("a", valid.PostgreSQLNameDataLen) + `"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := valid.PGSchemaName(tt.value).Validate(); !errors.Is(err, tt.expectError) {
+				t.Errorf("Validate(): error got:'%v' want:'%v'", err, tt.expectError)
+			}
+
+			if v := valid.PGSchemaName(tt.value).Sanitize(); v != tt.expectSQL {
+				t.Errorf("Sanitize(): got:'%s' want:'%s'", v, tt.expectSQL)
+			}
+		})
+	}
+}
+
+func TestPGExtensionName_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value       string
+		expectError error
+		expectSQL   string
+	}{
+		{"pgcrypto", nil, `"pgcrypto"`},
+		{"uuid-ossp", nil, `"uuid-ossp"`},
+		{"pg_trgm", nil, `"pg_trgm"`},
+		{`pg"crypto`, valid.ErrInvalidExt, `"pg""crypto"`},
+		{"PostGIS", valid.ErrInvalidExt, `"PostGIS"`},
+		{"-postgis", valid.ErrInvalidExt, `"-postgis"`},
+		{strings.Repeat("a", valid.PostgreSQLNameDataLen), valid.ErrInvalidExt,
+			`"` + strings.Repeat("a", valid.PostgreSQLNameDataLen) + `"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := valid.PGExtensionName(tt.value).Validate(); !errors.Is(err, tt.expectError) {
+				t.Errorf("Validate(): error got:'%v' want:'%v'", err, tt.expectError)
+			}
+
+			if v := valid.PGExtensionName(tt.value).Sanitize(); v != tt.expectSQL {
+				t.Errorf("Sanitize(): got:'%s' want:'%s'", v, tt.expectSQL)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	"github.com/dosquad/database-operator/internal/valid"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupDatabaseAccountWebhookWithManager registers the DatabaseAccount webhooks with the manager.
+func SetupDatabaseAccountWebhookWithManager(
+	mgr ctrl.Manager,
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&dbov1.DatabaseAccount{}).
+		WithDefaulter(&DatabaseAccountCustomDefaulter{Client: mgr.GetClient(), Config: ctrlConfig}).
+		WithValidator(&DatabaseAccountCustomValidator{Client: mgr.GetClient(), Config: ctrlConfig}).
+		Complete()
+}
+
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:webhook:path=/mutate-dbo-dosquad-github-io-v1-databaseaccount,mutating=true,failurePolicy=fail,sideEffects=None,groups=dbo.dosquad.github.io,resources=databaseaccounts,verbs=create,versions=v1,name=mdatabaseaccount-v1.kb.io,admissionReviewVersions=v1
+
+// DatabaseAccountCustomDefaulter fills in the values an account does not set from the annotations of
+// its namespace and the controller configuration, so the resolved values are stored on the account.
+type DatabaseAccountCustomDefaulter struct {
+	Client client.Reader
+	Config *dbov1.DatabaseAccountControllerConfig
+}
+
+var _ admission.CustomDefaulter = &DatabaseAccountCustomDefaulter{}
+
+// Default fills in the defaults of the account when it is created, the account is rejected if an
+// annotation of the namespace has an invalid value. Updates are not defaulted so a change to the
+// namespace annotations or the controller configuration is not written to existing accounts.
+func (d *DatabaseAccountCustomDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	dbAccount, ok := obj.(*dbov1.DatabaseAccount)
+	if !ok {
+		return fmt.Errorf("expected a DatabaseAccount object but got %T", obj)
+	}
+
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.Operation != admissionv1.Create {
+		return nil
+	}
+
+	log.FromContext(ctx).V(1).Info("Defaulting for DatabaseAccount", "name", dbAccount.GetName())
+
+	if d.Config == nil {
+		return nil
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := d.Client.Get(ctx, client.ObjectKey{Name: requestNamespace(ctx, dbAccount)}, namespace); err != nil &&
+		!apierrors.IsNotFound(err) {
+		return err
+	}
+
+	if err := d.Config.Default(dbAccount, namespace.GetAnnotations()); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	return nil
+}
+
+// requestNamespace returns the namespace of the account, the namespace of the admission request is
+// used when the account does not set it.
+func requestNamespace(ctx context.Context, dbAccount *dbov1.DatabaseAccount) string {
+	if ns := dbAccount.GetNamespace(); ns != "" {
+		return ns
+	}
+
+	if req, err := admission.RequestFromContext(ctx); err == nil {
+		return req.Namespace
+	}
+
+	return ""
+}
+
+//+kubebuilder:webhook:path=/validate-dbo-dosquad-github-io-v1-databaseaccount,mutating=false,failurePolicy=fail,sideEffects=None,groups=dbo.dosquad.github.io,resources=databaseaccounts,verbs=create;update,versions=v1,name=vdatabaseaccount-v1.kb.io,admissionReviewVersions=v1
+
+// DatabaseAccountCustomValidator rejects accounts the controller would fail to reconcile, so the errors
+// are returned to the client instead of surfacing as an Error stage.
+type DatabaseAccountCustomValidator struct {
+	Client client.Reader
+	Config *dbov1.DatabaseAccountControllerConfig
+}
+
+var _ admission.CustomValidator = &DatabaseAccountCustomValidator{}
+
+// ValidateCreate validates the name, the secret, the relay and the settings of a new account.
+func (v *DatabaseAccountCustomValidator) ValidateCreate(
+	ctx context.Context,
+	obj runtime.Object,
+) (admission.Warnings, error) {
+	dbAccount, ok := obj.(*dbov1.DatabaseAccount)
+	if !ok {
+		return nil, fmt.Errorf("expected a DatabaseAccount object but got %T", obj)
+	}
+
+	log.FromContext(ctx).V(1).Info("Validation for DatabaseAccount upon creation", "name", dbAccount.GetName())
+
+	allErrs := validateName(dbAccount)
+	allErrs = append(allErrs, validateRelay(v.Config, dbAccount)...)
+	allErrs = append(allErrs, validateSettings(v.Config, dbAccount)...)
+
+	secretErrs, err := v.validateSecret(ctx, dbAccount)
+	if err != nil {
+		return nil, err
+	}
+	allErrs = append(allErrs, secretErrs...)
+
+	return nil, invalid(dbAccount, allErrs)
+}
+
+// ValidateUpdate rejects changes to the immutable fields, the relay and the settings are only validated
+// when they change so a change to the controller configuration does not block unrelated updates.
+func (v *DatabaseAccountCustomValidator) ValidateUpdate(
+	ctx context.Context,
+	oldObj, newObj runtime.Object,
+) (admission.Warnings, error) {
+	oldAccount, ok := oldObj.(*dbov1.DatabaseAccount)
+	if !ok {
+		return nil, fmt.Errorf("expected a DatabaseAccount object for the oldObj but got %T", oldObj)
+	}
+
+	dbAccount, ok := newObj.(*dbov1.DatabaseAccount)
+	if !ok {
+		return nil, fmt.Errorf("expected a DatabaseAccount object for the newObj but got %T", newObj)
+	}
+
+	log.FromContext(ctx).V(1).Info("Validation for DatabaseAccount upon update", "name", dbAccount.GetName())
+
+	specPath := field.NewPath("spec")
+	var allErrs field.ErrorList
+	for _, v := range []struct {
+		name       string
+		old, value string
+	}{
+		{"name", oldAccount.Spec.Name.String(), dbAccount.Spec.Name.String()},
+		{"secretName", oldAccount.Spec.SecretName, dbAccount.Spec.SecretName},
+		{"username", oldAccount.Spec.Username, dbAccount.Spec.Username},
+	} {
+		if v.old != v.value {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child(v.name), "field is immutable"))
+		}
+	}
+
+	if oldAccount.GetSpecCreateRelay() != dbAccount.GetSpecCreateRelay() ||
+		!equality.Semantic.DeepEqual(oldAccount.Spec.Relay, dbAccount.Spec.Relay) {
+		allErrs = append(allErrs, validateRelay(v.Config, dbAccount)...)
+	}
+
+	if !equality.Semantic.DeepEqual(oldAccount.Spec.RoleSettings, dbAccount.Spec.RoleSettings) ||
+		!equality.Semantic.DeepEqual(oldAccount.Spec.DatabaseSettings, dbAccount.Spec.DatabaseSettings) {
+		allErrs = append(allErrs, validateSettings(v.Config, dbAccount)...)
+	}
+
+	return nil, invalid(dbAccount, allErrs)
+}
+
+// ValidateDelete allows every account to be deleted.
+func (v *DatabaseAccountCustomValidator) ValidateDelete(
+	_ context.Context,
+	_ runtime.Object,
+) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateName returns an error if the name of the account is not a valid PostgreSQL identifier, or
+// would be changed by sanitizing it as the database and role would not have the name of the spec.
+func validateName(dbAccount *dbov1.DatabaseAccount) field.ErrorList {
+	if dbAccount.Spec.Name == "" {
+		return nil
+	}
+
+	path := field.NewPath("spec", "name")
+	name, err := valid.PGIdentifier(dbAccount.Spec.Name.String()).Validate()
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, dbAccount.Spec.Name.String(), err.Error())}
+	}
+
+	if sanitized := strings.ToLower(name); sanitized != dbAccount.Spec.Name.String() {
+		return field.ErrorList{field.Invalid(path, dbAccount.Spec.Name.String(),
+			fmt.Sprintf("must be a lowercase PostgreSQL identifier such as %q", sanitized))}
+	}
+
+	return nil
+}
+
+// validateRelay returns an error if the relay settings are not allowed by the controller configuration.
+func validateRelay(
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	dbAccount *dbov1.DatabaseAccount,
+) field.ErrorList {
+	if ctrlConfig == nil || !dbAccount.GetSpecCreateRelay() {
+		return nil
+	}
+
+	if err := ctrlConfig.ValidateRelay(dbAccount); err != nil {
+		return field.ErrorList{field.Invalid(field.NewPath("spec", "relay"), dbAccount.Spec.Relay, err.Error())}
+	}
+
+	return nil
+}
+
+// validateSettings returns an error for each role and database setting that is not allowed by the
+// controller configuration.
+func validateSettings(
+	ctrlConfig *dbov1.DatabaseAccountControllerConfig,
+	dbAccount *dbov1.DatabaseAccount,
+) field.ErrorList {
+	if ctrlConfig == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	for _, v := range []struct {
+		name     string
+		settings map[string]string
+	}{
+		{"roleSettings", dbAccount.Spec.RoleSettings},
+		{"databaseSettings", dbAccount.Spec.DatabaseSettings},
+	} {
+		for _, name := range slices.Sorted(maps.Keys(v.settings)) {
+			if !slices.Contains(ctrlConfig.AllowedSettings, name) {
+				allErrs = append(allErrs, field.Forbidden(
+					field.NewPath("spec", v.name).Key(name),
+					"setting is not allowed by the controller configuration",
+				))
+			}
+		}
+	}
+
+	return allErrs
+}
+
+// validateSecret returns an error if the secret of the account exists and is not owned by an account
+// with the same name, the controller would overwrite the credentials of another application. A secret
+// without an account owner is allowed when the secret policy adopts or replaces existing secrets.
+func (v *DatabaseAccountCustomValidator) validateSecret(
+	ctx context.Context,
+	dbAccount *dbov1.DatabaseAccount,
+) (field.ErrorList, error) {
+	secret := &corev1.Secret{}
+	if err := v.Client.Get(ctx, dbAccount.GetSecretName(), secret); apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	owned := false
+	for _, ref := range secret.OwnerReferences {
+		if ref.Kind == dbov1.KindDatabaseAccount && ref.Name == dbAccount.GetName() {
+			return nil, nil
+		}
+
+		owned = owned || ref.Kind == dbov1.KindDatabaseAccount
+	}
+
+	if !owned && dbAccount.GetSpecSecretPolicy() != dbov1.SecretPolicyFail {
+		return nil, nil
+	}
+
+	path := field.NewPath("spec", "secretName")
+	if dbAccount.Spec.SecretName == "" {
+		path = field.NewPath("metadata", "name")
+	}
+
+	return field.ErrorList{
+		field.Duplicate(path, dbAccount.GetSecretName().Name),
+	}, nil
+}
+
+// invalid returns the errors as an Invalid error of the account, nil is returned if there are none.
+func invalid(dbAccount *dbov1.DatabaseAccount, allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	gk := dbov1.GroupVersion.WithKind("DatabaseAccount").GroupKind()
+
+	return apierrors.NewInvalid(gk, dbAccount.GetName(), allErrs)
+}
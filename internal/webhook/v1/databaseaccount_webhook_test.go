@@ -0,0 +1,286 @@
+package v1_test
+
+import (
+	"context"
+	"testing"
+
+	dbov1 "github.com/dosquad/database-operator/api/v1"
+	v1test "github.com/dosquad/database-operator/api/v1/test"
+	webhookv1 "github.com/dosquad/database-operator/internal/webhook/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// newValidator returns a validator where the secrets in the map exist.
+func newValidator(secrets map[string]*corev1.Secret) *webhookv1.DatabaseAccountCustomValidator {
+	c := v1test.NewMockClient()
+	c.OnGet = func(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+		if v, ok := obj.(*corev1.Secret); ok && secrets[key.Name] != nil {
+			secrets[key.Name].DeepCopyInto(v)
+
+			return nil
+		}
+
+		return apierrors.NewNotFound(corev1.Resource("secrets"), key.Name)
+	}
+
+	return &webhookv1.DatabaseAccountCustomValidator{Client: c, Config: &dbov1.DatabaseAccountControllerConfig{
+		AllowedSettings: []string{"statement_timeout"},
+	}}
+}
+
+func TestDatabaseAccountCustomValidator_ValidateCreate(t *testing.T) {
+	t.Parallel()
+
+	owned := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		OwnerReferences: []metav1.OwnerReference{{Kind: "DatabaseAccount", Name: v1test.DBAccount}},
+	}}
+	foreign := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		OwnerReferences: []metav1.OwnerReference{{Kind: "DatabaseAccount", Name: "otheraccount"}},
+	}}
+
+	tests := []struct {
+		name      string
+		spec      dbov1.DatabaseAccountSpec
+		secrets   map[string]*corev1.Secret
+		expectErr bool
+	}{
+		{"Valid", dbov1.DatabaseAccountSpec{}, nil, false},
+		{"Name", dbov1.DatabaseAccountSpec{Name: "app_db"}, nil, false},
+		{"InvalidName", dbov1.DatabaseAccountSpec{Name: "postgres"}, nil, true},
+		{"MixedCaseName", dbov1.DatabaseAccountSpec{Name: "App_DB"}, nil, true},
+		{"SanitizedName", dbov1.DatabaseAccountSpec{Name: "app-db"}, nil, true},
+		{"OwnedSecret", dbov1.DatabaseAccountSpec{}, map[string]*corev1.Secret{v1test.DBAccount: owned}, false},
+		{"ForeignSecret", dbov1.DatabaseAccountSpec{}, map[string]*corev1.Secret{v1test.DBAccount: foreign}, true},
+		{
+			"UnmanagedSecretName",
+			dbov1.DatabaseAccountSpec{SecretName: "app-credentials"},
+			map[string]*corev1.Secret{"app-credentials": {}},
+			true,
+		},
+		{
+			"UnmanagedSecretAdopt",
+			dbov1.DatabaseAccountSpec{SecretName: "app-credentials", SecretPolicy: dbov1.SecretPolicyAdopt},
+			map[string]*corev1.Secret{"app-credentials": {}},
+			false,
+		},
+		{
+			"ForeignSecretReplace",
+			dbov1.DatabaseAccountSpec{SecretPolicy: dbov1.SecretPolicyReplace},
+			map[string]*corev1.Secret{v1test.DBAccount: foreign},
+			true,
+		},
+		{"Relay", dbov1.DatabaseAccountSpec{Relay: &dbov1.DatabaseAccountRelay{}}, nil, false},
+		{
+			"RelayWithoutImage",
+			dbov1.DatabaseAccountSpec{Relay: &dbov1.DatabaseAccountRelay{Type: dbov1.RelayTypeOdyssey}},
+			nil,
+			true,
+		},
+		{
+			"SharedRelayTLS",
+			dbov1.DatabaseAccountSpec{Relay: &dbov1.DatabaseAccountRelay{
+				Shared: true,
+				TLS:    &dbov1.RelayTLS{Client: &dbov1.RelayClientTLS{}},
+			}},
+			nil,
+			true,
+		},
+		{
+			"RoleSetting",
+			dbov1.DatabaseAccountSpec{RoleSettings: map[string]string{"statement_timeout": "30s"}},
+			nil,
+			false,
+		},
+		{
+			"RoleSettingNotAllowed",
+			dbov1.DatabaseAccountSpec{RoleSettings: map[string]string{"log_statement": "none"}},
+			nil,
+			true,
+		},
+		{
+			"DatabaseSettingNotAllowed",
+			dbov1.DatabaseAccountSpec{DatabaseSettings: map[string]string{"log_statement": "none"}},
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dbAccount := v1test.NewDatabaseAccount()
+			dbAccount.Spec = tt.spec
+
+			_, err := newValidator(tt.secrets).ValidateCreate(t.Context(), &dbAccount)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("DatabaseAccountCustomValidator.ValidateCreate(): error, got '%v', want error '%t'",
+					err, tt.expectErr)
+			}
+
+			if err != nil && !apierrors.IsInvalid(err) {
+				t.Errorf("DatabaseAccountCustomValidator.ValidateCreate(): error, got '%v', want Invalid", err)
+			}
+		})
+	}
+}
+
+func TestDatabaseAccountCustomValidator_ValidateUpdate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		mutate    func(dbAccount *dbov1.DatabaseAccount)
+		expectErr bool
+	}{
+		{"Unchanged", func(*dbov1.DatabaseAccount) {}, false},
+		{"OnDelete", func(d *dbov1.DatabaseAccount) { d.Spec.OnDelete = dbov1.OnDeleteRetain }, false},
+		{"Name", func(d *dbov1.DatabaseAccount) { d.Spec.Name = "other_db" }, true},
+		{"SecretName", func(d *dbov1.DatabaseAccount) { d.Spec.SecretName = "other-secret" }, true},
+		{"Username", func(d *dbov1.DatabaseAccount) { d.Spec.Username = "other_user" }, true},
+		{
+			"InvalidRelay",
+			func(d *dbov1.DatabaseAccount) { d.Spec.Relay = &dbov1.DatabaseAccountRelay{Type: "pgpool"} },
+			true,
+		},
+		{
+			"RoleSetting",
+			func(d *dbov1.DatabaseAccount) { d.Spec.RoleSettings = map[string]string{"statement_timeout": "30s"} },
+			false,
+		},
+		{
+			"DatabaseSettingNotAllowed",
+			func(d *dbov1.DatabaseAccount) { d.Spec.DatabaseSettings = map[string]string{"work_mem": "64MB"} },
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			oldAccount := v1test.NewDatabaseAccount()
+			oldAccount.Spec.Name = "app_db"
+			dbAccount := *oldAccount.DeepCopy()
+			tt.mutate(&dbAccount)
+
+			_, err := newValidator(nil).ValidateUpdate(t.Context(), &oldAccount, &dbAccount)
+			if (err != nil) != tt.expectErr {
+				t.Errorf("DatabaseAccountCustomValidator.ValidateUpdate(): error, got '%v', want error '%t'",
+					err, tt.expectErr)
+			}
+		})
+	}
+}
+
+func TestDatabaseAccountCustomValidator_ValidateUpdate_UnchangedRelay(t *testing.T) {
+	t.Parallel()
+
+	// the relay was valid when it was created, an image removed from the configuration since then
+	// does not block other changes.
+	oldAccount := v1test.NewDatabaseAccount()
+	oldAccount.Spec.Relay = &dbov1.DatabaseAccountRelay{Type: dbov1.RelayTypeOdyssey}
+	dbAccount := *oldAccount.DeepCopy()
+	dbAccount.Spec.OnDelete = dbov1.OnDeleteRetain
+
+	if _, err := newValidator(nil).ValidateUpdate(t.Context(), &oldAccount, &dbAccount); err != nil {
+		t.Errorf("DatabaseAccountCustomValidator.ValidateUpdate(): error, got '%v', want 'nil'", err)
+	}
+}
+
+func TestDatabaseAccountCustomDefaulter_Default(t *testing.T) {
+	t.Parallel()
+
+	c := v1test.NewMockClient()
+	c.OnGet = func(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+		v, ok := obj.(*corev1.Namespace)
+		if !ok || key.Name != "default" {
+			return apierrors.NewNotFound(corev1.Resource("namespaces"), key.Name)
+		}
+
+		v.Name = key.Name
+		v.Annotations = map[string]string{dbov1.AnnotationDefaultOnDelete: "retain"}
+
+		return nil
+	}
+
+	defaulter := &webhookv1.DatabaseAccountCustomDefaulter{
+		Client: c,
+		Config: &dbov1.DatabaseAccountControllerConfig{
+			AccountDefaults: dbov1.DatabaseAccountDefaults{
+				OnDelete:     dbov1.OnDeleteDelete,
+				SecretLabels: map[string]string{"managed": "dbo"},
+			},
+		},
+	}
+
+	dbAccount := v1test.NewDatabaseAccount()
+	if err := defaulter.Default(t.Context(), &dbAccount); err != nil {
+		t.Fatalf("DatabaseAccountCustomDefaulter.Default(): error, got '%v', want 'nil'", err)
+	}
+
+	// the namespace annotation takes precedence over the controller configuration.
+	if v := dbAccount.Spec.OnDelete; v != dbov1.OnDeleteRetain {
+		t.Errorf("DatabaseAccountCustomDefaulter.Default(): onDelete, got '%s', want '%s'", v, dbov1.OnDeleteRetain)
+	}
+
+	if v := dbAccount.Spec.SecretTemplate.Labels["managed"]; v != "dbo" {
+		t.Errorf("DatabaseAccountCustomDefaulter.Default(): secret label, got '%s', want 'dbo'", v)
+	}
+}
+
+func TestDatabaseAccountCustomDefaulter_Default_Update(t *testing.T) {
+	t.Parallel()
+
+	c := v1test.NewMockClient()
+	c.OnGet = func(_ context.Context, _ client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+		obj.SetAnnotations(map[string]string{dbov1.AnnotationDefaultOnDelete: "retain"})
+
+		return nil
+	}
+
+	defaulter := &webhookv1.DatabaseAccountCustomDefaulter{
+		Client: c,
+		Config: &dbov1.DatabaseAccountControllerConfig{
+			AccountDefaults: dbov1.DatabaseAccountDefaults{SecretLabels: map[string]string{"managed": "dbo"}},
+		},
+	}
+
+	ctx := admission.NewContextWithRequest(t.Context(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Update},
+	})
+	dbAccount := v1test.NewDatabaseAccount()
+	want := dbAccount.DeepCopy()
+	if err := defaulter.Default(ctx, &dbAccount); err != nil {
+		t.Fatalf("DatabaseAccountCustomDefaulter.Default(): error, got '%v', want 'nil'", err)
+	}
+
+	// the defaults are only filled in when the account is created.
+	if !equality.Semantic.DeepEqual(&dbAccount, want) {
+		t.Errorf("DatabaseAccountCustomDefaulter.Default(): account changed on update, got '%+v'", dbAccount.Spec)
+	}
+}
+
+func TestDatabaseAccountCustomDefaulter_Default_InvalidAnnotation(t *testing.T) {
+	t.Parallel()
+
+	c := v1test.NewMockClient()
+	c.OnGet = func(_ context.Context, _ client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+		obj.SetAnnotations(map[string]string{dbov1.AnnotationDefaultOnDelete: "keep"})
+
+		return nil
+	}
+
+	defaulter := &webhookv1.DatabaseAccountCustomDefaulter{Client: c, Config: &dbov1.DatabaseAccountControllerConfig{}}
+
+	dbAccount := v1test.NewDatabaseAccount()
+	if err := defaulter.Default(t.Context(), &dbAccount); !apierrors.IsBadRequest(err) {
+		t.Errorf("DatabaseAccountCustomDefaulter.Default(): error, got '%v', want BadRequest", err)
+	}
+}